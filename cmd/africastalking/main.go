@@ -10,6 +10,7 @@ import (
 	"path"
 	"strconv"
 	"syscall"
+	"time"
 
 	"git.defalsify.org/vise.git/engine"
 	"git.defalsify.org/vise.git/lang"
@@ -22,7 +23,9 @@ import (
 
 	"git.grassecon.net/grassrootseconomics/sarafu-vise/args"
 	"git.grassecon.net/grassrootseconomics/sarafu-vise/handlers"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/internal/scheduler"
 	"git.grassecon.net/grassrootseconomics/sarafu-vise/services"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
 	at "git.grassecon.net/grassrootseconomics/visedriver-africastalking/africastalking"
 )
 
@@ -45,6 +48,7 @@ func main() {
 	var gettextDir string
 	var langs args.LangVar
 	var logDbConnStr string
+	var schedulerInterval time.Duration
 
 	flag.BoolVar(&engineDebug, "d", false, "use engine debug output")
 	flag.StringVar(&override.DbConn, "c", "?", "default connection string (replaces all unspecified strings)")
@@ -57,6 +61,7 @@ func main() {
 	flag.StringVar(&gettextDir, "gettext", "", "use gettext translations from given directory")
 	flag.Var(&langs, "language", "add symbol resolution for language")
 	flag.StringVar(&logDbConnStr, "log-c", "db-logs", "log db connection string")
+	flag.DurationVar(&schedulerInterval, "scheduler-interval", config.SchedulerInterval(), "time between checks for due scheduled transfers, 0 to not run the scheduler")
 	flag.Parse()
 
 	config.Apply(override)
@@ -135,6 +140,10 @@ func main() {
 		os.Exit(1)
 	}
 
+	// the scheduler shares the userdata store, and the locks on its entries, with the menu handlers
+	sc := scheduler.NewScheduler(&store.UserDataStore{Db: userdataStore}, accountService, path.Join(scriptDir, "locale"))
+	sc.Start(ctx, schedulerInterval)
+
 	stateStore, err := menuStorageService.GetStateStore(ctx)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "getstatestore: %v\n", err)
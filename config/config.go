@@ -3,6 +3,7 @@ package config
 import (
 	"strconv"
 	"strings"
+	"time"
 
 	apiconfig "git.grassecon.net/grassrootseconomics/sarafu-api/config"
 	viseconfig "git.grassecon.net/grassrootseconomics/visedriver/config"
@@ -28,6 +29,25 @@ const (
 	defaultHTTPHost string = "127.0.0.1"
 	defaultHTTPPort uint   = 7123
 	defaultDomain          = "sarafu.local"
+
+	defaultRemoteRetries          uint = 1
+	defaultRemoteBreakerThreshold uint = 5
+	defaultRemoteBreakerCooldown  uint = 30
+	defaultRemoteCacheStats       uint = 600
+
+	defaultNewAccountDays uint = 0
+
+	defaultAuthorizationLifetime uint   = 120
+	minAuthorizationLifetime     uint   = 10
+	defaultAuthorizationActions  uint   = 5
+	defaultAuthorizationAlways   string = "get_mpesa_confirmation"
+	defaultAuthorizationNever    string = "my_balance,community_balance"
+
+	defaultPinLockouts string = "15,60,1440"
+
+	defaultGuardianThreshold uint = 2
+
+	defaultSchedulerInterval uint = 60
 )
 
 func LoadConfig() error {
@@ -150,3 +170,238 @@ func DefaultStableVoucherAddress() string {
 func DefaultStableVoucherDecimals() string {
 	return env.GetEnv("DEFAULT_STABLE_VOUCHER_DECIMALS", "")
 }
+
+// parse a comma separated list of name=value pairs into durations of the given unit.
+//
+// Invalid pairs are skipped.
+func parseDurations(raw string, unit time.Duration) map[string]time.Duration {
+	parsed := make(map[string]time.Duration)
+
+	if raw == "" {
+		return parsed
+	}
+
+	for _, item := range strings.Split(raw, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseUint(strings.TrimSpace(v), 10, 32)
+		if err != nil {
+			continue
+		}
+		parsed[strings.TrimSpace(k)] = time.Duration(n) * unit
+	}
+
+	return parsed
+}
+
+// parse a comma separated list of name=amount pairs.
+//
+// Invalid pairs are skipped.
+func parseAmounts(raw string) map[string]float64 {
+	parsed := make(map[string]float64)
+
+	if raw == "" {
+		return parsed
+	}
+
+	for _, item := range strings.Split(raw, ",") {
+		k, v, ok := strings.Cut(strings.TrimSpace(item), "=")
+		if !ok {
+			continue
+		}
+		f, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		if err != nil || f < 0 {
+			continue
+		}
+		parsed[strings.TrimSpace(k)] = f
+	}
+
+	return parsed
+}
+
+// parse a single amount, where an invalid or missing value means no limit.
+func parseLimit(raw string) float64 {
+	f, err := strconv.ParseFloat(raw, 64)
+	if err != nil || f < 0 {
+		return 0
+	}
+	return f
+}
+
+// SpendLimitTransaction returns the largest amount of a voucher that can be spent in a single transaction.
+//
+// A value of 0 means no limit.
+func SpendLimitTransaction() float64 {
+	return parseLimit(env.GetEnv("SPEND_LIMIT_TRANSACTION", "0"))
+}
+
+// SpendLimitDaily returns the largest amount of a voucher that can be spent within 24 hours.
+//
+// A value of 0 means no limit.
+func SpendLimitDaily() float64 {
+	return parseLimit(env.GetEnv("SPEND_LIMIT_DAILY", "0"))
+}
+
+// SpendLimitVoucher returns the per-voucher overrides of the daily spending limit, by token address.
+//
+// The value is a comma separated list of address=amount pairs, e.g. "0xd4c288865Ce=1000,0x765DE816845=50".
+func SpendLimitVoucher() map[string]float64 {
+	return parseAmounts(env.GetEnv("SPEND_LIMIT_VOUCHER", ""))
+}
+
+// NewAccountDays returns the number of days after activation during which an account is subject
+// to the new account spending limits.
+//
+// A value of 0 disables the new account spending limits.
+func NewAccountDays() uint {
+	return env.GetEnvUint("NEW_ACCOUNT_DAYS", defaultNewAccountDays)
+}
+
+// NewAccountSpendLimitTransaction returns the transaction spending limit of new accounts.
+//
+// A value of 0 means no limit beyond SpendLimitTransaction.
+func NewAccountSpendLimitTransaction() float64 {
+	return parseLimit(env.GetEnv("NEW_ACCOUNT_SPEND_LIMIT_TRANSACTION", "0"))
+}
+
+// NewAccountSpendLimitDaily returns the daily spending limit of new accounts.
+//
+// A value of 0 means no limit beyond the daily limit of the voucher.
+func NewAccountSpendLimitDaily() float64 {
+	return parseLimit(env.GetEnv("NEW_ACCOUNT_SPEND_LIMIT_DAILY", "0"))
+}
+
+// parse a comma separated list, skipping empty items.
+func parseList(raw string) []string {
+	var parsed []string
+	for _, item := range strings.Split(raw, ",") {
+		clean := strings.TrimSpace(item)
+		if clean != "" {
+			parsed = append(parsed, clean)
+		}
+	}
+	return parsed
+}
+
+// AuthorizationLifetime returns the time after a PIN entry during which it authorizes further actions.
+//
+// It is never shorter than the time needed to complete the action the PIN was entered for.
+func AuthorizationLifetime() time.Duration {
+	v := env.GetEnvUint("AUTHORIZATION_LIFETIME", defaultAuthorizationLifetime)
+	if v < minAuthorizationLifetime {
+		v = minAuthorizationLifetime
+	}
+	return time.Duration(v) * time.Second
+}
+
+// AuthorizationActions returns the number of further actions a PIN entry authorizes.
+//
+// A value of 0 means no limit.
+func AuthorizationActions() uint {
+	return env.GetEnvUint("AUTHORIZATION_ACTIONS", defaultAuthorizationActions)
+}
+
+// AuthorizationHighValue returns the transfer amount from which the PIN is always asked for.
+//
+// A value of 0 disables the check.
+func AuthorizationHighValue() float64 {
+	return parseLimit(env.GetEnv("AUTHORIZATION_HIGH_VALUE", "0"))
+}
+
+// AuthorizationAlways returns the menu nodes that always ask for the PIN, regardless of earlier PIN entries.
+func AuthorizationAlways() []string {
+	return parseList(env.GetEnv("AUTHORIZATION_ALWAYS", defaultAuthorizationAlways))
+}
+
+// AuthorizationNever returns the menu nodes that never ask for the PIN.
+func AuthorizationNever() []string {
+	return parseList(env.GetEnv("AUTHORIZATION_NEVER", defaultAuthorizationNever))
+}
+
+// PinLockouts returns the durations of the successive temporary lockouts of an account after too many
+// incorrect PIN attempts. Once all of them have been used, the account is blocked until an admin resets the PIN.
+//
+// The value is a comma separated list of minutes, e.g. "15,60,1440". Invalid items are skipped.
+func PinLockouts() []time.Duration {
+	var parsed []time.Duration
+	for _, item := range parseList(env.GetEnv("PIN_LOCKOUT_MINUTES", defaultPinLockouts)) {
+		v, err := strconv.ParseUint(item, 10, 64)
+		if err != nil || v == 0 {
+			continue
+		}
+		parsed = append(parsed, time.Duration(v)*time.Minute)
+	}
+	return parsed
+}
+
+// GuardianThreshold returns the number of guardians that must approve the recovery of the PIN of an account.
+//
+// The menu handlers lower it to the number of guardians an account can nominate.
+func GuardianThreshold() uint {
+	v := env.GetEnvUint("GUARDIAN_THRESHOLD", defaultGuardianThreshold)
+	if v == 0 {
+		return defaultGuardianThreshold
+	}
+	return v
+}
+
+// SmsGatewayURL returns the endpoint of the HTTP SMS gateway sending messages of arbitrary content,
+// such as statements and notifications.
+//
+// If empty, such messages can only be sent by account services able to.
+func SmsGatewayURL() string {
+	return env.GetEnv("SMS_GATEWAY_URL", "")
+}
+
+// SmsGatewayToken returns the bearer token authorizing requests to the SMS gateway.
+func SmsGatewayToken() string {
+	return env.GetEnv("SMS_GATEWAY_TOKEN", "")
+}
+
+// RemoteCacheTTL returns the per-method overrides of the time remote call results are cached.
+//
+// The value is a comma separated list of method=seconds pairs, e.g. "FetchVouchers=30,FetchTopPools=600".
+// A value of 0 disables caching for the method.
+func RemoteCacheTTL() map[string]time.Duration {
+	return parseDurations(env.GetEnv("REMOTE_CACHE_TTL", ""), time.Second)
+}
+
+// RemoteCacheStatsInterval returns the interval at which the hit rates of the remote call cache are logged.
+//
+// A value of 0 disables the logging.
+func RemoteCacheStatsInterval() time.Duration {
+	return time.Duration(env.GetEnvUint("REMOTE_CACHE_STATS_INTERVAL", defaultRemoteCacheStats)) * time.Second
+}
+
+// RemoteTimeout returns the per-method overrides of the time allowed for a single remote call.
+//
+// The value is a comma separated list of method=milliseconds pairs, e.g. "FetchVouchers=1500".
+func RemoteTimeout() map[string]time.Duration {
+	return parseDurations(env.GetEnv("REMOTE_TIMEOUT", ""), time.Millisecond)
+}
+
+// RemoteRetries returns the number of times a failed remote read is retried.
+func RemoteRetries() uint {
+	return env.GetEnvUint("REMOTE_RETRIES", defaultRemoteRetries)
+}
+
+// RemoteBreakerThreshold returns the number of consecutive remote call failures after which calls are suspended.
+func RemoteBreakerThreshold() uint {
+	return env.GetEnvUint("REMOTE_BREAKER_THRESHOLD", defaultRemoteBreakerThreshold)
+}
+
+// RemoteBreakerCooldown returns the time remote calls stay suspended before they are tried again.
+func RemoteBreakerCooldown() time.Duration {
+	return time.Duration(env.GetEnvUint("REMOTE_BREAKER_COOLDOWN", defaultRemoteBreakerCooldown)) * time.Second
+}
+
+// SchedulerInterval returns the time between checks for due scheduled transfers by the scheduler
+// running inside the server.
+//
+// A value of 0 disables the scheduler. Only one server process may run the scheduler for a
+// userdata store.
+func SchedulerInterval() time.Duration {
+	return time.Duration(env.GetEnvUint("SCHEDULER_INTERVAL", defaultSchedulerInterval)) * time.Second
+}
@@ -16,6 +16,8 @@ type KeyInfo struct {
 	SessionId   string
 	Typ         uint8
 	SubTyp      storedb.DataTyp
+	Scope       storedb.Scope
+	Index       string
 	Label       string
 	Description string
 }
@@ -41,10 +43,25 @@ func ToKeyInfo(k []byte, sessionId string) (KeyInfo, error) {
 		if len(k) == 0 {
 			return o, fmt.Errorf("missing subtype key")
 		}
+		switch k[0] {
+		case storedb.KEY_MARKER_MENU:
+			o.Scope = storedb.SCOPE_MENU
+			k = k[1:]
+		case storedb.KEY_MARKER_INDEX:
+			o.Scope = storedb.SCOPE_INDEX
+			k = k[1:]
+		}
+		if len(k) < 2 {
+			return o, fmt.Errorf("missing subtype key")
+		}
 		v := binary.BigEndian.Uint16(k[:2])
 		o.SubTyp = storedb.DataTyp(v)
 		o.Label = subTypToString(o.SubTyp)
 		k = k[2:]
+		if o.Scope == storedb.SCOPE_INDEX {
+			o.Index = string(k)
+			k = k[len(k):]
+		}
 		if len(k) != 0 {
 			return o, fmt.Errorf("excess key information: %x", k)
 		}
@@ -33,6 +33,44 @@ func init() {
 	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_INCORRECT_PIN_ATTEMPTS] = "incorrect pin attempts"
 	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_SELECTED_LANGUAGE_CODE] = "selected language"
 	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_INITIAL_LANGUAGE_CODE] = "initial language"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_ALIAS_REVERSE] = "alias reverse"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_ALIAS_ADDRESS] = "alias address"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_TRANSACTION_NONCE] = "transaction nonce"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_INTENT_LIST] = "intent list"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_TRANSACTION_LEDGER] = "transaction ledger"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_ACCOUNT_ACTIVATED] = "account activated"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_STATEMENT_FILTER] = "statement filter"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_STATEMENT_PAGE] = "statement page"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_STATEMENT_SMS_LOG] = "statement sms log"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_STATEMENT_SELECTED] = "statement selected"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_CONTACTS] = "contacts"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_RECENT_RECIPIENTS] = "recent recipients"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_CONTACT_SELECTED] = "contact selected"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_SCHEDULES] = "schedules"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_SCHEDULE_INTERVAL] = "schedule interval"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_SCHEDULE_SELECTED] = "schedule selected"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_SCHEDULED_SESSIONS] = "scheduled sessions"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_PAYMENT_REQUESTS] = "payment requests"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_PAYMENT_REQUEST_LOG] = "payment request log"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_PAYMENT_REQUEST_SELECTED] = "payment request selected"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_CONTACT_GROUPS] = "contact groups"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_CONTACT_GROUP_SELECTED] = "contact group selected"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_MULTI_SEND_LEGS] = "multi send legs"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_MULTI_SENDS] = "multi sends"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_SPEND_LOG] = "spend log"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_SPEND_LIMIT_SYMBOL] = "spend limit symbol"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_AUTHORIZATION] = "authorization"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_PIN_LOCKOUTS] = "pin lockouts"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_PIN_LOCKOUT_EXPIRY] = "pin lockout expiry"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_GUARDIANS] = "guardians"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_GUARDIAN_SELECTED] = "guardian selected"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_RECOVERY] = "recovery"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_RECOVERY_REQUESTS] = "recovery requests"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_RECOVERY_SELECTED] = "recovery selected"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_AUDIT_LOG] = "audit log"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_ROLES] = "roles"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_PRIVACY_REQUESTS] = "privacy requests"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_SPEND_LIMIT_ADDRESS] = "spend limit address"
 	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_VOUCHER_SYMBOLS] = "voucher symbols"
 	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_VOUCHER_BALANCES] = "voucher balances"
 	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_VOUCHER_DECIMALS] = "voucher decimals"
@@ -45,4 +83,11 @@ func init() {
 	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_TX_DATES] = "tx dates"
 	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_TX_SYMBOLS] = "tx symbols"
 	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_TX_DECIMALS] = "tx decimals"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_VOUCHER_LIST] = "voucher list"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_ORDERED_VOUCHER_LIST] = "ordered voucher list"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_TX_LIST] = "tx list"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_TRANSACTIONS] = "transfer history"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_POOL_LIST] = "pool list"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_POOL_FROM_LIST] = "pool swap from list"
+	dbTypStr[db.DATATYPE_USERDATA+1+storedb.DATA_POOL_TO_LIST] = "pool swap to list"
 }
@@ -74,3 +74,38 @@ func TestDebugDbKeyInfoRestore(t *testing.T) {
 		}
 	}
 }
+
+func TestDebugDbKeyInfoScope(t *testing.T) {
+	s := "bar"
+	b := []byte{visedb.DATATYPE_USERDATA}
+	k := storedb.MenuKey(s, storedb.DATA_AMOUNT).Bytes()
+	b = append(b, k...)
+
+	r, err := ToKeyInfo(b, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Scope != storedb.SCOPE_MENU {
+		t.Fatalf("expected menu scope, got %d", r.Scope)
+	}
+	if r.SubTyp != storedb.DATA_AMOUNT {
+		t.Fatalf("expected %d, got %d", storedb.DATA_AMOUNT, r.SubTyp)
+	}
+
+	b = []byte{visedb.DATATYPE_USERDATA}
+	k = storedb.IndexKey(storedb.DATA_PUBLIC_KEY_REVERSE, "deadbeef").Bytes()
+	b = append(b, k...)
+	r, err = ToKeyInfo(b, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Scope != storedb.SCOPE_INDEX {
+		t.Fatalf("expected index scope, got %d", r.Scope)
+	}
+	if r.SubTyp != storedb.DATA_PUBLIC_KEY_REVERSE {
+		t.Fatalf("expected %d, got %d", storedb.DATA_PUBLIC_KEY_REVERSE, r.SubTyp)
+	}
+	if r.Index != "deadbeef" {
+		t.Fatalf("expected 'deadbeef', got '%s'", r.Index)
+	}
+}
@@ -25,13 +25,17 @@ func main() {
 
 	override := config.NewOverride()
 	var sessionId string
+	var actor string
+	var logDbConnStr string
 
 	flag.StringVar(&sessionId, "session-id", "075xx2123", "session id")
+	flag.StringVar(&actor, "actor", os.Getenv("USER"), "name of the operator, recorded in the audit log")
 	flag.StringVar(&override.DbConn, "c", "?", "default connection string (replaces all unspecified strings)")
 	flag.StringVar(&override.ResourceConn, "resource", "?", "resource data directory")
 
 	flag.StringVar(&override.UserConn, "userdata", "?", "userdata store connection string")
 	flag.StringVar(&override.StateConn, "state", "?", "state store connection string")
+	flag.StringVar(&logDbConnStr, "log-c", "db-logs", "log db connection string, empty to skip the log db")
 	flag.Parse()
 
 	config.Apply(override)
@@ -50,7 +54,13 @@ func main() {
 		os.Exit(1)
 	}
 
+	if actor == "" {
+		fmt.Fprintf(os.Stderr, "no actor given\n")
+		os.Exit(1)
+	}
+
 	x := cmd.NewCmd(sessionId, flagParser)
+	x.SetActor(actor)
 	err = x.Parse(flag.Args())
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "cmd parse fail: %v\n", err)
@@ -65,6 +75,20 @@ func main() {
 		os.Exit(1)
 	}
 
+	if logDbConnStr != "" {
+		userdataStore, err := menuStorageService.GetUserdataDb(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "get userdata db error: %v\n", err)
+			os.Exit(1)
+		}
+		logdb, err := menuStorageService.GetLogDb(ctx, userdataStore, logDbConnStr, "user-data")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "get log db error: %v\n", err)
+			os.Exit(1)
+		}
+		x.SetLogDb(logdb)
+	}
+
 	err = x.Exec(ctx, menuStorageService)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "cmd exec error: %v\n", err)
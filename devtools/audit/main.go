@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"git.defalsify.org/vise.git/logging"
+
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/config"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+	"git.grassecon.net/grassrootseconomics/visedriver/storage"
+)
+
+var (
+	logg = logging.NewVanilla()
+)
+
+// parse a time given either as a date or in RFC3339 format.
+//
+// An empty string gives the zero time.
+func parseTime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", s, time.Local)
+}
+
+func main() {
+	config.LoadConfig()
+
+	override := config.NewOverride()
+	var filter store.AuditFilter
+	var since string
+	var until string
+
+	flag.StringVar(&filter.Actor, "actor", "", "only list entries of this actor")
+	flag.StringVar(&filter.Target, "target", "", "only list entries on this target session id")
+	flag.StringVar(&since, "since", "", "only list entries at or after this time (YYYY-MM-DD or RFC3339)")
+	flag.StringVar(&until, "until", "", "only list entries before this time (YYYY-MM-DD or RFC3339)")
+	flag.StringVar(&override.DbConn, "c", "?", "default connection string (replaces all unspecified strings)")
+	flag.StringVar(&override.UserConn, "userdata", "?", "userdata store connection string")
+	flag.Parse()
+
+	var err error
+	filter.Since, err = parseTime(since)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid since time: %v\n", err)
+		os.Exit(1)
+	}
+	filter.Until, err = parseTime(until)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "invalid until time: %v\n", err)
+		os.Exit(1)
+	}
+
+	config.Apply(override)
+	conns, err := config.GetConns()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "conn specification error: %v\n", err)
+		os.Exit(1)
+	}
+
+	logg.Infof("start command", "conn", conns, "filter", filter)
+
+	ctx := context.Background()
+	menuStorageService := storage.NewMenuStorageService(conns)
+	userdataStore, err := menuStorageService.GetUserdataDb(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "get userdata db error: %v\n", err)
+		os.Exit(1)
+	}
+	userStore := &store.UserDataStore{
+		Db: userdataStore,
+	}
+
+	records, err := store.ReadAudit(ctx, userStore, filter)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "read audit log error: %v\n", err)
+		os.Exit(1)
+	}
+	for _, r := range records {
+		fmt.Printf("%s\t%s\t%s\t%s\t%s\t%s", r.Id, time.Unix(r.Time, 0).Format(time.RFC3339), r.Actor, r.Target, r.Action, r.Outcome)
+		if r.Detail != "" {
+			fmt.Printf("\t%s", r.Detail)
+		}
+		fmt.Println()
+	}
+
+	err = userdataStore.Close(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "close userdata db error: %v\n", err)
+		os.Exit(1)
+	}
+}
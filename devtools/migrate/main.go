@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"git.defalsify.org/vise.git/logging"
+
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/config"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+	"git.grassecon.net/grassrootseconomics/visedriver/storage"
+)
+
+var (
+	logg = logging.NewVanilla()
+)
+
+// read session ids from file, one per line.
+func readSessions(fp string) ([]string, error) {
+	var r []string
+	f, err := os.Open(fp)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		r = append(r, s)
+	}
+	return r, scanner.Err()
+}
+
+func main() {
+	config.LoadConfig()
+
+	override := config.NewOverride()
+	var sessionFile string
+	var all bool
+
+	flag.StringVar(&sessionFile, "f", "", "file with session ids to migrate, one per line")
+	flag.BoolVar(&all, "a", false, "migrate all accounts found in the userdata store")
+	flag.StringVar(&override.DbConn, "c", "?", "default connection string (replaces all unspecified strings)")
+	flag.StringVar(&override.UserConn, "userdata", "?", "userdata store connection string")
+	flag.Parse()
+
+	sessionIds := flag.Args()
+	if sessionFile != "" {
+		r, err := readSessions(sessionFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "read session file error: %v\n", err)
+			os.Exit(1)
+		}
+		sessionIds = append(sessionIds, r...)
+	}
+	if len(sessionIds) == 0 && !all {
+		fmt.Fprintf(os.Stderr, "no session ids given, use -a to migrate all accounts\n")
+		os.Exit(1)
+	}
+
+	config.Apply(override)
+	conns, err := config.GetConns()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "conn specification error: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	menuStorageService := storage.NewMenuStorageService(conns)
+	userdataStore, err := menuStorageService.GetUserdataDb(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "get userdata db error: %v\n", err)
+		os.Exit(1)
+	}
+	userStore := &store.UserDataStore{
+		Db: userdataStore,
+	}
+
+	if all {
+		r, err := store.ListSessions(ctx, userStore)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "list sessions error: %v\n", err)
+			os.Exit(1)
+		}
+		sessionIds = append(sessionIds, r...)
+	}
+	logg.Infof("start command", "conn", conns, "sessions", len(sessionIds))
+
+	var total int
+	var failed int
+	for _, sessionId := range sessionIds {
+		c, err := store.MigrateKeys(ctx, userStore, sessionId)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate session %s error: %v\n", sessionId, err)
+			failed++
+			continue
+		}
+		total += c
+	}
+
+	err = userdataStore.Close(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "close userdata db error: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("migrated %d entries for %d sessions\n", total, len(sessionIds)-failed)
+	if failed > 0 {
+		fmt.Fprintf(os.Stderr, "%d sessions failed\n", failed)
+		os.Exit(1)
+	}
+}
@@ -7,11 +7,14 @@ import (
 
 	"git.defalsify.org/vise.git/db"
 	"git.defalsify.org/vise.git/resource"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
 	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
 )
 
-// CheckAccountStatus queries the API using the TrackingId and sets flags
-// based on the account status.
+// CheckAccountStatus sets flags based on the account status.
+//
+// Accounts whose activation has been recorded are not checked further; otherwise the
+// status is queried from the API, and the activation is recorded once it is reported.
 func (h *MenuHandlers) CheckAccountStatus(ctx context.Context, sym string, input []byte) (resource.Result, error) {
 	var res resource.Result
 
@@ -24,8 +27,19 @@ func (h *MenuHandlers) CheckAccountStatus(ctx context.Context, sym string, input
 		return res, fmt.Errorf("missing session")
 	}
 
-	store := h.userdataStore
-	publicKey, err := store.ReadEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY)
+	userStore := h.userdataStore
+	active, err := store.IsAccountActive(ctx, userStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read account activation", "error", err)
+		return res, err
+	}
+	if active {
+		res.FlagSet = append(res.FlagSet, flag_account_success)
+		res.FlagReset = append(res.FlagReset, flag_api_error, flag_account_pending)
+		return res, nil
+	}
+
+	publicKey, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY)
 	if err != nil {
 		logg.ErrorCtxf(ctx, "failed to read publicKey entry with", "key", storedb.DATA_PUBLIC_KEY, "error", err)
 		return res, err
@@ -41,6 +55,12 @@ func (h *MenuHandlers) CheckAccountStatus(ctx context.Context, sym string, input
 	res.FlagReset = append(res.FlagReset, flag_api_error)
 
 	if r.Active {
+		// record the activation, in case the registration event was missed
+		_, err = store.MarkAccountActive(ctx, userStore, sessionId)
+		if err != nil {
+			logg.ErrorCtxf(ctx, "failed to record account activation", "error", err)
+			return res, err
+		}
 		res.FlagSet = append(res.FlagSet, flag_account_success)
 		res.FlagReset = append(res.FlagReset, flag_account_pending)
 	} else {
@@ -63,7 +83,11 @@ func (h *MenuHandlers) CheckAccountCreated(ctx context.Context, sym string, inpu
 		return res, fmt.Errorf("missing session")
 	}
 
-	_, err := store.ReadEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY)
+	publicKey, err := store.ReadEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY)
+	if err == nil && len(publicKey) == 0 {
+		// the entries of a deleted account are left empty
+		err = db.NewErrNotFound([]byte(sessionId))
+	}
 	if err != nil {
 		if db.IsNotFound(err) {
 			// reset major flags
@@ -82,7 +106,8 @@ func (h *MenuHandlers) CheckAccountCreated(ctx context.Context, sym string, inpu
 
 // CheckBlockedStatus:
 // 1. Checks whether the DATA_SELF_PIN_RESET is 1 and sets the flag_account_pin_reset
-// 2. resets the account blocked flag if the PIN attempts have been reset by an admin.
+// 2. resets the account blocked flag if the PIN attempts have been reset by an admin,
+// or if a temporary lockout is over.
 // 3. Sets key flags (language and PIN) if the data exists
 func (h *MenuHandlers) CheckBlockedStatus(ctx context.Context, sym string, input []byte) (resource.Result, error) {
 	var res resource.Result
@@ -125,6 +150,16 @@ func (h *MenuHandlers) CheckBlockedStatus(ctx context.Context, sym string, input
 		}
 	}
 
+	// a temporary lockout is lifted once it is over
+	lifted, err := h.liftExpiredLockout(ctx, sessionId)
+	if err != nil {
+		return res, err
+	}
+	if lifted {
+		res.FlagReset = append(res.FlagReset, flag_account_blocked)
+		return res, nil
+	}
+
 	currentWrongPinAttempts, err := store.ReadEntry(ctx, sessionId, storedb.DATA_INCORRECT_PIN_ATTEMPTS)
 	if err != nil {
 		if !db.IsNotFound(err) {
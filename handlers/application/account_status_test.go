@@ -8,14 +8,13 @@ import (
 	"git.defalsify.org/vise.git/state"
 	"git.grassecon.net/grassrootseconomics/sarafu-api/models"
 	"git.grassecon.net/grassrootseconomics/sarafu-api/testutil/mocks"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
 	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
 	"github.com/alecthomas/assert/v2"
 )
 
 func TestCheckAccountStatus(t *testing.T) {
-	sessionId := "session123"
-	ctx, store := InitializeTestStore(t)
-	ctx = context.WithValue(ctx, "SessionId", sessionId)
+	ctx, userStore := InitializeTestStore(t)
 
 	fm, err := NewFlagManager(flagsPath)
 	if err != nil {
@@ -27,12 +26,14 @@ func TestCheckAccountStatus(t *testing.T) {
 
 	tests := []struct {
 		name           string
+		sessionId      string
 		publicKey      []byte
 		response       *models.TrackStatusResult
 		expectedResult resource.Result
 	}{
 		{
 			name:      "Test when account is on the Sarafu network",
+			sessionId: "session123",
 			publicKey: []byte("TrackingId1234"),
 			response: &models.TrackStatusResult{
 				Active: true,
@@ -44,6 +45,7 @@ func TestCheckAccountStatus(t *testing.T) {
 		},
 		{
 			name:      "Test when the account is not yet on the sarafu network",
+			sessionId: "session456",
 			publicKey: []byte("TrackingId1234"),
 			response: &models.TrackStatusResult{
 				Active: false,
@@ -59,12 +61,13 @@ func TestCheckAccountStatus(t *testing.T) {
 			mockAccountService := new(mocks.MockAccountService)
 
 			h := &MenuHandlers{
-				userdataStore:  store,
+				userdataStore:  userStore,
 				accountService: mockAccountService,
 				flagManager:    fm,
 			}
+			ctx := context.WithValue(ctx, "SessionId", tt.sessionId)
 
-			err = store.WriteEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY, []byte(tt.publicKey))
+			err = userStore.WriteEntry(ctx, tt.sessionId, storedb.DATA_PUBLIC_KEY, []byte(tt.publicKey))
 			if err != nil {
 				t.Fatal(err)
 			}
@@ -79,10 +82,83 @@ func TestCheckAccountStatus(t *testing.T) {
 
 			//Assert that the account created flag has been set to the result
 			assert.Equal(t, res, tt.expectedResult, "Expected result should be equal to the actual result")
+
+			// the activation is recorded once it is reported
+			active, err := store.IsAccountActive(ctx, userStore, tt.sessionId)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.response.Active, active)
 		})
 	}
 }
 
+func TestCheckAccountStatusActivated(t *testing.T) {
+	sessionId := "session123"
+	ctx, userStore := InitializeTestStore(t)
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
+
+	fm, err := NewFlagManager(flagsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag_account_success, _ := fm.GetFlag("flag_account_success")
+	flag_account_pending, _ := fm.GetFlag("flag_account_pending")
+	flag_api_error, _ := fm.GetFlag("flag_api_call_error")
+
+	mockAccountService := new(mocks.MockAccountService)
+	h := &MenuHandlers{
+		userdataStore:  userStore,
+		accountService: mockAccountService,
+		flagManager:    fm,
+	}
+
+	_, err = store.MarkAccountActive(ctx, userStore, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := h.CheckAccountStatus(ctx, "check_account_status", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{
+		FlagSet:   []uint32{flag_account_success},
+		FlagReset: []uint32{flag_api_error, flag_account_pending},
+	}, res)
+	mockAccountService.AssertNotCalled(t, "TrackAccountStatus")
+}
+
+func TestCheckAccountCreatedDeleted(t *testing.T) {
+	sessionId := "session123"
+	ctx, userStore := InitializeTestStore(t)
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
+
+	fm, err := NewFlagManager(flagsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag_language_set, _ := fm.GetFlag("flag_language_set")
+	flag_account_created, _ := fm.GetFlag("flag_account_created")
+
+	h := &MenuHandlers{
+		userdataStore: userStore,
+		flagManager:   fm,
+	}
+
+	err = userStore.WriteEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY, []byte("0x5d5c2bd7C61C6F6D1D30E8B6C61E94AC2C4C2D92"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := h.CheckAccountCreated(ctx, "check_account_created", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagSet: []uint32{flag_account_created}}, res)
+
+	_, err = store.DeleteAccount(ctx, userStore, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = h.CheckAccountCreated(ctx, "check_account_created", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagReset: []uint32{flag_language_set, flag_account_created}}, res)
+}
+
 func TestCheckBlockedStatus(t *testing.T) {
 	ctx, store := InitializeTestStore(t)
 	sessionId := "session123"
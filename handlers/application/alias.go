@@ -9,6 +9,7 @@ import (
 	"git.defalsify.org/vise.git/db"
 	"git.defalsify.org/vise.git/resource"
 
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
 	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
 )
 
@@ -27,8 +28,8 @@ func (h *MenuHandlers) RequestCustomAlias(ctx context.Context, sym string, input
 	flag_api_error, _ := h.flagManager.GetFlag("flag_api_call_error")
 	flag_alias_unavailable, _ := h.flagManager.GetFlag("flag_alias_unavailable")
 
-	store := h.userdataStore
-	aliasHint, err := store.ReadEntry(ctx, sessionId, storedb.DATA_TEMPORARY_VALUE)
+	userStore := h.userdataStore
+	aliasHint, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_TEMPORARY_VALUE)
 	if err != nil {
 		if db.IsNotFound(err) {
 			return res, nil
@@ -37,11 +38,11 @@ func (h *MenuHandlers) RequestCustomAlias(ctx context.Context, sym string, input
 	}
 	//Ensures that the call doesn't happen twice for the same alias hint
 	if !bytes.Equal(aliasHint, input) {
-		err = store.WriteEntry(ctx, sessionId, storedb.DATA_TEMPORARY_VALUE, []byte(string(input)))
+		err = userStore.WriteEntry(ctx, sessionId, storedb.DATA_TEMPORARY_VALUE, []byte(string(input)))
 		if err != nil {
 			return res, err
 		}
-		publicKey, err := store.ReadEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY)
+		publicKey, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY)
 		if err != nil {
 			if db.IsNotFound(err) {
 				return res, nil
@@ -49,7 +50,7 @@ func (h *MenuHandlers) RequestCustomAlias(ctx context.Context, sym string, input
 		}
 		sanitizedInput := sanitizeAliasHint(string(input))
 		// Check if an alias already exists
-		existingAlias, err := store.ReadEntry(ctx, sessionId, storedb.DATA_ACCOUNT_ALIAS)
+		existingAlias, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_ACCOUNT_ALIAS)
 		if err == nil && len(existingAlias) > 0 {
 			logg.InfoCtxf(ctx, "Current alias", "alias", string(existingAlias))
 
@@ -98,11 +99,16 @@ func (h *MenuHandlers) RequestCustomAlias(ctx context.Context, sym string, input
 
 		//Store the new account alias
 		logg.InfoCtxf(ctx, "Final registered alias", "alias", alias)
-		err = store.WriteEntry(ctx, sessionId, storedb.DATA_ACCOUNT_ALIAS, []byte(alias))
+		err = userStore.WriteEntry(ctx, sessionId, storedb.DATA_ACCOUNT_ALIAS, []byte(alias))
 		if err != nil {
 			logg.ErrorCtxf(ctx, "failed to write account alias", "key", storedb.DATA_ACCOUNT_ALIAS, "value", alias, "error", err)
 			return res, err
 		}
+		err = store.WriteAliasIndex(ctx, userStore, sessionId, string(existingAlias), alias, string(publicKey))
+		if err != nil {
+			logg.ErrorCtxf(ctx, "failed to write alias index", "alias", alias, "error", err)
+			return res, err
+		}
 	}
 
 	return res, nil
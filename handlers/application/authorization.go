@@ -3,12 +3,105 @@ package application
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strconv"
 
+	"git.defalsify.org/vise.git/db"
 	"git.defalsify.org/vise.git/resource"
 	"git.grassecon.net/grassrootseconomics/common/pin"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/config"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
 	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
 )
 
+// authorization policies of menu nodes.
+const (
+	// a recent PIN entry authorizes the node, within the configured lifetime and number of actions.
+	authorizeRecent = iota
+	// the node always asks for the PIN.
+	authorizeAlways
+	// the node never asks for the PIN.
+	authorizeNever
+)
+
+// authorizationPolicy returns the configured authorization policy of the menu node with the given symbol.
+func authorizationPolicy(node string) int {
+	if slices.Contains(config.AuthorizationNever(), node) {
+		return authorizeNever
+	}
+	if slices.Contains(config.AuthorizationAlways(), node) {
+		return authorizeAlways
+	}
+	return authorizeRecent
+}
+
+// currentNode returns the symbol of the menu node being executed.
+func (h *MenuHandlers) currentNode() string {
+	if h.st == nil {
+		return ""
+	}
+	node, _ := h.st.Where()
+	return node
+}
+
+// isHighValue returns true if a transfer of the given amount always asks for the PIN.
+func isHighValue(amount float64) bool {
+	limit := config.AuthorizationHighValue()
+	return limit > 0 && amount >= limit
+}
+
+// authorizedAmount returns the amount of the transfer authorized by a PIN entry at the given menu node,
+// in voucher units, or 0 if the node authorizes no transfer.
+func (h *MenuHandlers) authorizedAmount(ctx context.Context, sessionId string, node string) (float64, error) {
+	var typ storedb.DataTyp
+	switch node {
+	case "transaction_pin", "pay_request", "schedule_pin", "multi_send_pin":
+		typ = storedb.DATA_AMOUNT
+	case "swap_preview", "transaction_swap":
+		// the amount held for the transfer of a swap is in the smallest token unit
+		typ = storedb.DATA_TEMPORARY_VALUE
+	default:
+		return 0, nil
+	}
+	v, err := h.userdataStore.ReadEntry(ctx, sessionId, typ)
+	if err != nil {
+		if db.IsNotFound(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	amount, err := strconv.ParseFloat(string(v), 64)
+	if err != nil {
+		return 0, err
+	}
+	if node == "multi_send_pin" {
+		// the amount is sent to each recipient
+		legs, err := store.ReadMultiSendLegs(ctx, h.userdataStore, sessionId)
+		if err != nil {
+			return 0, err
+		}
+		amount *= float64(len(legs))
+	}
+	return amount, nil
+}
+
+// grantAuthorization records a successful PIN entry at the given menu node, so that it authorizes further actions.
+//
+// A PIN entered at a node that always asks for it, or for a high value transfer of the given amount, authorizes nothing further.
+func (h *MenuHandlers) grantAuthorization(ctx context.Context, sessionId string, node string, amount float64) error {
+	var err error
+	if authorizationPolicy(node) == authorizeAlways || isHighValue(amount) {
+		err = store.RevokeAuthorization(ctx, h.userdataStore, sessionId)
+	} else {
+		err = store.GrantAuthorization(ctx, h.userdataStore, sessionId, node)
+	}
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to write authorization", "node", node, "error", err)
+		return err
+	}
+	return nil
+}
+
 // Authorize attempts to unlock the next sequential nodes by verifying the provided PIN against the already set PIN.
 // It sets the required flags that control the flow.
 func (h *MenuHandlers) Authorize(ctx context.Context, sym string, input []byte) (resource.Result, error) {
@@ -46,6 +139,16 @@ func (h *MenuHandlers) Authorize(ctx context.Context, sym string, input []byte)
 		if err != nil {
 			return res, err
 		}
+		node := h.currentNode()
+		amount, err := h.authorizedAmount(ctx, sessionId, node)
+		if err != nil {
+			logg.ErrorCtxf(ctx, "failed to read authorized amount", "node", node, "error", err)
+			return res, err
+		}
+		err = h.grantAuthorization(ctx, sessionId, node, amount)
+		if err != nil {
+			return res, err
+		}
 	} else {
 		// set the required flags for an incorrect PIN
 		res.FlagSet = append(res.FlagSet, flag_incorrect_pin)
@@ -60,6 +163,41 @@ func (h *MenuHandlers) Authorize(ctx context.Context, sym string, input []byte)
 	return res, nil
 }
 
+// CheckAuthorization applies the authorization policy to the current menu node.
+//
+// It sets the account authorized flag if the node needs no PIN, or if a recent PIN entry still authorizes it,
+// and resets it otherwise.
+func (h *MenuHandlers) CheckAuthorization(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	var err error
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_account_authorized, _ := h.flagManager.GetFlag("flag_account_authorized")
+
+	node := h.currentNode()
+	switch authorizationPolicy(node) {
+	case authorizeNever:
+		ok = true
+	case authorizeAlways:
+		ok = false
+	default:
+		ok, err = store.UseAuthorization(ctx, h.userdataStore, sessionId, node, config.AuthorizationLifetime(), config.AuthorizationActions())
+		if err != nil {
+			logg.ErrorCtxf(ctx, "failed to read authorization", "node", node, "error", err)
+			return res, err
+		}
+	}
+
+	if ok {
+		res.FlagSet = append(res.FlagSet, flag_account_authorized)
+	} else {
+		res.FlagReset = append(res.FlagReset, flag_account_authorized)
+	}
+	return res, nil
+}
+
 // ResetAllowUpdate resets the allowupdate flag that allows a user to update  profile data.
 func (h *MenuHandlers) ResetAllowUpdate(ctx context.Context, sym string, input []byte) (resource.Result, error) {
 	var res resource.Result
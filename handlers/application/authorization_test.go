@@ -9,6 +9,7 @@ import (
 	"git.defalsify.org/vise.git/state"
 	"git.grassecon.net/grassrootseconomics/common/pin"
 	"git.grassecon.net/grassrootseconomics/sarafu-api/testutil/mocks"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
 	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
 	"github.com/alecthomas/assert/v2"
 )
@@ -179,3 +180,115 @@ func TestResetAccountAuthorized(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckAuthorization(t *testing.T) {
+	t.Setenv("AUTHORIZATION_HIGH_VALUE", "100")
+
+	sessionId := "session123"
+	ctx, userStore := InitializeTestStore(t)
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
+
+	fm, err := NewFlagManager(flagsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag_incorrect_pin, _ := fm.GetFlag("flag_incorrect_pin")
+	flag_account_authorized, _ := fm.GetFlag("flag_account_authorized")
+	flag_allow_update, _ := fm.GetFlag("flag_allow_update")
+
+	mockState := state.NewState(16)
+	h := &MenuHandlers{
+		userdataStore:  userStore,
+		accountService: new(mocks.MockAccountService),
+		flagManager:    fm,
+		st:             mockState,
+	}
+
+	hashedPIN, err := pin.HashPIN("1234")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = userStore.WriteEntry(ctx, sessionId, storedb.DATA_ACCOUNT_PIN, []byte(hashedPIN))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authorized := resource.Result{FlagSet: []uint32{flag_account_authorized}}
+	unauthorized := resource.Result{FlagReset: []uint32{flag_account_authorized}}
+
+	// viewing the balance never asks for the PIN
+	mockState.ExecPath = []string{"my_balance"}
+	res, err := h.CheckAuthorization(ctx, "check_authorization", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, authorized, res)
+
+	mockState.ExecPath = []string{"view_profile"}
+	res, err = h.CheckAuthorization(ctx, "check_authorization", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, unauthorized, res)
+
+	mockState.ExecPath = []string{"pin_entry"}
+	res, err = h.Authorize(ctx, "authorize_account", []byte("1234"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagSet: []uint32{flag_allow_update, flag_account_authorized}, FlagReset: []uint32{flag_incorrect_pin}}, res)
+
+	// a recent PIN entry authorizes further actions
+	mockState.ExecPath = []string{"view_profile"}
+	res, err = h.CheckAuthorization(ctx, "check_authorization", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, authorized, res)
+
+	// M-Pesa withdrawals always ask for the PIN, and the PIN entered for them authorizes nothing further
+	mockState.ExecPath = []string{"get_mpesa_confirmation"}
+	res, err = h.CheckAuthorization(ctx, "check_authorization", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, unauthorized, res)
+	_, err = h.Authorize(ctx, "authorize_account", []byte("1234"))
+	assert.NoError(t, err)
+	mockState.ExecPath = []string{"view_profile"}
+	res, err = h.CheckAuthorization(ctx, "check_authorization", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, unauthorized, res)
+
+	// as does the PIN entered for a high value transfer
+	err = userStore.WriteEntry(ctx, sessionId, storedb.DATA_AMOUNT, []byte("150"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockState.ExecPath = []string{"transaction_pin"}
+	_, err = h.Authorize(ctx, "authorize_account", []byte("1234"))
+	assert.NoError(t, err)
+	mockState.ExecPath = []string{"view_profile"}
+	res, err = h.CheckAuthorization(ctx, "check_authorization", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, unauthorized, res)
+
+	// the amount is only that of the transfer being authorized
+	mockState.ExecPath = []string{"pin_entry"}
+	_, err = h.Authorize(ctx, "authorize_account", []byte("1234"))
+	assert.NoError(t, err)
+	mockState.ExecPath = []string{"view_profile"}
+	res, err = h.CheckAuthorization(ctx, "check_authorization", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, authorized, res)
+
+	// the amount of a multi-recipient transfer is sent to each recipient
+	err = userStore.WriteEntry(ctx, sessionId, storedb.DATA_AMOUNT, []byte("60"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.WriteMultiSendLegs(ctx, userStore, sessionId, []store.MultiSendLeg{
+		{RecipientInput: "0712345678"},
+		{RecipientInput: "0712345679"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockState.ExecPath = []string{"multi_send_pin"}
+	_, err = h.Authorize(ctx, "authorize_account", []byte("1234"))
+	assert.NoError(t, err)
+	mockState.ExecPath = []string{"view_profile"}
+	res, err = h.CheckAuthorization(ctx, "check_authorization", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, unauthorized, res)
+}
@@ -0,0 +1,310 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"git.defalsify.org/vise.git/resource"
+	"git.grassecon.net/grassrootseconomics/common/identity"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"gopkg.in/leonelquinteros/gotext.v1"
+)
+
+// contactLabel returns the name of the contact, or the recipient for recent recipients without name.
+func contactLabel(c store.ContactRecord) string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return store.ShortenAddress(c.Recipient)
+}
+
+// validContactName returns the trimmed contact name, and whether it is acceptable.
+func validContactName(input []byte) (string, bool) {
+	name := strings.TrimSpace(string(input))
+	if name == "" || utf8.RuneCountInString(name) > store.ContactNameMax {
+		return name, false
+	}
+	return name, true
+}
+
+// GetSendContacts shows the recipient prompt of the send menu, followed by the
+// saved contacts and recent recipients that may be selected by number.
+func (h *MenuHandlers) GetSendContacts(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	return h.contactsPrompt(ctx, sessionId, l.Get("Enter recipient's phone number/address/alias:"))
+}
+
+// contactsPrompt returns the given prompt followed by the saved contacts and
+// recent recipients that may be selected by number.
+func (h *MenuHandlers) contactsPrompt(ctx context.Context, sessionId string, prompt string) (resource.Result, error) {
+	var res resource.Result
+	contacts, err := store.SendContacts(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read contacts", "error", err)
+		return res, err
+	}
+
+	lines := []string{prompt}
+	for i, c := range contacts {
+		lines = append(lines, fmt.Sprintf("%d%s%s", i+1, h.ReplaceSeparatorFunc(":"), contactLabel(c)))
+	}
+	res.Content = strings.Join(lines, "\n")
+	return res, nil
+}
+
+// resolveSendContact returns the recipient of the contact selected by number in the send menu, if any.
+func (h *MenuHandlers) resolveSendContact(ctx context.Context, sessionId string, input string) (string, bool) {
+	if len(input) != 1 {
+		return "", false
+	}
+	index, err := strconv.Atoi(input)
+	if err != nil || index < 1 {
+		return "", false
+	}
+	contacts, err := store.SendContacts(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read contacts", "error", err)
+		return "", false
+	}
+	if index > len(contacts) {
+		return "", false
+	}
+	return contacts[index-1].Recipient, true
+}
+
+// recordRecentRecipient adds the recipient of a successfully submitted transfer to the recent recipients of the account.
+//
+// Failure to record it is logged, but does not affect the transfer.
+func (h *MenuHandlers) recordRecentRecipient(ctx context.Context, sessionId string, recipient string) {
+	if recipient == "" {
+		return
+	}
+	err := store.AddRecentRecipient(ctx, h.userdataStore, sessionId, recipient)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to record recent recipient", "recipient", recipient, "error", err)
+	}
+}
+
+// GetContacts lists the saved contacts of the account.
+func (h *MenuHandlers) GetContacts(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	contacts, err := store.ReadContacts(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read contacts", "error", err)
+		return res, err
+	}
+	if len(contacts) == 0 {
+		res.Content = l.Get("You have no saved contacts")
+		return res, nil
+	}
+
+	var lines []string
+	for i, c := range contacts {
+		lines = append(lines, fmt.Sprintf("%d%s%s", i+1, h.ReplaceSeparatorFunc(":"), c.Name))
+	}
+	res.Content = strings.Join(lines, "\n")
+	return res, nil
+}
+
+// SelectContact selects the saved contact with the given number for editing.
+func (h *MenuHandlers) SelectContact(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_invalid_contact, _ := h.flagManager.GetFlag("flag_invalid_contact")
+
+	contacts, err := store.ReadContacts(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read contacts", "error", err)
+		return res, err
+	}
+	index, err := strconv.Atoi(strings.TrimSpace(string(input)))
+	if err != nil || index < 1 || index > len(contacts) {
+		res.FlagSet = append(res.FlagSet, flag_invalid_contact)
+		return res, nil
+	}
+
+	err = h.userdataStore.WriteEntry(ctx, sessionId, storedb.DATA_CONTACT_SELECTED, []byte(strconv.Itoa(index-1)))
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to write selected contact", "error", err)
+		return res, err
+	}
+
+	c := contacts[index-1]
+	res.FlagReset = append(res.FlagReset, flag_invalid_contact)
+	res.Content = fmt.Sprintf("%s\n%s", c.Name, c.Recipient)
+	return res, nil
+}
+
+// SaveContactRecipient keeps the phone number, address or alias of the contact being added.
+func (h *MenuHandlers) SaveContactRecipient(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_invalid_contact, _ := h.flagManager.GetFlag("flag_invalid_contact")
+
+	recipient := strings.ReplaceAll(string(input), " ", "")
+	_, err := identity.CheckRecipient(recipient)
+	if err != nil {
+		res.FlagSet = append(res.FlagSet, flag_invalid_contact)
+		return res, nil
+	}
+
+	err = h.userdataStore.WriteEntry(ctx, sessionId, storedb.DATA_TEMPORARY_VALUE, []byte(recipient))
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to write temporaryValue entry with", "key", storedb.DATA_TEMPORARY_VALUE, "value", recipient, "error", err)
+		return res, err
+	}
+	res.FlagReset = append(res.FlagReset, flag_invalid_contact)
+	return res, nil
+}
+
+// AddContact saves the contact being added under the given name.
+func (h *MenuHandlers) AddContact(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_invalid_contact, _ := h.flagManager.GetFlag("flag_invalid_contact")
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	name, ok := validContactName(input)
+	if !ok {
+		res.FlagSet = append(res.FlagSet, flag_invalid_contact)
+		return res, nil
+	}
+	res.FlagReset = append(res.FlagReset, flag_invalid_contact)
+
+	recipient, err := h.userdataStore.ReadEntry(ctx, sessionId, storedb.DATA_TEMPORARY_VALUE)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read temporaryValue entry with", "key", storedb.DATA_TEMPORARY_VALUE, "error", err)
+		return res, err
+	}
+
+	err = store.AddContact(ctx, h.userdataStore, sessionId, store.ContactRecord{
+		Name:      name,
+		Recipient: string(recipient),
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrContactsFull) {
+			res.Content = l.Get("Your contacts list is full. Please remove a contact first")
+			return res, nil
+		}
+		logg.ErrorCtxf(ctx, "failed to add contact", "error", err)
+		return res, err
+	}
+
+	res.Content = l.Get("%s has been saved to your contacts", name)
+	return res, nil
+}
+
+// readSelectedContact returns the index of the contact selected for editing.
+func (h *MenuHandlers) readSelectedContact(ctx context.Context, sessionId string) (int, error) {
+	v, err := h.userdataStore.ReadEntry(ctx, sessionId, storedb.DATA_CONTACT_SELECTED)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read selected contact", "error", err)
+		return 0, err
+	}
+	return strconv.Atoi(string(v))
+}
+
+// RenameContact changes the name of the selected contact.
+func (h *MenuHandlers) RenameContact(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_invalid_contact, _ := h.flagManager.GetFlag("flag_invalid_contact")
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	name, ok := validContactName(input)
+	if !ok {
+		res.FlagSet = append(res.FlagSet, flag_invalid_contact)
+		return res, nil
+	}
+	res.FlagReset = append(res.FlagReset, flag_invalid_contact)
+
+	index, err := h.readSelectedContact(ctx, sessionId)
+	if err != nil {
+		return res, err
+	}
+	err = store.RenameContact(ctx, h.userdataStore, sessionId, index, name)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to rename contact", "index", index, "error", err)
+		return res, err
+	}
+
+	res.Content = l.Get("Your contact has been renamed to %s", name)
+	return res, nil
+}
+
+// DeleteContact removes the selected contact.
+func (h *MenuHandlers) DeleteContact(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	index, err := h.readSelectedContact(ctx, sessionId)
+	if err != nil {
+		return res, err
+	}
+	contacts, err := store.ReadContacts(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read contacts", "error", err)
+		return res, err
+	}
+	if index < 0 || index >= len(contacts) {
+		return res, fmt.Errorf("contact not found: index %d out of range", index)
+	}
+	name := contacts[index].Name
+
+	err = store.DeleteContact(ctx, h.userdataStore, sessionId, index)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to delete contact", "index", index, "error", err)
+		return res, err
+	}
+
+	res.Content = l.Get("%s has been removed from your contacts", name)
+	return res, nil
+}
@@ -0,0 +1,122 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"git.defalsify.org/vise.git/resource"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestContactsMenu(t *testing.T) {
+	sessionId := "session123"
+
+	ctx, userStore := InitializeTestStore(t)
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
+
+	fm, err := NewFlagManager(flagsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag_invalid_contact, _ := fm.GetFlag("flag_invalid_contact")
+
+	h := &MenuHandlers{
+		userdataStore:        userStore,
+		flagManager:          fm,
+		ReplaceSeparatorFunc: mockReplaceSeparator,
+	}
+
+	res, err := h.GetContacts(ctx, "get_contacts", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "You have no saved contacts", res.Content)
+
+	res, err = h.SaveContactRecipient(ctx, "save_contact_recipient", []byte("foo!"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagSet: []uint32{flag_invalid_contact}}, res)
+
+	res, err = h.SaveContactRecipient(ctx, "save_contact_recipient", []byte("0712 345 678"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagReset: []uint32{flag_invalid_contact}}, res)
+
+	res, err = h.AddContact(ctx, "add_contact", []byte("Jane Wanjiku Mwangi"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagSet: []uint32{flag_invalid_contact}}, res)
+
+	res, err = h.AddContact(ctx, "add_contact", []byte("Jane"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagReset: []uint32{flag_invalid_contact}, Content: "Jane has been saved to your contacts"}, res)
+
+	res, err = h.GetContacts(ctx, "get_contacts", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "1: Jane", res.Content)
+
+	res, err = h.SelectContact(ctx, "select_contact", []byte("2"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagSet: []uint32{flag_invalid_contact}}, res)
+
+	res, err = h.SelectContact(ctx, "select_contact", []byte("1"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagReset: []uint32{flag_invalid_contact}, Content: "Jane\n0712345678"}, res)
+
+	res, err = h.RenameContact(ctx, "rename_contact", []byte(" Janet "))
+	assert.NoError(t, err)
+	assert.Equal(t, "Your contact has been renamed to Janet", res.Content)
+
+	res, err = h.DeleteContact(ctx, "delete_contact", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "Janet has been removed from your contacts", res.Content)
+
+	res, err = h.GetContacts(ctx, "get_contacts", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "You have no saved contacts", res.Content)
+}
+
+func TestSendContactSelection(t *testing.T) {
+	sessionId := "session123"
+	address := "0xd4c288865Ce0985a481Eef3be02443dF5E2e4Ea9"
+
+	ctx, userStore := InitializeTestStore(t)
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
+
+	fm, err := NewFlagManager(flagsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := &MenuHandlers{
+		userdataStore:        userStore,
+		flagManager:          fm,
+		ReplaceSeparatorFunc: mockReplaceSeparator,
+	}
+
+	err = userStore.WriteEntry(ctx, sessionId, storedb.DATA_ACTIVE_ADDRESS, []byte("0x1234"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := h.GetSendContacts(ctx, "get_send_contacts", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "Enter recipient's phone number/address/alias:", res.Content)
+
+	h.recordRecentRecipient(ctx, sessionId, address)
+	_, err = h.SaveContactRecipient(ctx, "save_contact_recipient", []byte("jane.sarafu.eth"))
+	assert.NoError(t, err)
+	_, err = h.AddContact(ctx, "add_contact", []byte("Jane"))
+	assert.NoError(t, err)
+
+	res, err = h.GetSendContacts(ctx, "get_send_contacts", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "Enter recipient's phone number/address/alias:\n1: Jane\n2: 0xd4c2...4Ea9", res.Content)
+
+	// the selected recipient is resolved as if it was typed in
+	res, err = h.ValidateRecipient(ctx, "validate_recipient", []byte("2"))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(res.FlagSet))
+
+	recipientInput, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_RECIPIENT_INPUT)
+	assert.NoError(t, err)
+	assert.Equal(t, address, string(recipientInput))
+	_, err = userStore.ReadEntry(ctx, sessionId, storedb.DATA_RECIPIENT)
+	assert.NoError(t, err)
+}
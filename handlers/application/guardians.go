@@ -0,0 +1,488 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.defalsify.org/vise.git/db"
+	"git.defalsify.org/vise.git/resource"
+	"git.grassecon.net/grassrootseconomics/common/phone"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/config"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"gopkg.in/leonelquinteros/gotext.v1"
+)
+
+// logGuardianEntry records a step of guardian management or PIN recovery in the log database.
+//
+// Failure to write the entry is only logged.
+func (h *MenuHandlers) logGuardianEntry(ctx context.Context, sessionId string, key storedb.DataTyp, value string) {
+	err := h.logDb.WriteLogEntry(ctx, sessionId, key, []byte(value))
+	if err != nil {
+		logg.DebugCtxf(ctx, "Failed to write guardian log entry", "key", key, "value", value, "error", err)
+	}
+}
+
+// guardianThreshold returns the number of guardians that must approve the recovery of the PIN of an account.
+//
+// It is never more than the number of guardians an account can nominate.
+func guardianThreshold() int {
+	return min(int(config.GuardianThreshold()), store.GuardiansSize)
+}
+
+// GetGuardians lists the guardians nominated by the account.
+func (h *MenuHandlers) GetGuardians(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	guardians, err := store.ReadGuardians(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read guardians", "error", err)
+		return res, err
+	}
+	if len(guardians) == 0 {
+		res.Content = l.Get("You have no guardians")
+		return res, nil
+	}
+
+	var lines []string
+	for i, g := range guardians {
+		lines = append(lines, fmt.Sprintf("%d%s%s", i+1, h.ReplaceSeparatorFunc(":"), g.SessionId))
+	}
+	res.Content = strings.Join(lines, "\n")
+	return res, nil
+}
+
+// SelectGuardian selects the guardian with the given number and shows its details.
+func (h *MenuHandlers) SelectGuardian(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_invalid_guardian, _ := h.flagManager.GetFlag("flag_invalid_guardian")
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	guardians, err := store.ReadGuardians(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read guardians", "error", err)
+		return res, err
+	}
+	index, err := strconv.Atoi(strings.TrimSpace(string(input)))
+	if err != nil || index < 1 || index > len(guardians) {
+		res.FlagSet = append(res.FlagSet, flag_invalid_guardian)
+		return res, nil
+	}
+
+	g := guardians[index-1]
+	err = h.userdataStore.WriteEntry(ctx, sessionId, storedb.DATA_GUARDIAN_SELECTED, []byte(g.SessionId))
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to write selected guardian", "error", err)
+		return res, err
+	}
+	res.FlagReset = append(res.FlagReset, flag_invalid_guardian)
+
+	res.Content = l.Get("%s\nGuardian since: %s", g.SessionId, time.Unix(g.Added, 0).Format("2006-01-02"))
+	return res, nil
+}
+
+// RemoveGuardian removes the guardian selected with SelectGuardian.
+func (h *MenuHandlers) RemoveGuardian(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	guardian, err := h.userdataStore.ReadEntry(ctx, sessionId, storedb.DATA_GUARDIAN_SELECTED)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read selected guardian", "error", err)
+		return res, err
+	}
+	err = store.RemoveGuardian(ctx, h.userdataStore, sessionId, string(guardian))
+	if err != nil && !errors.Is(err, store.ErrGuardianNotFound) {
+		logg.ErrorCtxf(ctx, "failed to remove guardian", "guardian", string(guardian), "error", err)
+		return res, err
+	}
+	if err == nil {
+		h.logGuardianEntry(ctx, sessionId, storedb.DATA_GUARDIANS, "remove "+string(guardian))
+	}
+
+	res.Content = l.Get("%s is no longer your guardian", string(guardian))
+	return res, nil
+}
+
+// ValidateGuardian validates the registered phone number of a new guardian, and shows the PIN prompt
+// to confirm it.
+func (h *MenuHandlers) ValidateGuardian(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_invalid_guardian, _ := h.flagManager.GetFlag("flag_invalid_guardian")
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	guardian := strings.ReplaceAll(string(input), " ", "")
+	formattedNumber, err := phone.FormatPhoneNumber(guardian)
+	if err != nil || !phone.IsValidPhoneNumber(formattedNumber) {
+		res.FlagSet = append(res.FlagSet, flag_invalid_guardian)
+		res.Content = l.Get("%s is not a valid phone number", guardian)
+		return res, nil
+	}
+	if formattedNumber == sessionId {
+		res.FlagSet = append(res.FlagSet, flag_invalid_guardian)
+		res.Content = l.Get("You cannot be your own guardian")
+		return res, nil
+	}
+	_, err = h.userdataStore.ReadEntry(ctx, formattedNumber, storedb.DATA_PUBLIC_KEY)
+	if err != nil {
+		if db.IsNotFound(err) {
+			res.FlagSet = append(res.FlagSet, flag_invalid_guardian)
+			res.Content = l.Get("%s is not registered", guardian)
+			return res, nil
+		}
+		logg.ErrorCtxf(ctx, "failed to read publicKey", "guardian", formattedNumber, "error", err)
+		return res, err
+	}
+
+	guardians, err := store.ReadGuardians(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read guardians", "error", err)
+		return res, err
+	}
+	for _, g := range guardians {
+		if g.SessionId == formattedNumber {
+			res.FlagSet = append(res.FlagSet, flag_invalid_guardian)
+			res.Content = l.Get("%s is already your guardian", guardian)
+			return res, nil
+		}
+	}
+	if len(guardians) >= store.GuardiansSize {
+		res.FlagSet = append(res.FlagSet, flag_invalid_guardian)
+		res.Content = l.Get("You can have at most %d guardians", store.GuardiansSize)
+		return res, nil
+	}
+
+	err = h.userdataStore.WriteEntry(ctx, sessionId, storedb.DATA_GUARDIAN_SELECTED, []byte(formattedNumber))
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to write selected guardian", "error", err)
+		return res, err
+	}
+
+	res.FlagReset = append(res.FlagReset, flag_invalid_guardian)
+	res.Content = l.Get("%s will be able to approve the reset of your PIN.\nPlease enter your PIN to confirm:", formattedNumber)
+	return res, nil
+}
+
+// AddGuardian saves the guardian validated with ValidateGuardian, once the PIN has been confirmed,
+// and notifies it by SMS.
+func (h *MenuHandlers) AddGuardian(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_account_authorized, _ := h.flagManager.GetFlag("flag_account_authorized")
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	res.FlagReset = append(res.FlagReset, flag_account_authorized)
+
+	guardian, err := h.userdataStore.ReadEntry(ctx, sessionId, storedb.DATA_GUARDIAN_SELECTED)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read selected guardian", "error", err)
+		return res, err
+	}
+	err = store.AddGuardian(ctx, h.userdataStore, sessionId, string(guardian))
+	if err != nil {
+		if errors.Is(err, store.ErrGuardianExists) {
+			res.Content = l.Get("%s is already your guardian", string(guardian))
+			return res, nil
+		}
+		if errors.Is(err, store.ErrGuardiansFull) {
+			res.Content = l.Get("You can have at most %d guardians", store.GuardiansSize)
+			return res, nil
+		}
+		logg.ErrorCtxf(ctx, "failed to add guardian", "guardian", string(guardian), "error", err)
+		return res, err
+	}
+	h.logGuardianEntry(ctx, sessionId, storedb.DATA_GUARDIANS, "add "+string(guardian))
+
+	h.notifyAccount(ctx, string(guardian), "%s has added you as a guardian. If they are locked out of their account, you can approve the reset of their PIN", sessionId)
+
+	res.Content = l.Get("%s has been added as your guardian", string(guardian))
+	return res, nil
+}
+
+// RequestRecovery asks the guardians of the blocked account to approve the reset of its PIN,
+// and notifies them by SMS.
+//
+// If a request is already open, its progress is shown instead.
+func (h *MenuHandlers) RequestRecovery(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	threshold := guardianThreshold()
+
+	recovery, ok, err := store.ReadRecovery(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read recovery request", "error", err)
+		return res, err
+	}
+	if ok {
+		res.Content = l.Get("%d of %d guardians have approved the reset of your PIN", len(recovery.Approvals), threshold)
+		return res, nil
+	}
+
+	guardians, err := store.ReadGuardians(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read guardians", "error", err)
+		return res, err
+	}
+	if len(guardians) < threshold {
+		res.Content = l.Get("You need %d guardians to reset your PIN. For help on how to unblock your account, contact support at: 0757628885", threshold)
+		return res, nil
+	}
+
+	recovery, err = store.StartRecovery(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to start recovery request", "error", err)
+		return res, err
+	}
+	h.logGuardianEntry(ctx, sessionId, storedb.DATA_RECOVERY, "request "+recovery.Id)
+	logg.InfoCtxf(ctx, "PIN recovery requested", "id", recovery.Id)
+
+	for _, g := range guardians {
+		err = store.AddRecoveryRequest(ctx, h.userdataStore, g.SessionId, recovery)
+		if err != nil {
+			logg.ErrorCtxf(ctx, "failed to send recovery request", "guardian", g.SessionId, "error", err)
+			return res, err
+		}
+		h.notifyAccount(ctx, g.SessionId, "%s has asked you to approve the reset of their PIN. Dial in and go to PIN options > Guardians to approve", sessionId)
+	}
+
+	res.Content = l.Get("Your request has been sent to your guardians. Your PIN can be reset once %d of them approve", threshold)
+	return res, nil
+}
+
+// GetRecoveryRequests lists the open PIN recovery requests received by the account as a guardian.
+func (h *MenuHandlers) GetRecoveryRequests(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	requests, err := store.ReadRecoveryRequests(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read recovery requests", "error", err)
+		return res, err
+	}
+	if len(requests) == 0 {
+		res.Content = l.Get("You have no recovery requests")
+		return res, nil
+	}
+
+	var lines []string
+	for i, r := range requests {
+		lines = append(lines, fmt.Sprintf("%d%s%s", i+1, h.ReplaceSeparatorFunc(":"), r.Requester))
+	}
+	res.Content = strings.Join(lines, "\n")
+	return res, nil
+}
+
+// SelectRecoveryRequest selects the open recovery request with the given number, and shows the PIN prompt
+// to approve it.
+func (h *MenuHandlers) SelectRecoveryRequest(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_invalid_recovery_request, _ := h.flagManager.GetFlag("flag_invalid_recovery_request")
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	requests, err := store.ReadRecoveryRequests(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read recovery requests", "error", err)
+		return res, err
+	}
+	index, err := strconv.Atoi(strings.TrimSpace(string(input)))
+	if err != nil || index < 1 || index > len(requests) {
+		res.FlagSet = append(res.FlagSet, flag_invalid_recovery_request)
+		return res, nil
+	}
+
+	r := requests[index-1]
+	err = h.userdataStore.WriteEntry(ctx, sessionId, storedb.DATA_RECOVERY_SELECTED, []byte(r.Id))
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to write selected recovery request", "error", err)
+		return res, err
+	}
+	res.FlagReset = append(res.FlagReset, flag_invalid_recovery_request)
+
+	res.Content = l.Get(
+		"%s has asked you to approve the reset of their PIN\nExpires: %s\nPlease enter your PIN to approve:",
+		r.Requester,
+		time.Unix(r.Expires, 0).Format("2006-01-02 15:04"),
+	)
+	return res, nil
+}
+
+// ApproveRecovery records the approval of the recovery request selected with SelectRecoveryRequest,
+// once the PIN has been confirmed.
+//
+// When enough guardians have approved, the PIN of the requesting account is reset in the same way as
+// ResetOthersPin, and the request is withdrawn from the other guardians.
+func (h *MenuHandlers) ApproveRecovery(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_account_authorized, _ := h.flagManager.GetFlag("flag_account_authorized")
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	res.FlagReset = append(res.FlagReset, flag_account_authorized)
+
+	id, err := h.userdataStore.ReadEntry(ctx, sessionId, storedb.DATA_RECOVERY_SELECTED)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read selected recovery request", "error", err)
+		return res, err
+	}
+	request, err := store.GetRecoveryRequest(ctx, h.userdataStore, sessionId, string(id))
+	if err != nil {
+		if errors.Is(err, store.ErrRecoveryNotFound) {
+			res.Content = l.Get("This recovery request has expired")
+			return res, nil
+		}
+		logg.ErrorCtxf(ctx, "failed to read recovery request", "id", string(id), "error", err)
+		return res, err
+	}
+	err = store.RemoveRecoveryRequest(ctx, h.userdataStore, sessionId, request.Id)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to remove recovery request", "id", request.Id, "error", err)
+		return res, err
+	}
+
+	ok, err = store.IsGuardian(ctx, h.userdataStore, request.Requester, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read guardians", "requester", request.Requester, "error", err)
+		return res, err
+	}
+	if !ok {
+		res.Content = l.Get("You are no longer a guardian of %s", request.Requester)
+		return res, nil
+	}
+	threshold := guardianThreshold()
+	recovery, done, err := store.ApproveRecovery(ctx, h.userdataStore, request.Requester, request.Id, sessionId, threshold)
+	if err != nil {
+		if errors.Is(err, store.ErrRecoveryNotFound) {
+			res.Content = l.Get("This recovery request has expired")
+			return res, nil
+		}
+		logg.ErrorCtxf(ctx, "failed to approve recovery request", "id", request.Id, "error", err)
+		return res, err
+	}
+	h.logGuardianEntry(ctx, sessionId, storedb.DATA_RECOVERY_REQUESTS, fmt.Sprintf("approve %s %s", recovery.Id, recovery.Requester))
+	h.logGuardianEntry(ctx, recovery.Requester, storedb.DATA_RECOVERY, fmt.Sprintf("approve %s %s", recovery.Id, sessionId))
+
+	if !done {
+		h.notifyAccount(ctx, recovery.Requester, "%s has approved the reset of your PIN. %d of %d guardians have approved", sessionId, len(recovery.Approvals), threshold)
+		res.Content = l.Get("You have approved the reset of the PIN of %s", recovery.Requester)
+		return res, nil
+	}
+
+	err = h.completeRecovery(ctx, sessionId, recovery)
+	if err != nil {
+		return res, err
+	}
+	res.Content = l.Get("You have approved the reset of the PIN of %s. They can now set a new PIN", recovery.Requester)
+	return res, nil
+}
+
+// completeRecovery lets the account whose recovery was closed by the last approval set a new PIN,
+// and withdraws the recovery from the guardians that have not yet approved.
+//
+// The reset is recorded in the audit log as made by the guardian whose approval completed the recovery.
+func (h *MenuHandlers) completeRecovery(ctx context.Context, guardian string, recovery store.RecoveryRecord) error {
+	userStore := h.userdataStore
+	requester := recovery.Requester
+
+	err := userStore.WriteEntry(ctx, requester, storedb.DATA_SELF_PIN_RESET, []byte("1"))
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to write self PIN reset", "requester", requester, "error", err)
+		h.audit(ctx, guardian, requester, store.AuditGuardianResetPin, err)
+		return err
+	}
+	err = userStore.WriteEntry(ctx, requester, storedb.DATA_INCORRECT_PIN_ATTEMPTS, []byte("0"))
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to reset incorrect PIN attempts", "key", storedb.DATA_INCORRECT_PIN_ATTEMPTS, "error", err)
+		h.audit(ctx, guardian, requester, store.AuditGuardianResetPin, err)
+		return err
+	}
+	err = h.clearLockouts(ctx, requester)
+	if err != nil {
+		h.audit(ctx, guardian, requester, store.AuditGuardianResetPin, err)
+		return err
+	}
+	h.audit(ctx, guardian, requester, store.AuditGuardianResetPin, nil)
+	h.logGuardianEntry(ctx, requester, storedb.DATA_RECOVERY, "reset "+recovery.Id)
+	logg.InfoCtxf(ctx, "PIN recovery approved by guardians", "requester", requester, "id", recovery.Id)
+
+	guardians, err := store.ReadGuardians(ctx, userStore, requester)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read guardians", "requester", requester, "error", err)
+		return err
+	}
+	for _, g := range guardians {
+		err = store.RemoveRecoveryRequest(ctx, userStore, g.SessionId, recovery.Id)
+		if err != nil {
+			logg.WarnCtxf(ctx, "failed to withdraw recovery request", "guardian", g.SessionId, "error", err)
+		}
+	}
+
+	h.notifyAccount(ctx, requester, "Your guardians have approved the reset of your PIN. Dial in to set a new PIN")
+	return nil
+}
@@ -0,0 +1,176 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"git.defalsify.org/vise.git/resource"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/testutil/mocks"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/internal/sms"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestGuardianRecovery(t *testing.T) {
+	t.Setenv("GUARDIAN_THRESHOLD", "2")
+
+	requester := "+254712345678"
+	guardians := []string{"+254711111111", "+254722222222", "+254733333333"}
+
+	ctx, userStore := InitializeTestStore(t)
+	_, logdb := InitializeTestLogdbStore(t)
+	requesterCtx := context.WithValue(ctx, "SessionId", requester)
+
+	fm, err := NewFlagManager(flagsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag_invalid_guardian, _ := fm.GetFlag("flag_invalid_guardian")
+	flag_invalid_recovery_request, _ := fm.GetFlag("flag_invalid_recovery_request")
+	flag_account_authorized, _ := fm.GetFlag("flag_account_authorized")
+
+	svc := &messageAccountService{MockAccountService: new(mocks.MockAccountService)}
+	h := &MenuHandlers{
+		userdataStore:        userStore,
+		accountService:       svc,
+		flagManager:          fm,
+		logDb:                store.LogDb{Db: logdb},
+		ReplaceSeparatorFunc: mockReplaceSeparator,
+		smsService: sms.SmsService{
+			Accountservice: svc,
+			Userdatastore:  *userStore,
+		},
+	}
+
+	for _, sessionId := range append([]string{requester}, guardians...) {
+		err = userStore.WriteEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY, []byte("0X13242618721"))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := h.ValidateGuardian(requesterCtx, "validate_guardian", []byte("0712345678"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagSet: []uint32{flag_invalid_guardian}, Content: "You cannot be your own guardian"}, res)
+
+	res, err = h.ValidateGuardian(requesterCtx, "validate_guardian", []byte("0744444444"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagSet: []uint32{flag_invalid_guardian}, Content: "0744444444 is not registered"}, res)
+
+	res, err = h.ValidateGuardian(requesterCtx, "validate_guardian", []byte("0711 111 111"))
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{flag_invalid_guardian}, res.FlagReset)
+
+	res, err = h.AddGuardian(requesterCtx, "add_guardian", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagReset: []uint32{flag_account_authorized}, Content: "+254711111111 has been added as your guardian"}, res)
+	assert.Equal(t, []string{guardians[0]}, svc.phoneNumbers)
+
+	res, err = h.ValidateGuardian(requesterCtx, "validate_guardian", []byte("0711111111"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagSet: []uint32{flag_invalid_guardian}, Content: "0711111111 is already your guardian"}, res)
+
+	// too few guardians to recover
+	res, err = h.RequestRecovery(requesterCtx, "request_recovery", []byte("1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "You need 2 guardians to reset your PIN. For help on how to unblock your account, contact support at: 0757628885", res.Content)
+
+	for _, g := range guardians[1:] {
+		err = store.AddGuardian(ctx, userStore, requester, g)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	res, err = h.GetGuardians(requesterCtx, "get_guardians", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "1: +254711111111\n2: +254722222222\n3: +254733333333", res.Content)
+
+	res, err = h.SelectGuardian(requesterCtx, "select_guardian", []byte("4"))
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{flag_invalid_guardian}, res.FlagSet)
+	res, err = h.SelectGuardian(requesterCtx, "select_guardian", []byte("3"))
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{flag_invalid_guardian}, res.FlagReset)
+	res, err = h.RemoveGuardian(requesterCtx, "remove_guardian", []byte("1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "+254733333333 is no longer your guardian", res.Content)
+
+	err = userStore.WriteEntry(ctx, requester, storedb.DATA_INCORRECT_PIN_ATTEMPTS, []byte("3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	svc.phoneNumbers = nil
+	res, err = h.RequestRecovery(requesterCtx, "request_recovery", []byte("1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Your request has been sent to your guardians. Your PIN can be reset once 2 of them approve", res.Content)
+	assert.Equal(t, guardians[:2], svc.phoneNumbers)
+
+	// an open request is not sent again
+	res, err = h.RequestRecovery(requesterCtx, "request_recovery", []byte("1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "0 of 2 guardians have approved the reset of your PIN", res.Content)
+
+	// a removed guardian cannot see the request
+	removedCtx := context.WithValue(ctx, "SessionId", guardians[2])
+	res, err = h.GetRecoveryRequests(removedCtx, "get_recovery_requests", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "You have no recovery requests", res.Content)
+
+	for i, g := range guardians[:2] {
+		guardianCtx := context.WithValue(ctx, "SessionId", g)
+		res, err = h.GetRecoveryRequests(guardianCtx, "get_recovery_requests", []byte(""))
+		assert.NoError(t, err)
+		assert.Equal(t, "1: +254712345678", res.Content)
+
+		res, err = h.SelectRecoveryRequest(guardianCtx, "select_recovery_request", []byte("2"))
+		assert.NoError(t, err)
+		assert.Equal(t, []uint32{flag_invalid_recovery_request}, res.FlagSet)
+		res, err = h.SelectRecoveryRequest(guardianCtx, "select_recovery_request", []byte("1"))
+		assert.NoError(t, err)
+		assert.Equal(t, []uint32{flag_invalid_recovery_request}, res.FlagReset)
+
+		_, err = userStore.ReadEntry(ctx, requester, storedb.DATA_SELF_PIN_RESET)
+		assert.Error(t, err)
+
+		res, err = h.ApproveRecovery(guardianCtx, "approve_recovery", []byte(""))
+		assert.NoError(t, err)
+		assert.Equal(t, []uint32{flag_account_authorized}, res.FlagReset)
+		if i == 0 {
+			assert.Equal(t, "You have approved the reset of the PIN of +254712345678", res.Content)
+			assert.Equal(t, "+254711111111 has approved the reset of your PIN. 1 of 2 guardians have approved", svc.messages[len(svc.messages)-1])
+		} else {
+			assert.Equal(t, "You have approved the reset of the PIN of +254712345678. They can now set a new PIN", res.Content)
+			assert.Equal(t, "Your guardians have approved the reset of your PIN. Dial in to set a new PIN", svc.messages[len(svc.messages)-1])
+		}
+
+		res, err = h.GetRecoveryRequests(guardianCtx, "get_recovery_requests", []byte(""))
+		assert.NoError(t, err)
+		assert.Equal(t, "You have no recovery requests", res.Content)
+	}
+
+	v, err := userStore.ReadEntry(ctx, requester, storedb.DATA_SELF_PIN_RESET)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", string(v))
+	v, err = userStore.ReadEntry(ctx, requester, storedb.DATA_INCORRECT_PIN_ATTEMPTS)
+	assert.NoError(t, err)
+	assert.Equal(t, "0", string(v))
+	_, ok, err := store.ReadRecovery(ctx, userStore, requester)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	records, err := store.ReadAudit(ctx, userStore, store.AuditFilter{Target: requester})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, guardians[1], records[0].Actor)
+	assert.Equal(t, store.AuditGuardianResetPin, records[0].Action)
+}
+
+func TestGuardianThreshold(t *testing.T) {
+	t.Setenv("GUARDIAN_THRESHOLD", "2")
+	assert.Equal(t, 2, guardianThreshold())
+
+	// no more guardians can be required than an account can nominate
+	t.Setenv("GUARDIAN_THRESHOLD", "5")
+	assert.Equal(t, store.GuardiansSize, guardianThreshold())
+}
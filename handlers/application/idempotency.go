@@ -0,0 +1,115 @@
+package application
+
+import (
+	"context"
+	"errors"
+
+	"git.grassecon.net/grassrootseconomics/sarafu-api/remote/http"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/services/resilient"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+	"gopkg.in/leonelquinteros/gotext.v1"
+)
+
+// newIntentNonce starts a new transaction intent for the preview being shown.
+func (h *MenuHandlers) newIntentNonce(ctx context.Context, sessionId string) error {
+	_, err := store.NewIntentNonce(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to create transaction nonce", "error", err)
+		return err
+	}
+	return nil
+}
+
+// beginIntent registers the submission of a transaction of the given kind and parameters.
+//
+// If the same intent was already submitted, for example because the gateway resent the
+// request, the content to show instead of submitting again is returned with duplicate set.
+func (h *MenuHandlers) beginIntent(ctx context.Context, sessionId string, l *gotext.Locale, kind string, params ...string) (key string, content string, duplicate bool, err error) {
+	key, err = store.IntentKey(ctx, h.userdataStore, sessionId, kind, params...)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to create intent key", "kind", kind, "error", err)
+		return "", "", false, err
+	}
+	if key == "" {
+		logg.WarnCtxf(ctx, "no transaction nonce, submitting without idempotency check", "kind", kind)
+		return "", "", false, nil
+	}
+	prev, err := store.BeginIntent(ctx, h.userdataStore, sessionId, key)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to register intent", "kind", kind, "error", err)
+		return "", "", false, err
+	}
+	if prev == nil {
+		return key, "", false, nil
+	}
+	if prev.Pending() {
+		logg.WarnCtxf(ctx, "refusing resubmission of pending intent", "kind", kind, "key", key)
+		return key, l.Get("Your request is already being processed. Please await confirmation"), true, nil
+	}
+	logg.WarnCtxf(ctx, "refusing resubmission of completed intent", "kind", kind, "key", key, "trackingId", prev.TrackingId)
+	return key, l.Get("Your request has already been sent. Reference: %s", prev.TrackingId), true, nil
+}
+
+// completeIntent records the tracking id of a submitted intent.
+func (h *MenuHandlers) completeIntent(ctx context.Context, sessionId string, key string, trackingId string) {
+	if key == "" {
+		return
+	}
+	err := store.CompleteIntent(ctx, h.userdataStore, sessionId, key, trackingId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to complete intent", "key", key, "trackingId", trackingId, "error", err)
+	}
+}
+
+// stepIntent records the tracking id of a completed step of an intent submitted in several steps.
+//
+// The intent is then never released, even if a later step fails.
+func (h *MenuHandlers) stepIntent(ctx context.Context, sessionId string, key string, trackingId string) {
+	if key == "" {
+		return
+	}
+	err := store.StepIntent(ctx, h.userdataStore, sessionId, key, trackingId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to record intent step", "key", key, "trackingId", trackingId, "error", err)
+	}
+}
+
+// rejected returns true if the error of a remote call shows that the request was not carried out.
+//
+// Other errors, such as timeouts or broken connections, leave it unknown whether the request
+// reached the remote service.
+func rejected(err error) bool {
+	var apiErr *http.APIError
+	return errors.As(err, &apiErr) || errors.Is(err, resilient.ErrServiceDegraded)
+}
+
+// abortIntent releases an intent whose submission failed with the given error, so that the user can try again.
+//
+// The intent is only released if the request was rejected by the remote service. After any other
+// failure the transaction may still have been submitted, and the intent is left pending so that
+// it is not submitted a second time.
+func (h *MenuHandlers) abortIntent(ctx context.Context, sessionId string, key string, cause error) {
+	if key == "" {
+		return
+	}
+	if !rejected(cause) {
+		logg.WarnCtxf(ctx, "keeping intent pending after failure with unknown outcome", "key", key, "error", cause)
+		return
+	}
+	h.releaseIntent(ctx, sessionId, key)
+}
+
+// releaseIntent releases an intent that is known not to have been submitted, so that the user can try again.
+func (h *MenuHandlers) releaseIntent(ctx context.Context, sessionId string, key string) {
+	if key == "" {
+		return
+	}
+	ok, err := store.AbortIntent(ctx, h.userdataStore, sessionId, key)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to abort intent", "key", key, "error", err)
+		return
+	}
+	if !ok {
+		logg.WarnCtxf(ctx, "keeping intent with completed steps", "key", key)
+	}
+}
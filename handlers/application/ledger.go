@@ -0,0 +1,93 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"git.defalsify.org/vise.git/resource"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+	"gopkg.in/leonelquinteros/gotext.v1"
+)
+
+const (
+	// number of submitted transactions shown in the pending transactions menu.
+	ledgerDisplayCount = 5
+)
+
+// recordSubmission adds a submitted transaction to the ledger of the account.
+//
+// The transaction has already been submitted at this point, so failure to record it is only logged.
+func (h *MenuHandlers) recordSubmission(ctx context.Context, sessionId string, entry store.LedgerEntry) {
+	err := store.AddLedgerEntry(ctx, h.userdataStore, sessionId, entry)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to record submitted transaction", "type", entry.Type, "trackingId", entry.TrackingId, "error", err)
+	}
+}
+
+// recordFailure adds a transaction whose submission failed to the ledger of the account.
+//
+// A rejected submission is recorded as failed. Otherwise the transaction may still have been
+// submitted, so it is recorded as pending until it is confirmed or expires.
+func (h *MenuHandlers) recordFailure(ctx context.Context, sessionId string, entry store.LedgerEntry, cause error) {
+	if rejected(cause) {
+		entry.Status = store.LedgerFailed
+	}
+	h.recordSubmission(ctx, sessionId, entry)
+}
+
+// ledgerStatus returns the translated confirmation status of the submitted transaction.
+func ledgerStatus(l *gotext.Locale, e store.LedgerEntry) string {
+	switch e.Status {
+	case store.LedgerConfirmed:
+		return l.Get("Confirmed")
+	case store.LedgerFailed:
+		return l.Get("Failed")
+	case store.LedgerExpired:
+		return l.Get("Expired")
+	}
+	return l.Get("Pending")
+}
+
+// GetPendingTransactions lists the most recently submitted transactions with their confirmation status.
+func (h *MenuHandlers) GetPendingTransactions(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	_, err := store.ExpireLedgerEntries(ctx, h.userdataStore, sessionId, time.Now())
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to expire pending transactions", "error", err)
+		return res, err
+	}
+	entries, err := store.ReadLedger(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read transaction ledger", "error", err)
+		return res, err
+	}
+	if len(entries) == 0 {
+		res.Content = l.Get("You have no recent transactions")
+		return res, nil
+	}
+
+	var lines []string
+	for i := len(entries) - 1; i >= 0 && len(lines) < ledgerDisplayCount; i-- {
+		e := entries[i]
+		status := ledgerStatus(l, e)
+		target := e.Counterparty
+		if e.ToSymbol != "" {
+			target = strings.TrimSpace(e.ToAmount + " " + e.ToSymbol)
+		}
+		line := fmt.Sprintf("%d%s%s %s > %s %s", len(lines)+1, h.ReplaceSeparatorFunc(":"), e.Amount, e.Symbol, target, status)
+		lines = append(lines, line)
+	}
+	res.Content = strings.Join(lines, "\n")
+	return res, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"path"
+	"time"
 
 	"gopkg.in/leonelquinteros/gotext.v1"
 
@@ -78,10 +79,7 @@ func NewMenuHandlers(appFlags *FlagManager, userdataStore db.Db, logdb db.Db, ac
 	userDb := &store.UserDataStore{
 		Db: userdataStore,
 	}
-	smsservice := sms.SmsService{
-		Accountservice: accountService,
-		Userdatastore:  *userDb,
-	}
+	smsservice := sms.NewSmsService(accountService, *userDb)
 
 	logDb := store.LogDb{
 		Db: logdb,
@@ -158,7 +156,33 @@ func codeFromCtx(ctx context.Context) string {
 func (h *MenuHandlers) ResetApiCallFailure(ctx context.Context, sym string, input []byte) (resource.Result, error) {
 	var res resource.Result
 	flag_api_error, _ := h.flagManager.GetFlag("flag_api_call_error")
-	res.FlagReset = append(res.FlagReset, flag_api_error)
+	flag_service_degraded, _ := h.flagManager.GetFlag("flag_service_degraded")
+	res.FlagReset = append(res.FlagReset, flag_api_error, flag_service_degraded)
+	return res, nil
+}
+
+// CheckServiceStatus sets the service degraded flag if calls to the external service are currently suspended.
+func (h *MenuHandlers) CheckServiceStatus(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	flag_service_degraded, _ := h.flagManager.GetFlag("flag_service_degraded")
+
+	svc := h.accountService
+	for svc != nil {
+		if d, ok := svc.(interface{ Degraded() bool }); ok {
+			if d.Degraded() {
+				logg.WarnCtxf(ctx, "external service degraded")
+				res.FlagSet = append(res.FlagSet, flag_service_degraded)
+				return res, nil
+			}
+			break
+		}
+		u, ok := svc.(interface{ Unwrap() remote.AccountService })
+		if !ok {
+			break
+		}
+		svc = u.Unwrap()
+	}
+	res.FlagReset = append(res.FlagReset, flag_service_degraded)
 	return res, nil
 }
 
@@ -244,11 +268,28 @@ func (h *MenuHandlers) QuitWithHelp(ctx context.Context, sym string, input []byt
 }
 
 // ShowBlockedAccount displays a message after an account has been blocked and how to reach support.
+//
+// If the account is only locked out temporarily, it shows how long the user must wait instead.
 func (h *MenuHandlers) ShowBlockedAccount(ctx context.Context, sym string, input []byte) (resource.Result, error) {
 	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
 	code := codeFromCtx(ctx)
 	l := gotext.NewLocale(translationDir, code)
 	l.AddDomain("default")
+
+	expiry, ok, err := store.LockoutExpiry(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read PIN lockout expiry", "error", err)
+		return res, err
+	}
+	if ok && time.Now().Before(expiry) {
+		res.Content = l.Get("Your account has been locked after too many incorrect PIN attempts. Please try again in %s", formatLockoutWait(l, time.Until(expiry)))
+		return res, nil
+	}
 	res.Content = l.Get("Your account has been locked. For help on how to unblock your account, contact support at: 0757628885")
 	return res, nil
 }
@@ -2,11 +2,13 @@ package application
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"path"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"git.defalsify.org/vise.git/cache"
 	"git.defalsify.org/vise.git/persist"
@@ -15,6 +17,7 @@ import (
 	"git.grassecon.net/grassrootseconomics/common/pin"
 	"git.grassecon.net/grassrootseconomics/sarafu-api/testutil/mocks"
 	"git.grassecon.net/grassrootseconomics/sarafu-api/testutil/testservice"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/services/resilient"
 	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
 	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
 
@@ -554,14 +557,18 @@ func TestQuitWithHelp(t *testing.T) {
 
 func TestShowBlockedAccount(t *testing.T) {
 	sessionId := "session123"
-	ctx := context.WithValue(context.Background(), "SessionId", sessionId)
+	ctx, userStore := InitializeTestStore(t)
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
 
-	h := &MenuHandlers{}
+	h := &MenuHandlers{
+		userdataStore: userStore,
+	}
 
 	tests := []struct {
 		name           string
 		input          []byte
 		status         string
+		lockoutExpiry  string
 		expectedResult resource.Result
 	}{
 		{
@@ -570,10 +577,21 @@ func TestShowBlockedAccount(t *testing.T) {
 				Content: "Your account has been locked. For help on how to unblock your account, contact support at: 0757628885",
 			},
 		},
+		{
+			name:          "Test temporarily locked account",
+			lockoutExpiry: strconv.FormatInt(time.Now().Add(90*time.Minute).Unix(), 10),
+			expectedResult: resource.Result{
+				Content: "Your account has been locked after too many incorrect PIN attempts. Please try again in 2 hours",
+			},
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			err := userStore.WriteEntry(ctx, sessionId, storedb.DATA_PIN_LOCKOUT_EXPIRY, []byte(tt.lockoutExpiry))
+			if err != nil {
+				t.Fatal(err)
+			}
 			res, _ := h.ShowBlockedAccount(ctx, "show_blocked_account", tt.input)
 			//Assert that the result is as expected
 			assert.Equal(t, res, tt.expectedResult, "Expected result should be equal to the actual result")
@@ -633,3 +651,36 @@ func TestClearTemporaryValue(t *testing.T) {
 	// assert that the temp value is empty
 	assert.Equal(t, currentTempValue, []byte(""))
 }
+
+func TestCheckServiceStatus(t *testing.T) {
+	ctx := context.Background()
+
+	fm, err := NewFlagManager(flagsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag_service_degraded, _ := fm.GetFlag("flag_service_degraded")
+
+	mockAccountService := new(mocks.MockAccountService)
+	mockAccountService.On("FetchTopPools").Return(nil, fmt.Errorf("connection refused"))
+	rs := resilient.NewAccountService(mockAccountService, resilient.Config{
+		Threshold: 1,
+		Cooldown:  time.Minute,
+	})
+
+	h := &MenuHandlers{
+		flagManager:    fm,
+		accountService: rs,
+	}
+
+	res, err := h.CheckServiceStatus(ctx, "check_service_status", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagReset: []uint32{flag_service_degraded}}, res)
+
+	_, err = rs.FetchTopPools(ctx)
+	assert.Error(t, err)
+
+	res, err = h.CheckServiceStatus(ctx, "check_service_status", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagSet: []uint32{flag_service_degraded}}, res)
+}
@@ -9,6 +9,7 @@ import (
 	"git.defalsify.org/vise.git/resource"
 	"git.grassecon.net/grassrootseconomics/common/hex"
 	"git.grassecon.net/grassrootseconomics/common/phone"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/models"
 	"git.grassecon.net/grassrootseconomics/sarafu-vise/config"
 	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
 	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
@@ -29,6 +30,7 @@ func (h *MenuHandlers) GetMpesaMaxLimit(ctx context.Context, sym string, input [
 	flag_low_swap_amount, _ := h.flagManager.GetFlag("flag_low_swap_amount")
 	flag_incorrect_pool, _ := h.flagManager.GetFlag("flag_incorrect_pool")
 	flag_incorrect_voucher, _ := h.flagManager.GetFlag("flag_incorrect_voucher")
+	flag_spend_limit, _ := h.flagManager.GetFlag("flag_spend_limit")
 
 	code := codeFromCtx(ctx)
 	l := gotext.NewLocale(translationDir, code)
@@ -36,7 +38,7 @@ func (h *MenuHandlers) GetMpesaMaxLimit(ctx context.Context, sym string, input [
 
 	inputStr := string(input)
 	if inputStr == "0" || inputStr == "99" || inputStr == "88" || inputStr == "98" {
-		res.FlagReset = append(res.FlagReset, flag_low_swap_amount, flag_api_call_error, flag_incorrect_voucher, flag_incorrect_pool)
+		res.FlagReset = append(res.FlagReset, flag_low_swap_amount, flag_api_call_error, flag_incorrect_voucher, flag_incorrect_pool, flag_spend_limit)
 		return res, nil
 	}
 
@@ -56,6 +58,16 @@ func (h *MenuHandlers) GetMpesaMaxLimit(ctx context.Context, sym string, input [
 		return res, err
 	}
 
+	// nothing can be withdrawn once the spending limit of the voucher is reached
+	allowance, limited, err := h.spendAllowance(ctx, sessionId, metadata.TokenAddress)
+	if err != nil {
+		return res, err
+	}
+	if limited && allowance < minSpendAmount {
+		err = h.spendLimitReached(ctx, sessionId, metadata.TokenAddress, metadata.TokenSymbol, &res)
+		return res, err
+	}
+
 	// Fetch session data
 	_, _, _, _, publicKey, _, err := h.getSessionData(ctx, sessionId)
 	if err != nil {
@@ -82,7 +94,7 @@ func (h *MenuHandlers) GetMpesaMaxLimit(ctx context.Context, sym string, input [
 	}
 
 	// get the recipient's phone number from the address
-	recipientPhoneNumber, err := userStore.ReadEntry(ctx, publicKeyNormalized, storedb.DATA_PUBLIC_KEY_REVERSE)
+	recipientPhoneNumber, err := userStore.ReadIndex(ctx, storedb.DATA_PUBLIC_KEY_REVERSE, publicKeyNormalized)
 	if err != nil || len(recipientPhoneNumber) == 0 {
 		logg.WarnCtxf(ctx, "Alias address not registered, switching to normal transaction", "address", mpesaAddress)
 		recipientPhoneNumber = nil
@@ -115,6 +127,9 @@ func (h *MenuHandlers) GetMpesaMaxLimit(ctx context.Context, sym string, input [
 		}
 
 		activeFloat, _ := strconv.ParseFloat(string(metadata.Balance), 64)
+		if limited && allowance < activeFloat {
+			activeFloat = allowance
+		}
 		kshValue := activeFloat * rates.Buy
 
 		maxKshFormatted, _ := store.TruncateDecimalString(fmt.Sprintf("%f", kshValue), 0)
@@ -132,7 +147,7 @@ func (h *MenuHandlers) GetMpesaMaxLimit(ctx context.Context, sym string, input [
 			maxKshFormatted,
 		)
 
-		res.FlagReset = append(res.FlagReset, flag_low_swap_amount, flag_api_call_error, flag_incorrect_voucher, flag_incorrect_pool)
+		res.FlagReset = append(res.FlagReset, flag_low_swap_amount, flag_api_call_error, flag_incorrect_voucher, flag_incorrect_pool, flag_spend_limit)
 
 		return res, nil
 	}
@@ -215,7 +230,7 @@ func (h *MenuHandlers) GetMpesaMaxLimit(ctx context.Context, sym string, input [
 		maxKshFormatted,
 	)
 
-	res.FlagReset = append(res.FlagReset, flag_low_swap_amount, flag_api_call_error, flag_incorrect_voucher, flag_incorrect_pool)
+	res.FlagReset = append(res.FlagReset, flag_low_swap_amount, flag_api_call_error, flag_incorrect_voucher, flag_incorrect_pool, flag_spend_limit)
 
 	return res, nil
 }
@@ -290,6 +305,11 @@ func (h *MenuHandlers) GetMpesaPreview(ctx context.Context, sym string, input []
 		return res, err
 	}
 
+	err = h.newIntentNonce(ctx, sessionId)
+	if err != nil {
+		return res, err
+	}
+
 	if string(transactionType) == "normal" {
 		// get the max based on the selected voucher balance
 		maxValue, err := strconv.ParseFloat(mpesaWithdrawalVoucher.Balance, 64)
@@ -303,6 +323,11 @@ func (h *MenuHandlers) GetMpesaPreview(ctx context.Context, sym string, input []
 			return res, nil
 		}
 
+		ok, err = h.checkSpendLimit(ctx, sessionId, mpesaWithdrawalVoucher.TokenAddress, mpesaWithdrawalVoucher.TokenSymbol, inputAmount, inputAmount, &res)
+		if err != nil || !ok {
+			return res, err
+		}
+
 		// Format the input amount to 2 decimal places
 		inputAmountStr := fmt.Sprintf("%f", inputAmount)
 		qouteInputAmount, _ := store.TruncateDecimalString(inputAmountStr, 2)
@@ -388,6 +413,13 @@ func (h *MenuHandlers) GetMpesaPreview(ctx context.Context, sym string, input []
 
 	// covert for display
 	quoteInputStr := store.ScaleDownBalance(sendInputAmount, mpesaWithdrawalVoucher.TokenDecimals)
+
+	// the swapped amount must be within the spending limits of the account
+	quoteInputValue, _ := strconv.ParseFloat(quoteInputStr, 64)
+	ok, err = h.checkSpendLimit(ctx, sessionId, mpesaWithdrawalVoucher.TokenAddress, mpesaWithdrawalVoucher.TokenSymbol, quoteInputValue, quoteInputValue, &res)
+	if err != nil || !ok {
+		return res, err
+	}
 	// Format the quoteInputStr amount to 2 decimal places
 	qouteInputAmount, _ := store.TruncateDecimalString(quoteInputStr, 2)
 
@@ -443,8 +475,46 @@ func (h *MenuHandlers) InitiateGetMpesa(ctx context.Context, sym string, input [
 			return res, err
 		}
 
-		tokenTransfer, err := h.accountService.TokenTransfer(ctx, finalAmountStr, data.PublicKey, mpesaAddress, mpesaWithdrawalVoucher.TokenAddress)
+		intentKey, content, duplicate, err := h.beginIntent(ctx, sessionId, l, "get_mpesa", finalAmountStr, data.PublicKey, mpesaAddress, mpesaWithdrawalVoucher.TokenAddress)
+		if err != nil {
+			return res, err
+		}
+		if duplicate {
+			res.Content = content
+			res.FlagReset = append(res.FlagReset, flag_account_authorized)
+			return res, nil
+		}
+
+		entry := store.LedgerEntry{
+			Type:         store.LedgerMpesa,
+			Amount:       data.Amount,
+			Symbol:       mpesaWithdrawalVoucher.TokenSymbol,
+			Counterparty: "Mpesa",
+			From:         data.PublicKey,
+			To:           mpesaAddress,
+			TokenAddress: mpesaWithdrawalVoucher.TokenAddress,
+			Value:        finalAmountStr,
+		}
+
+		var tokenTransfer *models.TokenTransferResponse
+		ok, err = h.spend(ctx, sessionId, mpesaWithdrawalVoucher.TokenAddress, mpesaWithdrawalVoucher.TokenSymbol, data.Amount, func() error {
+			var err error
+			tokenTransfer, err = h.accountService.TokenTransfer(ctx, finalAmountStr, data.PublicKey, mpesaAddress, mpesaWithdrawalVoucher.TokenAddress)
+			return err
+		})
+		if !ok {
+			// not submitted
+			h.releaseIntent(ctx, sessionId, intentKey)
+			if err != nil {
+				return res, err
+			}
+			res.Content, err = h.spendLimitContent(ctx, l, sessionId, mpesaWithdrawalVoucher.TokenAddress, mpesaWithdrawalVoucher.TokenSymbol)
+			res.FlagReset = append(res.FlagReset, flag_account_authorized)
+			return res, err
+		}
 		if err != nil {
+			h.abortIntent(ctx, sessionId, intentKey, err)
+			h.recordFailure(ctx, sessionId, entry, err)
 			res.FlagSet = append(res.FlagSet, flag_api_call_error)
 			res.Content = l.Get("Your request failed. Please try again later.")
 			logg.ErrorCtxf(ctx, "failed on TokenTransfer", "error", err)
@@ -452,6 +522,9 @@ func (h *MenuHandlers) InitiateGetMpesa(ctx context.Context, sym string, input [
 		}
 
 		logg.InfoCtxf(ctx, "TokenTransfer normal", "trackingId", tokenTransfer.TrackingId)
+		h.completeIntent(ctx, sessionId, intentKey, tokenTransfer.TrackingId)
+		entry.TrackingId = tokenTransfer.TrackingId
+		h.recordSubmission(ctx, sessionId, entry)
 
 		res.Content = l.Get("Your request has been sent. Please await confirmation")
 
@@ -485,9 +558,35 @@ func (h *MenuHandlers) InitiateGetMpesa(ctx context.Context, sym string, input [
 
 	swapAmountStr := string(swapAmount)
 
+	intentKey, content, duplicate, err := h.beginIntent(ctx, sessionId, l, "get_mpesa_swap", swapAmountStr, string(publicKey), mpesaWithdrawalVoucher.TokenAddress, string(activePoolAddress), swapToVoucher.TokenAddress)
+	if err != nil {
+		return res, err
+	}
+	if duplicate {
+		res.Content = content
+		res.FlagReset = append(res.FlagReset, flag_account_authorized)
+		return res, nil
+	}
+
 	// Call the poolSwap API
-	poolSwap, err := h.accountService.PoolSwap(ctx, swapAmountStr, string(publicKey), mpesaWithdrawalVoucher.TokenAddress, string(activePoolAddress), swapToVoucher.TokenAddress)
+	var poolSwap *models.PoolSwapResult
+	ok, err = h.spend(ctx, sessionId, mpesaWithdrawalVoucher.TokenAddress, mpesaWithdrawalVoucher.TokenSymbol, store.ScaleDownBalance(swapAmountStr, mpesaWithdrawalVoucher.TokenDecimals), func() error {
+		var err error
+		poolSwap, err = h.accountService.PoolSwap(ctx, swapAmountStr, string(publicKey), mpesaWithdrawalVoucher.TokenAddress, string(activePoolAddress), swapToVoucher.TokenAddress)
+		return err
+	})
+	if !ok {
+		// not submitted
+		h.releaseIntent(ctx, sessionId, intentKey)
+		if err != nil {
+			return res, err
+		}
+		res.Content, err = h.spendLimitContent(ctx, l, sessionId, mpesaWithdrawalVoucher.TokenAddress, mpesaWithdrawalVoucher.TokenSymbol)
+		res.FlagReset = append(res.FlagReset, flag_account_authorized)
+		return res, err
+	}
 	if err != nil {
+		h.abortIntent(ctx, sessionId, intentKey, err)
 		flag_api_call_error, _ := h.flagManager.GetFlag("flag_api_call_error")
 		res.FlagSet = append(res.FlagSet, flag_api_call_error)
 		res.Content = l.Get("Your request failed. Please try again later.")
@@ -496,6 +595,8 @@ func (h *MenuHandlers) InitiateGetMpesa(ctx context.Context, sym string, input [
 	}
 
 	logg.InfoCtxf(ctx, "mpesa poolSwap before transfer", "swapTrackingId", poolSwap.TrackingId)
+	// the swap has been submitted, so the intent is kept whatever the outcome of the transfer
+	h.stepIntent(ctx, sessionId, intentKey, poolSwap.TrackingId)
 
 	// TODO: remove this temporary time delay and replace with a swap and send endpoint
 	time.Sleep(1 * time.Second)
@@ -505,9 +606,21 @@ func (h *MenuHandlers) InitiateGetMpesa(ctx context.Context, sym string, input [
 		return res, err
 	}
 
+	entry := store.LedgerEntry{
+		Type:         store.LedgerMpesa,
+		Amount:       store.ScaleDownBalance(string(amount), swapToVoucher.TokenDecimals),
+		Symbol:       swapToVoucher.TokenSymbol,
+		Counterparty: "Mpesa",
+		From:         string(publicKey),
+		To:           mpesaAddress,
+		TokenAddress: swapToVoucher.TokenAddress,
+		Value:        string(amount),
+	}
+
 	// Initiate a send to mpesa after the swap
 	tokenTransfer, err := h.accountService.TokenTransfer(ctx, string(amount), string(publicKey), mpesaAddress, swapToVoucher.TokenAddress)
 	if err != nil {
+		h.recordFailure(ctx, sessionId, entry, err)
 		res.FlagSet = append(res.FlagSet, flag_api_call_error)
 		res.Content = l.Get("Your request failed. Please try again later.")
 		logg.ErrorCtxf(ctx, "failed on TokenTransfer after swap", "error", err)
@@ -515,6 +628,9 @@ func (h *MenuHandlers) InitiateGetMpesa(ctx context.Context, sym string, input [
 	}
 
 	logg.InfoCtxf(ctx, "final TokenTransfer after swap", "trackingId", tokenTransfer.TrackingId)
+	h.completeIntent(ctx, sessionId, intentKey, tokenTransfer.TrackingId)
+	entry.TrackingId = tokenTransfer.TrackingId
+	h.recordSubmission(ctx, sessionId, entry)
 
 	res.Content = l.Get("Your request has been sent. Please await confirmation")
 	res.FlagReset = append(res.FlagReset, flag_account_authorized)
@@ -615,6 +731,11 @@ func (h *MenuHandlers) SendMpesaPreview(ctx context.Context, sym string, input [
 
 	defaultAsset := config.DefaultMpesaAsset()
 
+	err = h.newIntentNonce(ctx, sessionId)
+	if err != nil {
+		return res, err
+	}
+
 	res.Content = l.Get(
 		"You will get a prompt for your Mpesa PIN shortly to send %s ksh and receive ~ %s %s",
 		inputStr, estimateFormatted, defaultAsset,
@@ -669,9 +790,29 @@ func (h *MenuHandlers) InitiateSendMpesa(ctx context.Context, sym string, input
 		return res, err
 	}
 
+	intentKey, content, duplicate, err := h.beginIntent(ctx, sessionId, l, "send_mpesa", string(publicKey), phoneNumber, defaultAsset, string(amount))
+	if err != nil {
+		return res, err
+	}
+	if duplicate {
+		res.Content = content
+		res.FlagReset = append(res.FlagReset, flag_account_authorized)
+		return res, nil
+	}
+
+	entry := store.LedgerEntry{
+		Type:         store.LedgerMpesa,
+		Amount:       string(amount),
+		Symbol:       "Ksh",
+		Counterparty: defaultAsset,
+		To:           string(publicKey),
+	}
+
 	// Call the trigger onramp API
 	triggerOnramp, err := h.accountService.MpesaTriggerOnramp(ctx, string(publicKey), phoneNumber, defaultAsset, amountInt)
 	if err != nil {
+		h.abortIntent(ctx, sessionId, intentKey, err)
+		h.recordFailure(ctx, sessionId, entry, err)
 		flag_api_call_error, _ := h.flagManager.GetFlag("flag_api_call_error")
 		res.FlagSet = append(res.FlagSet, flag_api_call_error)
 		res.Content = l.Get("Your request failed. Please try again later.")
@@ -680,6 +821,10 @@ func (h *MenuHandlers) InitiateSendMpesa(ctx context.Context, sym string, input
 	}
 
 	logg.InfoCtxf(ctx, "MpesaTriggerOnramp", "transactionCode", triggerOnramp.TransactionCode)
+	h.completeIntent(ctx, sessionId, intentKey, triggerOnramp.TransactionCode)
+	// the onramp is confirmed by the first transfer received after the payment
+	entry.TrackingId = triggerOnramp.TransactionCode
+	h.recordSubmission(ctx, sessionId, entry)
 
 	res.Content = l.Get("Your request has been sent. Thank you for using Sarafu")
 	res.FlagReset = append(res.FlagReset, flag_account_authorized)
@@ -0,0 +1,604 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"git.defalsify.org/vise.git/resource"
+	"git.grassecon.net/grassrootseconomics/common/identity"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/models"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"gopkg.in/leonelquinteros/gotext.v1"
+)
+
+// resolveMultiSendRecipient returns the address of a single recipient of a multi-recipient transfer.
+//
+// The recipient is resolved like the recipient of a single transfer, so that phone numbers must
+// belong to registered accounts. It returns false if the recipient cannot be resolved.
+func (h *MenuHandlers) resolveMultiSendRecipient(ctx context.Context, sessionId string, recipient string) (string, bool, error) {
+	flag_invalid_recipient, _ := h.flagManager.GetFlag("flag_invalid_recipient")
+	flag_invalid_recipient_with_invite, _ := h.flagManager.GetFlag("flag_invalid_recipient_with_invite")
+	flag_api_call_error, _ := h.flagManager.GetFlag("flag_api_call_error")
+
+	recipientType, err := identity.CheckRecipient(recipient)
+	if err != nil {
+		return "", false, nil
+	}
+
+	var res resource.Result
+	switch recipientType {
+	case "phone number":
+		_, err = h.handlePhoneNumber(ctx, sessionId, recipient, &res)
+	case "address":
+		_, err = h.handleAddress(ctx, sessionId, recipient, &res)
+	case "alias":
+		_, err = h.handleAlias(ctx, sessionId, recipient, &res)
+	default:
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	for _, flag := range res.FlagSet {
+		switch flag {
+		case flag_invalid_recipient, flag_invalid_recipient_with_invite, flag_api_call_error:
+			return "", false, nil
+		}
+	}
+
+	address, err := h.userdataStore.ReadEntry(ctx, sessionId, storedb.DATA_RECIPIENT)
+	if err != nil {
+		return "", false, err
+	}
+	return string(address), true, nil
+}
+
+// validateMultiSendRecipients resolves the recipients of a multi-recipient transfer, and
+// saves them as the legs of the transfer.
+//
+// The first recipient that cannot be resolved is returned as content with the invalid recipient flag set.
+func (h *MenuHandlers) validateMultiSendRecipients(ctx context.Context, sessionId string, recipients []string, res *resource.Result) (resource.Result, error) {
+	flag_invalid_recipient, _ := h.flagManager.GetFlag("flag_invalid_recipient")
+	flag_multi_send, _ := h.flagManager.GetFlag("flag_multi_send")
+
+	if len(recipients) > store.MultiSendSize {
+		res.FlagSet = append(res.FlagSet, flag_invalid_recipient)
+		res.Content = strings.Join(recipients, ",")
+		return *res, nil
+	}
+
+	var legs []store.MultiSendLeg
+	for _, recipient := range recipients {
+		if contact, ok := h.resolveSendContact(ctx, sessionId, recipient); ok {
+			recipient = contact
+		}
+		address, ok, err := h.resolveMultiSendRecipient(ctx, sessionId, recipient)
+		if err != nil {
+			logg.ErrorCtxf(ctx, "failed to resolve recipient", "recipient", recipient, "error", err)
+			return *res, err
+		}
+		if !ok {
+			res.FlagSet = append(res.FlagSet, flag_invalid_recipient)
+			res.Content = recipient
+			return *res, nil
+		}
+		legs = append(legs, store.MultiSendLeg{
+			RecipientInput: recipient,
+			Recipient:      address,
+		})
+	}
+
+	err := store.WriteMultiSendLegs(ctx, h.userdataStore, sessionId, legs)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to write multi-recipient transfer legs", "error", err)
+		return *res, err
+	}
+	err = h.userdataStore.WriteEntry(ctx, sessionId, storedb.DATA_SEND_TRANSACTION_TYPE, []byte("normal"))
+	if err != nil {
+		logg.ErrorCtxf(ctx, "Failed to write transaction type", "type", "normal", "error", err)
+		return *res, err
+	}
+
+	res.FlagSet = append(res.FlagSet, flag_multi_send)
+	return *res, nil
+}
+
+// multiSendRecipients returns the recipients of the given input if it names several recipients,
+// either separated by commas or as a contact group.
+func (h *MenuHandlers) multiSendRecipients(ctx context.Context, sessionId string, input string) ([]string, bool) {
+	recipients := store.ParseMultiSendRecipients(input)
+	if len(recipients) > 1 {
+		return recipients, true
+	}
+	group, ok, err := store.FindContactGroup(ctx, h.userdataStore, sessionId, input)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read contact groups", "error", err)
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+	return group.Recipients, true
+}
+
+// MultiSendMaxAmount shows the amount prompt of a multi-recipient transfer, with the
+// active voucher balance divided among the recipients as the maximum amount.
+func (h *MenuHandlers) MultiSendMaxAmount(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	legs, err := store.ReadMultiSendLegs(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read multi-recipient transfer legs", "error", err)
+		return res, err
+	}
+	if len(legs) == 0 {
+		return res, fmt.Errorf("no recipients for multi-recipient transfer")
+	}
+	activeBal, err := h.userdataStore.ReadEntry(ctx, sessionId, storedb.DATA_ACTIVE_BAL)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read activeBal entry with", "key", storedb.DATA_ACTIVE_BAL, "error", err)
+		return res, err
+	}
+	activeSym, err := h.userdataStore.ReadEntry(ctx, sessionId, storedb.DATA_ACTIVE_SYM)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read activeSym entry with", "key", storedb.DATA_ACTIVE_SYM, "error", err)
+		return res, err
+	}
+
+	balance, err := strconv.ParseFloat(string(activeBal), 64)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "Failed to convert the activeBal to a float", "error", err)
+		return res, err
+	}
+	maxAmount, _ := store.TruncateDecimalString(strconv.FormatFloat(balance/float64(len(legs)), 'f', -1, 64), 2)
+
+	res.Content = l.Get("Maximum amount per recipient: %s %s\nEnter amount:", maxAmount, string(activeSym))
+	return res, nil
+}
+
+// ValidateMultiSendAmount validates the amount sent to each recipient of a multi-recipient
+// transfer, checking the total of all legs against the active voucher balance.
+func (h *MenuHandlers) ValidateMultiSendAmount(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_invalid_amount, _ := h.flagManager.GetFlag("flag_invalid_amount")
+
+	inputStr := string(input)
+	if inputStr == "0" {
+		res.FlagReset = append(res.FlagReset, flag_invalid_amount)
+		return res, nil
+	}
+
+	userStore := h.userdataStore
+	legs, err := store.ReadMultiSendLegs(ctx, userStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read multi-recipient transfer legs", "error", err)
+		return res, err
+	}
+	activeBal, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_ACTIVE_BAL)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read activeBal entry with", "key", storedb.DATA_ACTIVE_BAL, "error", err)
+		return res, err
+	}
+	maxValue, err := strconv.ParseFloat(string(activeBal), 64)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "Failed to convert the activeBal to a float", "error", err)
+		return res, err
+	}
+
+	formattedAmount, err := store.TruncateDecimalString(inputStr, 2)
+	if err != nil {
+		res.FlagSet = append(res.FlagSet, flag_invalid_amount)
+		res.Content = inputStr
+		return res, nil
+	}
+	inputAmount, _ := strconv.ParseFloat(formattedAmount, 64)
+	if inputAmount < 0.1 || inputAmount*float64(len(legs)) > maxValue {
+		res.FlagSet = append(res.FlagSet, flag_invalid_amount)
+		res.Content = inputStr
+		return res, nil
+	}
+
+	// each transfer and their total must be within the spending limits of the account
+	if spendLimitsEnabled() {
+		activeSym, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_ACTIVE_SYM)
+		if err != nil {
+			logg.ErrorCtxf(ctx, "failed to read activeSym entry with", "key", storedb.DATA_ACTIVE_SYM, "error", err)
+			return res, err
+		}
+		activeAddress, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_ACTIVE_ADDRESS)
+		if err != nil {
+			logg.ErrorCtxf(ctx, "failed to read activeAddress entry with", "key", storedb.DATA_ACTIVE_ADDRESS, "error", err)
+			return res, err
+		}
+		ok, err = h.checkSpendLimit(ctx, sessionId, string(activeAddress), string(activeSym), inputAmount, inputAmount*float64(len(legs)), &res)
+		if err != nil || !ok {
+			return res, err
+		}
+	}
+
+	err = userStore.WriteEntry(ctx, sessionId, storedb.DATA_AMOUNT, []byte(formattedAmount))
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to write amount entry with", "key", storedb.DATA_AMOUNT, "value", formattedAmount, "error", err)
+		return res, err
+	}
+
+	res.FlagReset = append(res.FlagReset, flag_invalid_amount)
+	res.Content = formattedAmount
+	return res, nil
+}
+
+// MultiSendPreview lists the legs of the multi-recipient transfer awaiting authorization.
+func (h *MenuHandlers) MultiSendPreview(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	userStore := h.userdataStore
+	legs, err := store.ReadMultiSendLegs(ctx, userStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read multi-recipient transfer legs", "error", err)
+		return res, err
+	}
+	amount, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_AMOUNT)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read amount entry with", "key", storedb.DATA_AMOUNT, "error", err)
+		return res, err
+	}
+	activeSym, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_ACTIVE_SYM)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read activeSym entry with", "key", storedb.DATA_ACTIVE_SYM, "error", err)
+		return res, err
+	}
+
+	err = h.newIntentNonce(ctx, sessionId)
+	if err != nil {
+		return res, err
+	}
+
+	value, _ := strconv.ParseFloat(string(amount), 64)
+	total, _ := store.TruncateDecimalString(strconv.FormatFloat(value*float64(len(legs)), 'f', -1, 64), 2)
+
+	lines := []string{l.Get("Send %s %s to each of:", string(amount), string(activeSym))}
+	for i, leg := range legs {
+		lines = append(lines, fmt.Sprintf("%d%s%s", i+1, h.ReplaceSeparatorFunc(":"), store.ShortenAddress(leg.RecipientInput)))
+	}
+	lines = append(lines, l.Get("Total: %s %s", total, string(activeSym)))
+	res.Content = strings.Join(lines, "\n")
+	return res, nil
+}
+
+// InitiateMultiSend submits each leg of the authorized multi-recipient transfer through
+// TokenTransfer, and records the result of every leg.
+//
+// Legs that fail do not stop the remaining legs from being submitted.
+func (h *MenuHandlers) InitiateMultiSend(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_account_authorized, _ := h.flagManager.GetFlag("flag_account_authorized")
+	flag_multi_send, _ := h.flagManager.GetFlag("flag_multi_send")
+	flag_api_call_error, _ := h.flagManager.GetFlag("flag_api_call_error")
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	userStore := h.userdataStore
+	legs, err := store.ReadMultiSendLegs(ctx, userStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read multi-recipient transfer legs", "error", err)
+		return res, err
+	}
+	_, _, activeSym, activeAddress, publicKey, activeDecimal, err := h.getSessionData(ctx, sessionId)
+	if err != nil {
+		return res, err
+	}
+	amount, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_AMOUNT)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read amount entry with", "key", storedb.DATA_AMOUNT, "error", err)
+		return res, err
+	}
+
+	value, err := store.ParseAndScaleAmount(string(amount), string(activeDecimal))
+	if err != nil {
+		return res, err
+	}
+
+	params := []string{value, string(publicKey), string(activeAddress)}
+	for _, leg := range legs {
+		params = append(params, leg.Recipient)
+	}
+	intentKey, content, duplicate, err := h.beginIntent(ctx, sessionId, l, "multi_send", params...)
+	if err != nil {
+		return res, err
+	}
+	res.FlagReset = append(res.FlagReset, flag_account_authorized, flag_multi_send)
+	if duplicate {
+		res.Content = content
+		return res, nil
+	}
+
+	var trackingIds []string
+	var failed []string
+	// the failure of a leg that may still have been submitted, if any
+	var cause error
+	for i, leg := range legs {
+		entry := store.LedgerEntry{
+			Type:         store.LedgerTransfer,
+			Amount:       string(amount),
+			Symbol:       string(activeSym),
+			Counterparty: leg.RecipientInput,
+			From:         string(publicKey),
+			To:           leg.Recipient,
+			TokenAddress: string(activeAddress),
+			Value:        value,
+		}
+		var r *models.TokenTransferResponse
+		ok, err := h.spend(ctx, sessionId, string(activeAddress), string(activeSym), string(amount), func() error {
+			var err error
+			r, err = h.accountService.TokenTransfer(ctx, value, string(publicKey), leg.Recipient, string(activeAddress))
+			return err
+		})
+		if !ok {
+			// not submitted
+			if err == nil {
+				err = errSpendLimit
+			}
+			logg.ErrorCtxf(ctx, "multi-recipient transfer leg not submitted", "recipient", leg.Recipient, "error", err)
+			legs[i].Error = err.Error()
+			failed = append(failed, store.ShortenAddress(leg.RecipientInput))
+			continue
+		}
+		if err != nil {
+			logg.ErrorCtxf(ctx, "failed on TokenTransfer", "recipient", leg.Recipient, "error", err)
+			h.recordFailure(ctx, sessionId, entry, err)
+			legs[i].Error = err.Error()
+			failed = append(failed, store.ShortenAddress(leg.RecipientInput))
+			if !rejected(err) {
+				cause = err
+			}
+			continue
+		}
+		logg.InfoCtxf(ctx, "TokenTransfer", "trackingId", r.TrackingId, "recipient", leg.Recipient)
+		legs[i].TrackingId = r.TrackingId
+		trackingIds = append(trackingIds, r.TrackingId)
+		entry.TrackingId = r.TrackingId
+		h.recordSubmission(ctx, sessionId, entry)
+		h.recordRecentRecipient(ctx, sessionId, leg.RecipientInput)
+	}
+
+	err = store.AddMultiSend(ctx, userStore, sessionId, store.MultiSendRecord{
+		Amount:       string(amount),
+		Symbol:       string(activeSym),
+		TokenAddress: string(activeAddress),
+		Legs:         legs,
+	})
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to record multi-recipient transfer", "error", err)
+	}
+
+	if len(trackingIds) == 0 {
+		// the transfer may only be tried again if none of the legs may have been submitted
+		if cause != nil {
+			h.abortIntent(ctx, sessionId, intentKey, cause)
+		} else {
+			h.releaseIntent(ctx, sessionId, intentKey)
+		}
+		res.FlagSet = append(res.FlagSet, flag_api_call_error)
+		res.Content = l.Get("Your request failed. Please try again later.")
+		return res, nil
+	}
+	h.completeIntent(ctx, sessionId, intentKey, strings.Join(trackingIds, ","))
+
+	res.Content = l.Get(
+		"Your request has been sent. %d of %d recipients will receive %s %s each.",
+		len(trackingIds),
+		len(legs),
+		string(amount),
+		string(activeSym),
+	)
+	if len(failed) > 0 {
+		res.Content += "\n" + l.Get("Failed: %s", strings.Join(failed, ", "))
+	}
+	return res, nil
+}
+
+// GetContactGroups lists the contact groups of the account.
+func (h *MenuHandlers) GetContactGroups(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	groups, err := store.ReadContactGroups(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read contact groups", "error", err)
+		return res, err
+	}
+	if len(groups) == 0 {
+		res.Content = l.Get("You have no groups")
+		return res, nil
+	}
+
+	var lines []string
+	for i, g := range groups {
+		lines = append(lines, fmt.Sprintf("%d%s%s (%d)", i+1, h.ReplaceSeparatorFunc(":"), g.Name, len(g.Recipients)))
+	}
+	res.Content = strings.Join(lines, "\n")
+	return res, nil
+}
+
+// SelectContactGroup selects the contact group with the given number and shows its members.
+func (h *MenuHandlers) SelectContactGroup(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_invalid_contact, _ := h.flagManager.GetFlag("flag_invalid_contact")
+
+	groups, err := store.ReadContactGroups(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read contact groups", "error", err)
+		return res, err
+	}
+	index, err := strconv.Atoi(strings.TrimSpace(string(input)))
+	if err != nil || index < 1 || index > len(groups) {
+		res.FlagSet = append(res.FlagSet, flag_invalid_contact)
+		return res, nil
+	}
+
+	err = h.userdataStore.WriteEntry(ctx, sessionId, storedb.DATA_CONTACT_GROUP_SELECTED, []byte(strconv.Itoa(index-1)))
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to write selected contact group", "error", err)
+		return res, err
+	}
+
+	g := groups[index-1]
+	res.FlagReset = append(res.FlagReset, flag_invalid_contact)
+	res.Content = fmt.Sprintf("%s\n%s", g.Name, strings.Join(g.Recipients, ", "))
+	return res, nil
+}
+
+// SaveContactGroupName keeps the name of the contact group being added.
+func (h *MenuHandlers) SaveContactGroupName(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_invalid_contact, _ := h.flagManager.GetFlag("flag_invalid_contact")
+
+	name, ok := validContactName(input)
+	if !ok || strings.Contains(name, ",") {
+		res.FlagSet = append(res.FlagSet, flag_invalid_contact)
+		return res, nil
+	}
+
+	err := h.userdataStore.WriteEntry(ctx, sessionId, storedb.DATA_TEMPORARY_VALUE, []byte(name))
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to write temporaryValue entry with", "key", storedb.DATA_TEMPORARY_VALUE, "value", name, "error", err)
+		return res, err
+	}
+	res.FlagReset = append(res.FlagReset, flag_invalid_contact)
+	return res, nil
+}
+
+// AddContactGroup saves the contact group being added with the given comma-separated members.
+func (h *MenuHandlers) AddContactGroup(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_invalid_contact, _ := h.flagManager.GetFlag("flag_invalid_contact")
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	recipients := store.ParseMultiSendRecipients(strings.ReplaceAll(string(input), " ", ""))
+	if len(recipients) < 2 || len(recipients) > store.MultiSendSize {
+		res.FlagSet = append(res.FlagSet, flag_invalid_contact)
+		return res, nil
+	}
+	for _, recipient := range recipients {
+		_, err := identity.CheckRecipient(recipient)
+		if err != nil {
+			res.FlagSet = append(res.FlagSet, flag_invalid_contact)
+			return res, nil
+		}
+	}
+	res.FlagReset = append(res.FlagReset, flag_invalid_contact)
+
+	name, err := h.userdataStore.ReadEntry(ctx, sessionId, storedb.DATA_TEMPORARY_VALUE)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read temporaryValue entry with", "key", storedb.DATA_TEMPORARY_VALUE, "error", err)
+		return res, err
+	}
+
+	err = store.AddContactGroup(ctx, h.userdataStore, sessionId, store.ContactGroupRecord{
+		Name:       string(name),
+		Recipients: recipients,
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrContactGroupsFull) {
+			res.Content = l.Get("You have too many groups. Please remove a group first")
+			return res, nil
+		}
+		logg.ErrorCtxf(ctx, "failed to add contact group", "error", err)
+		return res, err
+	}
+
+	res.Content = l.Get("The group %s has been saved. Enter its name when sending to pay all its members", string(name))
+	return res, nil
+}
+
+// DeleteContactGroup removes the selected contact group.
+func (h *MenuHandlers) DeleteContactGroup(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	v, err := h.userdataStore.ReadEntry(ctx, sessionId, storedb.DATA_CONTACT_GROUP_SELECTED)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read selected contact group", "error", err)
+		return res, err
+	}
+	index, err := strconv.Atoi(string(v))
+	if err != nil {
+		return res, err
+	}
+	groups, err := store.ReadContactGroups(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read contact groups", "error", err)
+		return res, err
+	}
+	if index < 0 || index >= len(groups) {
+		return res, fmt.Errorf("contact group not found: index %d out of range", index)
+	}
+
+	err = store.DeleteContactGroup(ctx, h.userdataStore, sessionId, index)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to delete contact group", "index", index, "error", err)
+		return res, err
+	}
+
+	res.Content = l.Get("The group %s has been removed", groups[index].Name)
+	return res, nil
+}
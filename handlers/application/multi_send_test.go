@@ -0,0 +1,182 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"git.defalsify.org/vise.git/resource"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/models"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/testutil/mocks"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestMultiSend(t *testing.T) {
+	sessionId := "session123"
+
+	ctx, userStore := InitializeTestStore(t)
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
+
+	fm, err := NewFlagManager(flagsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag_invalid_recipient, _ := fm.GetFlag("flag_invalid_recipient")
+	flag_multi_send, _ := fm.GetFlag("flag_multi_send")
+	flag_invalid_amount, _ := fm.GetFlag("flag_invalid_amount")
+	flag_account_authorized, _ := fm.GetFlag("flag_account_authorized")
+
+	mockAccountService := new(mocks.MockAccountService)
+	h := &MenuHandlers{
+		userdataStore:        userStore,
+		accountService:       mockAccountService,
+		flagManager:          fm,
+		ReplaceSeparatorFunc: mockReplaceSeparator,
+	}
+
+	recipients := map[string]string{
+		"+254711223344": "0x1111",
+		"+254722334455": "0x2222",
+	}
+	for number, publicKey := range recipients {
+		err = userStore.WriteEntry(ctx, number, storedb.DATA_PUBLIC_KEY, []byte(publicKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries := map[storedb.DataTyp][]byte{
+		storedb.DATA_PUBLIC_KEY:     []byte("0X13242618721"),
+		storedb.DATA_ACTIVE_SYM:     []byte("SRF"),
+		storedb.DATA_ACTIVE_BAL:     []byte("25"),
+		storedb.DATA_ACTIVE_DECIMAL: []byte("6"),
+		storedb.DATA_ACTIVE_ADDRESS: []byte("0xd4c288865Ce"),
+	}
+	for k, v := range entries {
+		err = userStore.WriteEntry(ctx, sessionId, k, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// every recipient must be registered
+	res, err := h.ValidateRecipient(ctx, "validate_recipient", []byte("0711223344, 0700000000"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagSet: []uint32{flag_invalid_recipient}, Content: "0700000000"}, res)
+
+	res, err = h.ValidateRecipient(ctx, "validate_recipient", []byte("0711223344, 0722334455"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagSet: []uint32{flag_multi_send}}, res)
+
+	res, err = h.MultiSendMaxAmount(ctx, "multi_send_max_amount", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "Maximum amount per recipient: 12.50 SRF\nEnter amount:", res.Content)
+
+	// the total of all recipients is checked against the balance
+	res, err = h.ValidateMultiSendAmount(ctx, "validate_multi_send_amount", []byte("15"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagSet: []uint32{flag_invalid_amount}, Content: "15"}, res)
+
+	res, err = h.ValidateMultiSendAmount(ctx, "validate_multi_send_amount", []byte("10"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagReset: []uint32{flag_invalid_amount}, Content: "10.00"}, res)
+
+	res, err = h.MultiSendPreview(ctx, "multi_send_preview", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "Send 10.00 SRF to each of:\n1: 0711223344\n2: 0722334455\nTotal: 20.00 SRF", res.Content)
+
+	// a failed recipient does not stop the others
+	mockAccountService.On("TokenTransfer").Return(&models.TokenTransferResponse{TrackingId: "1234567890"}, nil).Once()
+	mockAccountService.On("TokenTransfer").Return((*models.TokenTransferResponse)(nil), errors.New("transfer failed")).Once()
+	res, err = h.InitiateMultiSend(ctx, "initiate_multi_send", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{
+		FlagReset: []uint32{flag_account_authorized, flag_multi_send},
+		Content:   "Your request has been sent. 1 of 2 recipients will receive 10.00 SRF each.\nFailed: 0722334455",
+	}, res)
+	mockAccountService.AssertNumberOfCalls(t, "TokenTransfer", 2)
+
+	sends, err := store.ReadMultiSends(ctx, userStore, sessionId)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(sends))
+	assert.Equal(t, 1, sends[0].Succeeded())
+	assert.Equal(t, "0x1111", sends[0].Legs[0].Recipient)
+	assert.Equal(t, "1234567890", sends[0].Legs[0].TrackingId)
+}
+
+func TestContactGroups(t *testing.T) {
+	sessionId := "session123"
+
+	ctx, userStore := InitializeTestStore(t)
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
+
+	fm, err := NewFlagManager(flagsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag_invalid_contact, _ := fm.GetFlag("flag_invalid_contact")
+	flag_multi_send, _ := fm.GetFlag("flag_multi_send")
+
+	h := &MenuHandlers{
+		userdataStore:        userStore,
+		accountService:       new(mocks.MockAccountService),
+		flagManager:          fm,
+		ReplaceSeparatorFunc: mockReplaceSeparator,
+	}
+
+	for number, publicKey := range map[string]string{"+254711223344": "0x1111", "+254722334455": "0x2222"} {
+		err = userStore.WriteEntry(ctx, number, storedb.DATA_PUBLIC_KEY, []byte(publicKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = userStore.WriteEntry(ctx, sessionId, storedb.DATA_ACTIVE_ADDRESS, []byte("0xd4c288865Ce"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := h.GetContactGroups(ctx, "get_contact_groups", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "You have no groups", res.Content)
+
+	res, err = h.SaveContactGroupName(ctx, "save_contact_group_name", []byte("Chama"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagReset: []uint32{flag_invalid_contact}}, res)
+
+	// a group has at least two members
+	res, err = h.AddContactGroup(ctx, "add_contact_group", []byte("0711223344"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagSet: []uint32{flag_invalid_contact}}, res)
+
+	res, err = h.AddContactGroup(ctx, "add_contact_group", []byte("0711223344, 0722334455"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{
+		FlagReset: []uint32{flag_invalid_contact},
+		Content:   "The group Chama has been saved. Enter its name when sending to pay all its members",
+	}, res)
+
+	res, err = h.GetContactGroups(ctx, "get_contact_groups", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "1: Chama (2)", res.Content)
+
+	// the group name is accepted as recipient
+	res, err = h.ValidateRecipient(ctx, "validate_recipient", []byte("chama"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagSet: []uint32{flag_multi_send}}, res)
+	legs, err := store.ReadMultiSendLegs(ctx, userStore, sessionId)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(legs))
+
+	res, err = h.SelectContactGroup(ctx, "select_contact_group", []byte("1"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagReset: []uint32{flag_invalid_contact}, Content: "Chama\n0711223344, 0722334455"}, res)
+
+	res, err = h.DeleteContactGroup(ctx, "delete_contact_group", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "The group Chama has been removed", res.Content)
+
+	res, err = h.GetContactGroups(ctx, "get_contact_groups", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "You have no groups", res.Content)
+}
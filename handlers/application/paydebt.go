@@ -237,6 +237,11 @@ func (h *MenuHandlers) ConfirmDebtRemoval(ctx context.Context, sym string, input
 		return res, err
 	}
 
+	err = h.newIntentNonce(ctx, sessionId)
+	if err != nil {
+		return res, err
+	}
+
 	res.Content = l.Get(
 		"Please confirm that you will use %s %s to remove your debt of %s %s\nEnter your PIN:",
 		inputStr, payDebtVoucher.TokenSymbol, qouteStr, string(activeSym),
@@ -294,9 +299,32 @@ func (h *MenuHandlers) InitiatePayDebt(ctx context.Context, sym string, input []
 
 	swapAmountStr := string(swapAmount)
 
+	intentKey, content, duplicate, err := h.beginIntent(ctx, sessionId, l, "pay_debt", swapAmountStr, string(publicKey), payDebtVoucher.TokenAddress, string(activePoolAddress), string(activeAddress))
+	if err != nil {
+		return res, err
+	}
+	if duplicate {
+		res.Content = content
+		res.FlagReset = append(res.FlagReset, flag_account_authorized)
+		return res, nil
+	}
+
+	entry := store.LedgerEntry{
+		Type:         store.LedgerPayDebt,
+		Amount:       store.ScaleDownBalance(swapAmountStr, payDebtVoucher.TokenDecimals),
+		Symbol:       payDebtVoucher.TokenSymbol,
+		Counterparty: string(activePoolSymbol),
+		From:         string(publicKey),
+		To:           string(activePoolAddress),
+		TokenAddress: payDebtVoucher.TokenAddress,
+		Value:        swapAmountStr,
+	}
+
 	// Call the poolSwap API
 	r, err := h.accountService.PoolSwap(ctx, swapAmountStr, string(publicKey), payDebtVoucher.TokenAddress, string(activePoolAddress), string(activeAddress))
 	if err != nil {
+		h.abortIntent(ctx, sessionId, intentKey, err)
+		h.recordFailure(ctx, sessionId, entry, err)
 		flag_api_call_error, _ := h.flagManager.GetFlag("flag_api_call_error")
 		res.FlagSet = append(res.FlagSet, flag_api_call_error)
 		res.Content = l.Get("Your request failed. Please try again later.")
@@ -306,6 +334,9 @@ func (h *MenuHandlers) InitiatePayDebt(ctx context.Context, sym string, input []
 
 	trackingId := r.TrackingId
 	logg.InfoCtxf(ctx, "poolSwap", "trackingId", trackingId)
+	h.completeIntent(ctx, sessionId, intentKey, trackingId)
+	entry.TrackingId = trackingId
+	h.recordSubmission(ctx, sessionId, entry)
 
 	res.Content = l.Get(
 		"Your request has been sent. You will receive an SMS when your debt of %s %s has been removed from %s.",
@@ -0,0 +1,424 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.defalsify.org/vise.git/db"
+	"git.defalsify.org/vise.git/resource"
+	"git.grassecon.net/grassrootseconomics/common/phone"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"gopkg.in/leonelquinteros/gotext.v1"
+)
+
+// notifyAccount sends an SMS to the account of the given session, in the language selected by that account.
+//
+// Failure to send the message is only logged.
+func (h *MenuHandlers) notifyAccount(ctx context.Context, sessionId string, msgid string, vars ...any) {
+	code, _ := h.userdataStore.ReadEntry(ctx, sessionId, storedb.DATA_SELECTED_LANGUAGE_CODE)
+	l := gotext.NewLocale(translationDir, string(code))
+	l.AddDomain("default")
+
+	err := h.smsService.SendNotificationSMS(ctx, sessionId, l.Get(msgid, vars...))
+	if err != nil {
+		logg.WarnCtxf(ctx, "failed to send notification", "recipient", sessionId, "error", err)
+	}
+}
+
+// GetPaymentRequests lists the open payment requests received by the account.
+func (h *MenuHandlers) GetPaymentRequests(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	requests, err := store.ReadPaymentRequests(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read payment requests", "error", err)
+		return res, err
+	}
+	if len(requests) == 0 {
+		res.Content = l.Get("You have no payment requests")
+		return res, nil
+	}
+
+	var lines []string
+	for i, r := range requests {
+		lines = append(lines, fmt.Sprintf("%d%s%s %s < %s", i+1, h.ReplaceSeparatorFunc(":"), r.Amount, r.Symbol, store.MaskPhoneNumber(r.Requester)))
+	}
+	res.Content = strings.Join(lines, "\n")
+	return res, nil
+}
+
+// SelectPaymentRequest selects the open payment request with the given number and shows its details.
+func (h *MenuHandlers) SelectPaymentRequest(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_invalid_payment_request, _ := h.flagManager.GetFlag("flag_invalid_payment_request")
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	requests, err := store.ReadPaymentRequests(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read payment requests", "error", err)
+		return res, err
+	}
+	index, err := strconv.Atoi(strings.TrimSpace(string(input)))
+	if err != nil || index < 1 || index > len(requests) {
+		res.FlagSet = append(res.FlagSet, flag_invalid_payment_request)
+		return res, nil
+	}
+
+	r := requests[index-1]
+	err = h.userdataStore.WriteEntry(ctx, sessionId, storedb.DATA_PAYMENT_REQUEST_SELECTED, []byte(r.Id))
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to write selected payment request", "error", err)
+		return res, err
+	}
+	res.FlagReset = append(res.FlagReset, flag_invalid_payment_request)
+
+	res.Content = l.Get(
+		"%s requests %s %s\nExpires: %s",
+		store.CounterpartyLabel(ctx, h.userdataStore, r.Recipient),
+		r.Amount,
+		r.Symbol,
+		time.Unix(r.Expires, 0).Format("2006-01-02 15:04"),
+	)
+	return res, nil
+}
+
+// GetRequestContacts shows the prompt for the account to request payment from,
+// followed by the saved contacts and recent recipients that may be selected by number.
+func (h *MenuHandlers) GetRequestContacts(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	return h.contactsPrompt(ctx, sessionId, l.Get("Enter the phone number to request payment from:"))
+}
+
+// SetPaymentRequestRecipient validates the registered phone number to request payment from,
+// and shows the amount prompt.
+func (h *MenuHandlers) SetPaymentRequestRecipient(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_invalid_payment_request, _ := h.flagManager.GetFlag("flag_invalid_payment_request")
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	recipient := strings.ReplaceAll(string(input), " ", "")
+	if contact, ok := h.resolveSendContact(ctx, sessionId, recipient); ok {
+		recipient = contact
+	}
+
+	formattedNumber, err := phone.FormatPhoneNumber(recipient)
+	if err != nil || !phone.IsValidPhoneNumber(formattedNumber) {
+		res.FlagSet = append(res.FlagSet, flag_invalid_payment_request)
+		res.Content = l.Get("%s is not a valid phone number", recipient)
+		return res, nil
+	}
+	if formattedNumber == sessionId {
+		res.FlagSet = append(res.FlagSet, flag_invalid_payment_request)
+		res.Content = l.Get("You cannot request payment from yourself")
+		return res, nil
+	}
+	_, err = h.userdataStore.ReadEntry(ctx, formattedNumber, storedb.DATA_PUBLIC_KEY)
+	if err != nil {
+		if db.IsNotFound(err) {
+			res.FlagSet = append(res.FlagSet, flag_invalid_payment_request)
+			res.Content = l.Get("%s is not registered", recipient)
+			return res, nil
+		}
+		logg.ErrorCtxf(ctx, "failed to read publicKey", "recipient", formattedNumber, "error", err)
+		return res, err
+	}
+
+	err = h.userdataStore.WriteEntry(ctx, sessionId, storedb.DATA_TEMPORARY_VALUE, []byte(formattedNumber))
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to write temporaryValue entry with", "key", storedb.DATA_TEMPORARY_VALUE, "value", formattedNumber, "error", err)
+		return res, err
+	}
+	activeSym, err := h.userdataStore.ReadEntry(ctx, sessionId, storedb.DATA_ACTIVE_SYM)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read activeSym entry with", "key", storedb.DATA_ACTIVE_SYM, "error", err)
+		return res, err
+	}
+
+	res.FlagReset = append(res.FlagReset, flag_invalid_payment_request)
+	res.Content = l.Get("Enter the amount of %s to request from %s:", string(activeSym), recipient)
+	return res, nil
+}
+
+// CreatePaymentRequest sends a request for the given amount of the active voucher
+// to the account selected with SetPaymentRequestRecipient, and notifies it by SMS.
+func (h *MenuHandlers) CreatePaymentRequest(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_invalid_payment_request, _ := h.flagManager.GetFlag("flag_invalid_payment_request")
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	inputStr := strings.TrimSpace(string(input))
+	amount, err := strconv.ParseFloat(inputStr, 64)
+	if err != nil || amount < 0.1 {
+		res.FlagSet = append(res.FlagSet, flag_invalid_payment_request)
+		res.Content = l.Get("Amount %s is invalid, please try again:", inputStr)
+		return res, nil
+	}
+	formattedAmount, err := store.TruncateDecimalString(inputStr, 2)
+	if err != nil {
+		res.FlagSet = append(res.FlagSet, flag_invalid_payment_request)
+		res.Content = l.Get("Amount %s is invalid, please try again:", inputStr)
+		return res, nil
+	}
+
+	userStore := h.userdataStore
+	payer, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_TEMPORARY_VALUE)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read temporaryValue entry with", "key", storedb.DATA_TEMPORARY_VALUE, "error", err)
+		return res, err
+	}
+	publicKey, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read publicKey entry with", "key", storedb.DATA_PUBLIC_KEY, "error", err)
+		return res, err
+	}
+	activeSym, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_ACTIVE_SYM)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read activeSym entry with", "key", storedb.DATA_ACTIVE_SYM, "error", err)
+		return res, err
+	}
+	activeAddress, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_ACTIVE_ADDRESS)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read activeAddress entry with", "key", storedb.DATA_ACTIVE_ADDRESS, "error", err)
+		return res, err
+	}
+
+	var request store.PaymentRequestRecord
+	allowed, err := store.AllowPaymentRequest(ctx, userStore, sessionId, func() error {
+		var err error
+		request, err = store.AddPaymentRequest(ctx, userStore, string(payer), store.PaymentRequestRecord{
+			Requester:    sessionId,
+			Recipient:    string(publicKey),
+			Amount:       formattedAmount,
+			Symbol:       string(activeSym),
+			TokenAddress: string(activeAddress),
+		})
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrPaymentRequestsFull) {
+			res.FlagSet = append(res.FlagSet, flag_invalid_payment_request)
+			res.Content = l.Get("%s has too many open payment requests. Please try again later", store.MaskPhoneNumber(string(payer)))
+			return res, nil
+		}
+		logg.ErrorCtxf(ctx, "failed to add payment request", "payer", string(payer), "error", err)
+		return res, err
+	}
+	if !allowed {
+		res.FlagSet = append(res.FlagSet, flag_invalid_payment_request)
+		res.Content = l.Get("You have sent too many payment requests. Please try again later")
+		return res, nil
+	}
+	logg.InfoCtxf(ctx, "payment request sent", "payer", string(payer), "id", request.Id)
+
+	h.notifyAccount(ctx, string(payer), "%s has requested %s %s from you. Dial in to pay or decline the request", store.MaskPhoneNumber(sessionId), request.Amount, request.Symbol)
+
+	res.FlagReset = append(res.FlagReset, flag_invalid_payment_request)
+	res.Content = l.Get("Your request for %s %s has been sent to %s", request.Amount, request.Symbol, store.MaskPhoneNumber(string(payer)))
+	return res, nil
+}
+
+// selectedPaymentRequest retrieves the payment request selected with SelectPaymentRequest.
+func (h *MenuHandlers) selectedPaymentRequest(ctx context.Context, sessionId string) (store.PaymentRequestRecord, error) {
+	id, err := h.userdataStore.ReadEntry(ctx, sessionId, storedb.DATA_PAYMENT_REQUEST_SELECTED)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read selected payment request", "error", err)
+		return store.PaymentRequestRecord{}, err
+	}
+	return store.GetPaymentRequest(ctx, h.userdataStore, sessionId, string(id))
+}
+
+// PreparePaymentRequest sets up the transfer paying the selected payment request,
+// validating the requested amount against the active voucher balance.
+//
+// The transfer is then previewed, authorized and submitted like any other transfer.
+func (h *MenuHandlers) PreparePaymentRequest(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_invalid_payment_request, _ := h.flagManager.GetFlag("flag_invalid_payment_request")
+	flag_invalid_amount, _ := h.flagManager.GetFlag("flag_invalid_amount")
+	flag_spend_limit, _ := h.flagManager.GetFlag("flag_spend_limit")
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	request, err := h.selectedPaymentRequest(ctx, sessionId)
+	if err != nil {
+		if errors.Is(err, store.ErrPaymentRequestNotFound) {
+			res.FlagSet = append(res.FlagSet, flag_invalid_payment_request)
+			res.Content = l.Get("This payment request has expired")
+			return res, nil
+		}
+		return res, err
+	}
+
+	userStore := h.userdataStore
+	activeAddress, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_ACTIVE_ADDRESS)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read activeAddress entry with", "key", storedb.DATA_ACTIVE_ADDRESS, "error", err)
+		return res, err
+	}
+	if !strings.EqualFold(string(activeAddress), request.TokenAddress) {
+		res.FlagSet = append(res.FlagSet, flag_invalid_payment_request)
+		res.Content = l.Get("Please select %s as your active voucher to pay this request", request.Symbol)
+		return res, nil
+	}
+
+	_, err = h.TransactionReset(ctx, sym, input)
+	if err != nil {
+		return res, err
+	}
+	entries := map[storedb.DataTyp][]byte{
+		storedb.DATA_RECIPIENT:              []byte(request.Recipient),
+		storedb.DATA_RECIPIENT_INPUT:        []byte(request.Requester),
+		storedb.DATA_RECIPIENT_PHONE_NUMBER: []byte(request.Requester),
+		storedb.DATA_SEND_TRANSACTION_TYPE:  []byte("normal"),
+	}
+	for key, value := range entries {
+		err = userStore.WriteEntry(ctx, sessionId, key, value)
+		if err != nil {
+			logg.ErrorCtxf(ctx, "failed to write payment request transaction entry", "key", key, "error", err)
+			return res, err
+		}
+	}
+
+	amountRes, err := h.ValidateAmount(ctx, sym, []byte(request.Amount))
+	if err != nil {
+		return res, err
+	}
+	for _, flag := range amountRes.FlagSet {
+		switch flag {
+		case flag_invalid_amount:
+			res.FlagSet = append(res.FlagSet, flag_invalid_payment_request)
+			res.Content = l.Get("You do not have enough %s to pay this request", request.Symbol)
+			return res, nil
+		case flag_spend_limit:
+			res.FlagSet = append(res.FlagSet, flag_invalid_payment_request)
+			res.Content, err = h.spendLimitContent(ctx, l, sessionId, request.TokenAddress, request.Symbol)
+			return res, err
+		}
+	}
+
+	res.FlagReset = append(res.FlagReset, flag_invalid_payment_request)
+	return res, nil
+}
+
+// PayPaymentRequest submits the transfer set up by PreparePaymentRequest, and closes
+// the payment request once the transfer has been submitted.
+func (h *MenuHandlers) PayPaymentRequest(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return resource.Result{}, fmt.Errorf("missing session")
+	}
+	flag_api_call_error, _ := h.flagManager.GetFlag("flag_api_call_error")
+
+	request, err := h.selectedPaymentRequest(ctx, sessionId)
+	if err != nil {
+		if errors.Is(err, store.ErrPaymentRequestNotFound) {
+			// expired since it was previewed
+			code := codeFromCtx(ctx)
+			l := gotext.NewLocale(translationDir, code)
+			l.AddDomain("default")
+			return resource.Result{Content: l.Get("This payment request has expired")}, nil
+		}
+		return resource.Result{}, err
+	}
+
+	res, err := h.InitiateNormalTransaction(ctx, sym, input)
+	if err != nil {
+		return res, err
+	}
+	for _, flag := range res.FlagSet {
+		if flag == flag_api_call_error {
+			return res, nil
+		}
+	}
+
+	_, err = store.RemovePaymentRequest(ctx, h.userdataStore, sessionId, request.Id)
+	if err != nil {
+		if errors.Is(err, store.ErrPaymentRequestNotFound) {
+			// closed by an earlier submission of the same transfer
+			return res, nil
+		}
+		logg.ErrorCtxf(ctx, "failed to close paid payment request", "id", request.Id, "error", err)
+		return res, err
+	}
+	h.notifyAccount(ctx, request.Requester, "%s has paid your request of %s %s", store.MaskPhoneNumber(sessionId), request.Amount, request.Symbol)
+	return res, nil
+}
+
+// DeclinePaymentRequest closes the selected payment request without paying it, and notifies the requester.
+func (h *MenuHandlers) DeclinePaymentRequest(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	request, err := h.selectedPaymentRequest(ctx, sessionId)
+	if err == nil {
+		_, err = store.RemovePaymentRequest(ctx, h.userdataStore, sessionId, request.Id)
+	}
+	if err != nil {
+		if errors.Is(err, store.ErrPaymentRequestNotFound) {
+			res.Content = l.Get("This payment request has expired")
+			return res, nil
+		}
+		logg.ErrorCtxf(ctx, "failed to decline payment request", "error", err)
+		return res, err
+	}
+	h.notifyAccount(ctx, request.Requester, "%s has declined your request of %s %s", store.MaskPhoneNumber(sessionId), request.Amount, request.Symbol)
+
+	res.Content = l.Get("You have declined the request of %s %s from %s", request.Amount, request.Symbol, store.MaskPhoneNumber(request.Requester))
+	return res, nil
+}
@@ -0,0 +1,117 @@
+package application
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"git.defalsify.org/vise.git/resource"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/models"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/testutil/mocks"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/internal/sms"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestPaymentRequest(t *testing.T) {
+	requester := "+254712345678"
+	payer := "+254711223344"
+
+	ctx, userStore := InitializeTestStore(t)
+	requesterCtx := context.WithValue(ctx, "SessionId", requester)
+	payerCtx := context.WithValue(ctx, "SessionId", payer)
+
+	fm, err := NewFlagManager(flagsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag_invalid_payment_request, _ := fm.GetFlag("flag_invalid_payment_request")
+
+	svc := &messageAccountService{MockAccountService: new(mocks.MockAccountService)}
+	h := &MenuHandlers{
+		userdataStore:        userStore,
+		accountService:       svc,
+		flagManager:          fm,
+		ReplaceSeparatorFunc: mockReplaceSeparator,
+		smsService: sms.SmsService{
+			Accountservice: svc,
+			Userdatastore:  *userStore,
+		},
+	}
+
+	accounts := map[string]string{
+		requester: "0X13242618721",
+		payer:     "0X98765432109",
+	}
+	for sessionId, publicKey := range accounts {
+		entries := map[storedb.DataTyp][]byte{
+			storedb.DATA_PUBLIC_KEY:     []byte(publicKey),
+			storedb.DATA_ACTIVE_SYM:     []byte("SRF"),
+			storedb.DATA_ACTIVE_BAL:     []byte("5"),
+			storedb.DATA_ACTIVE_DECIMAL: []byte("6"),
+			storedb.DATA_ACTIVE_ADDRESS: []byte("0xd4c288865Ce"),
+		}
+		for k, v := range entries {
+			err = userStore.WriteEntry(ctx, sessionId, k, v)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	res, err := h.SetPaymentRequestRecipient(requesterCtx, "set_payment_request_recipient", []byte("0712345678"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagSet: []uint32{flag_invalid_payment_request}, Content: "You cannot request payment from yourself"}, res)
+
+	res, err = h.SetPaymentRequestRecipient(requesterCtx, "set_payment_request_recipient", []byte("0711 223 344"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagReset: []uint32{flag_invalid_payment_request}, Content: "Enter the amount of SRF to request from 0711223344:"}, res)
+
+	res, err = h.CreatePaymentRequest(requesterCtx, "create_payment_request", []byte("0"))
+	assert.NoError(t, err)
+	assert.Equal(t, []uint32{flag_invalid_payment_request}, res.FlagSet)
+
+	res, err = h.CreatePaymentRequest(requesterCtx, "create_payment_request", []byte("10"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Your request for 10.00 SRF has been sent to 0711***344", res.Content)
+	assert.Equal(t, []string{"0712***678 has requested 10.00 SRF from you. Dial in to pay or decline the request"}, svc.messages)
+
+	res, err = h.GetPaymentRequests(payerCtx, "get_payment_requests", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "1: 10.00 SRF < 0712***678", res.Content)
+
+	res, err = h.SelectPaymentRequest(payerCtx, "select_payment_request", []byte("1"))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(res.Content, "0X13242618721 requests 10.00 SRF\nExpires: "))
+
+	// the requested amount is checked against the balance
+	res, err = h.PreparePaymentRequest(payerCtx, "prepare_payment_request", []byte("1"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagSet: []uint32{flag_invalid_payment_request}, Content: "You do not have enough SRF to pay this request"}, res)
+
+	err = userStore.WriteEntry(ctx, payer, storedb.DATA_ACTIVE_BAL, []byte("100"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = h.PreparePaymentRequest(payerCtx, "prepare_payment_request", []byte("1"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagReset: []uint32{flag_invalid_payment_request}}, res)
+
+	_, err = h.NormalTransactionPreview(payerCtx, "normal_transaction_preview", []byte("1"))
+	assert.NoError(t, err)
+	svc.On("TokenTransfer").Return(&models.TokenTransferResponse{TrackingId: "1234567890"}, nil).Once()
+	res, err = h.PayPaymentRequest(payerCtx, "pay_payment_request", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "Your request has been sent. +254712345678 will receive 10.00 SRF from +254711223344.", res.Content)
+	assert.Equal(t, "0711***344 has paid your request of 10.00 SRF", svc.messages[1])
+
+	res, err = h.GetPaymentRequests(payerCtx, "get_payment_requests", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "You have no payment requests", res.Content)
+
+	// a request closed or expired after the preview is not paid
+	res, err = h.PayPaymentRequest(payerCtx, "pay_payment_request", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{Content: "This payment request has expired"}, res)
+	svc.AssertNumberOfCalls(t, "TokenTransfer", 1)
+}
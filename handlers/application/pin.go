@@ -3,13 +3,18 @@ package application
 import (
 	"context"
 	"fmt"
+	"math"
 	"strconv"
+	"time"
 
 	"git.defalsify.org/vise.git/db"
 	"git.defalsify.org/vise.git/resource"
 	"git.grassecon.net/grassrootseconomics/common/phone"
 	"git.grassecon.net/grassrootseconomics/common/pin"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/config"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
 	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"gopkg.in/leonelquinteros/gotext.v1"
 )
 
 // ResetIncorrectPin resets the incorrect pin flag after a new PIN attempt.
@@ -217,36 +222,57 @@ func (h *MenuHandlers) ValidateBlockedNumber(ctx context.Context, sym string, in
 
 // ResetOthersPin handles the PIN reset process for other users' accounts by:
 // 1. Retrieving the blocked phone number from the session
-// 2. Writing the DATA_SELF_PIN_RESET on the blocked phone number
-// 3. Resetting the DATA_INCORRECT_PIN_ATTEMPTS to 0 for the blocked phone number
+// 2. Checking that the account has the PIN reset role
+// 3. Writing the DATA_SELF_PIN_RESET on the blocked phone number
+// 4. Resetting the DATA_INCORRECT_PIN_ATTEMPTS to 0 and clearing the lockouts for the blocked phone number
 func (h *MenuHandlers) ResetOthersPin(ctx context.Context, sym string, input []byte) (resource.Result, error) {
 	var res resource.Result
 
-	store := h.userdataStore
+	userStore := h.userdataStore
 	smsservice := h.smsService
 
 	sessionId, ok := ctx.Value("SessionId").(string)
 	if !ok {
 		return res, fmt.Errorf("missing session")
 	}
-	blockedPhonenumber, err := store.ReadEntry(ctx, sessionId, storedb.DATA_BLOCKED_NUMBER)
+	blockedPhonenumber, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_BLOCKED_NUMBER)
 	if err != nil {
 		logg.ErrorCtxf(ctx, "failed to read blockedPhonenumber entry with", "key", storedb.DATA_BLOCKED_NUMBER, "error", err)
 		return res, err
 	}
+	blockedPhoneStr := string(blockedPhonenumber)
+
+	// the menu is only shown with the role, but it may have been revoked since
+	ok, err = h.hasRole(ctx, sessionId, store.RolePinReset)
+	if err != nil {
+		return res, err
+	}
+	if !ok {
+		err = fmt.Errorf("missing role: %s", store.RolePinReset)
+		h.audit(ctx, sessionId, blockedPhoneStr, store.AuditResetPin, err)
+		return res, err
+	}
 
 	// set the DATA_SELF_PIN_RESET for the account
-	err = store.WriteEntry(ctx, string(blockedPhonenumber), storedb.DATA_SELF_PIN_RESET, []byte("1"))
+	err = userStore.WriteEntry(ctx, blockedPhoneStr, storedb.DATA_SELF_PIN_RESET, []byte("1"))
 	if err != nil {
+		h.audit(ctx, sessionId, blockedPhoneStr, store.AuditResetPin, err)
 		return res, nil
 	}
 
-	err = store.WriteEntry(ctx, string(blockedPhonenumber), storedb.DATA_INCORRECT_PIN_ATTEMPTS, []byte(string("0")))
+	err = userStore.WriteEntry(ctx, blockedPhoneStr, storedb.DATA_INCORRECT_PIN_ATTEMPTS, []byte(string("0")))
 	if err != nil {
 		logg.ErrorCtxf(ctx, "failed to reset incorrect PIN attempts", "key", storedb.DATA_INCORRECT_PIN_ATTEMPTS, "error", err)
+		h.audit(ctx, sessionId, blockedPhoneStr, store.AuditResetPin, err)
 		return res, err
 	}
-	blockedPhoneStr := string(blockedPhonenumber)
+	err = h.clearLockouts(ctx, blockedPhoneStr)
+	if err != nil {
+		h.audit(ctx, sessionId, blockedPhoneStr, store.AuditResetPin, err)
+		return res, err
+	}
+	h.audit(ctx, sessionId, blockedPhoneStr, store.AuditResetPin, nil)
+
 	//Trigger an SMS to inform a user that the  blocked account has been reset
 	if phone.IsValidPhoneNumber(blockedPhoneStr) {
 		err = smsservice.SendPINResetSMS(ctx, sessionId, blockedPhoneStr)
@@ -258,6 +284,26 @@ func (h *MenuHandlers) ResetOthersPin(ctx context.Context, sym string, input []b
 	return res, nil
 }
 
+// audit records an action of the given actor on the account of the target session in the audit log.
+//
+// The action has already been made at this point, so failure to write the entry is only logged.
+func (h *MenuHandlers) audit(ctx context.Context, actor string, target string, action string, actionErr error) {
+	record := store.AuditRecord{
+		Actor:   actor,
+		Target:  target,
+		Action:  action,
+		Outcome: store.AuditSuccess,
+	}
+	if actionErr != nil {
+		record.Outcome = store.AuditFailure
+		record.Detail = actionErr.Error()
+	}
+	_, err := store.AppendAudit(ctx, h.userdataStore, record)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to write audit entry", "actor", actor, "target", target, "action", action, "error", err)
+	}
+}
+
 // incrementIncorrectPINAttempts keeps track of the number of incorrect PIN attempts
 func (h *MenuHandlers) incrementIncorrectPINAttempts(ctx context.Context, sessionId string) error {
 	var pinAttemptsCount uint8
@@ -284,6 +330,9 @@ func (h *MenuHandlers) incrementIncorrectPINAttempts(ctx context.Context, sessio
 		logg.ErrorCtxf(ctx, "failed to write incorrect PIN attempts ", "key", storedb.DATA_INCORRECT_PIN_ATTEMPTS, "value", pinAttemptsCount, "error", err)
 		return err
 	}
+	if pinAttemptsCount == pin.AllowedPINAttempts {
+		return h.lockAccount(ctx, sessionId)
+	}
 	return nil
 }
 
@@ -295,9 +344,68 @@ func (h *MenuHandlers) resetIncorrectPINAttempts(ctx context.Context, sessionId
 		logg.ErrorCtxf(ctx, "failed to reset incorrect PIN attempts ", "key", storedb.DATA_INCORRECT_PIN_ATTEMPTS, "error", err)
 		return err
 	}
+	return h.clearLockouts(ctx, sessionId)
+}
+
+// lockAccount locks the account out after the allowed incorrect PIN attempts have been used.
+//
+// The lockout is temporary for the first configured lockouts, and permanent after them.
+func (h *MenuHandlers) lockAccount(ctx context.Context, sessionId string) error {
+	expiry, ok, err := store.LockAccount(ctx, h.userdataStore, sessionId, config.PinLockouts())
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to write PIN lockout", "error", err)
+		return err
+	}
+	if ok {
+		logg.InfoCtxf(ctx, "account locked out after incorrect PIN attempts", "expiry", expiry)
+	} else {
+		logg.InfoCtxf(ctx, "account blocked after incorrect PIN attempts")
+	}
 	return nil
 }
 
+// liftExpiredLockout ends the temporary lockout of the account if it is over.
+//
+// Returns true if a lockout was lifted.
+func (h *MenuHandlers) liftExpiredLockout(ctx context.Context, sessionId string) (bool, error) {
+	expiry, ok, err := store.LockoutExpiry(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read PIN lockout expiry", "error", err)
+		return false, err
+	}
+	if !ok || time.Now().Before(expiry) {
+		return false, nil
+	}
+	err = store.LiftLockout(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to lift PIN lockout", "error", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// clearLockouts forgets the earlier lockouts of the account, so that the next lockout is the shortest again.
+func (h *MenuHandlers) clearLockouts(ctx context.Context, sessionId string) error {
+	err := store.ClearLockouts(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to clear PIN lockouts", "error", err)
+		return err
+	}
+	return nil
+}
+
+// formatLockoutWait describes the time left until the end of a lockout, rounded up to minutes or hours.
+func formatLockoutWait(l *gotext.Locale, d time.Duration) string {
+	minutes := int(math.Ceil(d.Minutes()))
+	if minutes < 1 {
+		minutes = 1
+	}
+	if minutes < 60 {
+		return l.Get("%d minutes", minutes)
+	}
+	return l.Get("%d hours", int(math.Ceil(d.Hours())))
+}
+
 // VerifyCreatePin checks whether the confirmation PIN is similar to the temporary PIN
 // If similar, it sets the USERFLAG_PIN_SET flag and writes the account PIN allowing the user
 // to access the main menu.
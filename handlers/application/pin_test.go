@@ -5,6 +5,7 @@ import (
 	"log"
 	"strconv"
 	"testing"
+	"time"
 
 	"git.defalsify.org/vise.git/resource"
 	"git.defalsify.org/vise.git/state"
@@ -287,7 +288,104 @@ func TestResetOthersPin(t *testing.T) {
 		t.Fatal(err)
 	}
 
+	// the PIN reset role is required
 	_, err = h.ResetOthersPin(ctx, "reset_others_pin", []byte(""))
+	assert.Error(t, err)
+	_, err = userStore.ReadEntry(ctx, blockedNumber, storedb.DATA_SELF_PIN_RESET)
+	assert.Error(t, err)
 
+	_, err = store.GrantRole(ctx, userStore, sessionId, store.RolePinReset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = h.ResetOthersPin(ctx, "reset_others_pin", []byte(""))
+
+	assert.NoError(t, err)
+
+	records, err := store.ReadAudit(ctx, userStore, store.AuditFilter{Target: blockedNumber})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, store.AuditFailure, records[0].Outcome)
+	assert.Equal(t, sessionId, records[1].Actor)
+	assert.Equal(t, store.AuditResetPin, records[1].Action)
+	assert.Equal(t, store.AuditSuccess, records[1].Outcome)
+}
+
+func TestPINLockout(t *testing.T) {
+	t.Setenv("PIN_LOCKOUT_MINUTES", "15,60")
+
+	ctx, userStore := InitializeTestStore(t)
+	sessionId := "session123"
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
+
+	fm, err := NewFlagManager(flagsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag_incorrect_pin, _ := fm.GetFlag("flag_incorrect_pin")
+	flag_account_blocked, _ := fm.GetFlag("flag_account_blocked")
+	flag_account_pin_reset, _ := fm.GetFlag("flag_account_pin_reset")
+
+	h := &MenuHandlers{
+		userdataStore: userStore,
+		flagManager:   fm,
+		st:            state.NewState(128),
+	}
+
+	for i := uint8(0); i < pin.AllowedPINAttempts; i++ {
+		err = h.incrementIncorrectPINAttempts(ctx, sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	res, err := h.ResetIncorrectPin(ctx, "reset_incorrect_pin", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagReset: []uint32{flag_incorrect_pin}, FlagSet: []uint32{flag_account_blocked}}, res)
+
+	expiry, ok, err := store.LockoutExpiry(ctx, userStore, sessionId)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	// the account stays blocked until the lockout is over
+	res, err = h.CheckBlockedStatus(ctx, "check_blocked_status", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagReset: []uint32{flag_account_pin_reset}}, res)
+
+	err = userStore.WriteEntry(ctx, sessionId, storedb.DATA_PIN_LOCKOUT_EXPIRY, []byte(strconv.FormatInt(expiry.Add(-time.Hour).Unix(), 10)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = h.CheckBlockedStatus(ctx, "check_blocked_status", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagReset: []uint32{flag_account_pin_reset, flag_account_blocked}}, res)
+
+	// the next lockout lasts longer
+	for i := uint8(0); i < pin.AllowedPINAttempts; i++ {
+		err = h.incrementIncorrectPINAttempts(ctx, sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	expiry, ok, err = store.LockoutExpiry(ctx, userStore, sessionId)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, time.Until(expiry) > 59*time.Minute)
+
+	// and the account is blocked once all lockouts have been used
+	err = store.LiftLockout(ctx, userStore, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := uint8(0); i < pin.AllowedPINAttempts; i++ {
+		err = h.incrementIncorrectPINAttempts(ctx, sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, ok, err = store.LockoutExpiry(ctx, userStore, sessionId)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	res, err = h.CheckBlockedStatus(ctx, "check_blocked_status", []byte(""))
 	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagReset: []uint32{flag_account_pin_reset}}, res)
 }
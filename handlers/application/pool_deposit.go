@@ -27,23 +27,17 @@ func (h *MenuHandlers) GetOrderedVouchers(ctx context.Context, sym string, input
 	userStore := h.userdataStore
 
 	// Read ordered vouchers from the store
-	voucherData, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_ORDERED_VOUCHER_SYMBOLS)
+	vouchers, err := store.ReadVoucherList(ctx, userStore, sessionId, storedb.DATA_ORDERED_VOUCHER_LIST)
 	if err != nil {
-		logg.ErrorCtxf(ctx, "failed to read stable voucherData entires with", "key", storedb.DATA_ORDERED_VOUCHER_SYMBOLS, "error", err)
+		logg.ErrorCtxf(ctx, "failed to read ordered voucher list with", "key", storedb.DATA_ORDERED_VOUCHER_LIST, "error", err)
 		return res, err
 	}
 
-	if len(voucherData) == 0 {
+	if len(vouchers) == 0 {
 		return res, nil
 	}
 
-	voucherBalances, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_ORDERED_VOUCHER_BALANCES)
-	if err != nil {
-		logg.ErrorCtxf(ctx, "failed to read stable voucherData entires with", "key", storedb.DATA_ORDERED_VOUCHER_BALANCES, "error", err)
-		return res, err
-	}
-
-	formattedVoucherList := store.FormatVoucherList(ctx, string(voucherData), string(voucherBalances))
+	formattedVoucherList := store.FormatVoucherList(ctx, vouchers)
 	finalOutput := strings.Join(formattedVoucherList, "\n")
 
 	res.Content = l.Get("Select number or symbol from your vouchers:\n%s", finalOutput)
@@ -150,6 +144,11 @@ func (h *MenuHandlers) ConfirmPoolDeposit(ctx context.Context, sym string, input
 		return res, err
 	}
 
+	err = h.newIntentNonce(ctx, sessionId)
+	if err != nil {
+		return res, err
+	}
+
 	res.Content = l.Get(
 		"You will deposit %s %s into %s\n",
 		inputStr, poolDepositVoucher.TokenSymbol, activePoolSymbol,
@@ -206,9 +205,32 @@ func (h *MenuHandlers) InitiatePoolDeposit(ctx context.Context, sym string, inpu
 		return res, err
 	}
 
+	intentKey, content, duplicate, err := h.beginIntent(ctx, sessionId, l, "pool_deposit", finalAmountStr, string(publicKey), string(activePoolAddress), poolDepositVoucher.TokenAddress)
+	if err != nil {
+		return res, err
+	}
+	if duplicate {
+		res.Content = content
+		res.FlagReset = append(res.FlagReset, flag_account_authorized)
+		return res, nil
+	}
+
+	entry := store.LedgerEntry{
+		Type:         store.LedgerDeposit,
+		Amount:       string(amount),
+		Symbol:       poolDepositVoucher.TokenSymbol,
+		Counterparty: string(activePoolSymbol),
+		From:         string(publicKey),
+		To:           string(activePoolAddress),
+		TokenAddress: poolDepositVoucher.TokenAddress,
+		Value:        finalAmountStr,
+	}
+
 	// Call token transfer API and send the token to the pool address
 	r, err := h.accountService.TokenTransfer(ctx, finalAmountStr, string(publicKey), string(activePoolAddress), poolDepositVoucher.TokenAddress)
 	if err != nil {
+		h.abortIntent(ctx, sessionId, intentKey, err)
+		h.recordFailure(ctx, sessionId, entry, err)
 		flag_api_call_error, _ := h.flagManager.GetFlag("flag_api_call_error")
 		res.FlagSet = append(res.FlagSet, flag_api_call_error)
 		res.Content = l.Get("Your request failed. Please try again later.")
@@ -218,6 +240,9 @@ func (h *MenuHandlers) InitiatePoolDeposit(ctx context.Context, sym string, inpu
 
 	trackingId := r.TrackingId
 	logg.InfoCtxf(ctx, "Pool deposit", "trackingId", trackingId)
+	h.completeIntent(ctx, sessionId, intentKey, trackingId)
+	entry.TrackingId = trackingId
+	h.recordSubmission(ctx, sessionId, entry)
 
 	res.Content = l.Get(
 		"Your request has been sent. You will receive an SMS when %s %s has been deposited into %s.",
@@ -58,21 +58,11 @@ func (h *MenuHandlers) GetPools(ctx context.Context, sym string, input []byte) (
 	data := store.ProcessPools(filteredPools)
 
 	// Store the filtered Pool data
-	dataMap := map[storedb.DataTyp]string{
-		storedb.DATA_POOL_NAMES:     data.PoolNames,
-		storedb.DATA_POOL_SYMBOLS:   data.PoolSymbols,
-		storedb.DATA_POOL_ADDRESSES: data.PoolContractAdrresses,
+	if err := store.WritePoolList(ctx, userStore, sessionId, data); err != nil {
+		logg.ErrorCtxf(ctx, "Failed to write pool list for sessionId: %s", sessionId, "key", storedb.DATA_POOL_LIST, "error", err)
 	}
 
-	// Write data entries
-	for key, value := range dataMap {
-		if err := userStore.WriteEntry(ctx, sessionId, key, []byte(value)); err != nil {
-			logg.ErrorCtxf(ctx, "Failed to write data entry for sessionId: %s", sessionId, "key", key, "error", err)
-			continue
-		}
-	}
-
-	res.Content = h.ReplaceSeparatorFunc(data.PoolSymbols)
+	res.Content = h.ReplaceSeparatorFunc(store.FormatPoolSymbols(data))
 
 	return res, nil
 }
@@ -7,6 +7,7 @@ import (
 
 	"git.defalsify.org/vise.git/db"
 	"git.defalsify.org/vise.git/resource"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/models"
 	"git.grassecon.net/grassrootseconomics/sarafu-vise/config"
 	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
 	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
@@ -136,21 +137,11 @@ func (h *MenuHandlers) LoadSwapToList(ctx context.Context, sym string, input []b
 	logg.InfoCtxf(ctx, "ProcessVouchers", "data", data)
 
 	// Store all swap_to tokens data
-	dataMap := map[storedb.DataTyp]string{
-		storedb.DATA_POOL_TO_SYMBOLS:   data.Symbols,
-		storedb.DATA_POOL_TO_BALANCES:  data.Balances,
-		storedb.DATA_POOL_TO_DECIMALS:  data.Decimals,
-		storedb.DATA_POOL_TO_ADDRESSES: data.Addresses,
+	if err := store.WriteVoucherList(ctx, userStore, sessionId, storedb.DATA_POOL_TO_LIST, data); err != nil {
+		logg.ErrorCtxf(ctx, "Failed to write swap to list for sessionId: %s", sessionId, "key", storedb.DATA_POOL_TO_LIST, "error", err)
 	}
 
-	for key, value := range dataMap {
-		if err := userStore.WriteEntry(ctx, sessionId, key, []byte(value)); err != nil {
-			logg.ErrorCtxf(ctx, "Failed to write data entry for sessionId: %s", sessionId, "key", key, "error", err)
-			continue
-		}
-	}
-
-	res.Content = h.ReplaceSeparatorFunc(data.Symbols)
+	res.Content = h.ReplaceSeparatorFunc(store.FormatVoucherSymbols(data))
 
 	return res, nil
 }
@@ -167,8 +158,9 @@ func (h *MenuHandlers) SwapMaxLimit(ctx context.Context, sym string, input []byt
 	flag_incorrect_voucher, _ := h.flagManager.GetFlag("flag_incorrect_voucher")
 	flag_api_call_error, _ := h.flagManager.GetFlag("flag_api_call_error")
 	flag_low_swap_amount, _ := h.flagManager.GetFlag("flag_low_swap_amount")
+	flag_spend_limit, _ := h.flagManager.GetFlag("flag_spend_limit")
 
-	res.FlagReset = append(res.FlagReset, flag_incorrect_voucher, flag_low_swap_amount)
+	res.FlagReset = append(res.FlagReset, flag_incorrect_voucher, flag_low_swap_amount, flag_spend_limit)
 
 	inputStr := string(input)
 	if inputStr == "0" || inputStr == "99" || inputStr == "88" || inputStr == "98" {
@@ -233,6 +225,19 @@ func (h *MenuHandlers) SwapMaxLimit(ctx context.Context, sym string, input []byt
 		return res, nil
 	}
 
+	// the maximum is further limited by the spending limits of the account
+	allowance, limited, err := h.spendAllowance(ctx, sessionId, swapData.ActiveSwapFromAddress)
+	if err != nil {
+		return res, err
+	}
+	if limited && allowance < maxAmountFloat {
+		if allowance < minSpendAmount {
+			err = h.spendLimitReached(ctx, sessionId, swapData.ActiveSwapFromAddress, swapData.ActiveSwapFromSym, &res)
+			return res, err
+		}
+		maxStr = formatSpendAmount(allowance)
+	}
+
 	err = userStore.WriteEntry(ctx, sessionId, storedb.DATA_ACTIVE_SWAP_MAX_AMOUNT, []byte(maxStr))
 	if err != nil {
 		logg.ErrorCtxf(ctx, "failed to write swap max amount entry with", "key", storedb.DATA_ACTIVE_SWAP_MAX_AMOUNT, "value", maxStr, "error", err)
@@ -327,6 +332,11 @@ func (h *MenuHandlers) SwapPreview(ctx context.Context, sym string, input []byte
 	// Format to 2 decimal places
 	qouteStr, _ := store.TruncateDecimalString(string(quoteAmountStr), 2)
 
+	err = h.newIntentNonce(ctx, sessionId)
+	if err != nil {
+		return res, err
+	}
+
 	res.Content = l.Get(
 		"You will swap %s %s for %s %s:",
 		inputStr, swapData.ActiveSwapFromSym, qouteStr, swapData.ActiveSwapToSym,
@@ -365,9 +375,47 @@ func (h *MenuHandlers) InitiateSwap(ctx context.Context, sym string, input []byt
 
 	swapAmountStr := string(swapAmount)
 
+	intentKey, content, duplicate, err := h.beginIntent(ctx, sessionId, l, "swap", swapAmountStr, swapData.PublicKey, swapData.ActiveSwapFromAddress, swapData.ActivePoolAddress, swapData.ActiveSwapToAddress)
+	if err != nil {
+		return res, err
+	}
+	if duplicate {
+		res.Content = content
+		res.FlagReset = append(res.FlagReset, flag_account_authorized)
+		return res, nil
+	}
+
+	entry := store.LedgerEntry{
+		Type:         store.LedgerSwap,
+		Amount:       swapData.TemporaryValue,
+		Symbol:       swapData.ActiveSwapFromSym,
+		ToSymbol:     swapData.ActiveSwapToSym,
+		From:         swapData.PublicKey,
+		To:           swapData.ActivePoolAddress,
+		TokenAddress: swapData.ActiveSwapFromAddress,
+		Value:        swapAmountStr,
+	}
+
 	// Call the poolSwap API
-	r, err := h.accountService.PoolSwap(ctx, swapAmountStr, swapData.PublicKey, swapData.ActiveSwapFromAddress, swapData.ActivePoolAddress, swapData.ActiveSwapToAddress)
+	var r *models.PoolSwapResult
+	ok, err = h.spend(ctx, sessionId, swapData.ActiveSwapFromAddress, swapData.ActiveSwapFromSym, swapData.TemporaryValue, func() error {
+		var err error
+		r, err = h.accountService.PoolSwap(ctx, swapAmountStr, swapData.PublicKey, swapData.ActiveSwapFromAddress, swapData.ActivePoolAddress, swapData.ActiveSwapToAddress)
+		return err
+	})
+	if !ok {
+		// not submitted
+		h.releaseIntent(ctx, sessionId, intentKey)
+		if err != nil {
+			return res, err
+		}
+		res.Content, err = h.spendLimitContent(ctx, l, sessionId, swapData.ActiveSwapFromAddress, swapData.ActiveSwapFromSym)
+		res.FlagReset = append(res.FlagReset, flag_account_authorized)
+		return res, err
+	}
 	if err != nil {
+		h.abortIntent(ctx, sessionId, intentKey, err)
+		h.recordFailure(ctx, sessionId, entry, err)
 		flag_api_call_error, _ := h.flagManager.GetFlag("flag_api_call_error")
 		res.FlagSet = append(res.FlagSet, flag_api_call_error)
 		res.Content = l.Get("Your request failed. Please try again later.")
@@ -377,6 +425,9 @@ func (h *MenuHandlers) InitiateSwap(ctx context.Context, sym string, input []byt
 
 	trackingId := r.TrackingId
 	logg.InfoCtxf(ctx, "poolSwap", "trackingId", trackingId)
+	h.completeIntent(ctx, sessionId, intentKey, trackingId)
+	entry.TrackingId = trackingId
+	h.recordSubmission(ctx, sessionId, entry)
 
 	res.Content = l.Get(
 		"Your request has been sent. You will receive an SMS when your %s %s has been swapped for %s.",
@@ -0,0 +1,73 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"git.defalsify.org/vise.git/resource"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+	"gopkg.in/leonelquinteros/gotext.v1"
+)
+
+// requestPrivacy records a request of the account for the export or erasure of its data, to be processed by support staff.
+//
+// Returns false if a request of the same kind is already pending.
+func (h *MenuHandlers) requestPrivacy(ctx context.Context, kind string, res *resource.Result) (bool, error) {
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return false, fmt.Errorf("missing session")
+	}
+	flag_account_authorized, _ := h.flagManager.GetFlag("flag_account_authorized")
+	res.FlagReset = append(res.FlagReset, flag_account_authorized)
+
+	err := store.AddPrivacyRequest(ctx, h.userdataStore, sessionId, kind)
+	if err != nil {
+		if errors.Is(err, store.ErrPrivacyRequestExists) {
+			return false, nil
+		}
+		logg.ErrorCtxf(ctx, "failed to add privacy request", "kind", kind, "error", err)
+		return false, err
+	}
+	return true, nil
+}
+
+// RequestDataExport records a request of the account for a copy of the data held about it.
+func (h *MenuHandlers) RequestDataExport(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	ok, err := h.requestPrivacy(ctx, store.PrivacyExport, &res)
+	if err != nil {
+		return res, err
+	}
+	if !ok {
+		res.Content = l.Get("You already have a pending request. Our support team will contact you.")
+		return res, nil
+	}
+	res.Content = l.Get("Your request for a copy of your data has been received. Our support team will contact you.")
+	return res, nil
+}
+
+// RequestDataErasure records a request of the account for the erasure of its personal data.
+func (h *MenuHandlers) RequestDataErasure(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	ok, err := h.requestPrivacy(ctx, store.PrivacyErasure, &res)
+	if err != nil {
+		return res, err
+	}
+	if !ok {
+		res.Content = l.Get("You already have a pending request. Our support team will contact you.")
+		return res, nil
+	}
+	res.Content = l.Get("Your request to erase your data has been received. Our support team will contact you.")
+	return res, nil
+}
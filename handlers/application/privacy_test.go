@@ -0,0 +1,56 @@
+package application
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	"git.defalsify.org/vise.git/resource"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestRequestPrivacy(t *testing.T) {
+	sessionId := "+254712345678"
+	ctx, userStore := InitializeTestStore(t)
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
+
+	fm, err := NewFlagManager(flagsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag_account_authorized, _ := fm.GetFlag("flag_account_authorized")
+
+	h := &MenuHandlers{
+		userdataStore: userStore,
+		flagManager:   fm,
+	}
+
+	res, err := h.RequestDataExport(ctx, "request_data_export", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{
+		FlagReset: []uint32{flag_account_authorized},
+		Content:   "Your request for a copy of your data has been received. Our support team will contact you.",
+	}, res)
+
+	res, err = h.RequestDataExport(ctx, "request_data_export", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "You already have a pending request. Our support team will contact you.", res.Content)
+
+	res, err = h.RequestDataErasure(ctx, "request_data_erasure", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "Your request to erase your data has been received. Our support team will contact you.", res.Content)
+
+	requests, err := store.ReadPrivacyRequests(ctx, userStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(requests))
+	kinds := []string{}
+	for _, v := range requests {
+		assert.Equal(t, sessionId, v.SessionId)
+		kinds = append(kinds, v.Kind)
+	}
+	assert.True(t, slices.Contains(kinds, store.PrivacyExport))
+	assert.True(t, slices.Contains(kinds, store.PrivacyErasure))
+}
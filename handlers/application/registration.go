@@ -27,6 +27,11 @@ func (h *MenuHandlers) createAccountNoExist(ctx context.Context, sessionId strin
 	trackingId := r.TrackingId
 	publicKey := r.PublicKey
 
+	publicKeyNormalized, err := hex.NormalizeHex(publicKey)
+	if err != nil {
+		return err
+	}
+
 	data := map[storedb.DataTyp]string{
 		storedb.DATA_TRACKING_ID:   trackingId,
 		storedb.DATA_PUBLIC_KEY:    publicKey,
@@ -34,25 +39,33 @@ func (h *MenuHandlers) createAccountNoExist(ctx context.Context, sessionId strin
 	}
 	store := h.userdataStore
 	logdb := h.logDb
+
+	// the account entries and the reverse lookup are written together,
+	// so that a session never ends up with only part of the account data.
+	batch := store.Begin(ctx)
 	for key, value := range data {
-		err = store.WriteEntry(ctx, sessionId, key, []byte(value))
+		err = batch.Put(ctx, storedb.EntryKey(sessionId, key), []byte(value))
 		if err != nil {
+			batch.Rollback(ctx)
 			return err
 		}
-		err = logdb.WriteLogEntry(ctx, sessionId, key, []byte(value))
-		if err != nil {
-			logg.DebugCtxf(ctx, "Failed to write log entry", "key", key, "value", value)
-		}
 	}
-	publicKeyNormalized, err := hex.NormalizeHex(publicKey)
+	err = batch.Put(ctx, storedb.IndexKey(storedb.DATA_PUBLIC_KEY_REVERSE, publicKeyNormalized), []byte(sessionId))
 	if err != nil {
+		batch.Rollback(ctx)
 		return err
 	}
-	err = store.WriteEntry(ctx, publicKeyNormalized, storedb.DATA_PUBLIC_KEY_REVERSE, []byte(sessionId))
+	err = batch.Commit(ctx)
 	if err != nil {
 		return err
 	}
 
+	for key, value := range data {
+		err = logdb.WriteLogEntry(ctx, sessionId, key, []byte(value))
+		if err != nil {
+			logg.DebugCtxf(ctx, "Failed to write log entry", "key", key, "value", value)
+		}
+	}
 	err = logdb.WriteLogEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY_REVERSE, []byte(sessionId))
 	if err != nil {
 		logg.DebugCtxf(ctx, "Failed to write log entry", "key", storedb.DATA_PUBLIC_KEY_REVERSE, "value", sessionId)
@@ -73,7 +86,11 @@ func (h *MenuHandlers) CreateAccount(ctx context.Context, sym string, input []by
 		return res, fmt.Errorf("missing session")
 	}
 	store := h.userdataStore
-	_, err = store.ReadEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY)
+	publicKey, err := store.ReadEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY)
+	if err == nil && len(publicKey) == 0 {
+		// the entries of a deleted account are left empty
+		err = db.NewErrNotFound([]byte(sessionId))
+	}
 	if err != nil {
 		if db.IsNotFound(err) {
 			logg.InfoCtxf(ctx, "Creating an account because it doesn't exist")
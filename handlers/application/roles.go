@@ -0,0 +1,135 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.defalsify.org/vise.git/db"
+	"git.defalsify.org/vise.git/resource"
+	"git.grassecon.net/grassrootseconomics/common/phone"
+	"git.grassecon.net/grassrootseconomics/common/pin"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"gopkg.in/leonelquinteros/gotext.v1"
+)
+
+// hasRole returns true if the role has been granted to the account.
+func (h *MenuHandlers) hasRole(ctx context.Context, sessionId string, role string) (bool, error) {
+	ok, err := store.HasRole(ctx, h.userdataStore, sessionId, role)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read roles", "role", role, "error", err)
+		return false, err
+	}
+	return ok, nil
+}
+
+// checkRole sets the admin privilege flag if the role has been granted to the account, and resets it otherwise.
+func (h *MenuHandlers) checkRole(ctx context.Context, role string) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_admin_privilege, _ := h.flagManager.GetFlag("flag_admin_privilege")
+
+	ok, err := h.hasRole(ctx, sessionId, role)
+	if err != nil {
+		return res, err
+	}
+	if ok {
+		res.FlagSet = append(res.FlagSet, flag_admin_privilege)
+	} else {
+		res.FlagReset = append(res.FlagReset, flag_admin_privilege)
+	}
+	return res, nil
+}
+
+// CheckPinResetRole sets the admin privilege flag if the account may reset the PIN of other accounts.
+func (h *MenuHandlers) CheckPinResetRole(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	return h.checkRole(ctx, store.RolePinReset)
+}
+
+// CheckSupportRole sets the admin privilege flag if the account may view the PIN status of other accounts.
+func (h *MenuHandlers) CheckSupportRole(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	return h.checkRole(ctx, store.RoleSupport)
+}
+
+// CheckPoolManagerRole sets the admin privilege flag if the account may manage pools.
+func (h *MenuHandlers) CheckPoolManagerRole(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	return h.checkRole(ctx, store.RolePoolManager)
+}
+
+// ShowAccountPinStatus shows the PIN status of the account with the given registered phone number,
+// for accounts with the support role.
+func (h *MenuHandlers) ShowAccountPinStatus(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_unregistered_number, _ := h.flagManager.GetFlag("flag_unregistered_number")
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	ok, err := h.hasRole(ctx, sessionId, store.RoleSupport)
+	if err != nil {
+		return res, err
+	}
+	if !ok {
+		res.Content = l.Get("You do not have privileges to perform this action")
+		return res, nil
+	}
+
+	formattedNumber, err := phone.FormatPhoneNumber(strings.ReplaceAll(string(input), " ", ""))
+	if err != nil || !phone.IsValidPhoneNumber(formattedNumber) {
+		res.FlagSet = append(res.FlagSet, flag_unregistered_number)
+		return res, nil
+	}
+	userStore := h.userdataStore
+	_, err = userStore.ReadEntry(ctx, formattedNumber, storedb.DATA_PUBLIC_KEY)
+	if err != nil {
+		if db.IsNotFound(err) {
+			res.FlagSet = append(res.FlagSet, flag_unregistered_number)
+			return res, nil
+		}
+		logg.ErrorCtxf(ctx, "failed to read publicKey", "number", formattedNumber, "error", err)
+		return res, err
+	}
+	res.FlagReset = append(res.FlagReset, flag_unregistered_number)
+
+	var attempts uint64
+	v, err := userStore.ReadEntry(ctx, formattedNumber, storedb.DATA_INCORRECT_PIN_ATTEMPTS)
+	if err == nil {
+		attempts, _ = strconv.ParseUint(string(v), 0, 64)
+	} else if !db.IsNotFound(err) {
+		logg.ErrorCtxf(ctx, "failed to read incorrect PIN attempts", "number", formattedNumber, "error", err)
+		return res, err
+	}
+	expiry, locked, err := store.LockoutExpiry(ctx, userStore, formattedNumber)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read PIN lockout expiry", "number", formattedNumber, "error", err)
+		return res, err
+	}
+	selfPinReset, _ := userStore.ReadEntry(ctx, formattedNumber, storedb.DATA_SELF_PIN_RESET)
+
+	// an expired lockout is lifted when the account next dials in
+	var status string
+	switch {
+	case string(selfPinReset) == "1":
+		status = l.Get("PIN reset pending")
+	case locked && time.Now().Before(expiry):
+		status = l.Get("Locked until %s", expiry.Format("2006-01-02 15:04"))
+	case !locked && attempts >= uint64(pin.AllowedPINAttempts):
+		status = l.Get("Blocked")
+	default:
+		status = l.Get("Active")
+	}
+
+	res.Content = l.Get("%s\nStatus: %s\nIncorrect PIN attempts: %d", formattedNumber, status, attempts)
+	return res, nil
+}
@@ -0,0 +1,103 @@
+package application
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"git.defalsify.org/vise.git/resource"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestCheckRole(t *testing.T) {
+	sessionId := "+254712345678"
+	ctx, userStore := InitializeTestStore(t)
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
+
+	fm, err := NewFlagManager(flagsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag_admin_privilege, _ := fm.GetFlag("flag_admin_privilege")
+
+	h := &MenuHandlers{
+		userdataStore: userStore,
+		flagManager:   fm,
+	}
+
+	res, err := h.CheckPinResetRole(ctx, "check_pin_reset_role", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagReset: []uint32{flag_admin_privilege}}, res)
+
+	_, err = store.GrantRole(ctx, userStore, sessionId, store.RolePinReset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = h.CheckPinResetRole(ctx, "check_pin_reset_role", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagSet: []uint32{flag_admin_privilege}}, res)
+
+	// roles are checked separately
+	res, err = h.CheckSupportRole(ctx, "check_support_role", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagReset: []uint32{flag_admin_privilege}}, res)
+	res, err = h.CheckPoolManagerRole(ctx, "check_pool_manager_role", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagReset: []uint32{flag_admin_privilege}}, res)
+}
+
+func TestShowAccountPinStatus(t *testing.T) {
+	sessionId := "+254712345678"
+	other := "+254711223344"
+	ctx, userStore := InitializeTestStore(t)
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
+
+	fm, err := NewFlagManager(flagsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag_unregistered_number, _ := fm.GetFlag("flag_unregistered_number")
+
+	h := &MenuHandlers{
+		userdataStore: userStore,
+		flagManager:   fm,
+	}
+
+	res, err := h.ShowAccountPinStatus(ctx, "show_account_pin_status", []byte("0711223344"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{Content: "You do not have privileges to perform this action"}, res)
+
+	_, err = store.GrantRole(ctx, userStore, sessionId, store.RoleSupport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = h.ShowAccountPinStatus(ctx, "show_account_pin_status", []byte("0711223344"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagSet: []uint32{flag_unregistered_number}}, res)
+
+	err = userStore.WriteEntry(ctx, other, storedb.DATA_PUBLIC_KEY, []byte("0X13242618721"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = h.ShowAccountPinStatus(ctx, "show_account_pin_status", []byte("0711223344"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagReset: []uint32{flag_unregistered_number}, Content: "+254711223344\nStatus: Active\nIncorrect PIN attempts: 0"}, res)
+
+	err = userStore.WriteEntry(ctx, other, storedb.DATA_INCORRECT_PIN_ATTEMPTS, []byte("3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = h.ShowAccountPinStatus(ctx, "show_account_pin_status", []byte("0711223344"))
+	assert.NoError(t, err)
+	assert.Equal(t, "+254711223344\nStatus: Blocked\nIncorrect PIN attempts: 3", res.Content)
+
+	_, _, err = store.LockAccount(ctx, userStore, other, []time.Duration{time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = h.ShowAccountPinStatus(ctx, "show_account_pin_status", []byte("0711223344"))
+	assert.NoError(t, err)
+	assert.Contains(t, res.Content, "Status: Locked until ")
+}
@@ -0,0 +1,316 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"git.defalsify.org/vise.git/resource"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"gopkg.in/leonelquinteros/gotext.v1"
+)
+
+// scheduleIntervals are the repeat intervals offered for selection, in menu order.
+var scheduleIntervals = []string{
+	store.ScheduleDaily,
+	store.ScheduleWeekly,
+	store.ScheduleMonthly,
+}
+
+// scheduleIntervalLabel returns the translated repeat interval of a scheduled transfer.
+func scheduleIntervalLabel(l *gotext.Locale, interval string) string {
+	switch interval {
+	case store.ScheduleDaily:
+		return l.Get("day")
+	case store.ScheduleMonthly:
+		return l.Get("month")
+	}
+	return l.Get("week")
+}
+
+// GetSchedules lists the scheduled transfers of the account.
+func (h *MenuHandlers) GetSchedules(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	schedules, err := store.ReadSchedules(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read scheduled transfers", "error", err)
+		return res, err
+	}
+	if len(schedules) == 0 {
+		res.Content = l.Get("You have no scheduled payments")
+		return res, nil
+	}
+
+	var lines []string
+	for i, s := range schedules {
+		line := fmt.Sprintf("%d%s%s %s > %s", i+1, h.ReplaceSeparatorFunc(":"), s.Amount, s.Symbol, store.ShortenAddress(s.RecipientInput))
+		if !s.Active {
+			line += " " + l.Get("Paused")
+		}
+		lines = append(lines, line)
+	}
+	res.Content = strings.Join(lines, "\n")
+	return res, nil
+}
+
+// SelectSchedule selects the scheduled transfer with the given number and shows its details.
+func (h *MenuHandlers) SelectSchedule(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_invalid_schedule, _ := h.flagManager.GetFlag("flag_invalid_schedule")
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	schedules, err := store.ReadSchedules(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read scheduled transfers", "error", err)
+		return res, err
+	}
+	index, err := strconv.Atoi(strings.TrimSpace(string(input)))
+	if err != nil || index < 1 || index > len(schedules) {
+		res.FlagSet = append(res.FlagSet, flag_invalid_schedule)
+		return res, nil
+	}
+
+	err = h.userdataStore.WriteEntry(ctx, sessionId, storedb.DATA_SCHEDULE_SELECTED, []byte(strconv.Itoa(index-1)))
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to write selected scheduled transfer", "error", err)
+		return res, err
+	}
+	res.FlagReset = append(res.FlagReset, flag_invalid_schedule)
+
+	s := schedules[index-1]
+	lines := []string{
+		l.Get("%s %s to %s every %s", s.Amount, s.Symbol, s.RecipientInput, scheduleIntervalLabel(l, s.Interval)),
+	}
+	if s.Active {
+		lines = append(lines, l.Get("Next: %s", time.Unix(s.Next, 0).Format("2006-01-02")))
+	} else {
+		lines = append(lines, l.Get("Paused after failed payments"))
+	}
+	if s.LastStatus == store.ScheduleFailed {
+		lines = append(lines, l.Get("Last payment failed"))
+	}
+	res.Content = strings.Join(lines, "\n")
+	return res, nil
+}
+
+// ScheduleMaxAmount shows the amount prompt of a new scheduled transfer, with the
+// balance of the active voucher as the maximum amount.
+func (h *MenuHandlers) ScheduleMaxAmount(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	activeBal, err := h.userdataStore.ReadEntry(ctx, sessionId, storedb.DATA_ACTIVE_BAL)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read activeBal entry with", "key", storedb.DATA_ACTIVE_BAL, "error", err)
+		return res, err
+	}
+	activeSym, err := h.userdataStore.ReadEntry(ctx, sessionId, storedb.DATA_ACTIVE_SYM)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read activeSym entry with", "key", storedb.DATA_ACTIVE_SYM, "error", err)
+		return res, err
+	}
+
+	formattedBalance, _ := store.TruncateDecimalString(string(activeBal), 2)
+	res.Content = l.Get("Maximum amount: %s %s\nEnter amount:", formattedBalance, string(activeSym))
+	return res, nil
+}
+
+// SetScheduleInterval saves the repeat interval selected for the new scheduled transfer.
+func (h *MenuHandlers) SetScheduleInterval(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_invalid_schedule, _ := h.flagManager.GetFlag("flag_invalid_schedule")
+
+	index, err := strconv.Atoi(strings.TrimSpace(string(input)))
+	if err != nil || index < 1 || index > len(scheduleIntervals) {
+		res.FlagSet = append(res.FlagSet, flag_invalid_schedule)
+		return res, nil
+	}
+
+	err = h.userdataStore.WriteEntry(ctx, sessionId, storedb.DATA_SCHEDULE_INTERVAL, []byte(scheduleIntervals[index-1]))
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to write schedule interval", "error", err)
+		return res, err
+	}
+	res.FlagReset = append(res.FlagReset, flag_invalid_schedule)
+	return res, nil
+}
+
+// SchedulePreview displays the scheduled transfer awaiting authorization.
+func (h *MenuHandlers) SchedulePreview(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	data, err := store.ReadTransactionData(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		return res, err
+	}
+	interval, err := h.userdataStore.ReadEntry(ctx, sessionId, storedb.DATA_SCHEDULE_INTERVAL)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read schedule interval", "error", err)
+		return res, err
+	}
+
+	err = h.newIntentNonce(ctx, sessionId)
+	if err != nil {
+		return res, err
+	}
+
+	start := store.NextScheduleRun(string(interval), time.Now())
+	res.Content = l.Get(
+		"%s will receive %s %s every %s, starting %s",
+		data.RecipientInput,
+		data.Amount,
+		data.ActiveSym,
+		scheduleIntervalLabel(l, string(interval)),
+		start.Format("2006-01-02"),
+	)
+	return res, nil
+}
+
+// CreateSchedule saves the authorized scheduled transfer, which is submitted by
+// the scheduler once every repeat interval.
+func (h *MenuHandlers) CreateSchedule(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+	flag_account_authorized, _ := h.flagManager.GetFlag("flag_account_authorized")
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	data, err := store.ReadTransactionData(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		return res, err
+	}
+	interval, err := h.userdataStore.ReadEntry(ctx, sessionId, storedb.DATA_SCHEDULE_INTERVAL)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read schedule interval", "error", err)
+		return res, err
+	}
+
+	value, err := store.ParseAndScaleAmount(data.Amount, data.ActiveDecimal)
+	if err != nil {
+		return res, err
+	}
+
+	intentKey, content, duplicate, err := h.beginIntent(ctx, sessionId, l, "schedule", value, data.Recipient, data.ActiveAddress, string(interval))
+	if err != nil {
+		return res, err
+	}
+	res.FlagReset = append(res.FlagReset, flag_account_authorized)
+	if duplicate {
+		res.Content = content
+		return res, nil
+	}
+
+	id, err := store.AddSchedule(ctx, h.userdataStore, sessionId, store.ScheduleRecord{
+		RecipientInput: data.RecipientInput,
+		Recipient:      data.Recipient,
+		Amount:         data.Amount,
+		Symbol:         data.ActiveSym,
+		TokenAddress:   data.ActiveAddress,
+		Value:          value,
+		Interval:       string(interval),
+		Next:           store.NextScheduleRun(string(interval), time.Now()).Unix(),
+	})
+	if err != nil {
+		h.releaseIntent(ctx, sessionId, intentKey)
+		if errors.Is(err, store.ErrSchedulesFull) {
+			res.Content = l.Get("You have too many scheduled payments. Please cancel one first")
+			return res, nil
+		}
+		logg.ErrorCtxf(ctx, "failed to add scheduled transfer", "error", err)
+		return res, err
+	}
+	h.completeIntent(ctx, sessionId, intentKey, id)
+
+	res.Content = l.Get(
+		"Your payment has been scheduled. %s will receive %s %s every %s.",
+		data.RecipientInput,
+		data.Amount,
+		data.ActiveSym,
+		scheduleIntervalLabel(l, string(interval)),
+	)
+	return res, nil
+}
+
+// CancelSchedule removes the selected scheduled transfer.
+func (h *MenuHandlers) CancelSchedule(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	v, err := h.userdataStore.ReadEntry(ctx, sessionId, storedb.DATA_SCHEDULE_SELECTED)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read selected scheduled transfer", "error", err)
+		return res, err
+	}
+	index, err := strconv.Atoi(string(v))
+	if err != nil {
+		return res, err
+	}
+	schedules, err := store.ReadSchedules(ctx, h.userdataStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read scheduled transfers", "error", err)
+		return res, err
+	}
+	if index < 0 || index >= len(schedules) {
+		return res, fmt.Errorf("scheduled transfer not found: index %d out of range", index)
+	}
+
+	err = store.CancelSchedule(ctx, h.userdataStore, sessionId, schedules[index].Id)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to cancel scheduled transfer", "id", schedules[index].Id, "error", err)
+		return res, err
+	}
+
+	res.Content = l.Get("Your scheduled payment to %s has been cancelled", schedules[index].RecipientInput)
+	return res, nil
+}
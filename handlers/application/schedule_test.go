@@ -0,0 +1,99 @@
+package application
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"git.defalsify.org/vise.git/resource"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestScheduleMenu(t *testing.T) {
+	sessionId := "session123"
+
+	ctx, userStore := InitializeTestStore(t)
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
+
+	fm, err := NewFlagManager(flagsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag_invalid_schedule, _ := fm.GetFlag("flag_invalid_schedule")
+	flag_account_authorized, _ := fm.GetFlag("flag_account_authorized")
+
+	h := &MenuHandlers{
+		userdataStore:        userStore,
+		flagManager:          fm,
+		ReplaceSeparatorFunc: mockReplaceSeparator,
+	}
+
+	entries := map[storedb.DataTyp][]byte{
+		storedb.DATA_RECIPIENT_INPUT: []byte("0711223344"),
+		storedb.DATA_ACTIVE_SYM:      []byte("SRF"),
+		storedb.DATA_AMOUNT:          []byte("1.00"),
+		storedb.DATA_PUBLIC_KEY:      []byte("0X13242618721"),
+		storedb.DATA_RECIPIENT:       []byte("0x12415ass27192"),
+		storedb.DATA_ACTIVE_DECIMAL:  []byte("6"),
+		storedb.DATA_ACTIVE_ADDRESS:  []byte("0xd4c288865Ce"),
+	}
+	for k, v := range entries {
+		err = userStore.WriteEntry(ctx, sessionId, k, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := h.GetSchedules(ctx, "get_schedules", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "You have no scheduled payments", res.Content)
+
+	res, err = h.SetScheduleInterval(ctx, "set_schedule_interval", []byte("4"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagSet: []uint32{flag_invalid_schedule}}, res)
+
+	res, err = h.SetScheduleInterval(ctx, "set_schedule_interval", []byte("2"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagReset: []uint32{flag_invalid_schedule}}, res)
+
+	res, err = h.SchedulePreview(ctx, "schedule_preview", []byte(""))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(res.Content, "0711223344 will receive 1.00 SRF every week, starting "))
+
+	res, err = h.CreateSchedule(ctx, "create_schedule", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{
+		FlagReset: []uint32{flag_account_authorized},
+		Content:   "Your payment has been scheduled. 0711223344 will receive 1.00 SRF every week.",
+	}, res)
+
+	// a resubmission of the same request does not schedule the payment again
+	_, err = h.CreateSchedule(ctx, "create_schedule", []byte(""))
+	assert.NoError(t, err)
+	schedules, err := store.ReadSchedules(ctx, userStore, sessionId)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(schedules))
+	assert.Equal(t, "1000000", schedules[0].Value)
+
+	res, err = h.GetSchedules(ctx, "get_schedules", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "1: 1.00 SRF > 0711223344", res.Content)
+
+	res, err = h.SelectSchedule(ctx, "select_schedule", []byte("2"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagSet: []uint32{flag_invalid_schedule}}, res)
+
+	res, err = h.SelectSchedule(ctx, "select_schedule", []byte("1"))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(res.Content, "1.00 SRF to 0711223344 every week\nNext: "))
+
+	res, err = h.CancelSchedule(ctx, "cancel_schedule", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "Your scheduled payment to 0711223344 has been cancelled", res.Content)
+
+	res, err = h.GetSchedules(ctx, "get_schedules", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "You have no scheduled payments", res.Content)
+}
@@ -13,6 +13,7 @@ import (
 	"git.grassecon.net/grassrootseconomics/common/hex"
 	"git.grassecon.net/grassrootseconomics/common/identity"
 	"git.grassecon.net/grassrootseconomics/common/phone"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/models"
 	"git.grassecon.net/grassrootseconomics/sarafu-api/remote/http"
 	"git.grassecon.net/grassrootseconomics/sarafu-vise/config"
 	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
@@ -39,6 +40,16 @@ func (h *MenuHandlers) ValidateRecipient(ctx context.Context, sym string, input
 		return res, nil
 	}
 
+	// several recipients may be given separated by commas, or as a contact group
+	if recipients, ok := h.multiSendRecipients(ctx, sessionId, recipient); ok {
+		return h.validateMultiSendRecipients(ctx, sessionId, recipients, &res)
+	}
+
+	// a saved contact or recent recipient may be selected by number
+	if contact, ok := h.resolveSendContact(ctx, sessionId, recipient); ok {
+		recipient = contact
+	}
+
 	recipientType, err := identity.CheckRecipient(recipient)
 	if err != nil {
 		// Invalid recipient format (not a phone number, address, or valid alias format)
@@ -118,7 +129,7 @@ func (h *MenuHandlers) handleAddress(ctx context.Context, sessionId, recipient s
 	}
 
 	// get the recipient's phone number from the address
-	recipientPhoneNumber, err := store.ReadEntry(ctx, publicKeyNormalized, storedb.DATA_PUBLIC_KEY_REVERSE)
+	recipientPhoneNumber, err := store.ReadIndex(ctx, storedb.DATA_PUBLIC_KEY_REVERSE, publicKeyNormalized)
 	if err != nil || len(recipientPhoneNumber) == 0 {
 		logg.WarnCtxf(ctx, "Recipient address not registered, switching to normal transaction", "address", address)
 		recipientPhoneNumber = nil
@@ -132,16 +143,23 @@ func (h *MenuHandlers) handleAddress(ctx context.Context, sessionId, recipient s
 }
 
 func (h *MenuHandlers) handleAlias(ctx context.Context, sessionId, recipient string, res *resource.Result) (resource.Result, error) {
-	store := h.userdataStore
+	userStore := h.userdataStore
 	flag_invalid_recipient, _ := h.flagManager.GetFlag("flag_invalid_recipient")
 	flag_api_call_error, _ := h.flagManager.GetFlag("flag_api_call_error")
 
 	var aliasAddressResult string
-
-	if strings.Contains(recipient, ".") {
+	var aliasFqdn string
+
+	// consult the local alias index before the api
+	aliasAddressResult, aliasFqdn = h.resolveLocalAlias(ctx, recipient)
+	aliasLocal := aliasAddressResult != ""
+	if aliasLocal {
+		logg.InfoCtxf(ctx, "Resolved alias from local index", "alias", aliasFqdn, "address", aliasAddressResult)
+	} else if strings.Contains(recipient, ".") {
 		alias, err := h.accountService.CheckAliasAddress(ctx, recipient)
 		if err == nil {
 			aliasAddressResult = alias.Address
+			aliasFqdn = recipient
 		} else {
 			logg.ErrorCtxf(ctx, "Failed to resolve alias", "alias", recipient, "error", err)
 		}
@@ -154,6 +172,7 @@ func (h *MenuHandlers) handleAlias(ctx context.Context, sessionId, recipient str
 			if err == nil {
 				res.FlagReset = append(res.FlagReset, flag_api_call_error)
 				aliasAddressResult = alias.Address
+				aliasFqdn = fqdn
 				break
 			} else {
 				res.FlagSet = append(res.FlagSet, flag_api_call_error)
@@ -169,7 +188,7 @@ func (h *MenuHandlers) handleAlias(ctx context.Context, sessionId, recipient str
 		return *res, nil
 	}
 
-	if err := store.WriteEntry(ctx, sessionId, storedb.DATA_RECIPIENT, []byte(aliasAddressResult)); err != nil {
+	if err := userStore.WriteEntry(ctx, sessionId, storedb.DATA_RECIPIENT, []byte(aliasAddressResult)); err != nil {
 		logg.ErrorCtxf(ctx, "Failed to store alias recipient", "error", err)
 		return *res, err
 	}
@@ -182,10 +201,16 @@ func (h *MenuHandlers) handleAlias(ctx context.Context, sessionId, recipient str
 	}
 
 	// get the recipient's phone number from the address
-	recipientPhoneNumber, err := store.ReadEntry(ctx, publicKeyNormalized, storedb.DATA_PUBLIC_KEY_REVERSE)
+	recipientPhoneNumber, err := userStore.ReadIndex(ctx, storedb.DATA_PUBLIC_KEY_REVERSE, publicKeyNormalized)
 	if err != nil || len(recipientPhoneNumber) == 0 {
 		logg.WarnCtxf(ctx, "Alias address not registered, switching to normal transaction", "address", aliasAddressResult)
 		recipientPhoneNumber = nil
+	} else if !aliasLocal {
+		// remember aliases of local accounts resolved through the api
+		err = store.WriteAliasIndex(ctx, userStore, string(recipientPhoneNumber), "", aliasFqdn, aliasAddressResult)
+		if err != nil {
+			logg.WarnCtxf(ctx, "Failed to update alias index", "alias", aliasFqdn, "error", err)
+		}
 	}
 
 	if err := h.determineAndSaveTransactionType(ctx, sessionId, []byte(aliasAddressResult), recipientPhoneNumber); err != nil {
@@ -195,6 +220,31 @@ func (h *MenuHandlers) handleAlias(ctx context.Context, sessionId, recipient str
 	return *res, nil
 }
 
+// resolveLocalAlias looks up the recipient alias in the local alias index.
+//
+// Aliases without a domain are tried with each of the configured search domains.
+// It returns the address and the fully qualified alias, or empty strings if the alias is not known locally.
+func (h *MenuHandlers) resolveLocalAlias(ctx context.Context, recipient string) (string, string) {
+	var fqdns []string
+	if strings.Contains(recipient, ".") {
+		fqdns = append(fqdns, recipient)
+	} else {
+		for _, domain := range config.SearchDomains() {
+			fqdns = append(fqdns, fmt.Sprintf("%s.%s", recipient, domain))
+		}
+	}
+	for _, fqdn := range fqdns {
+		address, _, err := store.ResolveAlias(ctx, h.userdataStore, fqdn)
+		if err == nil {
+			return address, fqdn
+		}
+		if !db.IsNotFound(err) {
+			logg.WarnCtxf(ctx, "Failed to read alias index", "alias", fqdn, "error", err)
+		}
+	}
+	return "", ""
+}
+
 // determineAndSaveTransactionType centralizes transaction-type logic and recipient info persistence.
 // It expects the session to already have the recipient's public key (address) written.
 func (h *MenuHandlers) determineAndSaveTransactionType(
@@ -277,6 +327,7 @@ func (h *MenuHandlers) TransactionReset(ctx context.Context, sym string, input [
 
 	flag_invalid_recipient, _ := h.flagManager.GetFlag("flag_invalid_recipient")
 	flag_invalid_recipient_with_invite, _ := h.flagManager.GetFlag("flag_invalid_recipient_with_invite")
+	flag_multi_send, _ := h.flagManager.GetFlag("flag_multi_send")
 
 	store := h.userdataStore
 	err = store.WriteEntry(ctx, sessionId, storedb.DATA_AMOUNT, []byte(""))
@@ -304,7 +355,7 @@ func (h *MenuHandlers) TransactionReset(ctx context.Context, sym string, input [
 		return res, nil
 	}
 
-	res.FlagReset = append(res.FlagReset, flag_invalid_recipient, flag_invalid_recipient_with_invite)
+	res.FlagReset = append(res.FlagReset, flag_invalid_recipient, flag_invalid_recipient_with_invite, flag_multi_send)
 
 	return res, nil
 }
@@ -645,6 +696,10 @@ func (h *MenuHandlers) ValidateAmount(ctx context.Context, sym string, input []b
 
 	userStore := h.userdataStore
 
+	// symbol and token address of the voucher sent, set below if a custom voucher is used
+	var activeSym []byte
+	var activeAddress []byte
+
 	// retrieve the active balance
 	activeBal, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_ACTIVE_BAL)
 	if err != nil {
@@ -664,6 +719,8 @@ func (h *MenuHandlers) ValidateAmount(ctx context.Context, sym string, input []b
 			}
 
 			activeBal = []byte(customTransactionVoucher.Balance)
+			activeSym = []byte(customTransactionVoucher.TokenSymbol)
+			activeAddress = []byte(customTransactionVoucher.TokenAddress)
 		}
 	}
 
@@ -688,6 +745,27 @@ func (h *MenuHandlers) ValidateAmount(ctx context.Context, sym string, input []b
 		return res, nil
 	}
 
+	// the amount must also be within the spending limits of the account
+	if spendLimitsEnabled() {
+		if activeSym == nil {
+			activeSym, err = userStore.ReadEntry(ctx, sessionId, storedb.DATA_ACTIVE_SYM)
+			if err != nil {
+				logg.ErrorCtxf(ctx, "failed to read activeSym entry with", "key", storedb.DATA_ACTIVE_SYM, "error", err)
+				return res, err
+			}
+			activeAddress, err = userStore.ReadEntry(ctx, sessionId, storedb.DATA_ACTIVE_ADDRESS)
+			if err != nil {
+				logg.ErrorCtxf(ctx, "failed to read activeAddress entry with", "key", storedb.DATA_ACTIVE_ADDRESS, "error", err)
+				return res, err
+			}
+		}
+		amount, _ := strconv.ParseFloat(formattedAmount, 64)
+		ok, err = h.checkSpendLimit(ctx, sessionId, string(activeAddress), string(activeSym), amount, amount, &res)
+		if err != nil || !ok {
+			return res, err
+		}
+	}
+
 	err = userStore.WriteEntry(ctx, sessionId, storedb.DATA_AMOUNT, []byte(formattedAmount))
 	if err != nil {
 		logg.ErrorCtxf(ctx, "failed to write amount entry with", "key", storedb.DATA_AMOUNT, "value", formattedAmount, "error", err)
@@ -798,6 +876,11 @@ func (h *MenuHandlers) NormalTransactionPreview(ctx context.Context, sym string,
 		}
 	}
 
+	err = h.newIntentNonce(ctx, sessionId)
+	if err != nil {
+		return res, err
+	}
+
 	res.Content = l.Get(
 		"%s will receive %s %s from %s",
 		data.RecipientInput,
@@ -854,9 +937,47 @@ func (h *MenuHandlers) InitiateNormalTransaction(ctx context.Context, sym string
 		return res, err
 	}
 
+	intentKey, content, duplicate, err := h.beginIntent(ctx, sessionId, l, "transfer", finalAmountStr, data.PublicKey, data.Recipient, data.ActiveAddress)
+	if err != nil {
+		return res, err
+	}
+	if duplicate {
+		res.Content = content
+		res.FlagReset = append(res.FlagReset, flag_account_authorized)
+		return res, nil
+	}
+
+	entry := store.LedgerEntry{
+		Type:         store.LedgerTransfer,
+		Amount:       data.Amount,
+		Symbol:       data.ActiveSym,
+		Counterparty: data.RecipientInput,
+		From:         data.PublicKey,
+		To:           data.Recipient,
+		TokenAddress: data.ActiveAddress,
+		Value:        finalAmountStr,
+	}
+
 	// Call TokenTransfer
-	r, err := h.accountService.TokenTransfer(ctx, finalAmountStr, data.PublicKey, data.Recipient, data.ActiveAddress)
+	var r *models.TokenTransferResponse
+	ok, err = h.spend(ctx, sessionId, data.ActiveAddress, data.ActiveSym, data.Amount, func() error {
+		var err error
+		r, err = h.accountService.TokenTransfer(ctx, finalAmountStr, data.PublicKey, data.Recipient, data.ActiveAddress)
+		return err
+	})
+	if !ok {
+		// not submitted
+		h.releaseIntent(ctx, sessionId, intentKey)
+		if err != nil {
+			return res, err
+		}
+		res.Content, err = h.spendLimitContent(ctx, l, sessionId, data.ActiveAddress, data.ActiveSym)
+		res.FlagReset = append(res.FlagReset, flag_account_authorized)
+		return res, err
+	}
 	if err != nil {
+		h.abortIntent(ctx, sessionId, intentKey, err)
+		h.recordFailure(ctx, sessionId, entry, err)
 		var apiErr *http.APIError
 		if errors.As(err, &apiErr) {
 			switch apiErr.Code {
@@ -877,6 +998,10 @@ func (h *MenuHandlers) InitiateNormalTransaction(ctx context.Context, sym string
 
 	trackingId := r.TrackingId
 	logg.InfoCtxf(ctx, "TokenTransfer", "trackingId", trackingId)
+	h.completeIntent(ctx, sessionId, intentKey, trackingId)
+	entry.TrackingId = trackingId
+	h.recordSubmission(ctx, sessionId, entry)
+	h.recordRecentRecipient(ctx, sessionId, data.RecipientInput)
 
 	res.Content = l.Get(
 		"Your request has been sent. %s will receive %s %s from %s.",
@@ -983,6 +1108,15 @@ func (h *MenuHandlers) TransactionSwapPreview(ctx context.Context, sym string, i
 
 	sendInputAmount := r.InputAmount // amount of SAT that should be swapped
 
+	// the swapped amount must be within the spending limits of the account
+	if spendLimitsEnabled() {
+		satAmount, _ := strconv.ParseFloat(store.ScaleDownBalance(sendInputAmount, selectedVoucher.TokenDecimals), 64)
+		ok, err = h.checkSpendLimit(ctx, sessionId, selectedVoucher.TokenAddress, selectedVoucher.TokenSymbol, satAmount, satAmount, &res)
+		if err != nil || !ok {
+			return res, err
+		}
+	}
+
 	// store the finalAmountStr as the final amount (that will be sent after the swap)
 	err = userStore.WriteEntry(ctx, sessionId, storedb.DATA_AMOUNT, []byte(finalAmountStr))
 	if err != nil {
@@ -1004,6 +1138,11 @@ func (h *MenuHandlers) TransactionSwapPreview(ctx context.Context, sym string, i
 		return res, err
 	}
 
+	err = h.newIntentNonce(ctx, sessionId)
+	if err != nil {
+		return res, err
+	}
+
 	res.Content = l.Get(
 		"%s will receive %s %s",
 		string(recipientInput),
@@ -1071,9 +1210,35 @@ func (h *MenuHandlers) TransactionInitiateSwap(ctx context.Context, sym string,
 
 	swapAmountStr := string(swapAmount)
 
+	intentKey, content, duplicate, err := h.beginIntent(ctx, sessionId, l, "transaction_swap", swapAmountStr, string(publicKey), selectedVoucher.TokenAddress, string(activePoolAddress), swapToVoucher.TokenAddress)
+	if err != nil {
+		return res, err
+	}
+	if duplicate {
+		res.Content = content
+		res.FlagReset = append(res.FlagReset, flag_account_authorized)
+		return res, nil
+	}
+
 	// Call the poolSwap API
-	poolSwap, err := h.accountService.PoolSwap(ctx, swapAmountStr, string(publicKey), selectedVoucher.TokenAddress, string(activePoolAddress), swapToVoucher.TokenAddress)
+	var poolSwap *models.PoolSwapResult
+	ok, err = h.spend(ctx, sessionId, selectedVoucher.TokenAddress, selectedVoucher.TokenSymbol, store.ScaleDownBalance(swapAmountStr, selectedVoucher.TokenDecimals), func() error {
+		var err error
+		poolSwap, err = h.accountService.PoolSwap(ctx, swapAmountStr, string(publicKey), selectedVoucher.TokenAddress, string(activePoolAddress), swapToVoucher.TokenAddress)
+		return err
+	})
+	if !ok {
+		// not submitted
+		h.releaseIntent(ctx, sessionId, intentKey)
+		if err != nil {
+			return res, err
+		}
+		res.Content, err = h.spendLimitContent(ctx, l, sessionId, selectedVoucher.TokenAddress, selectedVoucher.TokenSymbol)
+		res.FlagReset = append(res.FlagReset, flag_account_authorized, flag_swap_transaction)
+		return res, err
+	}
 	if err != nil {
+		h.abortIntent(ctx, sessionId, intentKey, err)
 		flag_api_call_error, _ := h.flagManager.GetFlag("flag_api_call_error")
 		res.FlagSet = append(res.FlagSet, flag_api_call_error)
 		res.Content = l.Get("Your request failed. Please try again later.")
@@ -1083,6 +1248,8 @@ func (h *MenuHandlers) TransactionInitiateSwap(ctx context.Context, sym string,
 
 	swapTrackingId := poolSwap.TrackingId
 	logg.InfoCtxf(ctx, "send poolSwap before transfer", "swapTrackingId", swapTrackingId)
+	// the swap has been submitted, so the intent is kept whatever the outcome of the transfer
+	h.stepIntent(ctx, sessionId, intentKey, swapTrackingId)
 
 	// TODO: remove this temporary time delay and replace with a swap and send endpoint
 	time.Sleep(1 * time.Second)
@@ -1106,9 +1273,21 @@ func (h *MenuHandlers) TransactionInitiateSwap(ctx context.Context, sym string,
 		return res, err
 	}
 
+	entry := store.LedgerEntry{
+		Type:         store.LedgerTransfer,
+		Amount:       string(quotedAmount),
+		Symbol:       swapToVoucher.TokenSymbol,
+		Counterparty: string(recipientInput),
+		From:         string(publicKey),
+		To:           string(recipientPublicKey),
+		TokenAddress: swapToVoucher.TokenAddress,
+		Value:        string(amount),
+	}
+
 	// Call TokenTransfer with the expected swap amount
 	tokenTransfer, err := h.accountService.TokenTransfer(ctx, string(amount), string(publicKey), string(recipientPublicKey), swapToVoucher.TokenAddress)
 	if err != nil {
+		h.recordFailure(ctx, sessionId, entry, err)
 		flag_api_call_error, _ := h.flagManager.GetFlag("flag_api_call_error")
 		res.FlagSet = append(res.FlagSet, flag_api_call_error)
 		res.Content = l.Get("Your request failed. Please try again later.")
@@ -1118,6 +1297,10 @@ func (h *MenuHandlers) TransactionInitiateSwap(ctx context.Context, sym string,
 
 	trackingId := tokenTransfer.TrackingId
 	logg.InfoCtxf(ctx, "send TokenTransfer after swap", "trackingId", trackingId)
+	h.completeIntent(ctx, sessionId, intentKey, trackingId)
+	entry.TrackingId = trackingId
+	h.recordSubmission(ctx, sessionId, entry)
+	h.recordRecentRecipient(ctx, sessionId, string(recipientInput))
 
 	res.Content = l.Get(
 		"Your request has been sent. %s will receive %s %s from %s.",
@@ -8,7 +8,9 @@ import (
 
 	"git.defalsify.org/vise.git/resource"
 	"git.grassecon.net/grassrootseconomics/sarafu-api/models"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/remote/http"
 	"git.grassecon.net/grassrootseconomics/sarafu-api/testutil/mocks"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
 	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
 	"github.com/alecthomas/assert/v2"
 )
@@ -143,6 +145,7 @@ func TestTransactionReset(t *testing.T) {
 
 	flag_invalid_recipient, _ := fm.GetFlag("flag_invalid_recipient")
 	flag_invalid_recipient_with_invite, _ := fm.GetFlag("flag_invalid_recipient_with_invite")
+	flag_multi_send, _ := fm.GetFlag("flag_multi_send")
 
 	mockAccountService := new(mocks.MockAccountService)
 
@@ -160,7 +163,7 @@ func TestTransactionReset(t *testing.T) {
 		{
 			name: "Test transaction reset for amount and recipient",
 			expectedResult: resource.Result{
-				FlagReset: []uint32{flag_invalid_recipient, flag_invalid_recipient_with_invite},
+				FlagReset: []uint32{flag_invalid_recipient, flag_invalid_recipient_with_invite, flag_multi_send},
 			},
 		},
 	}
@@ -548,3 +551,161 @@ func TestInitiateNormalTransaction(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateRecipientLocalAlias(t *testing.T) {
+	fm, err := NewFlagManager(flagsPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	sessionId := "session123"
+	address := "0xd4c288865Ce0985a481Eef3be02443dF5E2e4Ea9"
+	ctx, userStore := InitializeTestStore(t)
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
+
+	err = store.WriteAliasIndex(ctx, userStore, "+254711223344", "", "alias123.sarafu.local", address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = userStore.WriteEntry(ctx, sessionId, storedb.DATA_ACTIVE_ADDRESS, []byte("0x41c188d63Qa"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mockAccountService := new(mocks.MockAccountService)
+	h := &MenuHandlers{
+		flagManager:    fm,
+		userdataStore:  userStore,
+		accountService: mockAccountService,
+	}
+
+	_, err = h.ValidateRecipient(ctx, "validate_recepient", []byte("Alias123.sarafu.local"))
+	assert.NoError(t, err)
+
+	storedRecipientAddress, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_RECIPIENT)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte(address), storedRecipientAddress)
+	mockAccountService.AssertNotCalled(t, "CheckAliasAddress", "Alias123.sarafu.local")
+}
+
+func TestInitiateNormalTransactionDuplicate(t *testing.T) {
+	sessionId := "254712345678"
+	ctx, userStore := InitializeTestStore(t)
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
+
+	fm, err := NewFlagManager(flagsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	account_authorized_flag, _ := fm.GetFlag("flag_account_authorized")
+
+	mockAccountService := new(mocks.MockAccountService)
+	h := &MenuHandlers{
+		userdataStore:  userStore,
+		accountService: mockAccountService,
+		flagManager:    fm,
+	}
+
+	entries := map[storedb.DataTyp][]byte{
+		storedb.DATA_TEMPORARY_VALUE: []byte("0711223344"),
+		storedb.DATA_RECIPIENT_INPUT: []byte("0711223344"),
+		storedb.DATA_ACTIVE_SYM:      []byte("SRF"),
+		storedb.DATA_AMOUNT:          []byte("1.00"),
+		storedb.DATA_PUBLIC_KEY:      []byte("0X13242618721"),
+		storedb.DATA_RECIPIENT:       []byte("0x12415ass27192"),
+		storedb.DATA_ACTIVE_DECIMAL:  []byte("6"),
+		storedb.DATA_ACTIVE_ADDRESS:  []byte("0xd4c288865Ce"),
+	}
+	for k, v := range entries {
+		err = userStore.WriteEntry(ctx, sessionId, k, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = h.NormalTransactionPreview(ctx, "transaction_confirmation", []byte(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mockAccountService.On("TokenTransfer").Return(&models.TokenTransferResponse{TrackingId: "1234567890"}, nil).Once()
+
+	res, err := h.InitiateNormalTransaction(ctx, "transaction_initiated", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "Your request has been sent. 0711223344 will receive 1.00 SRF from 254712345678.", res.Content)
+
+	// a resubmission of the same request is not sent again
+	res, err = h.InitiateNormalTransaction(ctx, "transaction_initiated", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{
+		FlagReset: []uint32{account_authorized_flag},
+		Content:   "Your request has already been sent. Reference: 1234567890",
+	}, res)
+	mockAccountService.AssertNumberOfCalls(t, "TokenTransfer", 1)
+
+	// a new preview allows the same transaction to be made again
+	_, err = h.NormalTransactionPreview(ctx, "transaction_confirmation", []byte(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockAccountService.On("TokenTransfer").Return(&models.TokenTransferResponse{TrackingId: "1234567891"}, nil).Once()
+	_, err = h.InitiateNormalTransaction(ctx, "transaction_initiated", []byte(""))
+	assert.NoError(t, err)
+	mockAccountService.AssertNumberOfCalls(t, "TokenTransfer", 2)
+}
+
+func TestInitiateNormalTransactionFailure(t *testing.T) {
+	sessionId := "254712345678"
+	ctx, userStore := InitializeTestStore(t)
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
+
+	fm, err := NewFlagManager(flagsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mockAccountService := new(mocks.MockAccountService)
+	h := &MenuHandlers{
+		userdataStore:  userStore,
+		accountService: mockAccountService,
+		flagManager:    fm,
+	}
+
+	entries := map[storedb.DataTyp][]byte{
+		storedb.DATA_TEMPORARY_VALUE: []byte("0711223344"),
+		storedb.DATA_RECIPIENT_INPUT: []byte("0711223344"),
+		storedb.DATA_ACTIVE_SYM:      []byte("SRF"),
+		storedb.DATA_AMOUNT:          []byte("1.00"),
+		storedb.DATA_PUBLIC_KEY:      []byte("0X13242618721"),
+		storedb.DATA_RECIPIENT:       []byte("0x12415ass27192"),
+		storedb.DATA_ACTIVE_DECIMAL:  []byte("6"),
+		storedb.DATA_ACTIVE_ADDRESS:  []byte("0xd4c288865Ce"),
+	}
+	for k, v := range entries {
+		err = userStore.WriteEntry(ctx, sessionId, k, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a request rejected by the remote service may be tried again
+	_, err = h.NormalTransactionPreview(ctx, "transaction_confirmation", []byte(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mockAccountService.On("TokenTransfer").Return(nil, &http.APIError{Code: "E01"}).Once()
+	res, err := h.InitiateNormalTransaction(ctx, "transaction_initiated", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "Your request failed. Please try again later.", res.Content)
+	mockAccountService.On("TokenTransfer").Return(nil, context.DeadlineExceeded).Once()
+	res, err = h.InitiateNormalTransaction(ctx, "transaction_initiated", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "An unexpected error occurred. Please try again later.", res.Content)
+	mockAccountService.AssertNumberOfCalls(t, "TokenTransfer", 2)
+
+	// the transfer may have been submitted despite the timeout, so it is not sent again
+	res, err = h.InitiateNormalTransaction(ctx, "transaction_initiated", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "Your request is already being processed. Please await confirmation", res.Content)
+	mockAccountService.AssertNumberOfCalls(t, "TokenTransfer", 2)
+}
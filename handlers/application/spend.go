@@ -0,0 +1,178 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"git.defalsify.org/vise.git/resource"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/internal/spend"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"gopkg.in/leonelquinteros/gotext.v1"
+)
+
+const (
+	// smallest amount for which a transaction is offered.
+	minSpendAmount = 0.1
+)
+
+var (
+	// errSpendLimit is the failure of a transaction that was not submitted because it is over the spending limits of the account.
+	errSpendLimit = errors.New("over spending limit")
+)
+
+// spendLimitsEnabled returns true if any spending limit is configured.
+func spendLimitsEnabled() bool {
+	return spend.Enabled()
+}
+
+// spendLimits returns the spending limits of the account for the voucher with the given token address.
+func (h *MenuHandlers) spendLimits(ctx context.Context, sessionId string, tokenAddress string) (store.SpendLimits, error) {
+	limits, err := spend.Limits(ctx, h.userdataStore, sessionId, tokenAddress)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read spending limits", "error", err)
+		return limits, err
+	}
+	return limits, nil
+}
+
+// spendAllowance returns the largest amount of the voucher with the given token address the account
+// can spend in a single transaction.
+//
+// Returns false if no limit applies.
+func (h *MenuHandlers) spendAllowance(ctx context.Context, sessionId string, tokenAddress string) (float64, bool, error) {
+	limits, err := h.spendLimits(ctx, sessionId, tokenAddress)
+	if err != nil {
+		return 0, false, err
+	}
+	if !limits.Limited() {
+		return 0, false, nil
+	}
+	spent, err := store.DailySpend(ctx, h.userdataStore, sessionId, tokenAddress)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read spent amounts", "error", err)
+		return 0, false, err
+	}
+	allowance, ok := limits.Allowance(spent)
+	return allowance, ok, nil
+}
+
+// checkSpendLimit checks a transaction of the voucher with the given token address against the spending limits of the account.
+//
+// The amount is checked against the transaction limit, and the total against the amount that can still be spent
+// today. They differ only when the same amount is sent to several recipients.
+//
+// Returns false if the transaction is over the limit, in which case the spend limit flag is set.
+func (h *MenuHandlers) checkSpendLimit(ctx context.Context, sessionId string, tokenAddress string, symbol string, amount float64, total float64, res *resource.Result) (bool, error) {
+	flag_spend_limit, _ := h.flagManager.GetFlag("flag_spend_limit")
+
+	ok, err := spend.Allowed(ctx, h.userdataStore, sessionId, tokenAddress, amount, total)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to check spending limits", "error", err)
+		return false, err
+	}
+	if !ok {
+		return false, h.spendLimitReached(ctx, sessionId, tokenAddress, symbol, res)
+	}
+
+	res.FlagReset = append(res.FlagReset, flag_spend_limit)
+	return true, nil
+}
+
+// spendLimitReached sets the spend limit flag, and keeps the voucher whose limit was reached for SpendLimitInfo.
+func (h *MenuHandlers) spendLimitReached(ctx context.Context, sessionId string, tokenAddress string, symbol string, res *resource.Result) error {
+	flag_spend_limit, _ := h.flagManager.GetFlag("flag_spend_limit")
+
+	err := store.WriteEntries(ctx, h.userdataStore, sessionId, map[storedb.DataTyp][]byte{
+		storedb.DATA_SPEND_LIMIT_ADDRESS: []byte(tokenAddress),
+		storedb.DATA_SPEND_LIMIT_SYMBOL:  []byte(symbol),
+	})
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to write spend limit voucher", "symbol", symbol, "error", err)
+		return err
+	}
+	res.FlagSet = append(res.FlagSet, flag_spend_limit)
+	return nil
+}
+
+// spendLimitContent describes the spending limits of the account for the voucher with the given token address,
+// with the amount that can still be spent today.
+func (h *MenuHandlers) spendLimitContent(ctx context.Context, l *gotext.Locale, sessionId string, tokenAddress string, symbol string) (string, error) {
+	limits, err := h.spendLimits(ctx, sessionId, tokenAddress)
+	if err != nil {
+		return "", err
+	}
+	spent, err := store.DailySpend(ctx, h.userdataStore, sessionId, tokenAddress)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read spent amounts", "error", err)
+		return "", err
+	}
+
+	lines := []string{l.Get("This is over your spending limit.")}
+	if limits.Transaction > 0 {
+		lines = append(lines, l.Get("Limit per transaction: %s %s", formatSpendAmount(limits.Transaction), symbol))
+	}
+	if remaining, ok := limits.Remaining(spent); ok {
+		lines = append(lines, l.Get("Remaining today: %s %s", formatSpendAmount(remaining), symbol))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+func formatSpendAmount(v float64) string {
+	s, _ := store.TruncateDecimalString(strconv.FormatFloat(v, 'f', -1, 64), 2)
+	return s
+}
+
+// spend submits a transaction of the given amount of the voucher with the given token address with fn,
+// and records the amount spent by the account.
+//
+// The spending limits are checked again while the transaction is submitted, as other transactions may
+// have been submitted since the amount was validated.
+//
+// Returns false if the amount is over the limits, in which case fn is not called. If fn fails, its error
+// is returned with true.
+func (h *MenuHandlers) spend(ctx context.Context, sessionId string, tokenAddress string, symbol string, amount string, fn func() error) (bool, error) {
+	v, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to parse spent amount", "amount", amount, "error", err)
+		return false, err
+	}
+	ok, err := spend.Spend(ctx, h.userdataStore, sessionId, tokenAddress, symbol, v, fn)
+	if !ok && err == nil {
+		logg.WarnCtxf(ctx, "transaction over spending limit", "symbol", symbol, "amount", amount)
+	}
+	return ok, err
+}
+
+// SpendLimitInfo shows the spending limits of the voucher whose limit was reached, with the amount
+// that can still be spent today.
+func (h *MenuHandlers) SpendLimitInfo(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	tokenAddress, err := h.userdataStore.ReadEntry(ctx, sessionId, storedb.DATA_SPEND_LIMIT_ADDRESS)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read spend limit address", "error", err)
+		return res, err
+	}
+	symbol, err := h.userdataStore.ReadEntry(ctx, sessionId, storedb.DATA_SPEND_LIMIT_SYMBOL)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read spend limit symbol", "error", err)
+		return res, err
+	}
+	res.Content, err = h.spendLimitContent(ctx, l, sessionId, string(tokenAddress), string(symbol))
+	if err != nil {
+		return res, err
+	}
+	return res, nil
+}
@@ -0,0 +1,90 @@
+package application
+
+import (
+	"context"
+	"testing"
+
+	"git.defalsify.org/vise.git/resource"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/testutil/mocks"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestSpendLimit(t *testing.T) {
+	t.Setenv("SPEND_LIMIT_TRANSACTION", "5")
+	t.Setenv("SPEND_LIMIT_DAILY", "8")
+
+	sessionId := "session123"
+
+	ctx, userStore := InitializeTestStore(t)
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
+
+	fm, err := NewFlagManager(flagsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag_spend_limit, _ := fm.GetFlag("flag_spend_limit")
+
+	h := &MenuHandlers{
+		userdataStore:  userStore,
+		accountService: new(mocks.MockAccountService),
+		flagManager:    fm,
+	}
+
+	entries := map[storedb.DataTyp][]byte{
+		storedb.DATA_ACTIVE_SYM:     []byte("SRF"),
+		storedb.DATA_ACTIVE_BAL:     []byte("20"),
+		storedb.DATA_ACTIVE_ADDRESS: []byte("0xd4c288865Ce"),
+	}
+	for k, v := range entries {
+		err = userStore.WriteEntry(ctx, sessionId, k, v)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	res, err := h.ValidateAmount(ctx, "validate_amount", []byte("4"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagReset: []uint32{flag_spend_limit}, Content: "4.00"}, res)
+
+	// over the transaction limit
+	res, err = h.ValidateAmount(ctx, "validate_amount", []byte("6"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagSet: []uint32{flag_spend_limit}}, res)
+
+	// over the amount remaining today
+	ok, err := h.spend(ctx, sessionId, "0xd4c288865Ce", "SRF", "4", func() error {
+		return nil
+	})
+	if err != nil || !ok {
+		t.Fatal(err)
+	}
+	res, err = h.ValidateAmount(ctx, "validate_amount", []byte("5"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagSet: []uint32{flag_spend_limit}}, res)
+
+	res, err = h.SpendLimitInfo(ctx, "spend_limit_info", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "This is over your spending limit.\nLimit per transaction: 5.00 SRF\nRemaining today: 4.00 SRF", res.Content)
+
+	// other vouchers are counted separately
+	err = store.WriteEntries(ctx, userStore, sessionId, map[storedb.DataTyp][]byte{
+		storedb.DATA_ACTIVE_SYM:     []byte("cUSD"),
+		storedb.DATA_ACTIVE_ADDRESS: []byte("0x765DE816845"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err = h.ValidateAmount(ctx, "validate_amount", []byte("5"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagReset: []uint32{flag_spend_limit}, Content: "5.00"}, res)
+
+	// the limits are checked again when the transaction is submitted
+	ok, err = h.spend(ctx, sessionId, "0xd4c288865Ce", "SRF", "5", func() error {
+		t.Fatal("transaction over the limit submitted")
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,79 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"git.defalsify.org/vise.git/resource"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/internal/sms"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"gopkg.in/leonelquinteros/gotext.v1"
+)
+
+const (
+	// number of transfers included in the statement sent by SMS.
+	miniStatementSize = 10
+)
+
+// SendMiniStatement sends the most recent transfers of the account by SMS
+// to the phone number of the session.
+func (h *MenuHandlers) SendMiniStatement(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	userStore := h.userdataStore
+	publicKey, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read publicKey entry with", "key", storedb.DATA_PUBLIC_KEY, "error", err)
+		return res, err
+	}
+
+	transfers, err := store.ReadTransferHistory(ctx, userStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "Failed to read the transfer history", "error", err)
+		return res, err
+	}
+	if len(transfers) == 0 {
+		res.Content = l.Get("You have no recent transactions")
+		return res, nil
+	}
+	if len(transfers) > miniStatementSize {
+		transfers = transfers[:miniStatementSize]
+	}
+
+	lines := []string{l.Get("Sarafu statement")}
+	for _, t := range transfers {
+		date := t.Date.Format("2006-01-02")
+		var line string
+		if strings.EqualFold(t.Sender, string(publicKey)) {
+			line = l.Get("%s Sent %s %s to %s", date, t.Value, t.Symbol, store.CounterpartyLabel(ctx, userStore, t.Recipient))
+		} else {
+			line = l.Get("%s Received %s %s from %s", date, t.Value, t.Symbol, store.CounterpartyLabel(ctx, userStore, t.Sender))
+		}
+		lines = append(lines, line)
+	}
+
+	err = h.smsService.SendStatementSMS(ctx, sessionId, lines)
+	if err != nil {
+		if errors.Is(err, sms.ErrRateLimited) {
+			res.Content = l.Get("You have requested too many statements. Please try again later")
+			return res, nil
+		}
+		logg.ErrorCtxf(ctx, "failed to send statement sms", "error", err)
+		res.Content = l.Get("Your statement could not be sent. Please try again later")
+		return res, nil
+	}
+
+	res.Content = l.Get("Your statement has been sent by SMS")
+	return res, nil
+}
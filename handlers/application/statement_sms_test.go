@@ -0,0 +1,132 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"git.grassecon.net/grassrootseconomics/sarafu-api/testutil/mocks"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/internal/sms"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"github.com/alecthomas/assert/v2"
+)
+
+// messageAccountService is an account service able to send SMS of arbitrary content.
+type messageAccountService struct {
+	*mocks.MockAccountService
+	phoneNumbers []string
+	messages     []string
+}
+
+func (s *messageAccountService) SendSMS(ctx context.Context, phoneNumber string, message string) error {
+	s.phoneNumbers = append(s.phoneNumbers, phoneNumber)
+	s.messages = append(s.messages, message)
+	return nil
+}
+
+func TestSendMiniStatement(t *testing.T) {
+	sessionId := "+254712345678"
+	publicKey := "0X13242618721"
+	counterparty := "0x41c188d63Qa"
+
+	ctx, userStore := InitializeTestStore(t)
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
+
+	svc := &messageAccountService{MockAccountService: new(mocks.MockAccountService)}
+	h := &MenuHandlers{
+		userdataStore: userStore,
+		smsService: sms.SmsService{
+			Accountservice: svc,
+			Userdatastore:  *userStore,
+		},
+	}
+
+	err := userStore.WriteEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY, []byte(publicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := h.SendMiniStatement(ctx, "send_mini_statement", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "You have no recent transactions", res.Content)
+	assert.Equal(t, 0, len(svc.messages))
+
+	date := time.Date(2024, 10, 3, 7, 23, 12, 0, time.UTC)
+	var transfers []store.TransferRecord
+	for i := 0; i < 12; i++ {
+		transfers = append(transfers, store.TransferRecord{
+			Sender: publicKey, Recipient: counterparty, Value: fmt.Sprintf("%d", i+1), ContractAddress: "0xaaa",
+			TxHash: fmt.Sprintf("0x%d", i), Date: date, Symbol: "SRF",
+		})
+	}
+	transfers[1].Sender, transfers[1].Recipient = counterparty, publicKey
+	if _, err := store.AddTransfers(ctx, userStore, sessionId, transfers); err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = h.SendMiniStatement(ctx, "send_mini_statement", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "Your statement has been sent by SMS", res.Content)
+
+	// the statement is split over several numbered messages
+	assert.True(t, len(svc.messages) > 1)
+	statement := ""
+	for i, msg := range svc.messages {
+		assert.True(t, len(msg) <= 160)
+		assert.Equal(t, "+254712345678", svc.phoneNumbers[i])
+		prefix := fmt.Sprintf("%d/%d\n", i+1, len(svc.messages))
+		assert.True(t, strings.HasPrefix(msg, prefix))
+		statement += strings.TrimPrefix(msg, prefix) + "\n"
+	}
+	lines := strings.Split(strings.TrimSpace(statement), "\n")
+	assert.Equal(t, miniStatementSize+1, len(lines))
+	assert.Equal(t, "Sarafu statement", lines[0])
+	assert.Equal(t, "2024-10-03 Sent 1 SRF to 0x41c188d63Qa", lines[1])
+	assert.Equal(t, "2024-10-03 Received 2 SRF from 0x41c188d63Qa", lines[2])
+
+	// statements are rate limited
+	for i := 0; i < 2; i++ {
+		res, err = h.SendMiniStatement(ctx, "send_mini_statement", []byte(""))
+		assert.NoError(t, err)
+		assert.Equal(t, "Your statement has been sent by SMS", res.Content)
+	}
+	count := len(svc.messages)
+	res, err = h.SendMiniStatement(ctx, "send_mini_statement", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "You have requested too many statements. Please try again later", res.Content)
+	assert.Equal(t, count, len(svc.messages))
+}
+
+func TestSendMiniStatementUnsupported(t *testing.T) {
+	sessionId := "+254712345678"
+	publicKey := "0X13242618721"
+
+	ctx, userStore := InitializeTestStore(t)
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
+
+	h := &MenuHandlers{
+		userdataStore: userStore,
+		smsService: sms.SmsService{
+			Accountservice: new(mocks.MockAccountService),
+			Userdatastore:  *userStore,
+		},
+	}
+
+	err := userStore.WriteEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY, []byte(publicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddTransfers(ctx, userStore, sessionId, []store.TransferRecord{
+		{Sender: publicKey, Recipient: "0x41c188d63Qa", Value: "1", TxHash: "0x1", Symbol: "SRF"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := h.SendMiniStatement(ctx, "send_mini_statement", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "Your statement could not be sent. Please try again later", res.Content)
+}
@@ -6,12 +6,147 @@ import (
 	"strconv"
 	"strings"
 
+	"git.defalsify.org/vise.git/db"
 	"git.defalsify.org/vise.git/resource"
 	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
 	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"gopkg.in/leonelquinteros/gotext.v1"
 )
 
-// CheckTransactions retrieves the transactions from the API using the "PublicKey" and stores to prefixDb.
+const (
+	// maximum size in bytes of the transactions listed on a single statement page,
+	// leaving room for the navigation and menu within the USSD output limit.
+	statementPageBytes = 100
+	// maximum number of transactions on a single statement page, keeping the
+	// selection numbers clear of the navigation inputs.
+	statementPageMax = 9
+)
+
+// statement is the page of the filtered transfer history being browsed.
+type statement struct {
+	publicKey string
+	transfers []store.TransferRecord
+	// start index of each page in transfers
+	pages []int
+	page  int
+}
+
+// current returns the transfers on the current page.
+func (s statement) current() []store.TransferRecord {
+	if len(s.pages) == 0 {
+		return nil
+	}
+	end := len(s.transfers)
+	if s.page+1 < len(s.pages) {
+		end = s.pages[s.page+1]
+	}
+	return s.transfers[s.pages[s.page]:end]
+}
+
+// formats a transfer as a line of the statement, without the selection number.
+func statementLine(t store.TransferRecord, publicKey string) string {
+	status := "Received"
+	if strings.EqualFold(t.Sender, publicKey) {
+		status = "Sent"
+	}
+	date := t.Date.Format("2006-01-02")
+	return fmt.Sprintf("%s %s %s %s", status, t.Value, t.Symbol, date)
+}
+
+// paginateStatement splits the statement lines into pages fitting statementPageBytes,
+// returning the start index of each page.
+func paginateStatement(lines []string, sep string) []int {
+	var pages []int
+	size := 0
+	count := 0
+	for i, line := range lines {
+		n := len(fmt.Sprintf("%d%s%s\n", count+1, sep, line))
+		if i == 0 || count == statementPageMax || size+n > statementPageBytes {
+			pages = append(pages, i)
+			size = 0
+			count = 0
+			n = len(fmt.Sprintf("%d%s%s\n", 1, sep, line))
+		}
+		size += n
+		count++
+	}
+	return pages
+}
+
+// readStatement reads the transfer history with the filter and page being browsed applied.
+func (h *MenuHandlers) readStatement(ctx context.Context, sessionId string) (statement, error) {
+	var st statement
+	userStore := h.userdataStore
+	publicKey, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read publicKey entry with", "key", storedb.DATA_PUBLIC_KEY, "error", err)
+		return st, err
+	}
+	st.publicKey = string(publicKey)
+
+	transfers, err := store.ReadTransferHistory(ctx, userStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "Failed to read the transfer history", "error", err)
+		return st, err
+	}
+
+	filter := store.StatementFilterAll
+	v, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_STATEMENT_FILTER)
+	if err == nil {
+		filter = string(v)
+	} else if !db.IsNotFound(err) {
+		return st, err
+	}
+	var voucherAddress []byte
+	if filter == store.StatementFilterVoucher {
+		voucherAddress, err = userStore.ReadEntry(ctx, sessionId, storedb.DATA_ACTIVE_ADDRESS)
+		if err != nil && !db.IsNotFound(err) {
+			return st, err
+		}
+	}
+	st.transfers = store.FilterTransfers(transfers, filter, st.publicKey, string(voucherAddress))
+
+	var lines []string
+	for _, t := range st.transfers {
+		lines = append(lines, statementLine(t, st.publicKey))
+	}
+	sep := ":"
+	if h.ReplaceSeparatorFunc != nil {
+		sep = h.ReplaceSeparatorFunc(sep)
+	}
+	st.pages = paginateStatement(lines, sep)
+
+	v, err = userStore.ReadEntry(ctx, sessionId, storedb.DATA_STATEMENT_PAGE)
+	if err == nil {
+		st.page, _ = strconv.Atoi(string(v))
+	} else if !db.IsNotFound(err) {
+		return st, err
+	}
+	if st.page >= len(st.pages) {
+		st.page = len(st.pages) - 1
+	}
+	if st.page < 0 {
+		st.page = 0
+	}
+	return st, nil
+}
+
+// resetStatement starts browsing the statement from the first page, without filter.
+func (h *MenuHandlers) resetStatement(ctx context.Context, sessionId string) error {
+	userStore := h.userdataStore
+	err := userStore.WriteEntry(ctx, sessionId, storedb.DATA_STATEMENT_FILTER, []byte(store.StatementFilterAll))
+	if err != nil {
+		return err
+	}
+	return userStore.WriteEntry(ctx, sessionId, storedb.DATA_STATEMENT_PAGE, []byte("0"))
+}
+
+// CheckTransactions makes sure the local transfer history is available for the recent transactions menus.
+//
+// The history is only retrieved from the API using the "PublicKey" if it is empty; it is
+// otherwise kept current by the transfer events. The API only provides the most recent
+// transfers, and the history only keeps a limited number of transfers, so the statement
+// lists recent transactions rather than the full history of the account.
 func (h *MenuHandlers) CheckTransactions(ctx context.Context, sym string, input []byte) (resource.Result, error) {
 	var res resource.Result
 	sessionId, ok := ctx.Value("SessionId").(string)
@@ -30,6 +165,23 @@ func (h *MenuHandlers) CheckTransactions(ctx context.Context, sym string, input
 		return res, err
 	}
 
+	err = h.resetStatement(ctx, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to reset statement", "error", err)
+		return res, err
+	}
+
+	// Use the local transfer history, backfilling it from the API if empty
+	transfers, err := store.ReadTransferHistory(ctx, userStore, sessionId)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read transfer history", "error", err)
+		return res, err
+	}
+	if len(transfers) > 0 {
+		res.FlagReset = append(res.FlagReset, flag_no_transfers)
+		return res, nil
+	}
+
 	// Fetch transactions from the API using the public key
 	transactionsResp, err := h.accountService.FetchTransactions(ctx, string(publicKey))
 	if err != nil {
@@ -48,26 +200,16 @@ func (h *MenuHandlers) CheckTransactions(ctx context.Context, sym string, input
 	data := store.ProcessTransfers(transactionsResp)
 
 	// Store all transaction data
-	dataMap := map[storedb.DataTyp]string{
-		storedb.DATA_TX_SENDERS:    data.Senders,
-		storedb.DATA_TX_RECIPIENTS: data.Recipients,
-		storedb.DATA_TX_VALUES:     data.TransferValues,
-		storedb.DATA_TX_ADDRESSES:  data.Addresses,
-		storedb.DATA_TX_HASHES:     data.TxHashes,
-		storedb.DATA_TX_DATES:      data.Dates,
-		storedb.DATA_TX_SYMBOLS:    data.Symbols,
-		storedb.DATA_TX_DECIMALS:   data.Decimals,
-	}
-
-	for key, value := range dataMap {
-		if err := h.prefixDb.Put(ctx, []byte(storedb.ToBytes(key)), []byte(value)); err != nil {
-			logg.ErrorCtxf(ctx, "failed to write to prefixDb", "error", err)
-			return res, err
-		}
-		err = logdb.WriteLogEntry(ctx, sessionId, key, []byte(value))
-		if err != nil {
-			logg.DebugCtxf(ctx, "Failed to write tx db log entry", "key", key, "value", value)
-		}
+	if _, err := store.AddTransfers(ctx, userStore, sessionId, data); err != nil {
+		logg.ErrorCtxf(ctx, "failed to backfill transfer history", "error", err)
+		return res, err
+	}
+	value, err := store.EncodeList(data)
+	if err == nil {
+		err = logdb.WriteLogEntry(ctx, sessionId, storedb.DATA_TX_LIST, value)
+	}
+	if err != nil {
+		logg.DebugCtxf(ctx, "Failed to write tx db log entry", "key", storedb.DATA_TX_LIST, "error", err)
 	}
 
 	res.FlagReset = append(res.FlagReset, flag_no_transfers)
@@ -75,7 +217,10 @@ func (h *MenuHandlers) CheckTransactions(ctx context.Context, sym string, input
 	return res, nil
 }
 
-// GetTransactionsList fetches the list of transactions and formats them.
+// GetTransactionsList formats the current page of the filtered transaction statement.
+//
+// Transactions are numbered within the page, and navigation to the next and
+// previous pages is offered where available.
 func (h *MenuHandlers) GetTransactionsList(ctx context.Context, sym string, input []byte) (resource.Result, error) {
 	var res resource.Result
 	sessionId, ok := ctx.Value("SessionId").(string)
@@ -83,60 +228,104 @@ func (h *MenuHandlers) GetTransactionsList(ctx context.Context, sym string, inpu
 		return res, fmt.Errorf("missing session")
 	}
 
-	userStore := h.userdataStore
-	publicKey, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY)
+	code := codeFromCtx(ctx)
+	l := gotext.NewLocale(translationDir, code)
+	l.AddDomain("default")
+
+	st, err := h.readStatement(ctx, sessionId)
 	if err != nil {
-		logg.ErrorCtxf(ctx, "failed to read publicKey entry with", "key", storedb.DATA_PUBLIC_KEY, "error", err)
 		return res, err
 	}
+	if len(st.transfers) == 0 {
+		res.Content = l.Get("No matching transactions")
+		return res, nil
+	}
 
-	// Read transactions from the store and format them
-	TransactionSenders, err := h.prefixDb.Get(ctx, storedb.ToBytes(storedb.DATA_TX_SENDERS))
-	if err != nil {
-		logg.ErrorCtxf(ctx, "Failed to read the TransactionSenders from prefixDb", "error", err)
-		return res, err
+	// Use the ReplaceSeparator function for the menu separator
+	sep := h.ReplaceSeparatorFunc(":")
+	var formattedTransactions []string
+	for i, t := range st.current() {
+		transactionLine := fmt.Sprintf("%d%s%s", i+1, sep, statementLine(t, st.publicKey))
+		formattedTransactions = append(formattedTransactions, transactionLine)
 	}
-	TransactionSyms, err := h.prefixDb.Get(ctx, storedb.ToBytes(storedb.DATA_TX_SYMBOLS))
-	if err != nil {
-		logg.ErrorCtxf(ctx, "Failed to read the TransactionSyms from prefixDb", "error", err)
-		return res, err
+	if st.page+1 < len(st.pages) {
+		formattedTransactions = append(formattedTransactions, fmt.Sprintf("11%s%s", sep, l.Get("Next")))
+	}
+	if st.page > 0 {
+		formattedTransactions = append(formattedTransactions, fmt.Sprintf("22%s%s", sep, l.Get("Prev")))
 	}
-	TransactionValues, err := h.prefixDb.Get(ctx, storedb.ToBytes(storedb.DATA_TX_VALUES))
+
+	res.Content = strings.Join(formattedTransactions, "\n")
+
+	return res, nil
+}
+
+// StatementNext moves to the next page of the transaction statement, if any.
+func (h *MenuHandlers) StatementNext(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	return h.moveStatementPage(ctx, 1)
+}
+
+// StatementPrevious moves to the previous page of the transaction statement, if any.
+func (h *MenuHandlers) StatementPrevious(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	return h.moveStatementPage(ctx, -1)
+}
+
+func (h *MenuHandlers) moveStatementPage(ctx context.Context, delta int) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
+	st, err := h.readStatement(ctx, sessionId)
 	if err != nil {
-		logg.ErrorCtxf(ctx, "Failed to read the TransactionValues from prefixDb", "error", err)
 		return res, err
 	}
-	TransactionDates, err := h.prefixDb.Get(ctx, storedb.ToBytes(storedb.DATA_TX_DATES))
+	page := st.page + delta
+	if page < 0 || page >= len(st.pages) {
+		return res, nil
+	}
+	err = h.userdataStore.WriteEntry(ctx, sessionId, storedb.DATA_STATEMENT_PAGE, []byte(strconv.Itoa(page)))
 	if err != nil {
-		logg.ErrorCtxf(ctx, "Failed to read the TransactionDates from prefixDb", "error", err)
+		logg.ErrorCtxf(ctx, "failed to write statement page", "error", err)
 		return res, err
 	}
+	return res, nil
+}
 
-	// Parse the data
-	senders := strings.Split(string(TransactionSenders), "\n")
-	syms := strings.Split(string(TransactionSyms), "\n")
-	values := strings.Split(string(TransactionValues), "\n")
-	dates := strings.Split(string(TransactionDates), "\n")
-
-	var formattedTransactions []string
-	for i := 0; i < len(senders); i++ {
-		sender := strings.TrimSpace(senders[i])
-		sym := strings.TrimSpace(syms[i])
-		value := strings.TrimSpace(values[i])
-		date := strings.Split(strings.TrimSpace(dates[i]), " ")[0]
-
-		status := "Received"
-		if sender == string(publicKey) {
-			status = "Sent"
-		}
-
-		// Use the ReplaceSeparator function for the menu separator
-		transactionLine := fmt.Sprintf("%d%s%s %s %s %s", i+1, h.ReplaceSeparatorFunc(":"), status, value, sym, date)
-		formattedTransactions = append(formattedTransactions, transactionLine)
+// SetStatementFilter applies the selected filter to the transaction statement,
+// returning to its first page.
+//
+// An invalid selection leaves the filter unchanged.
+func (h *MenuHandlers) SetStatementFilter(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
 	}
 
-	res.Content = strings.Join(formattedTransactions, "\n")
+	filters := map[string]string{
+		"1": store.StatementFilterAll,
+		"2": store.StatementFilterSent,
+		"3": store.StatementFilterReceived,
+		"4": store.StatementFilterVoucher,
+	}
+	filter, ok := filters[strings.TrimSpace(string(input))]
+	if !ok {
+		return res, nil
+	}
 
+	userStore := h.userdataStore
+	err := userStore.WriteEntry(ctx, sessionId, storedb.DATA_STATEMENT_FILTER, []byte(filter))
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to write statement filter", "error", err)
+		return res, err
+	}
+	err = userStore.WriteEntry(ctx, sessionId, storedb.DATA_STATEMENT_PAGE, []byte("0"))
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to write statement page", "error", err)
+		return res, err
+	}
 	return res, nil
 }
 
@@ -158,7 +347,7 @@ func (h *MenuHandlers) ViewTransactionStatement(ctx context.Context, sym string,
 	flag_incorrect_statement, _ := h.flagManager.GetFlag("flag_incorrect_statement")
 
 	inputStr := string(input)
-	if inputStr == "0" || inputStr == "99" || inputStr == "11" || inputStr == "22" {
+	if inputStr == "0" || inputStr == "99" || inputStr == "11" || inputStr == "22" || inputStr == "33" {
 		res.FlagReset = append(res.FlagReset, flag_incorrect_statement)
 		return res, nil
 	}
@@ -173,18 +362,65 @@ func (h *MenuHandlers) ViewTransactionStatement(ctx context.Context, sym string,
 		return res, fmt.Errorf("invalid input: index must be between 1 and 10")
 	}
 
-	statement, err := store.GetTransferData(ctx, h.prefixDb, string(publicKey), index)
+	// The index refers to the current page of the filtered statement
+	st, err := h.readStatement(ctx, sessionId)
 	if err != nil {
-		return res, fmt.Errorf("failed to retrieve transfer data: %v", err)
+		return res, err
 	}
+	transfers := st.current()
+	if index > len(transfers) {
+		return res, fmt.Errorf("failed to retrieve transfer data: transaction not found: index %d out of range", index)
+	}
+	t := transfers[index-1]
 
-	if statement == "" {
-		res.FlagSet = append(res.FlagSet, flag_incorrect_statement)
-		return res, nil
+	// Remember the transaction for the full details
+	selected := st.pages[st.page] + index - 1
+	err = userStore.WriteEntry(ctx, sessionId, storedb.DATA_STATEMENT_SELECTED, []byte(strconv.Itoa(selected)))
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to write selected statement entry", "error", err)
+		return res, err
 	}
 
+	party := t.Sender
+	if strings.EqualFold(t.Sender, string(publicKey)) {
+		party = t.Recipient
+	}
+	statement := store.TransferSummary(t, string(publicKey), store.CounterpartyLabel(ctx, userStore, party))
+
 	res.FlagReset = append(res.FlagReset, flag_incorrect_statement)
 	res.Content = statement
 
 	return res, nil
 }
+
+// ViewTransactionDetails displays the full details of the transaction last viewed
+// in the statement, including the addresses and transaction hash.
+func (h *MenuHandlers) ViewTransactionDetails(ctx context.Context, sym string, input []byte) (resource.Result, error) {
+	var res resource.Result
+	sessionId, ok := ctx.Value("SessionId").(string)
+	if !ok {
+		return res, fmt.Errorf("missing session")
+	}
+
+	v, err := h.userdataStore.ReadEntry(ctx, sessionId, storedb.DATA_STATEMENT_SELECTED)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to read selected statement entry", "error", err)
+		return res, err
+	}
+	selected, err := strconv.Atoi(string(v))
+	if err != nil {
+		return res, err
+	}
+
+	st, err := h.readStatement(ctx, sessionId)
+	if err != nil {
+		return res, err
+	}
+	statement, err := store.TransferDetail(st.transfers, st.publicKey, selected+1)
+	if err != nil {
+		return res, fmt.Errorf("failed to retrieve transfer data: %v", err)
+	}
+
+	res.Content = statement
+	return res, nil
+}
@@ -58,25 +58,66 @@ func TestCheckTransactions(t *testing.T) {
 		},
 	}
 
-	expectedSenders := []byte("0X13242618721\n0x41c188d63Qa")
+	expectedSenders := []string{"0X13242618721", "0x41c188d63Qa"}
 
 	mockAccountService.On("FetchTransactions", string(publicKey)).Return(mockTXResponse, nil)
 
 	_, err = h.CheckTransactions(ctx, "check_transactions", []byte(""))
 	assert.NoError(t, err)
 
-	// Read tranfers senders data from the store
-	senderData, err := spdb.Get(ctx, storedb.ToBytes(storedb.DATA_TX_SENDERS))
+	// Read tranfers data from the store
+	transfers, err := store.ReadTransferHistory(ctx, userStore, sessionId)
 	if err != nil {
 		t.Fatal(err)
 	}
 
 	// assert that the data is stored correctly
-	assert.Equal(t, expectedSenders, senderData)
+	var senders []string
+	for _, tx := range transfers {
+		senders = append(senders, tx.Sender)
+	}
+	assert.Equal(t, expectedSenders, senders)
 
 	mockAccountService.AssertExpectations(t)
 }
 
+func TestCheckTransactionsLocalHistory(t *testing.T) {
+	mockAccountService := new(mocks.MockAccountService)
+	sessionId := "session123"
+	publicKey := "0X13242618721"
+
+	ctx, userStore := InitializeTestStore(t)
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
+
+	fm, err := NewFlagManager(flagsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag_no_transfers, _ := fm.GetFlag("flag_no_transfers")
+
+	h := &MenuHandlers{
+		userdataStore:  userStore,
+		accountService: mockAccountService,
+		flagManager:    fm,
+	}
+
+	err = userStore.WriteEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY, []byte(publicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddTransfers(ctx, userStore, sessionId, []store.TransferRecord{
+		{Sender: "0x41c188d63Qa", Recipient: publicKey, Value: "20", TxHash: "0xq34wresfdb44", Symbol: "SRF"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := h.CheckTransactions(ctx, "check_transactions", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{FlagReset: []uint32{flag_no_transfers}}, res)
+	mockAccountService.AssertNotCalled(t, "FetchTransactions", publicKey)
+}
+
 func TestGetTransactionsList(t *testing.T) {
 	sessionId := "session123"
 	publicKey := "0X13242618721"
@@ -117,21 +158,8 @@ func TestGetTransactionsList(t *testing.T) {
 	data := store.ProcessTransfers(mockTXResponse)
 
 	// Store all transaction data
-	dataMap := map[storedb.DataTyp]string{
-		storedb.DATA_TX_SENDERS:    data.Senders,
-		storedb.DATA_TX_RECIPIENTS: data.Recipients,
-		storedb.DATA_TX_VALUES:     data.TransferValues,
-		storedb.DATA_TX_ADDRESSES:  data.Addresses,
-		storedb.DATA_TX_HASHES:     data.TxHashes,
-		storedb.DATA_TX_DATES:      data.Dates,
-		storedb.DATA_TX_SYMBOLS:    data.Symbols,
-		storedb.DATA_TX_DECIMALS:   data.Decimals,
-	}
-
-	for key, value := range dataMap {
-		if err := h.prefixDb.Put(ctx, []byte(storedb.ToBytes(key)), []byte(value)); err != nil {
-			t.Fatal(err)
-		}
+	if _, err := store.AddTransfers(ctx, userStore, sessionId, data); err != nil {
+		t.Fatal(err)
 	}
 
 	expectedTransactionList := []byte("1: Sent 10 SRF 2024-10-03\n2: Received 20 SRF 2024-10-03")
@@ -186,21 +214,8 @@ func TestViewTransactionStatement(t *testing.T) {
 	data := store.ProcessTransfers(mockTXResponse)
 
 	// Store all transaction data
-	dataMap := map[storedb.DataTyp]string{
-		storedb.DATA_TX_SENDERS:    data.Senders,
-		storedb.DATA_TX_RECIPIENTS: data.Recipients,
-		storedb.DATA_TX_VALUES:     data.TransferValues,
-		storedb.DATA_TX_ADDRESSES:  data.Addresses,
-		storedb.DATA_TX_HASHES:     data.TxHashes,
-		storedb.DATA_TX_DATES:      data.Dates,
-		storedb.DATA_TX_SYMBOLS:    data.Symbols,
-		storedb.DATA_TX_DECIMALS:   data.Decimals,
-	}
-
-	for key, value := range dataMap {
-		if err := h.prefixDb.Put(ctx, []byte(storedb.ToBytes(key)), []byte(value)); err != nil {
-			t.Fatal(err)
-		}
+	if _, err := store.AddTransfers(ctx, userStore, sessionId, data); err != nil {
+		t.Fatal(err)
 	}
 
 	tests := []struct {
@@ -214,7 +229,7 @@ func TestViewTransactionStatement(t *testing.T) {
 			input:         []byte("1"),
 			expectedError: nil,
 			expectedResult: resource.Result{
-				Content:   "Sent 10 SRF\nTo: 0x41c188d63Qa\nContract address: 0X1324262343rfdGW23\nTxhash: 0x123wefsf34rf\nDate: 2024-10-03 07:23:12 AM",
+				Content:   "Sent 10 SRF\nTo: 0x41c188d63Qa\nDate: 2024-10-03 07:23:12 AM",
 				FlagReset: []uint32{flag_incorrect_statement},
 			},
 		},
@@ -223,7 +238,7 @@ func TestViewTransactionStatement(t *testing.T) {
 			input:         []byte("2"),
 			expectedError: nil,
 			expectedResult: resource.Result{
-				Content:   "Received 20 SRF\nFrom: 0x41c188d63Qa\nContract address: 0X1324262343rfdGW23\nTxhash: 0xq34wresfdb44\nDate: 2024-10-03 07:23:12 AM",
+				Content:   "Received 20 SRF\nFrom: 0x41c188d63Qa\nDate: 2024-10-03 07:23:12 AM",
 				FlagReset: []uint32{flag_incorrect_statement},
 			},
 		},
@@ -263,3 +278,253 @@ func TestViewTransactionStatement(t *testing.T) {
 		})
 	}
 }
+
+func TestGetPendingTransactions(t *testing.T) {
+	sessionId := "session123"
+
+	ctx, userStore := InitializeTestStore(t)
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
+
+	h := &MenuHandlers{
+		userdataStore:        userStore,
+		ReplaceSeparatorFunc: mockReplaceSeparator,
+	}
+
+	res, err := h.GetPendingTransactions(ctx, "get_pending_transactions", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "You have no recent transactions", res.Content)
+
+	err = store.AddLedgerEntry(ctx, userStore, sessionId, store.LedgerEntry{
+		TrackingId:   "1234567890",
+		Type:         store.LedgerTransfer,
+		Amount:       "1.00",
+		Symbol:       "SRF",
+		Counterparty: "0711223344",
+		To:           "0x41c188d63Qa",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.AddLedgerEntry(ctx, userStore, sessionId, store.LedgerEntry{
+		TrackingId: "1234567891",
+		Type:       store.LedgerSwap,
+		Amount:     "5",
+		Symbol:     "SRF",
+		ToSymbol:   "USD",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.ConfirmLedgerEntry(ctx, userStore, sessionId, "", "0x41c188d63Qa", "", "", "0x123wefsf34rf")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err = h.GetPendingTransactions(ctx, "get_pending_transactions", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "1: 5 SRF > USD Pending\n2: 1.00 SRF > 0711223344 Confirmed", res.Content)
+}
+
+// seeds a history of seven transfers, alternately sent and received, of which
+// the last two are of another voucher.
+func seedStatement(t *testing.T, ctx context.Context, userStore *store.UserDataStore, sessionId string, publicKey string) {
+	dateBlock, err := time.Parse(time.RFC3339, "2024-10-03T07:23:12Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var transfers []store.TransferRecord
+	for i := 0; i < 7; i++ {
+		tr := store.TransferRecord{
+			Sender: publicKey, Recipient: "0x41c188d63Qa", Value: "10", ContractAddress: "0xaaa",
+			TxHash: fmt.Sprintf("0x%d", i), Date: dateBlock, Symbol: "SRF",
+		}
+		if i%2 == 1 {
+			tr.Sender, tr.Recipient = tr.Recipient, tr.Sender
+		}
+		if i >= 5 {
+			tr.ContractAddress = "0xbbb"
+		}
+		transfers = append(transfers, tr)
+	}
+	if _, err := store.AddTransfers(ctx, userStore, sessionId, transfers); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestStatementPages(t *testing.T) {
+	sessionId := "session123"
+	publicKey := "0X13242618721"
+
+	ctx, userStore := InitializeTestStore(t)
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
+
+	fm, err := NewFlagManager(flagsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := &MenuHandlers{
+		userdataStore:        userStore,
+		flagManager:          fm,
+		ReplaceSeparatorFunc: mockReplaceSeparator,
+	}
+
+	err = userStore.WriteEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY, []byte(publicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seedStatement(t, ctx, userStore, sessionId, publicKey)
+
+	res, err := h.GetTransactionsList(ctx, "", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "1: Sent 10 SRF 2024-10-03\n2: Received 10 SRF 2024-10-03\n3: Sent 10 SRF 2024-10-03\n11: Next", res.Content)
+
+	// there is no page before the first
+	_, err = h.StatementPrevious(ctx, "statement_prev", []byte(""))
+	assert.NoError(t, err)
+	res, err = h.GetTransactionsList(ctx, "", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "1: Sent 10 SRF 2024-10-03\n2: Received 10 SRF 2024-10-03\n3: Sent 10 SRF 2024-10-03\n11: Next", res.Content)
+
+	// nor after the last
+	for i := 0; i < 3; i++ {
+		_, err = h.StatementNext(ctx, "statement_next", []byte(""))
+		assert.NoError(t, err)
+	}
+	res, err = h.GetTransactionsList(ctx, "", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "1: Sent 10 SRF 2024-10-03\n22: Prev", res.Content)
+
+	_, err = h.StatementPrevious(ctx, "statement_prev", []byte(""))
+	assert.NoError(t, err)
+	res, err = h.GetTransactionsList(ctx, "", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "1: Received 10 SRF 2024-10-03\n2: Sent 10 SRF 2024-10-03\n3: Received 10 SRF 2024-10-03\n11: Next\n22: Prev", res.Content)
+
+	// the selection refers to the current page
+	res, err = h.ViewTransactionStatement(ctx, "view_statement", []byte("2"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Sent 10 SRF\nTo: 0x41c188d63Qa\nDate: 2024-10-03 07:23:12 AM", res.Content)
+
+	res, err = h.ViewTransactionDetails(ctx, "view_statement_details", []byte("1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Sent 10 SRF\nTo: 0x41c188d63Qa\nContract address: 0xaaa\nTxhash: 0x4\nDate: 2024-10-03 07:23:12 AM", res.Content)
+}
+
+func TestSetStatementFilter(t *testing.T) {
+	sessionId := "session123"
+	publicKey := "0X13242618721"
+
+	ctx, userStore := InitializeTestStore(t)
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
+
+	fm, err := NewFlagManager(flagsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := &MenuHandlers{
+		userdataStore:        userStore,
+		flagManager:          fm,
+		ReplaceSeparatorFunc: mockReplaceSeparator,
+	}
+
+	err = userStore.WriteEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY, []byte(publicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = userStore.WriteEntry(ctx, sessionId, storedb.DATA_ACTIVE_ADDRESS, []byte("0xBBB"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	seedStatement(t, ctx, userStore, sessionId, publicKey)
+
+	_, err = h.StatementNext(ctx, "statement_next", []byte(""))
+	assert.NoError(t, err)
+
+	// selecting a filter returns to the first page
+	_, err = h.SetStatementFilter(ctx, "set_statement_filter", []byte("3"))
+	assert.NoError(t, err)
+	res, err := h.GetTransactionsList(ctx, "", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "1: Received 10 SRF 2024-10-03\n2: Received 10 SRF 2024-10-03\n3: Received 10 SRF 2024-10-03", res.Content)
+
+	res, err = h.ViewTransactionStatement(ctx, "view_statement", []byte("2"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Received 10 SRF\nFrom: 0x41c188d63Qa\nDate: 2024-10-03 07:23:12 AM", res.Content)
+
+	_, err = h.SetStatementFilter(ctx, "set_statement_filter", []byte("4"))
+	assert.NoError(t, err)
+
+	// an invalid selection keeps the filter
+	_, err = h.SetStatementFilter(ctx, "set_statement_filter", []byte("7"))
+	assert.NoError(t, err)
+	res, err = h.GetTransactionsList(ctx, "", []byte(""))
+	assert.NoError(t, err)
+	assert.Equal(t, "1: Received 10 SRF 2024-10-03\n2: Sent 10 SRF 2024-10-03", res.Content)
+
+	_, err = h.SetStatementFilter(ctx, "set_statement_filter", []byte("2"))
+	assert.NoError(t, err)
+	res, err = h.ViewTransactionStatement(ctx, "view_statement", []byte("3"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Sent 10 SRF\nTo: 0x41c188d63Qa\nDate: 2024-10-03 07:23:12 AM", res.Content)
+}
+
+func TestViewTransactionStatementCounterparty(t *testing.T) {
+	sessionId := "session123"
+	publicKey := "0X13242618721"
+	counterparty := "0x41c188d63aab"
+
+	ctx, userStore := InitializeTestStore(t)
+	ctx = context.WithValue(ctx, "SessionId", sessionId)
+
+	fm, err := NewFlagManager(flagsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flag_incorrect_statement, _ := fm.GetFlag("flag_incorrect_statement")
+
+	h := &MenuHandlers{
+		userdataStore:        userStore,
+		flagManager:          fm,
+		ReplaceSeparatorFunc: mockReplaceSeparator,
+	}
+
+	err = userStore.WriteEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY, []byte(publicKey))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = userStore.WriteIndex(ctx, storedb.DATA_PUBLIC_KEY_REVERSE, "41c188d63aab", []byte("+254712345678"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = userStore.WriteEntry(ctx, "+254712345678", storedb.DATA_FIRST_NAME, []byte("Jane"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = userStore.WriteEntry(ctx, "+254712345678", storedb.DATA_FAMILY_NAME, []byte("Mwangi"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dateBlock, err := time.Parse(time.RFC3339, "2024-10-03T07:23:12Z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = store.AddTransfers(ctx, userStore, sessionId, []store.TransferRecord{
+		{Sender: counterparty, Recipient: publicKey, Value: "20", ContractAddress: "0xaaa", TxHash: "0x1", Date: dateBlock, Symbol: "SRF"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := h.ViewTransactionStatement(ctx, "view_statement", []byte("1"))
+	assert.NoError(t, err)
+	assert.Equal(t, resource.Result{
+		Content:   "Received 20 SRF\nFrom: Jane M. (0712***678)\nDate: 2024-10-03 07:23:12 AM",
+		FlagReset: []uint32{flag_incorrect_statement},
+	}, res)
+
+	res, err = h.ViewTransactionDetails(ctx, "view_statement_details", []byte("1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "Received 20 SRF\nFrom: 0x41c188d63aab\nContract address: 0xaaa\nTxhash: 0x1\nDate: 2024-10-03 07:23:12 AM", res.Content)
+}
@@ -52,18 +52,16 @@ func (h *MenuHandlers) ManageVouchers(ctx context.Context, sym string, input []b
 		// clear the current active voucher data if it exists
 		_, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_ACTIVE_SYM)
 		if err == nil {
-			firstVoucherMap := map[storedb.DataTyp]string{
-				storedb.DATA_ACTIVE_SYM:     "",
-				storedb.DATA_ACTIVE_BAL:     "",
-				storedb.DATA_ACTIVE_DECIMAL: "",
-				storedb.DATA_ACTIVE_ADDRESS: "",
+			firstVoucherMap := map[storedb.DataTyp][]byte{
+				storedb.DATA_ACTIVE_SYM:     []byte(""),
+				storedb.DATA_ACTIVE_BAL:     []byte(""),
+				storedb.DATA_ACTIVE_DECIMAL: []byte(""),
+				storedb.DATA_ACTIVE_ADDRESS: []byte(""),
 			}
 
-			for key, value := range firstVoucherMap {
-				if err := userStore.WriteEntry(ctx, sessionId, key, []byte(value)); err != nil {
-					logg.ErrorCtxf(ctx, "Failed to reset active voucher data", "key", key, "error", err)
-					return res, err
-				}
+			if err := store.WriteEntries(ctx, userStore, sessionId, firstVoucherMap); err != nil {
+				logg.ErrorCtxf(ctx, "Failed to reset active voucher data", "error", err)
+				return res, err
 			}
 
 			logg.InfoCtxf(ctx, "Default voucher reset")
@@ -101,19 +99,20 @@ func (h *MenuHandlers) ManageVouchers(ctx context.Context, sym string, input []b
 			// Scale down the balance
 			scaledBalance := store.ScaleDownBalance(defaultBal, defaultDec)
 
-			firstVoucherMap := map[storedb.DataTyp]string{
-				storedb.DATA_ACTIVE_SYM:     defaultSym,
-				storedb.DATA_ACTIVE_BAL:     scaledBalance,
-				storedb.DATA_ACTIVE_DECIMAL: defaultDec,
-				storedb.DATA_ACTIVE_ADDRESS: defaultAddr,
+			firstVoucherMap := map[storedb.DataTyp][]byte{
+				storedb.DATA_ACTIVE_SYM:     []byte(defaultSym),
+				storedb.DATA_ACTIVE_BAL:     []byte(scaledBalance),
+				storedb.DATA_ACTIVE_DECIMAL: []byte(defaultDec),
+				storedb.DATA_ACTIVE_ADDRESS: []byte(defaultAddr),
+			}
+
+			if err := store.WriteEntries(ctx, userStore, sessionId, firstVoucherMap); err != nil {
+				logg.ErrorCtxf(ctx, "Failed to write active voucher data", "error", err)
+				return res, err
 			}
 
 			for key, value := range firstVoucherMap {
-				if err := userStore.WriteEntry(ctx, sessionId, key, []byte(value)); err != nil {
-					logg.ErrorCtxf(ctx, "Failed to write active voucher data", "key", key, "error", err)
-					return res, err
-				}
-				err = logdb.WriteLogEntry(ctx, sessionId, key, []byte(value))
+				err = logdb.WriteLogEntry(ctx, sessionId, key, value)
 				if err != nil {
 					logg.DebugCtxf(ctx, "Failed to write voucher db log entry", "key", key, "value", value)
 				}
@@ -198,39 +197,30 @@ func (h *MenuHandlers) ManageVouchers(ctx context.Context, sym string, input []b
 	// Process & store
 	data := store.ProcessVouchers(orderedFilteredVouchers)
 
-	dataMap := map[storedb.DataTyp]string{
-		storedb.DATA_VOUCHER_SYMBOLS:   data.Symbols,
-		storedb.DATA_VOUCHER_BALANCES:  data.Balances,
-		storedb.DATA_VOUCHER_DECIMALS:  data.Decimals,
-		storedb.DATA_VOUCHER_ADDRESSES: data.Addresses,
-	}
-
-	// Write data entries
-	for key, value := range dataMap {
-		if err := userStore.WriteEntry(ctx, sessionId, key, []byte(value)); err != nil {
-			logg.ErrorCtxf(ctx, "Failed to write data entry for sessionId: %s", sessionId, "key", key, "error", err)
-		}
-	}
-
 	// Order all vouchers
 	orderedVouchers := orderVouchers(vouchersResp)
 
 	// Process ALL vouchers (stable first)
 	orderedVoucherData := store.ProcessVouchers(orderedVouchers)
 
-	orderedVoucherDataMap := map[storedb.DataTyp]string{
-		storedb.DATA_ORDERED_VOUCHER_SYMBOLS:   orderedVoucherData.Symbols,
-		storedb.DATA_ORDERED_VOUCHER_BALANCES:  orderedVoucherData.Balances,
-		storedb.DATA_ORDERED_VOUCHER_DECIMALS:  orderedVoucherData.Decimals,
-		storedb.DATA_ORDERED_VOUCHER_ADDRESSES: orderedVoucherData.Addresses,
+	voucherList, err := store.EncodeList(data)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "Failed to encode voucher list", "error", err)
+		return res, err
+	}
+	orderedVoucherList, err := store.EncodeList(orderedVoucherData)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "Failed to encode ordered voucher list", "error", err)
+		return res, err
 	}
 
-	// Write data entries
-	for key, value := range orderedVoucherDataMap {
-		if err := userStore.WriteEntry(ctx, sessionId, key, []byte(value)); err != nil {
-			logg.ErrorCtxf(ctx, "Failed to write data entry for sessionId: %s", sessionId, "key", key, "error", err)
-			continue
-		}
+	// Write both lists together, so that they never go out of sync
+	voucherLists := map[storedb.DataTyp][]byte{
+		storedb.DATA_VOUCHER_LIST:         voucherList,
+		storedb.DATA_ORDERED_VOUCHER_LIST: orderedVoucherList,
+	}
+	if err := store.WriteEntries(ctx, userStore, sessionId, voucherLists); err != nil {
+		logg.ErrorCtxf(ctx, "Failed to write voucher lists for sessionId: %s", sessionId, "error", err)
 	}
 
 	return res, nil
@@ -258,14 +248,14 @@ func (h *MenuHandlers) GetVoucherList(ctx context.Context, sym string, input []b
 	}
 
 	// Read vouchers from the store
-	voucherData, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_VOUCHER_SYMBOLS)
-	logg.InfoCtxf(ctx, "reading voucherData in GetVoucherList", "sessionId", sessionId, "key", storedb.DATA_VOUCHER_SYMBOLS, "voucherData", voucherData)
+	vouchers, err := store.ReadVoucherList(ctx, userStore, sessionId, storedb.DATA_VOUCHER_LIST)
+	logg.InfoCtxf(ctx, "reading vouchers in GetVoucherList", "sessionId", sessionId, "key", storedb.DATA_VOUCHER_LIST, "vouchers", vouchers)
 	if err != nil {
-		logg.ErrorCtxf(ctx, "failed to read voucherData entires with", "key", storedb.DATA_VOUCHER_SYMBOLS, "error", err)
+		logg.ErrorCtxf(ctx, "failed to read voucher list with", "key", storedb.DATA_VOUCHER_LIST, "error", err)
 		return res, err
 	}
 
-	if len(voucherData) == 0 {
+	if len(vouchers) == 0 {
 		if sym == "get_paydebt_voucher_list" {
 			res.Content = l.Get("You need another voucher to proceed. Only found %s.", string(activeSym))
 		} else {
@@ -275,14 +265,7 @@ func (h *MenuHandlers) GetVoucherList(ctx context.Context, sym string, input []b
 		return res, nil
 	}
 
-	voucherBalances, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_VOUCHER_BALANCES)
-	logg.InfoCtxf(ctx, "reading voucherBalances in GetVoucherList", "sessionId", sessionId, "key", storedb.DATA_VOUCHER_BALANCES, "voucherBalances", voucherBalances)
-	if err != nil {
-		logg.ErrorCtxf(ctx, "failed to read voucherData entires with", "key", storedb.DATA_VOUCHER_BALANCES, "error", err)
-		return res, err
-	}
-
-	formattedVoucherList := store.FormatVoucherList(ctx, string(voucherData), string(voucherBalances))
+	formattedVoucherList := store.FormatVoucherList(ctx, vouchers)
 	finalOutput := strings.Join(formattedVoucherList, "\n")
 
 	logg.InfoCtxf(ctx, "final output for GetVoucherList", "sessionId", sessionId, "finalOutput", finalOutput)
@@ -125,9 +125,9 @@ func TestManageVouchers(t *testing.T) {
 
 			if tt.storedActiveVoucher != "" {
 				// Validate stored voucher symbols
-				voucherData, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_VOUCHER_SYMBOLS)
+				vouchers, err := store.ReadVoucherList(ctx, userStore, sessionId, storedb.DATA_VOUCHER_LIST)
 				assert.NoError(t, err)
-				assert.Equal(t, tt.expectedVoucherSymbols, voucherData)
+				assert.Equal(t, tt.expectedVoucherSymbols, []byte(store.FormatVoucherSymbols(vouchers)))
 
 				// Validate stored active contract address
 				updatedAddress, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_ACTIVE_ADDRESS)
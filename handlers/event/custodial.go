@@ -4,20 +4,18 @@ import (
 	"context"
 	"fmt"
 
+	"git.defalsify.org/vise.git/db"
+	"git.grassecon.net/grassrootseconomics/common/identity"
 	apievent "git.grassecon.net/grassrootseconomics/sarafu-api/event"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/internal/sms"
 	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
-)
-
-const (
-	// TODO: consolidate with loaded flags
-	accountCreatedFlag = 9
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
 )
 
 // handle custodial registration.
 //
-// TODO: implement account created in userstore instead, so that
-// the need for persister and state use here is eliminated (it
-// introduces concurrency risks)
+// The activation of the account is recorded in the userdata store, and the
+// user is welcomed by SMS the first time the event is seen for the account.
 func (eh *EventsUpdater) handleCustodialRegistration(ctx context.Context, ev any) error {
 	o, ok := ev.(*apievent.EventCustodialRegistration)
 	if !ok {
@@ -36,5 +34,74 @@ func (eu *EventsUpdater) HandleCustodialRegistration(ctx context.Context, ev *ap
 		return err
 	}
 	logg.DebugCtxf(ctx, "received custodial registration event", "identity", identity)
+	err = eu.updateAliasIndex(ctx, identity, userStore)
+	if err != nil {
+		return err
+	}
+	err = eu.updateActiveVoucher(ctx, identity, userStore)
+	if err != nil {
+		return err
+	}
+	activated, err := store.MarkAccountActive(ctx, userStore, identity.SessionId)
+	if err != nil {
+		return err
+	}
+	if !activated {
+		logg.DebugCtxf(ctx, "account activation already recorded", "identity", identity)
+		return nil
+	}
+	smsService := sms.NewSmsService(eu.api, *userStore)
+	err = smsService.SendWelcomeSMS(ctx, identity.SessionId, identity.ChecksumAddress)
+	if err != nil {
+		// the activation is recorded regardless, the message is a courtesy
+		logg.WarnCtxf(ctx, "failed to send welcome sms", "identity", identity, "error", err)
+	}
 	return nil
 }
+
+// set the active voucher of a newly registered account, unless already set.
+//
+// Nothing is set if the account does not yet hold any vouchers; the first
+// transfer or mint to the account will then set it.
+func (eu *EventsUpdater) updateActiveVoucher(ctx context.Context, identity identity.Identity, userStore *store.UserDataStore) error {
+	_, err := userStore.ReadEntry(ctx, identity.SessionId, storedb.DATA_ACTIVE_SYM)
+	if err == nil {
+		return nil
+	}
+	if !db.IsNotFound(err) {
+		return err
+	}
+	err = eu.updateTokenList(ctx, identity, userStore)
+	if err != nil {
+		return err
+	}
+	vouchers, err := store.ReadVoucherList(ctx, userStore, identity.SessionId, storedb.DATA_VOUCHER_LIST)
+	if err != nil {
+		return err
+	}
+	if len(vouchers) == 0 {
+		return nil
+	}
+	return eu.updateDefaultToken(ctx, identity, userStore, vouchers[0].Symbol)
+}
+
+// make sure the alias of the account, if any, resolves to it in the alias index.
+func (eu *EventsUpdater) updateAliasIndex(ctx context.Context, identity identity.Identity, userStore *store.UserDataStore) error {
+	alias, err := userStore.ReadEntry(ctx, identity.SessionId, storedb.DATA_ACCOUNT_ALIAS)
+	if err != nil {
+		if db.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if len(alias) == 0 {
+		return nil
+	}
+	address, sessionId, err := store.ResolveAlias(ctx, userStore, string(alias))
+	if err == nil && sessionId == identity.SessionId && address == identity.ChecksumAddress {
+		return nil
+	} else if err != nil && !db.IsNotFound(err) {
+		return err
+	}
+	return store.WriteAliasIndex(ctx, userStore, identity.SessionId, "", string(alias), identity.ChecksumAddress)
+}
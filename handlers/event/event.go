@@ -2,7 +2,6 @@ package event
 
 import (
 	"context"
-	"fmt"
 
 	"git.defalsify.org/vise.git/logging"
 	"git.defalsify.org/vise.git/persist"
@@ -16,18 +15,19 @@ var (
 	logg = logging.NewVanilla().WithDomain("sarafu-vise.handlers.event")
 )
 
+// implemented by account services caching results of remote calls.
+type accountInvalidator interface {
+	InvalidateAccount(ctx context.Context, address string)
+}
+
 type EventsUpdater struct {
-	api        remote.AccountService
-	formatFunc func(string, int, any) string
-	store      storage.StorageService
+	api   remote.AccountService
+	store storage.StorageService
 }
 
 func NewEventsUpdater(api remote.AccountService, store storage.StorageService) *EventsUpdater {
 	return &EventsUpdater{
-		api: api,
-		formatFunc: func(tag string, i int, o any) string {
-			return fmt.Sprintf("%d %v", i, o)
-		},
+		api:   api,
 		store: store,
 	}
 }
@@ -40,6 +40,15 @@ func (eu *EventsUpdater) ToEventsHandler() *apievent.EventsHandler {
 	return eh
 }
 
+// drop cached remote results for the account, so that the following refresh is current.
+func (eu *EventsUpdater) invalidateAccount(ctx context.Context, address string) {
+	inv, ok := eu.api.(accountInvalidator)
+	if !ok {
+		return
+	}
+	inv.InvalidateAccount(ctx, address)
+}
+
 func (eu *EventsUpdater) handleNoop(ctx context.Context, ev any) error {
 	logg.WarnCtxf(ctx, "noop event handler")
 	return nil
@@ -3,7 +3,9 @@ package event
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"git.defalsify.org/vise.git/db"
 	"git.grassecon.net/grassrootseconomics/common/identity"
@@ -19,6 +21,11 @@ func (eu *EventsUpdater) updateToken(ctx context.Context, identity identity.Iden
 		return err
 	}
 
+	err = eu.updateAliasIndex(ctx, identity, userStore)
+	if err != nil {
+		return err
+	}
+
 	userStore.Db.SetSession(identity.SessionId)
 	activeSym, err := userStore.ReadEntry(ctx, identity.SessionId, storedb.DATA_ACTIVE_SYM)
 	if err == nil {
@@ -34,17 +41,7 @@ func (eu *EventsUpdater) updateToken(ctx context.Context, identity identity.Iden
 		}
 	}
 
-	err = eu.updateDefaultToken(ctx, identity, userStore, string(activeSym))
-	if err != nil {
-		return err
-	}
-
-	err = eu.updateTokenTransferList(ctx, identity, userStore)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return eu.updateDefaultToken(ctx, identity, userStore, string(activeSym))
 }
 
 // set default token to given symbol.
@@ -72,12 +69,19 @@ func (eu *EventsUpdater) HandleTokenTransfer(ctx context.Context, ev *apievent.E
 	if err != nil {
 		return err
 	}
+	eu.invalidateAccount(ctx, ev.From)
+	eu.invalidateAccount(ctx, ev.To)
+	value := fmt.Sprintf("%d", ev.Value)
+	// the event carries no block time, so both sides record the time it was received
+	date := time.Now()
 	identity, err := store.IdentityFromAddress(ctx, userStore, ev.From)
 	if err != nil {
 		if !db.IsNotFound(err) {
 			return err
 		}
 	} else {
+		eu.updateLedger(ctx, identity, userStore, ev.From, ev.To, ev.VoucherAddress, value, ev.TxHash)
+		eu.updateTransferHistory(ctx, identity, userStore, ev.From, ev.To, ev.VoucherAddress, value, ev.TxHash, date)
 		err = eu.updateToken(ctx, identity, userStore, ev.VoucherAddress)
 		if err != nil {
 			return err
@@ -91,6 +95,8 @@ func (eu *EventsUpdater) HandleTokenTransfer(ctx context.Context, ev *apievent.E
 				return err
 			}
 		} else {
+			eu.updateLedger(ctx, identity, userStore, ev.From, ev.To, ev.VoucherAddress, value, ev.TxHash)
+			eu.updateTransferHistory(ctx, identity, userStore, ev.From, ev.To, ev.VoucherAddress, value, ev.TxHash, date)
 			err = eu.updateToken(ctx, identity, userStore, ev.VoucherAddress)
 			if err != nil {
 				return err
@@ -114,12 +120,16 @@ func (eu *EventsUpdater) HandleTokenMint(ctx context.Context, ev *apievent.Event
 	if err != nil {
 		return err
 	}
+	eu.invalidateAccount(ctx, ev.To)
 	identity, err := store.IdentityFromAddress(ctx, userStore, ev.To)
 	if err != nil {
 		if !db.IsNotFound(err) {
 			return err
 		}
 	} else {
+		value := fmt.Sprintf("%d", ev.Value)
+		eu.updateLedger(ctx, identity, userStore, "", ev.To, ev.VoucherAddress, value, ev.TxHash)
+		eu.updateTransferHistory(ctx, identity, userStore, store.MintSender, ev.To, ev.VoucherAddress, value, ev.TxHash, time.Now())
 		err = eu.updateToken(ctx, identity, userStore, ev.VoucherAddress)
 		if err != nil {
 			return err
@@ -128,6 +138,21 @@ func (eu *EventsUpdater) HandleTokenMint(ctx context.Context, ev *apievent.Event
 	return nil
 }
 
+// mark the submitted transaction confirmed by the token transfer, if any, in the ledger of the account.
+//
+// The ledger is informational only, so errors are logged and do not fail the event.
+func (eu *EventsUpdater) updateLedger(ctx context.Context, identity identity.Identity, userStore *store.UserDataStore, from string, to string, tokenAddress string, value string, txHash string) {
+	userStore.Db.SetSession(identity.SessionId)
+	ok, err := store.ConfirmLedgerEntry(ctx, userStore, identity.SessionId, from, to, tokenAddress, value, txHash)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to update transaction ledger", "session", identity.SessionId, "tx", txHash, "error", err)
+		return
+	}
+	if ok {
+		logg.DebugCtxf(ctx, "confirmed submitted transaction", "session", identity.SessionId, "tx", txHash)
+	}
+}
+
 // use api to resolve address to token symbol.
 func (eu *EventsUpdater) toSym(ctx context.Context, address string) ([]byte, error) {
 	voucherData, err := eu.api.VoucherData(ctx, address)
@@ -143,56 +168,47 @@ func (eu *EventsUpdater) updateTokenList(ctx context.Context, identity identity.
 	if err != nil {
 		return err
 	}
-	metadata := store.ProcessVouchers(holdings)
-	_ = metadata
-
-	// TODO: make sure subprefixdb is thread safe when using gdbm
-	// TODO: why is address session here unless explicitly set
-	pfxDb := toPrefixDb(userStore, identity.SessionId)
-
-	typ := storedb.ToBytes(storedb.DATA_VOUCHER_SYMBOLS)
-	err = pfxDb.Put(ctx, typ, []byte(metadata.Symbols))
-	if err != nil {
-		return err
-	}
-
-	typ = storedb.ToBytes(storedb.DATA_VOUCHER_BALANCES)
-	err = pfxDb.Put(ctx, typ, []byte(metadata.Balances))
-	if err != nil {
-		return err
-	}
-
-	typ = storedb.ToBytes(storedb.DATA_VOUCHER_DECIMALS)
-	err = pfxDb.Put(ctx, typ, []byte(metadata.Decimals))
+	vouchers := store.ProcessVouchers(holdings)
+	v, err := store.EncodeList(vouchers)
 	if err != nil {
 		return err
 	}
 
-	typ = storedb.ToBytes(storedb.DATA_VOUCHER_ADDRESSES)
-	err = pfxDb.Put(ctx, typ, []byte(metadata.Addresses))
-	if err != nil {
-		return err
-	}
+	// TODO: make sure subprefixdb is thread safe when using gdbm
+	// TODO: why is address session here unless explicitly set
+	pfxDb := toPrefixDb(userStore, identity.SessionId)
 
-	return nil
+	typ := storedb.ToBytes(storedb.DATA_VOUCHER_LIST)
+	return pfxDb.Put(ctx, typ, v)
 }
 
-// refresh and store transaction history.
-func (eu *EventsUpdater) updateTokenTransferList(ctx context.Context, identity identity.Identity, userStore *store.UserDataStore) error {
-	var r []string
-
-	txs, err := eu.api.FetchTransactions(ctx, identity.ChecksumAddress)
+// add the transfer to the local transfer history of the account.
+//
+// Transfers are deduplicated by the history, so a redelivered event is harmless. The history
+// is informational only, and backfilled from the data service when viewed, so errors are
+// logged and do not fail the event.
+func (eu *EventsUpdater) updateTransferHistory(ctx context.Context, identity identity.Identity, userStore *store.UserDataStore, from string, to string, tokenAddress string, value string, txHash string, date time.Time) {
+	voucherData, err := eu.api.VoucherData(ctx, tokenAddress)
 	if err != nil {
-		return err
+		logg.ErrorCtxf(ctx, "failed to resolve voucher of transfer", "session", identity.SessionId, "tx", txHash, "voucher", tokenAddress, "error", err)
+		return
+	}
+	decimals := strconv.Itoa(voucherData.TokenDecimals)
+	tx := store.TransferRecord{
+		Sender:          from,
+		Recipient:       to,
+		Value:           store.ScaleDownBalance(value, decimals),
+		ContractAddress: tokenAddress,
+		TxHash:          txHash,
+		Date:            date,
+		Symbol:          voucherData.TokenSymbol,
+		Decimals:        decimals,
 	}
-
-	for i, tx := range txs {
-		r = append(r, eu.formatFunc(apievent.EventTokenTransferTag, i, tx))
+	userStore.Db.SetSession(identity.SessionId)
+	_, err = store.AddTransfers(ctx, userStore, identity.SessionId, []store.TransferRecord{tx})
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to update transfer history", "session", identity.SessionId, "tx", txHash, "error", err)
 	}
-
-	s := strings.Join(r, "\n")
-
-	return userStore.WriteEntry(ctx, identity.SessionId, storedb.DATA_TRANSACTIONS, []byte(s))
 }
 
 func toPrefixDb(userStore *store.UserDataStore, sessionId string) storedb.PrefixDb {
@@ -80,10 +80,18 @@ func (ls *LocalHandlerService) GetHandler(accountService remote.AccountService)
 	ls.DbRs.AddLocalFunc("check_identifier", appHandlers.CheckIdentifier)
 	ls.DbRs.AddLocalFunc("check_account_status", appHandlers.CheckAccountStatus)
 	ls.DbRs.AddLocalFunc("authorize_account", appHandlers.Authorize)
+	ls.DbRs.AddLocalFunc("check_authorization", appHandlers.CheckAuthorization)
 	ls.DbRs.AddLocalFunc("quit", appHandlers.Quit)
 	ls.DbRs.AddLocalFunc("calc_credit_debt", appHandlers.CalculateCreditAndDebt)
 	ls.DbRs.AddLocalFunc("check_balance", appHandlers.CheckBalance)
 	ls.DbRs.AddLocalFunc("validate_recipient", appHandlers.ValidateRecipient)
+	ls.DbRs.AddLocalFunc("get_send_contacts", appHandlers.GetSendContacts)
+	ls.DbRs.AddLocalFunc("get_contacts", appHandlers.GetContacts)
+	ls.DbRs.AddLocalFunc("select_contact", appHandlers.SelectContact)
+	ls.DbRs.AddLocalFunc("save_contact_recipient", appHandlers.SaveContactRecipient)
+	ls.DbRs.AddLocalFunc("add_contact", appHandlers.AddContact)
+	ls.DbRs.AddLocalFunc("rename_contact", appHandlers.RenameContact)
+	ls.DbRs.AddLocalFunc("delete_contact", appHandlers.DeleteContact)
 	ls.DbRs.AddLocalFunc("transaction_reset", appHandlers.TransactionReset)
 	ls.DbRs.AddLocalFunc("invite_valid_recipient", appHandlers.InviteValidRecipient)
 	ls.DbRs.AddLocalFunc("send_max_amount", appHandlers.MaxAmount)
@@ -124,10 +132,20 @@ func (ls *LocalHandlerService) GetHandler(accountService remote.AccountService)
 	ls.DbRs.AddLocalFunc("retrieve_blocked_number", appHandlers.RetrieveBlockedNumber)
 	ls.DbRs.AddLocalFunc("reset_unregistered_number", appHandlers.ResetUnregisteredNumber)
 	ls.DbRs.AddLocalFunc("reset_others_pin", appHandlers.ResetOthersPin)
+	ls.DbRs.AddLocalFunc("check_pin_reset_role", appHandlers.CheckPinResetRole)
+	ls.DbRs.AddLocalFunc("check_support_role", appHandlers.CheckSupportRole)
+	ls.DbRs.AddLocalFunc("check_pool_manager_role", appHandlers.CheckPoolManagerRole)
+	ls.DbRs.AddLocalFunc("show_account_pin_status", appHandlers.ShowAccountPinStatus)
+	ls.DbRs.AddLocalFunc("request_data_export", appHandlers.RequestDataExport)
+	ls.DbRs.AddLocalFunc("request_data_erasure", appHandlers.RequestDataErasure)
 	ls.DbRs.AddLocalFunc("get_current_profile_info", appHandlers.GetCurrentProfileInfo)
 	ls.DbRs.AddLocalFunc("check_transactions", appHandlers.CheckTransactions)
 	ls.DbRs.AddLocalFunc("get_transactions", appHandlers.GetTransactionsList)
+	ls.DbRs.AddLocalFunc("statement_next", appHandlers.StatementNext)
+	ls.DbRs.AddLocalFunc("statement_prev", appHandlers.StatementPrevious)
+	ls.DbRs.AddLocalFunc("set_statement_filter", appHandlers.SetStatementFilter)
 	ls.DbRs.AddLocalFunc("view_statement", appHandlers.ViewTransactionStatement)
+	ls.DbRs.AddLocalFunc("view_statement_details", appHandlers.ViewTransactionDetails)
 	ls.DbRs.AddLocalFunc("update_all_profile_items", appHandlers.UpdateAllProfileItems)
 	ls.DbRs.AddLocalFunc("set_back", appHandlers.SetBack)
 	ls.DbRs.AddLocalFunc("show_blocked_account", appHandlers.ShowBlockedAccount)
@@ -136,6 +154,43 @@ func (ls *LocalHandlerService) GetHandler(accountService remote.AccountService)
 	ls.DbRs.AddLocalFunc("request_custom_alias", appHandlers.RequestCustomAlias)
 	ls.DbRs.AddLocalFunc("check_account_created", appHandlers.CheckAccountCreated)
 	ls.DbRs.AddLocalFunc("reset_api_call_failure", appHandlers.ResetApiCallFailure)
+	ls.DbRs.AddLocalFunc("check_service_status", appHandlers.CheckServiceStatus)
+	ls.DbRs.AddLocalFunc("get_pending_transactions", appHandlers.GetPendingTransactions)
+	ls.DbRs.AddLocalFunc("send_mini_statement", appHandlers.SendMiniStatement)
+	ls.DbRs.AddLocalFunc("get_schedules", appHandlers.GetSchedules)
+	ls.DbRs.AddLocalFunc("select_schedule", appHandlers.SelectSchedule)
+	ls.DbRs.AddLocalFunc("schedule_max_amount", appHandlers.ScheduleMaxAmount)
+	ls.DbRs.AddLocalFunc("set_schedule_interval", appHandlers.SetScheduleInterval)
+	ls.DbRs.AddLocalFunc("schedule_preview", appHandlers.SchedulePreview)
+	ls.DbRs.AddLocalFunc("create_schedule", appHandlers.CreateSchedule)
+	ls.DbRs.AddLocalFunc("cancel_schedule", appHandlers.CancelSchedule)
+	ls.DbRs.AddLocalFunc("get_payment_requests", appHandlers.GetPaymentRequests)
+	ls.DbRs.AddLocalFunc("select_payment_request", appHandlers.SelectPaymentRequest)
+	ls.DbRs.AddLocalFunc("get_request_contacts", appHandlers.GetRequestContacts)
+	ls.DbRs.AddLocalFunc("set_payment_request_recipient", appHandlers.SetPaymentRequestRecipient)
+	ls.DbRs.AddLocalFunc("create_payment_request", appHandlers.CreatePaymentRequest)
+	ls.DbRs.AddLocalFunc("prepare_payment_request", appHandlers.PreparePaymentRequest)
+	ls.DbRs.AddLocalFunc("pay_payment_request", appHandlers.PayPaymentRequest)
+	ls.DbRs.AddLocalFunc("decline_payment_request", appHandlers.DeclinePaymentRequest)
+	ls.DbRs.AddLocalFunc("get_guardians", appHandlers.GetGuardians)
+	ls.DbRs.AddLocalFunc("select_guardian", appHandlers.SelectGuardian)
+	ls.DbRs.AddLocalFunc("remove_guardian", appHandlers.RemoveGuardian)
+	ls.DbRs.AddLocalFunc("validate_guardian", appHandlers.ValidateGuardian)
+	ls.DbRs.AddLocalFunc("add_guardian", appHandlers.AddGuardian)
+	ls.DbRs.AddLocalFunc("request_recovery", appHandlers.RequestRecovery)
+	ls.DbRs.AddLocalFunc("get_recovery_requests", appHandlers.GetRecoveryRequests)
+	ls.DbRs.AddLocalFunc("select_recovery_request", appHandlers.SelectRecoveryRequest)
+	ls.DbRs.AddLocalFunc("approve_recovery", appHandlers.ApproveRecovery)
+	ls.DbRs.AddLocalFunc("multi_send_max_amount", appHandlers.MultiSendMaxAmount)
+	ls.DbRs.AddLocalFunc("validate_multi_send_amount", appHandlers.ValidateMultiSendAmount)
+	ls.DbRs.AddLocalFunc("multi_send_preview", appHandlers.MultiSendPreview)
+	ls.DbRs.AddLocalFunc("initiate_multi_send", appHandlers.InitiateMultiSend)
+	ls.DbRs.AddLocalFunc("get_contact_groups", appHandlers.GetContactGroups)
+	ls.DbRs.AddLocalFunc("select_contact_group", appHandlers.SelectContactGroup)
+	ls.DbRs.AddLocalFunc("save_contact_group_name", appHandlers.SaveContactGroupName)
+	ls.DbRs.AddLocalFunc("add_contact_group", appHandlers.AddContactGroup)
+	ls.DbRs.AddLocalFunc("delete_contact_group", appHandlers.DeleteContactGroup)
+	ls.DbRs.AddLocalFunc("spend_limit_info", appHandlers.SpendLimitInfo)
 	ls.DbRs.AddLocalFunc("swap_to_list", appHandlers.LoadSwapToList)
 	ls.DbRs.AddLocalFunc("swap_max_limit", appHandlers.SwapMaxLimit)
 	ls.DbRs.AddLocalFunc("swap_preview", appHandlers.SwapPreview)
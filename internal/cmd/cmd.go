@@ -2,11 +2,22 @@ package cmd
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"git.defalsify.org/vise.git/db"
 	"git.defalsify.org/vise.git/logging"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/config"
 	"git.grassecon.net/grassrootseconomics/sarafu-vise/handlers/application"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
 	"git.grassecon.net/grassrootseconomics/visedriver/storage"
+	dataserviceapi "github.com/grassrootseconomics/ussd-data-service/pkg/api"
 )
 
 var (
@@ -15,10 +26,15 @@ var (
 
 type Cmd struct {
 	sessionId  string
+	actor      string
 	conn       storage.ConnData
 	flagParser *application.FlagManager
 	cmd        int
 	enable     bool
+	role       string
+	action     string
+	out        io.Writer
+	logDb      *store.LogDb
 	exec       func(ctx context.Context, ss storage.StorageService) error
 }
 
@@ -26,56 +42,539 @@ func NewCmd(sessionId string, flagParser *application.FlagManager) *Cmd {
 	return &Cmd{
 		sessionId:  sessionId,
 		flagParser: flagParser,
+		out:        os.Stdout,
 	}
 }
 
+// SetActor sets the name of the operator running the command, recorded in the audit log.
+func (c *Cmd) SetActor(actor string) {
+	c.actor = actor
+}
+
+// SetLogDb sets the log database, whose entries of the account are included in data exports and erasures.
+func (c *Cmd) SetLogDb(logDb db.Db) {
+	c.logDb = &store.LogDb{
+		Db: logDb,
+	}
+}
+
+// Exec runs the parsed command, and records its outcome in the audit log if it changes the state of the account.
 func (c *Cmd) Exec(ctx context.Context, ss storage.StorageService) error {
-	return c.exec(ctx, ss)
+	err := c.exec(ctx, ss)
+	if c.action == "" {
+		return err
+	}
+	auditErr := c.audit(ctx, ss, err)
+	if err != nil {
+		return err
+	}
+	return auditErr
+}
+
+// userStore returns the userdata store of the storage service.
+func (c *Cmd) userStore(ctx context.Context, ss storage.StorageService) (*store.UserDataStore, error) {
+	userdataDb, err := ss.GetUserdataDb(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &store.UserDataStore{
+		Db: userdataDb,
+	}, nil
+}
+
+// audit records the outcome of the command in the audit log.
+func (c *Cmd) audit(ctx context.Context, ss storage.StorageService, actionErr error) error {
+	userStore, err := c.userStore(ctx, ss)
+	if err != nil {
+		return err
+	}
+	record := store.AuditRecord{
+		Actor:   c.actor,
+		Target:  c.sessionId,
+		Action:  c.action,
+		Outcome: store.AuditSuccess,
+		Detail:  c.role,
+	}
+	if actionErr != nil {
+		record.Outcome = store.AuditFailure
+		if record.Detail != "" {
+			record.Detail += ": "
+		}
+		record.Detail += actionErr.Error()
+	}
+	record, err = store.AppendAudit(ctx, userStore, record)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "failed to write audit entry", "action", c.action, "error", err)
+		return err
+	}
+	logg.DebugCtxf(ctx, "audit entry written", "id", record.Id)
+	return nil
+}
+
+func (c *Cmd) execGrantRole(ctx context.Context, ss storage.StorageService) error {
+	userStore, err := c.userStore(ctx, ss)
+	if err != nil {
+		return err
+	}
+	ok, err := store.GrantRole(ctx, userStore, c.sessionId, c.role)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		logg.InfoCtxf(ctx, "role already granted", "role", c.role)
+	}
+	return nil
+}
+
+func (c *Cmd) execRevokeRole(ctx context.Context, ss storage.StorageService) error {
+	userStore, err := c.userStore(ctx, ss)
+	if err != nil {
+		return err
+	}
+	ok, err := store.RevokeRole(ctx, userStore, c.sessionId, c.role)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		logg.InfoCtxf(ctx, "role not granted", "role", c.role)
+	}
+	return nil
+}
+
+func (c *Cmd) execListRoles(ctx context.Context, ss storage.StorageService) error {
+	userStore, err := c.userStore(ctx, ss)
+	if err != nil {
+		return err
+	}
+	roles, err := store.ReadRoles(ctx, userStore, c.sessionId)
+	if err != nil {
+		return err
+	}
+	for _, v := range roles {
+		fmt.Fprintln(c.out, v)
+	}
+	return nil
 }
 
-func (c *Cmd) execAdmin(ctx context.Context, ss storage.StorageService) error {
+// execMigrateRole grants the PIN reset role to the account if its menu state has the admin privilege flag
+// set by the legacy admin command, and resets the flag.
+//
+// The grant is only recorded in the audit log if the account had the flag.
+func (c *Cmd) execMigrateRole(ctx context.Context, ss storage.StorageService) error {
 	pe, err := ss.GetPersister(ctx)
 	if err != nil {
 		return err
 	}
 	err = pe.Load(c.sessionId)
+	if err != nil {
+		// the account has not accessed the menu yet
+		logg.InfoCtxf(ctx, "no menu state to migrate", "error", err)
+		return nil
+	}
+	flag, err := c.flagParser.GetFlag("flag_admin_privilege")
 	if err != nil {
 		return err
 	}
-	defer func() {
-		err := pe.Save(c.sessionId)
-		if err != nil {
-			logg.ErrorCtxf(ctx, "failed persister save: %v", err)
+	st := pe.GetState()
+	if !st.MatchFlag(flag, true) {
+		logg.InfoCtxf(ctx, "no admin privilege to migrate")
+		return nil
+	}
+
+	c.action = store.AuditRoleGrant
+	c.role = store.RolePinReset
+	err = c.execGrantRole(ctx, ss)
+	if err != nil {
+		return err
+	}
+	st.ResetFlag(flag)
+	return pe.Save(c.sessionId)
+}
+
+// show writes the given fields of the account, one per line.
+func (c *Cmd) show(fields [][2]string) {
+	for _, v := range fields {
+		fmt.Fprintf(c.out, "%s\t%s\n", v[0], v[1])
+	}
+}
+
+// entry is a named entry of the account shown by a command.
+type entry struct {
+	name string
+	typ  storedb.DataTyp
+}
+
+// showEntries writes the given entries of the account, one per line.
+func (c *Cmd) showEntries(ctx context.Context, ss storage.StorageService, entries []entry) error {
+	userStore, err := c.userStore(ctx, ss)
+	if err != nil {
+		return err
+	}
+	var fields [][2]string
+	for _, e := range entries {
+		v, err := userStore.ReadEntry(ctx, c.sessionId, e.typ)
+		if err != nil && !db.IsNotFound(err) {
+			return err
 		}
-	}()
+		fields = append(fields, [2]string{e.name, string(v)})
+	}
+	c.show(fields)
+	return nil
+}
 
-	st := pe.GetState()
-	flag, err := c.flagParser.GetFlag("flag_admin_privilege")
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func (c *Cmd) execShowAccount(ctx context.Context, ss storage.StorageService) error {
+	userStore, err := c.userStore(ctx, ss)
+	if err != nil {
+		return err
+	}
+	summary, err := store.ReadAccountSummary(ctx, userStore, c.sessionId)
+	if err != nil {
+		return err
+	}
+	c.show([][2]string{
+		{"session_id", summary.SessionId},
+		{"public_key", summary.PublicKey},
+		{"alias", summary.Alias},
+		{"first_name", summary.FirstName},
+		{"family_name", summary.FamilyName},
+		{"language", summary.Language},
+		{"active_voucher", summary.ActiveSym},
+		{"active_balance", summary.ActiveBal},
+		{"active_pool", summary.ActivePool},
+		{"incorrect_pin_attempts", strconv.FormatUint(summary.IncorrectPinAttempts, 10)},
+		{"pin_lockouts", strconv.FormatUint(summary.PinLockouts, 10)},
+		{"lockout_expiry", formatTime(summary.LockoutExpiry)},
+		{"self_pin_reset", strconv.FormatBool(summary.SelfPinReset)},
+		{"activated", formatTime(summary.Activated)},
+		{"roles", strings.Join(summary.Roles, ",")},
+	})
+	return nil
+}
+
+func (c *Cmd) execUnblock(ctx context.Context, ss storage.StorageService) error {
+	userStore, err := c.userStore(ctx, ss)
+	if err != nil {
+		return err
+	}
+	return store.UnblockAccount(ctx, userStore, c.sessionId)
+}
+
+func (c *Cmd) execAnonymize(ctx context.Context, ss storage.StorageService) error {
+	userStore, err := c.userStore(ctx, ss)
+	if err != nil {
+		return err
+	}
+	return store.AnonymizeAccount(ctx, userStore, c.sessionId)
+}
+
+func (c *Cmd) execDelete(ctx context.Context, ss storage.StorageService) error {
+	userStore, err := c.userStore(ctx, ss)
+	if err != nil {
+		return err
+	}
+	n, err := store.DeleteAccount(ctx, userStore, c.sessionId)
+	if err != nil {
+		return err
+	}
+	logg.InfoCtxf(ctx, "account deleted", "entries", n)
+	return nil
+}
+
+func (c *Cmd) execPinReset(ctx context.Context, ss storage.StorageService) error {
+	userStore, err := c.userStore(ctx, ss)
+	if err != nil {
+		return err
+	}
+	return store.ForcePinReset(ctx, userStore, c.sessionId)
+}
+
+func (c *Cmd) execShowVoucher(ctx context.Context, ss storage.StorageService) error {
+	return c.showEntries(ctx, ss, []entry{
+		{"symbol", storedb.DATA_ACTIVE_SYM},
+		{"balance", storedb.DATA_ACTIVE_BAL},
+		{"decimals", storedb.DATA_ACTIVE_DECIMAL},
+		{"address", storedb.DATA_ACTIVE_ADDRESS},
+	})
+}
+
+func (c *Cmd) execClearVoucher(ctx context.Context, ss storage.StorageService) error {
+	userStore, err := c.userStore(ctx, ss)
+	if err != nil {
+		return err
+	}
+	return store.ClearActiveVoucher(ctx, userStore, c.sessionId)
+}
+
+func (c *Cmd) execShowPool(ctx context.Context, ss storage.StorageService) error {
+	return c.showEntries(ctx, ss, []entry{
+		{"name", storedb.DATA_ACTIVE_POOL_NAME},
+		{"symbol", storedb.DATA_ACTIVE_POOL_SYM},
+		{"address", storedb.DATA_ACTIVE_POOL_ADDRESS},
+	})
+}
+
+// execClearPool sets the active pool of the account back to the default pool.
+func (c *Cmd) execClearPool(ctx context.Context, ss storage.StorageService) error {
+	userStore, err := c.userStore(ctx, ss)
+	if err != nil {
+		return err
+	}
+	return store.UpdatePoolData(ctx, userStore, c.sessionId, &dataserviceapi.PoolDetails{
+		PoolName:            config.DefaultPoolName(),
+		PoolSymbol:          config.DefaultPoolSymbol(),
+		PoolContractAdrress: config.DefaultPoolAddress(),
+	})
+}
+
+// execResetLanguage unsets the language of the account, and resets the language flag of its menu state
+// so that it is asked to select a language the next time it accesses the menu.
+func (c *Cmd) execResetLanguage(ctx context.Context, ss storage.StorageService) error {
+	userStore, err := c.userStore(ctx, ss)
+	if err != nil {
+		return err
+	}
+	err = store.ResetLanguage(ctx, userStore, c.sessionId)
+	if err != nil {
+		return err
+	}
+
+	pe, err := ss.GetPersister(ctx)
+	if err != nil {
+		return err
+	}
+	err = pe.Load(c.sessionId)
+	if err != nil {
+		// the account has not accessed the menu yet
+		logg.InfoCtxf(ctx, "no menu state to reset", "error", err)
+		return nil
+	}
+	flag, err := c.flagParser.GetFlag("flag_language_set")
+	if err != nil {
+		return err
+	}
+	pe.GetState().ResetFlag(flag)
+	return pe.Save(c.sessionId)
+}
+
+// processedPrivacyRequest removes the pending request of the given kind of the account, if any.
+func (c *Cmd) processedPrivacyRequest(ctx context.Context, userStore *store.UserDataStore, kind string) error {
+	ok, err := store.RemovePrivacyRequest(ctx, userStore, c.sessionId, kind)
 	if err != nil {
 		return err
 	}
-	if c.enable {
-		logg.InfoCtxf(ctx, "setting admin flag", "flag", flag)
-		st.SetFlag(flag)
-	} else {
-		st.ResetFlag(flag)
+	if ok {
+		logg.InfoCtxf(ctx, "privacy request processed", "kind", kind)
 	}
 	return nil
 }
 
+func (c *Cmd) execExport(ctx context.Context, ss storage.StorageService) error {
+	userStore, err := c.userStore(ctx, ss)
+	if err != nil {
+		return err
+	}
+	if c.logDb == nil {
+		logg.WarnCtxf(ctx, "no log db, log entries are not exported")
+	}
+	export, err := store.ExportAccount(ctx, userStore, c.logDb, c.sessionId)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(c.out)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(export)
+	if err != nil {
+		return err
+	}
+	return c.processedPrivacyRequest(ctx, userStore, store.PrivacyExport)
+}
+
+func (c *Cmd) execErase(ctx context.Context, ss storage.StorageService) error {
+	userStore, err := c.userStore(ctx, ss)
+	if err != nil {
+		return err
+	}
+	if c.logDb == nil {
+		logg.WarnCtxf(ctx, "no log db, log entries are not erased")
+	}
+	err = store.EraseAccount(ctx, userStore, c.logDb, c.sessionId)
+	if err != nil {
+		return err
+	}
+	return c.processedPrivacyRequest(ctx, userStore, store.PrivacyErasure)
+}
+
+// execListPrivacyRequests lists the pending privacy requests of all accounts.
+func (c *Cmd) execListPrivacyRequests(ctx context.Context, ss storage.StorageService) error {
+	userStore, err := c.userStore(ctx, ss)
+	if err != nil {
+		return err
+	}
+	requests, err := store.ReadPrivacyRequests(ctx, userStore)
+	if err != nil {
+		return err
+	}
+	for _, v := range requests {
+		fmt.Fprintf(c.out, "%s\t%s\t%s\n", time.Unix(v.Time, 0).Format(time.RFC3339), v.SessionId, v.Kind)
+	}
+	return nil
+}
+
+// parseCmdAdmin handles the legacy admin command, which grants or revokes the PIN reset role.
 func (c *Cmd) parseCmdAdmin(cmd string, param string, more []string) (bool, error) {
 	if cmd == "admin" {
+		c.role = store.RolePinReset
 		if param == "1" {
 			c.enable = true
-		} else if param != "0" {
+			c.action = store.AuditRoleGrant
+			c.exec = c.execGrantRole
+		} else if param == "0" {
+			c.action = store.AuditRoleRevoke
+			c.exec = c.execRevokeRole
+		} else {
 			return false, fmt.Errorf("invalid parameter: %v", param)
 		}
-		c.exec = c.execAdmin
 		return true, nil
 	}
 	return false, nil
 }
 
+func (c *Cmd) parseCmdRole(cmd string, param string, more []string) (bool, error) {
+	if cmd != "role" {
+		return false, nil
+	}
+	switch param {
+	case "list":
+		c.exec = c.execListRoles
+		return true, nil
+	case "migrate":
+		c.exec = c.execMigrateRole
+		return true, nil
+	}
+	if len(more) != 1 {
+		return false, fmt.Errorf("missing role, one of: %v", store.Roles())
+	}
+	c.role = more[0]
+	if !store.ValidRole(c.role) {
+		return false, fmt.Errorf("invalid role: %s, must be one of: %v", c.role, store.Roles())
+	}
+	switch param {
+	case "grant":
+		c.action = store.AuditRoleGrant
+		c.exec = c.execGrantRole
+	case "revoke":
+		c.action = store.AuditRoleRevoke
+		c.exec = c.execRevokeRole
+	default:
+		return false, fmt.Errorf("invalid parameter: %v", param)
+	}
+	return true, nil
+}
+
+func (c *Cmd) parseCmdAccount(cmd string, param string, more []string) (bool, error) {
+	if cmd != "account" {
+		return false, nil
+	}
+	switch param {
+	case "show":
+		c.exec = c.execShowAccount
+	case "unblock":
+		c.action = store.AuditUnblock
+		c.exec = c.execUnblock
+	case "anonymize":
+		c.action = store.AuditAnonymize
+		c.exec = c.execAnonymize
+	case "delete":
+		c.action = store.AuditDelete
+		c.exec = c.execDelete
+	default:
+		return false, fmt.Errorf("invalid parameter: %v", param)
+	}
+	return true, nil
+}
+
+func (c *Cmd) parseCmdPin(cmd string, param string, more []string) (bool, error) {
+	if cmd != "pin" {
+		return false, nil
+	}
+	if param != "reset" {
+		return false, fmt.Errorf("invalid parameter: %v", param)
+	}
+	c.action = store.AuditResetPin
+	c.exec = c.execPinReset
+	return true, nil
+}
+
+func (c *Cmd) parseCmdVoucher(cmd string, param string, more []string) (bool, error) {
+	if cmd != "voucher" {
+		return false, nil
+	}
+	switch param {
+	case "show":
+		c.exec = c.execShowVoucher
+	case "clear":
+		c.action = store.AuditClearVoucher
+		c.exec = c.execClearVoucher
+	default:
+		return false, fmt.Errorf("invalid parameter: %v", param)
+	}
+	return true, nil
+}
+
+func (c *Cmd) parseCmdPool(cmd string, param string, more []string) (bool, error) {
+	if cmd != "pool" {
+		return false, nil
+	}
+	switch param {
+	case "show":
+		c.exec = c.execShowPool
+	case "clear":
+		c.action = store.AuditClearPool
+		c.exec = c.execClearPool
+	default:
+		return false, fmt.Errorf("invalid parameter: %v", param)
+	}
+	return true, nil
+}
+
+func (c *Cmd) parseCmdLanguage(cmd string, param string, more []string) (bool, error) {
+	if cmd != "language" {
+		return false, nil
+	}
+	if param != "reset" {
+		return false, fmt.Errorf("invalid parameter: %v", param)
+	}
+	c.action = store.AuditResetLanguage
+	c.exec = c.execResetLanguage
+	return true, nil
+}
+
+func (c *Cmd) parseCmdData(cmd string, param string, more []string) (bool, error) {
+	if cmd != "data" {
+		return false, nil
+	}
+	switch param {
+	case "export":
+		c.action = store.AuditExport
+		c.exec = c.execExport
+	case "erase":
+		c.action = store.AuditErase
+		c.exec = c.execErase
+	case "requests":
+		c.exec = c.execListPrivacyRequests
+	default:
+		return false, fmt.Errorf("invalid parameter: %v", param)
+	}
+	return true, nil
+}
+
 func (c *Cmd) Parse(args []string) error {
 	if len(args) < 2 {
 		return fmt.Errorf("Wrong number of arguments: %v", args)
@@ -92,5 +591,23 @@ func (c *Cmd) Parse(args []string) error {
 		return nil
 	}
 
+	for _, parse := range []func(string, string, []string) (bool, error){
+		c.parseCmdRole,
+		c.parseCmdAccount,
+		c.parseCmdPin,
+		c.parseCmdVoucher,
+		c.parseCmdPool,
+		c.parseCmdLanguage,
+		c.parseCmdData,
+	} {
+		r, err = parse(cmd, param, args)
+		if err != nil {
+			return err
+		}
+		if r {
+			return nil
+		}
+	}
+
 	return fmt.Errorf("unknown subcommand: %s", cmd)
 }
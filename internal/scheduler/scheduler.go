@@ -0,0 +1,274 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"git.defalsify.org/vise.git/logging"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/models"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/remote"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/remote/http"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/internal/sms"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/internal/spend"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/services/resilient"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"gopkg.in/leonelquinteros/gotext.v1"
+)
+
+var (
+	logg = logging.NewVanilla().WithDomain("scheduler").WithContextKey("SessionId")
+
+	// errAccountBlocked is the failure of a run of a scheduled transfer of an account that cannot authorize transactions.
+	errAccountBlocked = errors.New("account blocked")
+	// errSpendLimit is the failure of a run of a scheduled transfer that is over the spending limits of the account.
+	errSpendLimit = errors.New("over spending limit")
+)
+
+// Scheduler submits the scheduled transfers of all accounts as they become due.
+//
+// Transfers are submitted through the TokenTransfer call of the account service,
+// like transfers initiated from the menu. The outcome is recorded with the scheduled
+// transfer and in the transaction ledger of the account, and the user is notified by SMS.
+//
+// Runs are claimed, and their spending and ledger entries recorded, under the same locks
+// held in process memory as the updates made by the menu handlers. The Scheduler must thus
+// run inside the server process serving the menu, and only one server process may run it
+// for a userdata store.
+type Scheduler struct {
+	userdataStore  *store.UserDataStore
+	accountService remote.AccountService
+	smsService     sms.SmsService
+	localeDir      string
+	now            func() time.Time
+}
+
+// NewScheduler creates a new Scheduler using the given userdata store and account service.
+//
+// Notifications are translated with the gettext translations in localeDir.
+func NewScheduler(userdataStore *store.UserDataStore, accountService remote.AccountService, localeDir string) *Scheduler {
+	return &Scheduler{
+		userdataStore:  userdataStore,
+		accountService: accountService,
+		smsService:     sms.NewSmsService(accountService, *userdataStore),
+		localeDir:      localeDir,
+		now:            time.Now,
+	}
+}
+
+// WithClock sets the function used to get the current time.
+func (s *Scheduler) WithClock(now func() time.Time) *Scheduler {
+	s.now = now
+	return s
+}
+
+// Run submits the due scheduled transfers at the given interval, until the context is done.
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		_, err := s.RunDue(ctx)
+		if err != nil {
+			logg.ErrorCtxf(ctx, "scheduler run failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Start runs the scheduler in the background at the given interval, until the context is done.
+//
+// An interval of 0 leaves the scheduler stopped.
+func (s *Scheduler) Start(ctx context.Context, interval time.Duration) {
+	if interval == 0 {
+		logg.InfoCtxf(ctx, "scheduler disabled")
+		return
+	}
+	logg.InfoCtxf(ctx, "start scheduler", "interval", interval)
+	go func() {
+		err := s.Run(ctx, interval)
+		if err != nil && err != context.Canceled {
+			logg.ErrorCtxf(ctx, "scheduler exited with error", "error", err)
+		}
+	}()
+}
+
+// RunDue submits the scheduled transfers of all accounts that are due.
+//
+// Failures of single accounts are logged and do not stop the run. The number of
+// transfers submitted successfully is returned.
+func (s *Scheduler) RunDue(ctx context.Context) (int, error) {
+	sessionIds, err := store.ReadScheduledSessions(ctx, s.userdataStore)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read scheduled sessions: %v", err)
+	}
+	var c int
+	for _, sessionId := range sessionIds {
+		sessionCtx := context.WithValue(ctx, "SessionId", sessionId)
+		n, err := s.runAccount(sessionCtx, sessionId)
+		if err != nil {
+			logg.ErrorCtxf(sessionCtx, "failed to run scheduled transfers", "error", err)
+		}
+		c += n
+	}
+	return c, nil
+}
+
+func (s *Scheduler) runAccount(ctx context.Context, sessionId string) (int, error) {
+	now := s.now()
+	schedules, err := store.DueSchedules(ctx, s.userdataStore, sessionId, now)
+	if err != nil {
+		return 0, err
+	}
+	if len(schedules) == 0 {
+		// the account is only unregistered if it has no scheduled transfers left
+		return 0, store.RemoveScheduledSession(ctx, s.userdataStore, sessionId)
+	}
+
+	var c int
+	for _, schedule := range schedules {
+		ok, err := s.runSchedule(ctx, sessionId, schedule, now)
+		if err != nil {
+			return c, err
+		}
+		if ok {
+			c++
+		}
+	}
+	return c, nil
+}
+
+// runSchedule submits a single due transfer. It returns true if the transfer was submitted.
+//
+// The transfer is not submitted, and the run fails, if the account is blocked or the transfer
+// is over the spending limits of the account at the time of the run.
+func (s *Scheduler) runSchedule(ctx context.Context, sessionId string, schedule store.ScheduleRecord, now time.Time) (bool, error) {
+	userStore := s.userdataStore
+	_, ok, err := store.ClaimScheduleRun(ctx, userStore, sessionId, schedule.Id, schedule.Next, now)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		logg.DebugCtxf(ctx, "scheduled transfer run already claimed", "id", schedule.Id)
+		return false, nil
+	}
+
+	err = s.checkSchedule(ctx, sessionId, now)
+	if err != nil {
+		logg.WarnCtxf(ctx, "scheduled transfer not submitted", "id", schedule.Id, "error", err)
+		return false, s.finishSchedule(ctx, sessionId, schedule.Id, now, "", err)
+	}
+
+	var trackingId string
+	publicKey, err := userStore.ReadEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "scheduled transfer failed", "id", schedule.Id, "error", err)
+		return false, s.finishSchedule(ctx, sessionId, schedule.Id, now, "", err)
+	}
+	amount, err := strconv.ParseFloat(schedule.Amount, 64)
+	if err != nil {
+		logg.ErrorCtxf(ctx, "scheduled transfer failed", "id", schedule.Id, "error", err)
+		return false, s.finishSchedule(ctx, sessionId, schedule.Id, now, "", err)
+	}
+
+	entry := store.LedgerEntry{
+		Type:         store.LedgerTransfer,
+		Amount:       schedule.Amount,
+		Symbol:       schedule.Symbol,
+		Counterparty: schedule.RecipientInput,
+		From:         string(publicKey),
+		To:           schedule.Recipient,
+		TokenAddress: schedule.TokenAddress,
+		Value:        schedule.Value,
+		Submitted:    now.Unix(),
+	}
+	// scheduled transfers are subject to the spending limits of the account, and count towards them
+	var r *models.TokenTransferResponse
+	ok, err = spend.Spend(ctx, userStore, sessionId, schedule.TokenAddress, schedule.Symbol, amount, func() error {
+		var err error
+		r, err = s.accountService.TokenTransfer(ctx, schedule.Value, string(publicKey), schedule.Recipient, schedule.TokenAddress)
+		return err
+	})
+	if !ok {
+		if err == nil {
+			err = errSpendLimit
+		}
+		logg.WarnCtxf(ctx, "scheduled transfer not submitted", "id", schedule.Id, "error", err)
+		return false, s.finishSchedule(ctx, sessionId, schedule.Id, now, "", err)
+	}
+	if err != nil {
+		logg.ErrorCtxf(ctx, "scheduled transfer failed", "id", schedule.Id, "error", err)
+		// after any other failure the transfer may still have been submitted
+		if rejected(err) {
+			entry.Status = store.LedgerFailed
+		}
+	} else {
+		trackingId = r.TrackingId
+		entry.TrackingId = trackingId
+		logg.InfoCtxf(ctx, "scheduled transfer submitted", "id", schedule.Id, "trackingId", trackingId)
+	}
+	lerr := store.AddLedgerEntry(ctx, userStore, sessionId, entry)
+	if lerr != nil {
+		logg.ErrorCtxf(ctx, "failed to record submitted transaction", "trackingId", trackingId, "error", lerr)
+	}
+	return err == nil, s.finishSchedule(ctx, sessionId, schedule.Id, now, trackingId, err)
+}
+
+// checkSchedule returns an error if the account cannot authorize transactions, so that its scheduled
+// transfers may not be submitted either.
+func (s *Scheduler) checkSchedule(ctx context.Context, sessionId string, now time.Time) error {
+	blocked, err := store.AccountBlocked(ctx, s.userdataStore, sessionId, now)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return errAccountBlocked
+	}
+	return nil
+}
+
+// finishSchedule records the outcome of the run of the scheduled transfer, and notifies the user.
+func (s *Scheduler) finishSchedule(ctx context.Context, sessionId string, id string, now time.Time, trackingId string, runErr error) error {
+	schedule, err := store.RecordScheduleRun(ctx, s.userdataStore, sessionId, id, now, trackingId, runErr)
+	if err != nil {
+		return err
+	}
+	s.notify(ctx, sessionId, schedule)
+	return nil
+}
+
+// rejected returns true if the error of the transfer shows that it was not carried out.
+func rejected(err error) bool {
+	var apiErr *http.APIError
+	return errors.As(err, &apiErr) || errors.Is(err, resilient.ErrServiceDegraded)
+}
+
+// notify informs the user of the outcome of the last run of the scheduled transfer.
+//
+// The outcome is recorded regardless, so failure to send the message is only logged.
+func (s *Scheduler) notify(ctx context.Context, sessionId string, schedule store.ScheduleRecord) {
+	code, _ := s.userdataStore.ReadEntry(ctx, sessionId, storedb.DATA_SELECTED_LANGUAGE_CODE)
+	l := gotext.NewLocale(s.localeDir, string(code))
+	l.AddDomain("default")
+
+	var msg string
+	switch {
+	case schedule.LastStatus == store.ScheduleSubmitted:
+		msg = l.Get("Your scheduled payment of %s %s to %s has been sent. Reference: %s", schedule.Amount, schedule.Symbol, schedule.RecipientInput, schedule.LastTrackingId)
+	case !schedule.Active:
+		msg = l.Get("Your scheduled payment of %s %s to %s failed and has been paused", schedule.Amount, schedule.Symbol, schedule.RecipientInput)
+	default:
+		msg = l.Get("Your scheduled payment of %s %s to %s failed. It will be tried again later", schedule.Amount, schedule.Symbol, schedule.RecipientInput)
+	}
+
+	err := s.smsService.SendNotificationSMS(ctx, sessionId, msg)
+	if err != nil {
+		logg.WarnCtxf(ctx, "failed to send scheduled transfer notification", "id", schedule.Id, "error", err)
+	}
+}
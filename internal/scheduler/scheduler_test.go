@@ -0,0 +1,163 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	memdb "git.defalsify.org/vise.git/db/mem"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/models"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/testutil/mocks"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	sessionId = "+254712345678"
+	publicKey = "0X13242618721"
+)
+
+// messageAccountService is an account service able to send SMS of arbitrary content.
+type messageAccountService struct {
+	*mocks.MockAccountService
+	messages []string
+}
+
+func (s *messageAccountService) SendSMS(ctx context.Context, phoneNumber string, message string) error {
+	s.messages = append(s.messages, message)
+	return nil
+}
+
+func initializeTestStore(t *testing.T) (context.Context, *store.UserDataStore) {
+	ctx := context.Background()
+	db := memdb.NewMemDb()
+	err := db.Connect(ctx, "")
+	require.NoError(t, err, "Failed to connect to memDb")
+	t.Cleanup(func() {
+		db.Close(ctx)
+	})
+	return ctx, &store.UserDataStore{Db: db}
+}
+
+func TestRunDue(t *testing.T) {
+	ctx, userStore := initializeTestStore(t)
+	start := time.Date(2024, 10, 3, 7, 0, 0, 0, time.UTC)
+
+	err := userStore.WriteEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY, []byte(publicKey))
+	require.NoError(t, err)
+	id, err := store.AddSchedule(ctx, userStore, sessionId, store.ScheduleRecord{
+		RecipientInput: "0700000001",
+		Recipient:      "0x41c188d63Qa",
+		Amount:         "10",
+		Symbol:         "SRF",
+		TokenAddress:   "0xd4c288865Ce",
+		Value:          "10000000",
+		Interval:       store.ScheduleDaily,
+		Next:           start.Unix(),
+	})
+	require.NoError(t, err)
+
+	svc := &messageAccountService{MockAccountService: new(mocks.MockAccountService)}
+	svc.On("TokenTransfer").Return(&models.TokenTransferResponse{TrackingId: "1234567890"}, nil).Once()
+	svc.On("TokenTransfer").Return(nil, fmt.Errorf("connection reset")).Once()
+
+	now := start.Add(-time.Minute)
+	sc := NewScheduler(userStore, svc, "../../services/registration/locale").WithClock(func() time.Time {
+		return now
+	})
+
+	n, err := sc.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	now = start
+	n, err = sc.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, []string{"Your scheduled payment of 10 SRF to 0700000001 has been sent. Reference: 1234567890"}, svc.messages)
+
+	// the run is not repeated until the next interval
+	n, err = sc.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	entries, err := store.ReadLedger(ctx, userStore, sessionId)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, "1234567890", entries[0].TrackingId)
+
+	now = start.AddDate(0, 0, 1)
+	n, err = sc.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, "Your scheduled payment of 10 SRF to 0700000001 failed. It will be tried again later", svc.messages[1])
+
+	schedules, err := store.ReadSchedules(ctx, userStore, sessionId)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(schedules))
+	assert.Equal(t, id, schedules[0].Id)
+	assert.Equal(t, store.ScheduleFailed, schedules[0].LastStatus)
+	assert.Equal(t, 1, schedules[0].Failures)
+	svc.AssertNumberOfCalls(t, "TokenTransfer", 2)
+}
+
+func TestRunDueBlockedAccount(t *testing.T) {
+	ctx, userStore := initializeTestStore(t)
+	start := time.Date(2024, 10, 3, 7, 0, 0, 0, time.UTC)
+
+	err := userStore.WriteEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY, []byte(publicKey))
+	require.NoError(t, err)
+	err = userStore.WriteEntry(ctx, sessionId, storedb.DATA_SELF_PIN_RESET, []byte("1"))
+	require.NoError(t, err)
+	_, err = store.AddSchedule(ctx, userStore, sessionId, store.ScheduleRecord{
+		RecipientInput: "0700000001",
+		Recipient:      "0x41c188d63Qa",
+		Amount:         "10",
+		Symbol:         "SRF",
+		TokenAddress:   "0xd4c288865Ce",
+		Value:          "10000000",
+		Interval:       store.ScheduleDaily,
+		Next:           start.Unix(),
+	})
+	require.NoError(t, err)
+
+	svc := &messageAccountService{MockAccountService: new(mocks.MockAccountService)}
+	sc := NewScheduler(userStore, svc, "../../services/registration/locale").WithClock(func() time.Time {
+		return start
+	})
+	n, err := sc.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, []string{"Your scheduled payment of 10 SRF to 0700000001 failed. It will be tried again later"}, svc.messages)
+
+	schedules, err := store.ReadSchedules(ctx, userStore, sessionId)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(schedules))
+	assert.Equal(t, 1, schedules[0].Failures)
+	// the retry does not move the next regular run
+	assert.Equal(t, start.Add(time.Hour).Unix(), schedules[0].Next)
+	assert.Equal(t, start.AddDate(0, 0, 1).Unix(), schedules[0].Scheduled)
+	svc.AssertNumberOfCalls(t, "TokenTransfer", 0)
+}
+
+func TestRunDueUnregistersIdleAccounts(t *testing.T) {
+	ctx, userStore := initializeTestStore(t)
+
+	id, err := store.AddSchedule(ctx, userStore, sessionId, store.ScheduleRecord{Interval: store.ScheduleWeekly})
+	require.NoError(t, err)
+	err = store.CancelSchedule(ctx, userStore, sessionId, id)
+	require.NoError(t, err)
+
+	svc := &messageAccountService{MockAccountService: new(mocks.MockAccountService)}
+	sc := NewScheduler(userStore, svc, "../../services/registration/locale")
+	n, err := sc.RunDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	sessionIds, err := store.ReadScheduledSessions(ctx, userStore)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(sessionIds))
+}
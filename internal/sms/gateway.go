@@ -0,0 +1,73 @@
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const (
+	// time allowed for the gateway to accept a single message.
+	gatewayTimeout = 10 * time.Second
+)
+
+// gatewayMessage is the request body of a message posted to the SMS gateway.
+type gatewayMessage struct {
+	PhoneNumber string `json:"phone_number"`
+	Message     string `json:"message"`
+}
+
+// GatewaySender sends messages of arbitrary content through an HTTP SMS gateway.
+//
+// Each message is posted as JSON to the endpoint of the gateway, with the bearer token if set.
+// Any response status other than 2xx is an error.
+type GatewaySender struct {
+	endpoint string
+	token    string
+	client   *http.Client
+}
+
+// NewGatewaySender creates a new GatewaySender posting messages to the given endpoint.
+func NewGatewaySender(endpoint string, token string) *GatewaySender {
+	return &GatewaySender{
+		endpoint: endpoint,
+		token:    token,
+		client: &http.Client{
+			Timeout: gatewayTimeout,
+		},
+	}
+}
+
+// SendSMS implements MessageSender.
+func (g *GatewaySender) SendSMS(ctx context.Context, phoneNumber string, message string) error {
+	body, err := json.Marshal(gatewayMessage{
+		PhoneNumber: phoneNumber,
+		Message:     message,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if g.token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.token)
+	}
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// drain the body so that the connection can be reused
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("sms gateway returned status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGatewaySender(t *testing.T) {
+	var got gatewayMessage
+	var auth string
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		err := json.NewDecoder(r.Body).Decode(&got)
+		require.NoError(t, err)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	sender := NewGatewaySender(srv.URL, "secret")
+	err := sender.SendSMS(context.Background(), "+254712345678", "hello")
+	require.NoError(t, err)
+	assert.Equal(t, gatewayMessage{PhoneNumber: "+254712345678", Message: "hello"}, got)
+	assert.Equal(t, "Bearer secret", auth)
+
+	status = http.StatusBadGateway
+	err = sender.SendSMS(context.Background(), "+254712345678", "hello")
+	assert.Error(t, err)
+}
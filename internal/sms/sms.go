@@ -2,11 +2,15 @@ package sms
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
+	"unicode/utf8"
 
 	"git.defalsify.org/vise.git/logging"
 	"git.grassecon.net/grassrootseconomics/common/phone"
 	"git.grassecon.net/grassrootseconomics/sarafu-api/remote"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/config"
 	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
 	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
 )
@@ -15,9 +19,44 @@ var (
 	logg = logging.NewVanilla().WithDomain("smsservice")
 )
 
+var (
+	// ErrRateLimited is returned when the account has requested too many messages recently.
+	ErrRateLimited = errors.New("sms rate limit reached")
+	// ErrUnsupported is returned when no SMS gateway is configured, and the account service cannot send messages of arbitrary content.
+	ErrUnsupported = errors.New("sms sending not supported by account service")
+)
+
+const (
+	// maximum length of a single SMS message.
+	smsSize = 160
+	// maximum number of statements sent to an account within statementWindow.
+	statementLimit  = 3
+	statementWindow = 24 * time.Hour
+)
+
+// MessageSender is implemented by account services able to send an SMS of arbitrary content.
+type MessageSender interface {
+	SendSMS(ctx context.Context, phoneNumber string, message string) error
+}
+
 type SmsService struct {
 	Accountservice remote.AccountService
 	Userdatastore  store.UserDataStore
+	// Sender sends messages of arbitrary content. If nil, they are sent by the account service, if able to.
+	Sender MessageSender
+}
+
+// NewSmsService creates a new SmsService, sending messages of arbitrary content through the SMS gateway if one is configured.
+func NewSmsService(accountService remote.AccountService, userdataStore store.UserDataStore) SmsService {
+	smsService := SmsService{
+		Accountservice: accountService,
+		Userdatastore:  userdataStore,
+	}
+	endpoint := config.SmsGatewayURL()
+	if endpoint != "" {
+		smsService.Sender = NewGatewaySender(endpoint, config.SmsGatewayToken())
+	}
+	return smsService
 }
 
 // SendUpsellSMS will send an invitation SMS to an unregistered phone number
@@ -94,3 +133,155 @@ func (smsService *SmsService) SendAddressSMS(ctx context.Context) error {
 	}
 	return nil
 }
+
+// SendWelcomeSMS will send an SMS to the phonenumber of the given session once the associated account has been activated.
+//
+// The message sent is the address SMS, informing the user of the address of their new account.
+func (smsService *SmsService) SendWelcomeSMS(ctx context.Context, sessionId string, publicKey string) error {
+	originPhone, err := phone.FormatPhoneNumber(sessionId)
+	if err != nil {
+		return fmt.Errorf("failed to format phone number: %w", err)
+	}
+	if !phone.IsValidPhoneNumber(originPhone) {
+		return fmt.Errorf("invalid phone number %v", originPhone)
+	}
+	err = smsService.Accountservice.SendAddressSMS(ctx, publicKey, originPhone)
+	if err != nil {
+		return fmt.Errorf("failed to send welcome sms: %v", err)
+	}
+	return nil
+}
+
+// SendStatementSMS sends the lines of a statement to the phonenumber of the given session, split into as many SMS as needed.
+//
+// At most statementLimit statements are sent to an account within statementWindow, after which ErrRateLimited is returned.
+func (smsService *SmsService) SendStatementSMS(ctx context.Context, sessionId string, lines []string) error {
+	originPhone, err := phone.FormatPhoneNumber(sessionId)
+	if err != nil {
+		return fmt.Errorf("failed to format phone number: %w", err)
+	}
+	if !phone.IsValidPhoneNumber(originPhone) {
+		return fmt.Errorf("invalid phone number %v", originPhone)
+	}
+
+	sender, ok := smsService.sender()
+	if !ok {
+		return ErrUnsupported
+	}
+
+	// a statement that could not be sent does not count towards the limit
+	ok, err = store.AllowEvent(ctx, &smsService.Userdatastore, sessionId, storedb.DATA_STATEMENT_SMS_LOG, statementLimit, statementWindow, func() error {
+		for _, msg := range SplitMessage(lines) {
+			err := sender.SendSMS(ctx, originPhone, msg)
+			if err != nil {
+				return fmt.Errorf("failed to send statement sms: %v", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		logg.InfoCtxf(ctx, "statement sms rate limit reached", "sessionId", sessionId)
+		return ErrRateLimited
+	}
+	return nil
+}
+
+// SendNotificationSMS sends a message to the phonenumber of the given session, split into as many SMS as needed.
+func (smsService *SmsService) SendNotificationSMS(ctx context.Context, sessionId string, message string) error {
+	originPhone, err := phone.FormatPhoneNumber(sessionId)
+	if err != nil {
+		return fmt.Errorf("failed to format phone number: %w", err)
+	}
+	if !phone.IsValidPhoneNumber(originPhone) {
+		return fmt.Errorf("invalid phone number %v", originPhone)
+	}
+
+	sender, ok := smsService.sender()
+	if !ok {
+		return ErrUnsupported
+	}
+
+	for _, msg := range SplitMessage([]string{message}) {
+		err = sender.SendSMS(ctx, originPhone, msg)
+		if err != nil {
+			return fmt.Errorf("failed to send notification sms: %v", err)
+		}
+	}
+	return nil
+}
+
+// sender returns the sender of messages of arbitrary content, falling back to the account service.
+func (smsService *SmsService) sender() (MessageSender, bool) {
+	if smsService.Sender != nil {
+		return smsService.Sender, true
+	}
+	return messageSender(smsService.Accountservice)
+}
+
+// messageSender returns the account service, or a service it wraps, that can send messages of arbitrary content.
+func messageSender(svc remote.AccountService) (MessageSender, bool) {
+	for svc != nil {
+		sender, ok := svc.(MessageSender)
+		if ok {
+			return sender, true
+		}
+		wrapper, ok := svc.(interface{ Unwrap() remote.AccountService })
+		if !ok {
+			break
+		}
+		svc = wrapper.Unwrap()
+	}
+	return nil, false
+}
+
+// SplitMessage packs the lines of text into as few SMS messages as possible.
+//
+// Lines are only broken up if longer than a single message. If more than one
+// message is needed, each message starts with its number and the total, e.g. "1/3".
+func SplitMessage(lines []string) []string {
+	msgs := packLines(lines, smsSize)
+	if len(msgs) < 2 {
+		return msgs
+	}
+	// reserve room for the numbering, which is at most "99/99\n"
+	msgs = packLines(lines, smsSize-6)
+	for i, msg := range msgs {
+		msgs[i] = fmt.Sprintf("%d/%d\n%s", i+1, len(msgs), msg)
+	}
+	return msgs
+}
+
+func packLines(lines []string, size int) []string {
+	var msgs []string
+	var cur string
+	for _, line := range lines {
+		for len(line) > size {
+			if cur != "" {
+				msgs = append(msgs, cur)
+				cur = ""
+			}
+			// do not cut through a multi-byte character
+			n := size
+			for n > 0 && !utf8.RuneStart(line[n]) {
+				n--
+			}
+			msgs = append(msgs, line[:n])
+			line = line[n:]
+		}
+		if cur == "" {
+			cur = line
+		} else if len(cur)+1+len(line) <= size {
+			cur += "\n" + line
+		} else {
+			msgs = append(msgs, cur)
+			cur = line
+		}
+	}
+	if cur != "" {
+		msgs = append(msgs, cur)
+	}
+	return msgs
+}
@@ -0,0 +1,99 @@
+package spend
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/config"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
+)
+
+// Enabled returns true if any spending limit is configured.
+func Enabled() bool {
+	return config.SpendLimitTransaction() > 0 ||
+		config.SpendLimitDaily() > 0 ||
+		len(config.SpendLimitVoucher()) > 0 ||
+		(config.NewAccountDays() > 0 && (config.NewAccountSpendLimitTransaction() > 0 || config.NewAccountSpendLimitDaily() > 0))
+}
+
+// stricterLimit returns the lower of two limits, where 0 means no limit.
+func stricterLimit(a float64, b float64) float64 {
+	if a <= 0 || (b > 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// Limits returns the spending limits of the account for the voucher with the given token address.
+//
+// Accounts activated less than the configured number of days ago are subject to the stricter of the
+// regular and the new account limits. Accounts without a recorded activation time were activated
+// before it was recorded, and are not new.
+func Limits(ctx context.Context, userStore store.DataStore, sessionId string, tokenAddress string) (store.SpendLimits, error) {
+	limits := store.SpendLimits{
+		Transaction: config.SpendLimitTransaction(),
+		Daily:       config.SpendLimitDaily(),
+	}
+	for k, v := range config.SpendLimitVoucher() {
+		if strings.EqualFold(k, tokenAddress) {
+			limits.Daily = v
+			break
+		}
+	}
+
+	days := config.NewAccountDays()
+	if days == 0 {
+		return limits, nil
+	}
+	activated, ok, err := store.AccountActivated(ctx, userStore, sessionId)
+	if err != nil {
+		return limits, err
+	}
+	if !ok || time.Since(activated) >= time.Duration(days)*24*time.Hour {
+		return limits, nil
+	}
+	limits.Transaction = stricterLimit(limits.Transaction, config.NewAccountSpendLimitTransaction())
+	limits.Daily = stricterLimit(limits.Daily, config.NewAccountSpendLimitDaily())
+	return limits, nil
+}
+
+// Allowed checks a transaction of the voucher with the given token address against the spending limits of the account.
+//
+// The amount is checked against the transaction limit, and the total against the amount that can still be spent
+// today. They differ only when the same amount is sent to several recipients.
+//
+// Returns false if the transaction is over the limit. The limits are checked again when the transaction is
+// carried out with Spend.
+func Allowed(ctx context.Context, userStore store.DataStore, sessionId string, tokenAddress string, amount float64, total float64) (bool, error) {
+	limits, err := Limits(ctx, userStore, sessionId, tokenAddress)
+	if err != nil {
+		return false, err
+	}
+	if !limits.Limited() {
+		return true, nil
+	}
+	spent, err := store.DailySpend(ctx, userStore, sessionId, tokenAddress)
+	if err != nil {
+		return false, err
+	}
+	return limits.Allows(spent, amount, total), nil
+}
+
+// Spend carries out a transaction of the given amount of the voucher with the given token address with fn,
+// and records the amount spent, unless it is over the spending limits of the account.
+//
+// Returns false if the amount is over the limits, in which case fn is not called. If fn fails, its error is
+// returned, and the amount is recorded unless the transaction was rejected.
+func Spend(ctx context.Context, userStore store.DataStore, sessionId string, tokenAddress string, symbol string, amount float64, fn func() error) (bool, error) {
+	limits, err := Limits(ctx, userStore, sessionId, tokenAddress)
+	if err != nil {
+		return false, err
+	}
+	record := store.SpendRecord{
+		TokenAddress: tokenAddress,
+		Symbol:       symbol,
+		Amount:       amount,
+	}
+	return store.SpendWithin(ctx, userStore, sessionId, record, limits, fn)
+}
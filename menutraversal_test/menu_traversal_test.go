@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"git.defalsify.org/vise.git/logging"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
 	"git.grassecon.net/grassrootseconomics/sarafu-vise/testutil"
 	"git.grassecon.net/grassrootseconomics/visedriver/testutil/driver"
 	"github.com/gofrs/uuid"
@@ -391,16 +392,28 @@ func TestGroups(t *testing.T) {
 	if err != nil {
 		log.Fatalf("Failed to load test groups: %v", err)
 	}
-	en, fn, _, pe, flagParser := testutil.TestEngine(sessionID)
+	en, fn, _, _, _ := testutil.TestEngine(sessionID)
 	defer fn()
 	ctx := context.Background()
 
-	flag_admin_privilege, _ := flagParser.GetFlag("flag_admin_privilege")
-
 	// Create test cases from loaded groups
 	tests := driver.CreateTestCases(groups)
+	group := ""
 	for _, tt := range tests {
 		t.Run(tt.Name, func(t *testing.T) {
+			// the groups share a session, and each starts without a recent PIN entry
+			if tt.Name != group {
+				group = tt.Name
+				err := testutil.ResetAuthorization(ctx, sessionID)
+				if err != nil {
+					t.Fatalf("Test case '%s' failed to reset authorization: %v", tt.Name, err)
+				}
+				// the role is checked by the menu itself, and must be set in the userdata store
+				err = testutil.SetRole(ctx, sessionID, store.RolePinReset, tt.Name != "menu_my_account_reset_others_pin_with_no_privileges")
+				if err != nil {
+					t.Fatalf("Test case '%s' failed to set role: %v", tt.Name, err)
+				}
+			}
 			cont, err := en.Exec(ctx, []byte(tt.Input))
 			if err != nil {
 				t.Errorf("Test case '%s' failed at input '%s': %v", tt.Name, tt.Input, err)
@@ -417,15 +430,6 @@ func TestGroups(t *testing.T) {
 			balance := extractBalance(b)
 			attempts := extractRemainingAttempts(b)
 
-			st := pe.GetState()
-
-			if st != nil {
-				st.SetFlag(flag_admin_privilege)
-				if tt.Name == "menu_my_account_reset_others_pin_with_no_privileges" {
-					st.ResetFlag(flag_admin_privilege)
-				}
-			}
-
 			expectedContent := []byte(tt.ExpectedContent)
 			expectedContent = bytes.Replace(expectedContent, []byte("{balance}"), []byte(balance), -1)
 			expectedContent = bytes.Replace(expectedContent, []byte("{attempts}"), []byte(attempts), -1)
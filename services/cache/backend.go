@@ -0,0 +1,125 @@
+package cache
+
+import (
+	"sync"
+	"time"
+)
+
+// Backend stores cached results of remote calls.
+//
+// Entries are grouped by scope, which is the account address for results
+// specific to an account, and empty for results shared by all accounts.
+type Backend interface {
+	// Get returns the value of an entry that has not yet expired.
+	Get(scope string, key string) (any, bool)
+	// Set adds an entry that expires after the given duration.
+	Set(scope string, key string, value any, ttl time.Duration)
+	// Invalidate removes all entries of the given scope.
+	Invalidate(scope string)
+}
+
+const (
+	// interval between removals of expired entries from a MemBackend.
+	sweepInterval = time.Minute
+)
+
+type memEntry struct {
+	value   any
+	expires time.Time
+}
+
+// MemBackend is a Backend keeping entries in process memory.
+//
+// Expired entries are removed when next read, and all expired entries are swept
+// when entries are added, at most once every sweepInterval. The size of the
+// backend is thus bounded by the entries added within the longest ttl.
+type MemBackend struct {
+	mu        sync.Mutex
+	entries   map[string]map[string]memEntry
+	now       func() time.Time
+	nextSweep time.Time
+}
+
+// NewMemBackend creates a new, empty in-memory backend.
+func NewMemBackend() *MemBackend {
+	return &MemBackend{
+		entries: make(map[string]map[string]memEntry),
+		now:     time.Now,
+	}
+}
+
+// Get implements Backend.
+func (mb *MemBackend) Get(scope string, key string) (any, bool) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	m, ok := mb.entries[scope]
+	if !ok {
+		return nil, false
+	}
+	e, ok := m[key]
+	if !ok {
+		return nil, false
+	}
+	if !mb.now().Before(e.expires) {
+		delete(m, key)
+		if len(m) == 0 {
+			delete(mb.entries, scope)
+		}
+		return nil, false
+	}
+	return e.value, true
+}
+
+// Set implements Backend.
+func (mb *MemBackend) Set(scope string, key string, value any, ttl time.Duration) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	now := mb.now()
+	if !now.Before(mb.nextSweep) {
+		mb.sweep(now)
+		mb.nextSweep = now.Add(sweepInterval)
+	}
+	m, ok := mb.entries[scope]
+	if !ok {
+		m = make(map[string]memEntry)
+		mb.entries[scope] = m
+	}
+	m[key] = memEntry{
+		value:   value,
+		expires: now.Add(ttl),
+	}
+}
+
+// Invalidate implements Backend.
+func (mb *MemBackend) Invalidate(scope string) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	delete(mb.entries, scope)
+}
+
+// Len returns the number of entries held, including expired entries not yet removed.
+func (mb *MemBackend) Len() int {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+	c := 0
+	for _, m := range mb.entries {
+		c += len(m)
+	}
+	return c
+}
+
+// sweep removes all entries expired at the given time.
+//
+// It must be called with the lock held.
+func (mb *MemBackend) sweep(now time.Time) {
+	for scope, m := range mb.entries {
+		for k, e := range m {
+			if !now.Before(e.expires) {
+				delete(m, k)
+			}
+		}
+		if len(m) == 0 {
+			delete(mb.entries, scope)
+		}
+	}
+}
@@ -0,0 +1,340 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"git.defalsify.org/vise.git/logging"
+	"git.grassecon.net/grassrootseconomics/common/hex"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/models"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/remote"
+	dataserviceapi "github.com/grassrootseconomics/ussd-data-service/pkg/api"
+)
+
+var (
+	logg = logging.NewVanilla().WithDomain("sarafu-vise.services.cache")
+)
+
+// DefaultTTL is the time results of each cached method are kept unless overridden.
+//
+// Results specific to an account are kept briefly, as they are also invalidated
+// on transfers made by or to the account. Methods not listed are never cached.
+var DefaultTTL = map[string]time.Duration{
+	"CheckBalance":                 30 * time.Second,
+	"FetchVouchers":                60 * time.Second,
+	"FetchTransactions":            60 * time.Second,
+	"GetPoolSwappableFromVouchers": 60 * time.Second,
+	"GetSwapFromTokenMaxLimit":     30 * time.Second,
+	"GetCreditSendMaxLimit":        30 * time.Second,
+	"VoucherData":                  time.Hour,
+	"FetchTopPools":                10 * time.Minute,
+	"RetrievePoolDetails":          10 * time.Minute,
+	"GetPoolSwappableVouchers":     5 * time.Minute,
+	"CheckTokenInPool":             10 * time.Minute,
+	"GetMpesaOnrampRates":          5 * time.Minute,
+}
+
+// accountMethods are the cached methods whose results are specific to an account.
+var accountMethods = map[string]bool{
+	"CheckBalance":                 true,
+	"FetchVouchers":                true,
+	"FetchTransactions":            true,
+	"GetPoolSwappableFromVouchers": true,
+	"GetSwapFromTokenMaxLimit":     true,
+	"GetCreditSendMaxLimit":        true,
+}
+
+// SharedTTL returns the given ttl overrides, with caching disabled for the methods whose results
+// are specific to an account unless they are listed in the overrides.
+//
+// It is used where the cache is not told of all transfers involving the accounts, such as when
+// chain events are processed by a separate service. Results specific to an account would
+// otherwise stay stale until they expire.
+func SharedTTL(ttl map[string]time.Duration) map[string]time.Duration {
+	r := make(map[string]time.Duration)
+	for k := range accountMethods {
+		r[k] = 0
+	}
+	for k, v := range ttl {
+		r[k] = v
+	}
+	return r
+}
+
+// Stat holds the hit and miss counts of a cached method.
+type Stat struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// HitRate returns the share of lookups served from the cache.
+func (s Stat) HitRate() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+type counter struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+}
+
+// AccountService wraps a remote.AccountService, serving repeated read calls from a cache.
+//
+// Calls that change the state of an account invalidate the cached results of the
+// accounts involved. All other calls are passed through unchanged.
+//
+// Cached values are shared between callers, and must not be modified.
+type AccountService struct {
+	remote.AccountService
+	backend  Backend
+	ttl      map[string]time.Duration
+	counters map[string]*counter
+}
+
+// NewAccountService creates a caching decorator for the given account service.
+//
+// The ttl map overrides DefaultTTL for individual methods. A zero duration
+// disables caching of the method.
+func NewAccountService(svc remote.AccountService, backend Backend, ttl map[string]time.Duration) *AccountService {
+	cs := &AccountService{
+		AccountService: svc,
+		backend:        backend,
+		ttl:            make(map[string]time.Duration),
+		counters:       make(map[string]*counter),
+	}
+	for k, v := range DefaultTTL {
+		cs.ttl[k] = v
+	}
+	for k, v := range ttl {
+		if _, ok := DefaultTTL[k]; !ok {
+			logg.Warnf("ignoring ttl for method that is not cached", "method", k)
+			continue
+		}
+		cs.ttl[k] = v
+	}
+	for k := range cs.ttl {
+		cs.counters[k] = &counter{}
+	}
+	return cs
+}
+
+// InvalidateAccount removes all cached results specific to the account with the given address.
+func (cs *AccountService) InvalidateAccount(ctx context.Context, address string) {
+	logg.DebugCtxf(ctx, "invalidate cached account results", "address", address)
+	cs.backend.Invalidate(toScope(address))
+}
+
+// Unwrap returns the wrapped account service.
+func (cs *AccountService) Unwrap() remote.AccountService {
+	return cs.AccountService
+}
+
+// Stats returns the hit and miss counts of each cached method.
+func (cs *AccountService) Stats() map[string]Stat {
+	r := make(map[string]Stat)
+	for k, c := range cs.counters {
+		r[k] = Stat{
+			Hits:   c.hits.Load(),
+			Misses: c.misses.Load(),
+		}
+	}
+	return r
+}
+
+// String returns a summary of the hit rates of all cached methods.
+func (cs *AccountService) String() string {
+	var r []string
+	for k, v := range cs.Stats() {
+		r = append(r, fmt.Sprintf("%s: %d/%d (%.1f%%)", k, v.Hits, v.Hits+v.Misses, v.HitRate()*100))
+	}
+	sort.Strings(r)
+	return strings.Join(r, "\n")
+}
+
+// ReportStats logs the hit and miss counts of the cached methods at the given interval, until the context is done.
+//
+// Nothing is logged if the interval is not positive.
+func (cs *AccountService) ReportStats(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		stats := cs.Stats()
+		methods := make([]string, 0, len(stats))
+		for k := range stats {
+			methods = append(methods, k)
+		}
+		sort.Strings(methods)
+		for _, k := range methods {
+			v := stats[k]
+			if v.Hits+v.Misses == 0 {
+				continue
+			}
+			logg.InfoCtxf(ctx, "remote cache stats", "method", k, "hits", v.Hits, "misses", v.Misses, "rate", v.HitRate())
+		}
+	}
+}
+
+func toScope(address string) string {
+	if address == "" {
+		return ""
+	}
+	r, err := hex.NormalizeHex(address)
+	if err != nil {
+		return strings.ToLower(address)
+	}
+	return r
+}
+
+// get returns the cached result of the method call, or executes the call and caches the result.
+//
+// Errors are never cached.
+func get[T any](ctx context.Context, cs *AccountService, method string, scope string, fn func() (T, error), args ...string) (T, error) {
+	ttl := cs.ttl[method]
+	if ttl <= 0 {
+		return fn()
+	}
+	c := cs.counters[method]
+	scope = toScope(scope)
+	key := method + "\x00" + strings.Join(args, "\x00")
+	v, ok := cs.backend.Get(scope, key)
+	if ok {
+		r, ok := v.(T)
+		if ok {
+			c.hits.Add(1)
+			logg.TraceCtxf(ctx, "cache hit", "method", method, "args", args)
+			return r, nil
+		}
+	}
+	c.misses.Add(1)
+	logg.TraceCtxf(ctx, "cache miss", "method", method, "args", args)
+	r, err := fn()
+	if err != nil {
+		return r, err
+	}
+	cs.backend.Set(scope, key, r, ttl)
+	return r, nil
+}
+
+// CheckBalance implements remote.AccountService.
+func (cs *AccountService) CheckBalance(ctx context.Context, publicKey string) (*models.BalanceResult, error) {
+	return get(ctx, cs, "CheckBalance", publicKey, func() (*models.BalanceResult, error) {
+		return cs.AccountService.CheckBalance(ctx, publicKey)
+	})
+}
+
+// FetchVouchers implements remote.AccountService.
+func (cs *AccountService) FetchVouchers(ctx context.Context, publicKey string) ([]dataserviceapi.TokenHoldings, error) {
+	return get(ctx, cs, "FetchVouchers", publicKey, func() ([]dataserviceapi.TokenHoldings, error) {
+		return cs.AccountService.FetchVouchers(ctx, publicKey)
+	})
+}
+
+// FetchTransactions implements remote.AccountService.
+func (cs *AccountService) FetchTransactions(ctx context.Context, publicKey string) ([]dataserviceapi.Last10TxResponse, error) {
+	return get(ctx, cs, "FetchTransactions", publicKey, func() ([]dataserviceapi.Last10TxResponse, error) {
+		return cs.AccountService.FetchTransactions(ctx, publicKey)
+	})
+}
+
+// VoucherData implements remote.AccountService.
+func (cs *AccountService) VoucherData(ctx context.Context, address string) (*models.VoucherDataResult, error) {
+	return get(ctx, cs, "VoucherData", "", func() (*models.VoucherDataResult, error) {
+		return cs.AccountService.VoucherData(ctx, address)
+	}, address)
+}
+
+// FetchTopPools implements remote.AccountService.
+func (cs *AccountService) FetchTopPools(ctx context.Context) ([]dataserviceapi.PoolDetails, error) {
+	return get(ctx, cs, "FetchTopPools", "", func() ([]dataserviceapi.PoolDetails, error) {
+		return cs.AccountService.FetchTopPools(ctx)
+	})
+}
+
+// RetrievePoolDetails implements remote.AccountService.
+func (cs *AccountService) RetrievePoolDetails(ctx context.Context, sym string) (*dataserviceapi.PoolDetails, error) {
+	return get(ctx, cs, "RetrievePoolDetails", "", func() (*dataserviceapi.PoolDetails, error) {
+		return cs.AccountService.RetrievePoolDetails(ctx, sym)
+	}, sym)
+}
+
+// GetPoolSwappableFromVouchers implements remote.AccountService.
+func (cs *AccountService) GetPoolSwappableFromVouchers(ctx context.Context, poolAddress, publicKey string) ([]dataserviceapi.TokenHoldings, error) {
+	return get(ctx, cs, "GetPoolSwappableFromVouchers", publicKey, func() ([]dataserviceapi.TokenHoldings, error) {
+		return cs.AccountService.GetPoolSwappableFromVouchers(ctx, poolAddress, publicKey)
+	}, poolAddress)
+}
+
+// GetPoolSwappableVouchers implements remote.AccountService.
+func (cs *AccountService) GetPoolSwappableVouchers(ctx context.Context, poolAddress string) ([]dataserviceapi.TokenHoldings, error) {
+	return get(ctx, cs, "GetPoolSwappableVouchers", "", func() ([]dataserviceapi.TokenHoldings, error) {
+		return cs.AccountService.GetPoolSwappableVouchers(ctx, poolAddress)
+	}, poolAddress)
+}
+
+// GetSwapFromTokenMaxLimit implements remote.AccountService.
+func (cs *AccountService) GetSwapFromTokenMaxLimit(ctx context.Context, poolAddress, fromTokenAddress, toTokenAddress, publicKey string) (*models.MaxLimitResult, error) {
+	return get(ctx, cs, "GetSwapFromTokenMaxLimit", publicKey, func() (*models.MaxLimitResult, error) {
+		return cs.AccountService.GetSwapFromTokenMaxLimit(ctx, poolAddress, fromTokenAddress, toTokenAddress, publicKey)
+	}, poolAddress, fromTokenAddress, toTokenAddress)
+}
+
+// CheckTokenInPool implements remote.AccountService.
+func (cs *AccountService) CheckTokenInPool(ctx context.Context, poolAddress, tokenAddress string) (*models.TokenInPoolResult, error) {
+	return get(ctx, cs, "CheckTokenInPool", "", func() (*models.TokenInPoolResult, error) {
+		return cs.AccountService.CheckTokenInPool(ctx, poolAddress, tokenAddress)
+	}, poolAddress, tokenAddress)
+}
+
+// GetCreditSendMaxLimit implements remote.AccountService.
+func (cs *AccountService) GetCreditSendMaxLimit(ctx context.Context, poolAddress, fromTokenAddress, toTokenAddress, publicKey string) (*models.CreditSendLimitsResult, error) {
+	return get(ctx, cs, "GetCreditSendMaxLimit", publicKey, func() (*models.CreditSendLimitsResult, error) {
+		return cs.AccountService.GetCreditSendMaxLimit(ctx, poolAddress, fromTokenAddress, toTokenAddress, publicKey)
+	}, poolAddress, fromTokenAddress, toTokenAddress)
+}
+
+// GetMpesaOnrampRates implements remote.AccountService.
+func (cs *AccountService) GetMpesaOnrampRates(ctx context.Context) (*models.MpesaOnrampRatesResult, error) {
+	return get(ctx, cs, "GetMpesaOnrampRates", "", func() (*models.MpesaOnrampRatesResult, error) {
+		return cs.AccountService.GetMpesaOnrampRates(ctx)
+	})
+}
+
+// TokenTransfer implements remote.AccountService.
+//
+// The cached results of both sender and recipient are invalidated.
+func (cs *AccountService) TokenTransfer(ctx context.Context, amount, from, to, tokenAddress string) (*models.TokenTransferResponse, error) {
+	defer cs.InvalidateAccount(ctx, to)
+	defer cs.InvalidateAccount(ctx, from)
+	return cs.AccountService.TokenTransfer(ctx, amount, from, to, tokenAddress)
+}
+
+// PoolSwap implements remote.AccountService.
+//
+// The cached results of the swapping account are invalidated.
+func (cs *AccountService) PoolSwap(ctx context.Context, amount, from, fromTokenAddress, poolAddress, toTokenAddress string) (*models.PoolSwapResult, error) {
+	defer cs.InvalidateAccount(ctx, from)
+	return cs.AccountService.PoolSwap(ctx, amount, from, fromTokenAddress, poolAddress, toTokenAddress)
+}
+
+// MpesaTriggerOnramp implements remote.AccountService.
+//
+// The cached results of the receiving account are invalidated.
+func (cs *AccountService) MpesaTriggerOnramp(ctx context.Context, address, phoneNumber, asset string, amount int) (*models.MpesaOnrampResponse, error) {
+	defer cs.InvalidateAccount(ctx, address)
+	return cs.AccountService.MpesaTriggerOnramp(ctx, address, phoneNumber, asset, amount)
+}
@@ -0,0 +1,158 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+
+	"git.grassecon.net/grassrootseconomics/sarafu-api/models"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/testutil/mocks"
+	dataserviceapi "github.com/grassrootseconomics/ussd-data-service/pkg/api"
+)
+
+const (
+	publicKey = "0xd4c288865Ce0985a481Eef3be02443dF5E2e4Ea9"
+)
+
+func TestCacheHit(t *testing.T) {
+	ctx := context.Background()
+	mockAccountService := new(mocks.MockAccountService)
+	holdings := []dataserviceapi.TokenHoldings{
+		{TokenSymbol: "SRF", Balance: "100"},
+	}
+	mockAccountService.On("FetchVouchers", publicKey).Return(holdings, nil).Once()
+
+	cs := NewAccountService(mockAccountService, NewMemBackend(), nil)
+	for i := 0; i < 3; i++ {
+		r, err := cs.FetchVouchers(ctx, publicKey)
+		require.NoError(t, err)
+		assert.Equal(t, holdings, r)
+	}
+	mockAccountService.AssertExpectations(t)
+
+	stat := cs.Stats()["FetchVouchers"]
+	assert.Equal(t, uint64(2), stat.Hits)
+	assert.Equal(t, uint64(1), stat.Misses)
+}
+
+func TestCacheError(t *testing.T) {
+	ctx := context.Background()
+	mockAccountService := new(mocks.MockAccountService)
+	mockAccountService.On("FetchTopPools").Return(nil, fmt.Errorf("api down")).Twice()
+
+	cs := NewAccountService(mockAccountService, NewMemBackend(), nil)
+	_, err := cs.FetchTopPools(ctx)
+	assert.Error(t, err)
+	_, err = cs.FetchTopPools(ctx)
+	assert.Error(t, err)
+	mockAccountService.AssertExpectations(t)
+}
+
+func TestCacheInvalidate(t *testing.T) {
+	ctx := context.Background()
+	mockAccountService := new(mocks.MockAccountService)
+	balance := &models.BalanceResult{Balance: "100"}
+	mockAccountService.On("CheckBalance", publicKey).Return(balance, nil).Twice()
+	mockAccountService.On("FetchTopPools").Return([]dataserviceapi.PoolDetails{}, nil).Once()
+
+	cs := NewAccountService(mockAccountService, NewMemBackend(), nil)
+	_, err := cs.CheckBalance(ctx, publicKey)
+	require.NoError(t, err)
+	_, err = cs.FetchTopPools(ctx)
+	require.NoError(t, err)
+
+	// shared results are kept when an account is invalidated
+	cs.InvalidateAccount(ctx, "0xD4C288865CE0985A481EEF3BE02443DF5E2E4EA9")
+	_, err = cs.CheckBalance(ctx, publicKey)
+	require.NoError(t, err)
+	_, err = cs.FetchTopPools(ctx)
+	require.NoError(t, err)
+	mockAccountService.AssertExpectations(t)
+}
+
+func TestCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	backend := NewMemBackend()
+	backend.now = func() time.Time {
+		return now
+	}
+	mockAccountService := new(mocks.MockAccountService)
+	mockAccountService.On("GetMpesaOnrampRates").Return(&models.MpesaOnrampRatesResult{}, nil).Twice()
+
+	cs := NewAccountService(mockAccountService, backend, map[string]time.Duration{
+		"GetMpesaOnrampRates": time.Minute,
+	})
+	_, err := cs.GetMpesaOnrampRates(ctx)
+	require.NoError(t, err)
+	now = now.Add(59 * time.Second)
+	_, err = cs.GetMpesaOnrampRates(ctx)
+	require.NoError(t, err)
+	now = now.Add(time.Second)
+	_, err = cs.GetMpesaOnrampRates(ctx)
+	require.NoError(t, err)
+	mockAccountService.AssertExpectations(t)
+}
+
+func TestCacheDisabled(t *testing.T) {
+	ctx := context.Background()
+	mockAccountService := new(mocks.MockAccountService)
+	mockAccountService.On("FetchTransactions", publicKey).Return([]dataserviceapi.Last10TxResponse{}, nil).Twice()
+
+	cs := NewAccountService(mockAccountService, NewMemBackend(), map[string]time.Duration{
+		"FetchTransactions": 0,
+	})
+	_, err := cs.FetchTransactions(ctx, publicKey)
+	require.NoError(t, err)
+	_, err = cs.FetchTransactions(ctx, publicKey)
+	require.NoError(t, err)
+	mockAccountService.AssertExpectations(t)
+}
+
+func TestCacheShared(t *testing.T) {
+	ctx := context.Background()
+	mockAccountService := new(mocks.MockAccountService)
+	mockAccountService.On("CheckBalance", publicKey).Return(&models.BalanceResult{Balance: "100"}, nil).Twice()
+	mockAccountService.On("FetchVouchers", publicKey).Return([]dataserviceapi.TokenHoldings{}, nil).Once()
+	mockAccountService.On("FetchTopPools").Return([]dataserviceapi.PoolDetails{}, nil).Once()
+
+	cs := NewAccountService(mockAccountService, NewMemBackend(), SharedTTL(map[string]time.Duration{
+		"FetchVouchers": time.Minute,
+	}))
+	for i := 0; i < 2; i++ {
+		_, err := cs.CheckBalance(ctx, publicKey)
+		require.NoError(t, err)
+		_, err = cs.FetchVouchers(ctx, publicKey)
+		require.NoError(t, err)
+		_, err = cs.FetchTopPools(ctx)
+		require.NoError(t, err)
+	}
+	mockAccountService.AssertExpectations(t)
+}
+
+func TestMemBackendSweep(t *testing.T) {
+	now := time.Now()
+	backend := NewMemBackend()
+	backend.now = func() time.Time {
+		return now
+	}
+
+	backend.Set(publicKey, "foo", 1, time.Second)
+	backend.Set("", "bar", 2, time.Hour)
+	assert.Equal(t, 2, backend.Len())
+
+	// expired entries are kept until the next sweep
+	now = now.Add(time.Second)
+	backend.Set("", "baz", 3, time.Hour)
+	assert.Equal(t, 3, backend.Len())
+
+	now = now.Add(sweepInterval)
+	backend.Set("", "baz", 3, time.Hour)
+	assert.Equal(t, 2, backend.Len())
+	_, ok := backend.Get("", "bar")
+	assert.True(t, ok)
+}
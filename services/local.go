@@ -10,7 +10,9 @@ import (
 	devremote "git.grassecon.net/grassrootseconomics/sarafu-api/dev"
 	apievent "git.grassecon.net/grassrootseconomics/sarafu-api/event"
 	"git.grassecon.net/grassrootseconomics/sarafu-api/remote"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/config"
 	"git.grassecon.net/grassrootseconomics/sarafu-vise/handlers/event"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/services/cache"
 	"git.grassecon.net/grassrootseconomics/visedriver/storage"
 )
 
@@ -41,11 +43,13 @@ func (d *localEmitter) emit(ctx context.Context, msg apievent.Msg) error {
 func New(ctx context.Context, storageService storage.StorageService) remote.AccountService {
 	svc := devremote.NewDevAccountService(ctx, storageService)
 	svc = svc.WithAutoVoucher(ctx, "FOO", 42)
-	eu := event.NewEventsUpdater(svc, storageService)
+	cached := cache.NewAccountService(svc, cache.NewMemBackend(), config.RemoteCacheTTL())
+	eu := event.NewEventsUpdater(cached, storageService)
 	emitter := &localEmitter{
 		h: eu.ToEventsHandler(),
 	}
 	svc = svc.WithEmitter(emitter.emit)
 	svc.AddVoucher(ctx, "BAR")
-	return svc
+	go cached.ReportStats(ctx, config.RemoteCacheStatsInterval())
+	return cached
 }
@@ -8,12 +8,26 @@ import (
 
 	"git.grassecon.net/grassrootseconomics/sarafu-api/remote"
 	httpremote "git.grassecon.net/grassrootseconomics/sarafu-api/remote/http"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/config"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/services/cache"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/services/resilient"
 	"git.grassecon.net/grassrootseconomics/visedriver/storage"
 )
 
 func New(ctx context.Context, storageService storage.StorageService) remote.AccountService {
-	return &httpremote.HTTPAccountService{
+	svc := &httpremote.HTTPAccountService{
 		SS:     storageService,
 		UseApi: true,
 	}
+	rs := resilient.NewAccountService(svc, resilient.Config{
+		Timeout:   config.RemoteTimeout(),
+		Retries:   int(config.RemoteRetries()),
+		Threshold: int(config.RemoteBreakerThreshold()),
+		Cooldown:  config.RemoteBreakerCooldown(),
+	})
+	// chain events are processed by a separate service, which cannot invalidate the
+	// results cached here, so only results shared by all accounts are cached by default.
+	cs := cache.NewAccountService(rs, cache.NewMemBackend(), cache.SharedTTL(config.RemoteCacheTTL()))
+	go cs.ReportStats(ctx, config.RemoteCacheStatsInterval())
+	return cs
 }
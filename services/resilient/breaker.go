@@ -0,0 +1,97 @@
+package resilient
+
+import (
+	"sync"
+	"time"
+)
+
+type breakerState uint8
+
+const (
+	stateClosed breakerState = iota
+	stateOpen
+	stateHalfOpen
+)
+
+// Breaker is a circuit breaker tracking consecutive failures of calls to a remote service.
+//
+// The breaker opens after threshold consecutive failures, rejecting all calls.
+// Once the cooldown has passed, a single trial call is let through. If it
+// succeeds the breaker closes again, otherwise it stays open for another cooldown.
+type Breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	state     breakerState
+	openedAt  time.Time
+	now       func() time.Time
+}
+
+// NewBreaker creates a new breaker in closed state.
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	if threshold < 1 {
+		threshold = 1
+	}
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// Allow returns true if a call may be made.
+func (b *Breaker) Allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case stateOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.state = stateHalfOpen
+		return true
+	case stateHalfOpen:
+		// a trial call is already in progress
+		return false
+	}
+	return true
+}
+
+// Success records a successful call, closing the breaker.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.state = stateClosed
+}
+
+// Failure records a failed call, opening the breaker if the threshold is reached.
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.state == stateHalfOpen || b.failures >= b.threshold {
+		b.state = stateOpen
+		b.openedAt = b.now()
+	}
+}
+
+// Cancel records a call that was abandoned before its outcome was known.
+//
+// If it was the trial call, the breaker returns to open with the cooldown already
+// passed, so that the next call is let through as a new trial.
+func (b *Breaker) Cancel() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == stateHalfOpen {
+		b.state = stateOpen
+	}
+}
+
+// Open returns true if calls are currently being rejected.
+func (b *Breaker) Open() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state != stateClosed
+}
@@ -0,0 +1,69 @@
+package resilient
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker(3, time.Minute)
+	b.now = func() time.Time {
+		return now
+	}
+
+	for i := 0; i < 2; i++ {
+		assert.True(t, b.Allow())
+		b.Failure()
+	}
+	assert.False(t, b.Open())
+
+	// a success resets the count
+	b.Success()
+	for i := 0; i < 3; i++ {
+		assert.True(t, b.Allow())
+		b.Failure()
+	}
+	assert.True(t, b.Open())
+	assert.False(t, b.Allow())
+
+	// a single trial call after the cooldown
+	now = now.Add(time.Minute)
+	assert.True(t, b.Allow())
+	assert.False(t, b.Allow())
+	b.Failure()
+	assert.False(t, b.Allow())
+
+	now = now.Add(time.Minute)
+	assert.True(t, b.Allow())
+	b.Success()
+	assert.False(t, b.Open())
+	assert.True(t, b.Allow())
+}
+
+func TestBreakerCancelTrial(t *testing.T) {
+	now := time.Now()
+	b := NewBreaker(1, time.Minute)
+	b.now = func() time.Time {
+		return now
+	}
+
+	assert.True(t, b.Allow())
+	b.Failure()
+	now = now.Add(time.Minute)
+	assert.True(t, b.Allow())
+	assert.False(t, b.Allow())
+
+	// an abandoned trial lets the next call through as a new trial
+	b.Cancel()
+	assert.True(t, b.Open())
+	assert.True(t, b.Allow())
+	b.Success()
+	assert.False(t, b.Open())
+
+	// cancelling a call in closed state changes nothing
+	b.Cancel()
+	assert.False(t, b.Open())
+}
@@ -0,0 +1,349 @@
+package resilient
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"git.defalsify.org/vise.git/logging"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/models"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/remote"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/remote/http"
+	dataserviceapi "github.com/grassrootseconomics/ussd-data-service/pkg/api"
+)
+
+var (
+	logg = logging.NewVanilla().WithDomain("sarafu-vise.services.resilient")
+)
+
+var (
+	// ErrServiceDegraded is returned without calling the remote service while the circuit breaker is open.
+	ErrServiceDegraded = errors.New("remote service degraded")
+)
+
+const (
+	defaultReadTimeout  = 3 * time.Second
+	defaultWriteTimeout = 5 * time.Second
+	retryBackoff        = 100 * time.Millisecond
+)
+
+// DefaultTimeout is the time allowed for a single call to each method unless overridden.
+//
+// The timeouts are chosen so that a menu step including retries completes well within
+// the time limit of a USSD session. Methods not listed use the read timeout.
+var DefaultTimeout = map[string]time.Duration{
+	"CreateAccount":      defaultWriteTimeout,
+	"TokenTransfer":      defaultWriteTimeout,
+	"PoolSwap":           defaultWriteTimeout,
+	"MpesaTriggerOnramp": defaultWriteTimeout,
+	"RequestAlias":       defaultWriteTimeout,
+	"UpdateAlias":        defaultWriteTimeout,
+	"FetchTransactions":  2 * time.Second,
+	"FetchVouchers":      2 * time.Second,
+	"CheckBalance":       2 * time.Second,
+}
+
+// Config holds the call policy of the resilient account service.
+type Config struct {
+	// Overrides of DefaultTimeout for individual methods.
+	Timeout map[string]time.Duration
+	// Number of times a failed read is retried.
+	Retries int
+	// Number of consecutive failures after which the circuit breaker opens.
+	Threshold int
+	// Time the circuit breaker stays open before a trial call is made.
+	Cooldown time.Duration
+}
+
+// AccountService wraps a remote.AccountService with timeouts, retries and a circuit breaker.
+//
+// Every call is limited by a per-method timeout. Reads are retried a bounded number of
+// times; calls that change state are never retried, as a timed out call may still have
+// been carried out by the remote service.
+//
+// Errors reported by the remote service itself (http.APIError) are passed on as is, and
+// do not count as failures. All other errors, including timeouts, count towards opening
+// the circuit breaker. While it is open, calls fail immediately with ErrServiceDegraded.
+type AccountService struct {
+	remote.AccountService
+	breaker *Breaker
+	timeout map[string]time.Duration
+	retries int
+}
+
+// NewAccountService creates a resilient wrapper for the given account service.
+func NewAccountService(svc remote.AccountService, cfg Config) *AccountService {
+	rs := &AccountService{
+		AccountService: svc,
+		breaker:        NewBreaker(cfg.Threshold, cfg.Cooldown),
+		timeout:        make(map[string]time.Duration),
+		retries:        cfg.Retries,
+	}
+	for k, v := range DefaultTimeout {
+		rs.timeout[k] = v
+	}
+	for k, v := range cfg.Timeout {
+		rs.timeout[k] = v
+	}
+	return rs
+}
+
+// Degraded returns true while the circuit breaker is open.
+func (rs *AccountService) Degraded() bool {
+	return rs.breaker.Open()
+}
+
+// Unwrap returns the wrapped account service.
+func (rs *AccountService) Unwrap() remote.AccountService {
+	return rs.AccountService
+}
+
+func (rs *AccountService) timeoutOf(method string) time.Duration {
+	v, ok := rs.timeout[method]
+	if !ok || v <= 0 {
+		return defaultReadTimeout
+	}
+	return v
+}
+
+// returns true if the error indicates a failure of the remote service rather than of the request.
+func isFailure(err error) bool {
+	var apiErr *http.APIError
+	return !errors.As(err, &apiErr)
+}
+
+// run a single call, abandoning it if it does not complete within the timeout.
+func attempt[T any](ctx context.Context, timeout time.Duration, fn func(context.Context) (T, error)) (T, error) {
+	type result struct {
+		v   T
+		err error
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ch := make(chan result, 1)
+	go func() {
+		v, err := fn(ctx)
+		ch <- result{v, err}
+	}()
+	select {
+	case r := <-ch:
+		return r.v, r.err
+	case <-ctx.Done():
+		var v T
+		return v, ctx.Err()
+	}
+}
+
+// call runs the method through the circuit breaker, retrying failed reads if retry is set.
+func call[T any](ctx context.Context, rs *AccountService, method string, retry bool, fn func(context.Context) (T, error)) (T, error) {
+	var v T
+	var err error
+	tries := 1
+	if retry {
+		tries += rs.retries
+	}
+	for i := 0; i < tries; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return v, ctx.Err()
+			case <-time.After(retryBackoff * time.Duration(i)):
+			}
+			logg.DebugCtxf(ctx, "retrying remote call", "method", method, "attempt", i+1)
+		}
+		if !rs.breaker.Allow() {
+			logg.WarnCtxf(ctx, "remote call rejected by open circuit breaker", "method", method)
+			return v, ErrServiceDegraded
+		}
+		v, err = attempt(ctx, rs.timeoutOf(method), fn)
+		if err == nil || !isFailure(err) {
+			rs.breaker.Success()
+			return v, err
+		}
+		if ctx.Err() != nil {
+			// the caller gave up, which says nothing about the remote service
+			rs.breaker.Cancel()
+			return v, err
+		}
+		rs.breaker.Failure()
+		logg.WarnCtxf(ctx, "remote call failed", "method", method, "attempt", i+1, "error", err)
+	}
+	return v, err
+}
+
+// CheckBalance implements remote.AccountService.
+func (rs *AccountService) CheckBalance(ctx context.Context, publicKey string) (*models.BalanceResult, error) {
+	return call(ctx, rs, "CheckBalance", true, func(ctx context.Context) (*models.BalanceResult, error) {
+		return rs.AccountService.CheckBalance(ctx, publicKey)
+	})
+}
+
+// CreateAccount implements remote.AccountService.
+func (rs *AccountService) CreateAccount(ctx context.Context) (*models.AccountResult, error) {
+	return call(ctx, rs, "CreateAccount", false, func(ctx context.Context) (*models.AccountResult, error) {
+		return rs.AccountService.CreateAccount(ctx)
+	})
+}
+
+// TrackAccountStatus implements remote.AccountService.
+func (rs *AccountService) TrackAccountStatus(ctx context.Context, publicKey string) (*models.TrackStatusResult, error) {
+	return call(ctx, rs, "TrackAccountStatus", true, func(ctx context.Context) (*models.TrackStatusResult, error) {
+		return rs.AccountService.TrackAccountStatus(ctx, publicKey)
+	})
+}
+
+// FetchVouchers implements remote.AccountService.
+func (rs *AccountService) FetchVouchers(ctx context.Context, publicKey string) ([]dataserviceapi.TokenHoldings, error) {
+	return call(ctx, rs, "FetchVouchers", true, func(ctx context.Context) ([]dataserviceapi.TokenHoldings, error) {
+		return rs.AccountService.FetchVouchers(ctx, publicKey)
+	})
+}
+
+// FetchTransactions implements remote.AccountService.
+func (rs *AccountService) FetchTransactions(ctx context.Context, publicKey string) ([]dataserviceapi.Last10TxResponse, error) {
+	return call(ctx, rs, "FetchTransactions", true, func(ctx context.Context) ([]dataserviceapi.Last10TxResponse, error) {
+		return rs.AccountService.FetchTransactions(ctx, publicKey)
+	})
+}
+
+// VoucherData implements remote.AccountService.
+func (rs *AccountService) VoucherData(ctx context.Context, address string) (*models.VoucherDataResult, error) {
+	return call(ctx, rs, "VoucherData", true, func(ctx context.Context) (*models.VoucherDataResult, error) {
+		return rs.AccountService.VoucherData(ctx, address)
+	})
+}
+
+// TokenTransfer implements remote.AccountService.
+func (rs *AccountService) TokenTransfer(ctx context.Context, amount, from, to, tokenAddress string) (*models.TokenTransferResponse, error) {
+	return call(ctx, rs, "TokenTransfer", false, func(ctx context.Context) (*models.TokenTransferResponse, error) {
+		return rs.AccountService.TokenTransfer(ctx, amount, from, to, tokenAddress)
+	})
+}
+
+// CheckAliasAddress implements remote.AccountService.
+func (rs *AccountService) CheckAliasAddress(ctx context.Context, alias string) (*models.AliasAddress, error) {
+	return call(ctx, rs, "CheckAliasAddress", true, func(ctx context.Context) (*models.AliasAddress, error) {
+		return rs.AccountService.CheckAliasAddress(ctx, alias)
+	})
+}
+
+// RequestAlias implements remote.AccountService.
+func (rs *AccountService) RequestAlias(ctx context.Context, publicKey string, hint string) (*models.RequestAliasResult, error) {
+	return call(ctx, rs, "RequestAlias", false, func(ctx context.Context) (*models.RequestAliasResult, error) {
+		return rs.AccountService.RequestAlias(ctx, publicKey, hint)
+	})
+}
+
+// UpdateAlias implements remote.AccountService.
+func (rs *AccountService) UpdateAlias(ctx context.Context, name string, publicKey string) (*models.RequestAliasResult, error) {
+	return call(ctx, rs, "UpdateAlias", false, func(ctx context.Context) (*models.RequestAliasResult, error) {
+		return rs.AccountService.UpdateAlias(ctx, name, publicKey)
+	})
+}
+
+// SendUpsellSMS implements remote.AccountService.
+func (rs *AccountService) SendUpsellSMS(ctx context.Context, inviterPhone, inviteePhone string) (*models.SendSMSResponse, error) {
+	return call(ctx, rs, "SendUpsellSMS", false, func(ctx context.Context) (*models.SendSMSResponse, error) {
+		return rs.AccountService.SendUpsellSMS(ctx, inviterPhone, inviteePhone)
+	})
+}
+
+// SendAddressSMS implements remote.AccountService.
+func (rs *AccountService) SendAddressSMS(ctx context.Context, publicKey, originPhone string) error {
+	_, err := call(ctx, rs, "SendAddressSMS", false, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, rs.AccountService.SendAddressSMS(ctx, publicKey, originPhone)
+	})
+	return err
+}
+
+// SendPINResetSMS implements remote.AccountService.
+func (rs *AccountService) SendPINResetSMS(ctx context.Context, admin, phone string) error {
+	_, err := call(ctx, rs, "SendPINResetSMS", false, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, rs.AccountService.SendPINResetSMS(ctx, admin, phone)
+	})
+	return err
+}
+
+// FetchTopPools implements remote.AccountService.
+func (rs *AccountService) FetchTopPools(ctx context.Context) ([]dataserviceapi.PoolDetails, error) {
+	return call(ctx, rs, "FetchTopPools", true, func(ctx context.Context) ([]dataserviceapi.PoolDetails, error) {
+		return rs.AccountService.FetchTopPools(ctx)
+	})
+}
+
+// RetrievePoolDetails implements remote.AccountService.
+func (rs *AccountService) RetrievePoolDetails(ctx context.Context, sym string) (*dataserviceapi.PoolDetails, error) {
+	return call(ctx, rs, "RetrievePoolDetails", true, func(ctx context.Context) (*dataserviceapi.PoolDetails, error) {
+		return rs.AccountService.RetrievePoolDetails(ctx, sym)
+	})
+}
+
+// GetPoolSwappableFromVouchers implements remote.AccountService.
+func (rs *AccountService) GetPoolSwappableFromVouchers(ctx context.Context, poolAddress, publicKey string) ([]dataserviceapi.TokenHoldings, error) {
+	return call(ctx, rs, "GetPoolSwappableFromVouchers", true, func(ctx context.Context) ([]dataserviceapi.TokenHoldings, error) {
+		return rs.AccountService.GetPoolSwappableFromVouchers(ctx, poolAddress, publicKey)
+	})
+}
+
+// GetPoolSwappableVouchers implements remote.AccountService.
+func (rs *AccountService) GetPoolSwappableVouchers(ctx context.Context, poolAddress string) ([]dataserviceapi.TokenHoldings, error) {
+	return call(ctx, rs, "GetPoolSwappableVouchers", true, func(ctx context.Context) ([]dataserviceapi.TokenHoldings, error) {
+		return rs.AccountService.GetPoolSwappableVouchers(ctx, poolAddress)
+	})
+}
+
+// GetPoolSwapQuote implements remote.AccountService.
+func (rs *AccountService) GetPoolSwapQuote(ctx context.Context, amount, from, fromTokenAddress, poolAddress, toTokenAddress string) (*models.PoolSwapQuoteResult, error) {
+	return call(ctx, rs, "GetPoolSwapQuote", true, func(ctx context.Context) (*models.PoolSwapQuoteResult, error) {
+		return rs.AccountService.GetPoolSwapQuote(ctx, amount, from, fromTokenAddress, poolAddress, toTokenAddress)
+	})
+}
+
+// PoolSwap implements remote.AccountService.
+func (rs *AccountService) PoolSwap(ctx context.Context, amount, from, fromTokenAddress, poolAddress, toTokenAddress string) (*models.PoolSwapResult, error) {
+	return call(ctx, rs, "PoolSwap", false, func(ctx context.Context) (*models.PoolSwapResult, error) {
+		return rs.AccountService.PoolSwap(ctx, amount, from, fromTokenAddress, poolAddress, toTokenAddress)
+	})
+}
+
+// GetSwapFromTokenMaxLimit implements remote.AccountService.
+func (rs *AccountService) GetSwapFromTokenMaxLimit(ctx context.Context, poolAddress, fromTokenAddress, toTokenAddress, publicKey string) (*models.MaxLimitResult, error) {
+	return call(ctx, rs, "GetSwapFromTokenMaxLimit", true, func(ctx context.Context) (*models.MaxLimitResult, error) {
+		return rs.AccountService.GetSwapFromTokenMaxLimit(ctx, poolAddress, fromTokenAddress, toTokenAddress, publicKey)
+	})
+}
+
+// CheckTokenInPool implements remote.AccountService.
+func (rs *AccountService) CheckTokenInPool(ctx context.Context, poolAddress, tokenAddress string) (*models.TokenInPoolResult, error) {
+	return call(ctx, rs, "CheckTokenInPool", true, func(ctx context.Context) (*models.TokenInPoolResult, error) {
+		return rs.AccountService.CheckTokenInPool(ctx, poolAddress, tokenAddress)
+	})
+}
+
+// GetCreditSendMaxLimit implements remote.AccountService.
+func (rs *AccountService) GetCreditSendMaxLimit(ctx context.Context, poolAddress, fromTokenAddress, toTokenAddress, publicKey string) (*models.CreditSendLimitsResult, error) {
+	return call(ctx, rs, "GetCreditSendMaxLimit", true, func(ctx context.Context) (*models.CreditSendLimitsResult, error) {
+		return rs.AccountService.GetCreditSendMaxLimit(ctx, poolAddress, fromTokenAddress, toTokenAddress, publicKey)
+	})
+}
+
+// GetCreditSendReverseQuote implements remote.AccountService.
+func (rs *AccountService) GetCreditSendReverseQuote(ctx context.Context, poolAddress, fromTokenAddress, toTokenAddress, amount string) (*models.CreditSendReverseQouteResult, error) {
+	return call(ctx, rs, "GetCreditSendReverseQuote", true, func(ctx context.Context) (*models.CreditSendReverseQouteResult, error) {
+		return rs.AccountService.GetCreditSendReverseQuote(ctx, poolAddress, fromTokenAddress, toTokenAddress, amount)
+	})
+}
+
+// GetMpesaOnrampRates implements remote.AccountService.
+func (rs *AccountService) GetMpesaOnrampRates(ctx context.Context) (*models.MpesaOnrampRatesResult, error) {
+	return call(ctx, rs, "GetMpesaOnrampRates", true, func(ctx context.Context) (*models.MpesaOnrampRatesResult, error) {
+		return rs.AccountService.GetMpesaOnrampRates(ctx)
+	})
+}
+
+// MpesaTriggerOnramp implements remote.AccountService.
+func (rs *AccountService) MpesaTriggerOnramp(ctx context.Context, address, phoneNumber, asset string, amount int) (*models.MpesaOnrampResponse, error) {
+	return call(ctx, rs, "MpesaTriggerOnramp", false, func(ctx context.Context) (*models.MpesaOnrampResponse, error) {
+		return rs.AccountService.MpesaTriggerOnramp(ctx, address, phoneNumber, asset, amount)
+	})
+}
@@ -0,0 +1,114 @@
+package resilient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+
+	"git.grassecon.net/grassrootseconomics/sarafu-api/models"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/remote/http"
+	"git.grassecon.net/grassrootseconomics/sarafu-api/testutil/mocks"
+	dataserviceapi "github.com/grassrootseconomics/ussd-data-service/pkg/api"
+)
+
+const (
+	publicKey = "0xd4c288865Ce0985a481Eef3be02443dF5E2e4Ea9"
+)
+
+func TestRetryRead(t *testing.T) {
+	ctx := context.Background()
+	mockAccountService := new(mocks.MockAccountService)
+	holdings := []dataserviceapi.TokenHoldings{
+		{TokenSymbol: "SRF", Balance: "100"},
+	}
+	mockAccountService.On("FetchVouchers", publicKey).Return(nil, fmt.Errorf("connection reset")).Once()
+	mockAccountService.On("FetchVouchers", publicKey).Return(holdings, nil).Once()
+
+	rs := NewAccountService(mockAccountService, Config{
+		Retries:   1,
+		Threshold: 5,
+		Cooldown:  time.Minute,
+	})
+	r, err := rs.FetchVouchers(ctx, publicKey)
+	assert.NoError(t, err)
+	assert.Equal(t, holdings, r)
+	mockAccountService.AssertExpectations(t)
+}
+
+func TestNoRetryWrite(t *testing.T) {
+	ctx := context.Background()
+	mockAccountService := new(mocks.MockAccountService)
+	mockAccountService.On("TokenTransfer").Return(nil, fmt.Errorf("connection reset")).Once()
+
+	rs := NewAccountService(mockAccountService, Config{
+		Retries:   3,
+		Threshold: 5,
+		Cooldown:  time.Minute,
+	})
+	_, err := rs.TokenTransfer(ctx, "1000", publicKey, publicKey, publicKey)
+	assert.Error(t, err)
+	mockAccountService.AssertExpectations(t)
+}
+
+func TestApiErrorNotFailure(t *testing.T) {
+	ctx := context.Background()
+	mockAccountService := new(mocks.MockAccountService)
+	apiErr := &http.APIError{
+		Code:        "E10",
+		Description: "invalid voucher",
+	}
+	mockAccountService.On("TokenTransfer").Return(nil, apiErr).Twice()
+
+	rs := NewAccountService(mockAccountService, Config{
+		Threshold: 1,
+		Cooldown:  time.Minute,
+	})
+	for i := 0; i < 2; i++ {
+		_, err := rs.TokenTransfer(ctx, "1000", publicKey, publicKey, publicKey)
+		assert.True(t, errors.Is(err, apiErr))
+	}
+	assert.False(t, rs.Degraded())
+	mockAccountService.AssertExpectations(t)
+}
+
+func TestCircuitOpen(t *testing.T) {
+	ctx := context.Background()
+	mockAccountService := new(mocks.MockAccountService)
+	mockAccountService.On("FetchTopPools").Return(nil, fmt.Errorf("connection refused")).Twice()
+
+	rs := NewAccountService(mockAccountService, Config{
+		Retries:   1,
+		Threshold: 2,
+		Cooldown:  time.Minute,
+	})
+	_, err := rs.FetchTopPools(ctx)
+	assert.Error(t, err)
+	assert.True(t, rs.Degraded())
+
+	// fails fast without calling the remote service
+	_, err = rs.GetMpesaOnrampRates(ctx)
+	assert.True(t, errors.Is(err, ErrServiceDegraded))
+	mockAccountService.AssertExpectations(t)
+}
+
+func TestTimeout(t *testing.T) {
+	ctx := context.Background()
+	mockAccountService := new(mocks.MockAccountService)
+	mockAccountService.On("GetMpesaOnrampRates").Return(&models.MpesaOnrampRatesResult{}, nil).WaitUntil(time.After(time.Second)).Once()
+
+	rs := NewAccountService(mockAccountService, Config{
+		Timeout: map[string]time.Duration{
+			"GetMpesaOnrampRates": 10 * time.Millisecond,
+		},
+		Threshold: 5,
+		Cooldown:  time.Minute,
+	})
+	start := time.Now()
+	_, err := rs.GetMpesaOnrampRates(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.True(t, time.Since(start) < time.Second)
+}
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	visedb "git.defalsify.org/vise.git/db"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+// IsAccountActive returns true if the activation of the custodial account has been recorded.
+func IsAccountActive(ctx context.Context, store DataStore, sessionId string) (bool, error) {
+	v, err := store.ReadEntry(ctx, sessionId, storedb.DATA_ACCOUNT_ACTIVATED)
+	if err != nil {
+		if visedb.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return len(v) > 0, nil
+}
+
+// MarkAccountActive records the activation of the custodial account.
+//
+// Returns false if the activation was already recorded, in which case nothing is changed.
+// The activation may be seen both from the registration event and from the menu, and is
+// recorded while holding the lock of the entry so that it is only recorded once.
+func MarkAccountActive(ctx context.Context, store DataStore, sessionId string) (bool, error) {
+	unlock := lockKey(storedb.EntryKey(sessionId, storedb.DATA_ACCOUNT_ACTIVATED))
+	defer unlock()
+	active, err := IsAccountActive(ctx, store, sessionId)
+	if err != nil {
+		return false, err
+	}
+	if active {
+		return false, nil
+	}
+	v := strconv.FormatInt(time.Now().Unix(), 10)
+	err = store.WriteEntry(ctx, sessionId, storedb.DATA_ACCOUNT_ACTIVATED, []byte(v))
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// AccountActivated returns the time the activation of the custodial account was recorded.
+//
+// Returns false if the activation has not been recorded.
+func AccountActivated(ctx context.Context, store DataStore, sessionId string) (time.Time, bool, error) {
+	v, err := store.ReadEntry(ctx, sessionId, storedb.DATA_ACCOUNT_ACTIVATED)
+	if err != nil {
+		if visedb.IsNotFound(err) {
+			return time.Time{}, false, nil
+		}
+		return time.Time{}, false, err
+	}
+	if len(v) == 0 {
+		return time.Time{}, false, nil
+	}
+	t, err := strconv.ParseInt(string(v), 10, 64)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return time.Unix(t, 0), true, nil
+}
@@ -0,0 +1,30 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkAccountActive(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+
+	active, err := IsAccountActive(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.False(t, active)
+
+	ok, err := MarkAccountActive(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	active, err = IsAccountActive(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.True(t, active)
+
+	// a repeated activation is not recorded again
+	ok, err = MarkAccountActive(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,277 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	visedb "git.defalsify.org/vise.git/db"
+	"git.grassecon.net/grassrootseconomics/common/hex"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+var (
+	// entries holding the profile of an account.
+	profileTyps = []storedb.DataTyp{
+		storedb.DATA_FIRST_NAME,
+		storedb.DATA_FAMILY_NAME,
+		storedb.DATA_YOB,
+		storedb.DATA_LOCATION,
+		storedb.DATA_GENDER,
+		storedb.DATA_OFFERINGS,
+	}
+)
+
+// AccountSummary is an overview of the state of an account, for support staff.
+type AccountSummary struct {
+	SessionId            string
+	PublicKey            string
+	Alias                string
+	FirstName            string
+	FamilyName           string
+	Language             string
+	ActiveSym            string
+	ActiveBal            string
+	ActivePool           string
+	IncorrectPinAttempts uint64
+	PinLockouts          uint64
+	// End of the current temporary PIN lockout, zero if none.
+	LockoutExpiry time.Time
+	SelfPinReset  bool
+	// Time of the activation of the custodial account, zero if not yet activated.
+	Activated time.Time
+	Roles     []string
+}
+
+// readString retrieves an entry of the account, where a missing entry is returned as empty.
+func readString(ctx context.Context, store DataStore, sessionId string, typ storedb.DataTyp) (string, error) {
+	v, err := store.ReadEntry(ctx, sessionId, typ)
+	if err != nil {
+		if visedb.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return string(v), nil
+}
+
+// ReadAccountSummary retrieves an overview of the state of the account.
+func ReadAccountSummary(ctx context.Context, store DataStore, sessionId string) (AccountSummary, error) {
+	var err error
+	summary := AccountSummary{
+		SessionId: sessionId,
+	}
+	fields := []struct {
+		typ storedb.DataTyp
+		v   *string
+	}{
+		{storedb.DATA_PUBLIC_KEY, &summary.PublicKey},
+		{storedb.DATA_ACCOUNT_ALIAS, &summary.Alias},
+		{storedb.DATA_FIRST_NAME, &summary.FirstName},
+		{storedb.DATA_FAMILY_NAME, &summary.FamilyName},
+		{storedb.DATA_SELECTED_LANGUAGE_CODE, &summary.Language},
+		{storedb.DATA_ACTIVE_SYM, &summary.ActiveSym},
+		{storedb.DATA_ACTIVE_BAL, &summary.ActiveBal},
+		{storedb.DATA_ACTIVE_POOL_SYM, &summary.ActivePool},
+	}
+	for _, f := range fields {
+		*f.v, err = readString(ctx, store, sessionId, f.typ)
+		if err != nil {
+			return summary, err
+		}
+	}
+
+	summary.IncorrectPinAttempts, err = readUint(ctx, store, sessionId, storedb.DATA_INCORRECT_PIN_ATTEMPTS)
+	if err != nil {
+		return summary, err
+	}
+	summary.PinLockouts, err = readUint(ctx, store, sessionId, storedb.DATA_PIN_LOCKOUTS)
+	if err != nil {
+		return summary, err
+	}
+	summary.LockoutExpiry, _, err = LockoutExpiry(ctx, store, sessionId)
+	if err != nil {
+		return summary, err
+	}
+	selfPinReset, err := readString(ctx, store, sessionId, storedb.DATA_SELF_PIN_RESET)
+	if err != nil {
+		return summary, err
+	}
+	summary.SelfPinReset = selfPinReset == "1"
+	summary.Activated, _, err = AccountActivated(ctx, store, sessionId)
+	if err != nil {
+		return summary, err
+	}
+	summary.Roles, err = ReadRoles(ctx, store, sessionId)
+	if err != nil {
+		return summary, err
+	}
+	return summary, nil
+}
+
+// UnblockAccount allows new PIN attempts on the account, and forgets its earlier lockouts.
+func UnblockAccount(ctx context.Context, store DataStore, sessionId string) error {
+	return WriteEntries(ctx, store, sessionId, map[storedb.DataTyp][]byte{
+		storedb.DATA_INCORRECT_PIN_ATTEMPTS: []byte("0"),
+		storedb.DATA_PIN_LOCKOUT_EXPIRY:     []byte(""),
+		storedb.DATA_PIN_LOCKOUTS:           []byte("0"),
+	})
+}
+
+// ForcePinReset unblocks the account and requires it to set a new PIN the next time it accesses the menu.
+func ForcePinReset(ctx context.Context, store DataStore, sessionId string) error {
+	return WriteEntries(ctx, store, sessionId, map[storedb.DataTyp][]byte{
+		storedb.DATA_SELF_PIN_RESET:         []byte("1"),
+		storedb.DATA_INCORRECT_PIN_ATTEMPTS: []byte("0"),
+		storedb.DATA_PIN_LOCKOUT_EXPIRY:     []byte(""),
+		storedb.DATA_PIN_LOCKOUTS:           []byte("0"),
+	})
+}
+
+// ClearActiveVoucher unsets the active voucher of the account.
+//
+// A new active voucher is chosen from the holdings of the account the next time its vouchers are fetched.
+func ClearActiveVoucher(ctx context.Context, store DataStore, sessionId string) error {
+	return WriteEntries(ctx, store, sessionId, map[storedb.DataTyp][]byte{
+		storedb.DATA_ACTIVE_SYM:     []byte(""),
+		storedb.DATA_ACTIVE_BAL:     []byte(""),
+		storedb.DATA_ACTIVE_DECIMAL: []byte(""),
+		storedb.DATA_ACTIVE_ADDRESS: []byte(""),
+	})
+}
+
+// ResetLanguage unsets the language selected by the account, so that it is asked to select one again.
+//
+// The language initially selected by the account is kept.
+func ResetLanguage(ctx context.Context, store DataStore, sessionId string) error {
+	return store.WriteEntry(ctx, sessionId, storedb.DATA_SELECTED_LANGUAGE_CODE, []byte(""))
+}
+
+// ownIndexes returns the keys of the address and alias index entries that map to the account.
+func ownIndexes(ctx context.Context, store DataStore, sessionId string, alias bool, address bool) ([]storedb.Key, error) {
+	var keys []storedb.Key
+	if address {
+		publicKey, err := readString(ctx, store, sessionId, storedb.DATA_PUBLIC_KEY)
+		if err != nil {
+			return nil, err
+		}
+		// an invalid address cannot have been indexed
+		v, err := hex.NormalizeHex(publicKey)
+		if publicKey != "" && err == nil {
+			keys = append(keys, storedb.IndexKey(storedb.DATA_PUBLIC_KEY_REVERSE, v))
+		}
+	}
+	if alias {
+		v, err := readString(ctx, store, sessionId, storedb.DATA_ACCOUNT_ALIAS)
+		if err != nil {
+			return nil, err
+		}
+		if v != "" {
+			keys = append(keys, storedb.IndexKey(storedb.DATA_ALIAS_REVERSE, NormalizeAlias(v)))
+		}
+	}
+
+	var r []storedb.Key
+	for _, k := range keys {
+		v, err := store.Read(ctx, k)
+		if err != nil {
+			if visedb.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		// the entry may have been taken over by another account
+		if string(v) != sessionId {
+			continue
+		}
+		r = append(r, k)
+		if k.Typ == storedb.DATA_ALIAS_REVERSE {
+			r = append(r, storedb.IndexKey(storedb.DATA_ALIAS_ADDRESS, k.Id))
+		}
+	}
+	return r, nil
+}
+
+// clearIndexes adds the clearing of the address and alias index entries that map to the account to the batch.
+func clearIndexes(ctx context.Context, b *Batch, store DataStore, sessionId string, alias bool, address bool) error {
+	keys, err := ownIndexes(ctx, store, sessionId, alias, address)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		err = b.Put(ctx, k, []byte{})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// clearProfile clears the profile and the alias of the account, and the index entries
+// of its alias and optionally of its address.
+func clearProfile(ctx context.Context, store DataStore, sessionId string, address bool) error {
+	b := store.Begin(ctx)
+	err := clearIndexes(ctx, b, store, sessionId, true, address)
+	if err != nil {
+		b.Rollback(ctx)
+		return err
+	}
+	for _, typ := range append(profileTyps, storedb.DATA_ACCOUNT_ALIAS) {
+		err = b.Put(ctx, storedb.EntryKey(sessionId, typ), []byte(""))
+		if err != nil {
+			b.Rollback(ctx)
+			return err
+		}
+	}
+	return b.Commit(ctx)
+}
+
+// AnonymizeAccount clears the profile and the alias of the account.
+//
+// The account can still be used, and its vouchers and transaction history are kept.
+func AnonymizeAccount(ctx context.Context, store DataStore, sessionId string) error {
+	return clearProfile(ctx, store, sessionId, false)
+}
+
+// DeleteAccount clears all entries of the account, and the index entries that map to it.
+//
+// The account is registered anew the next time it accesses the menu.
+//
+// It returns the number of entries cleared.
+func DeleteAccount(ctx context.Context, store DataStore, sessionId string) (int, error) {
+	b := store.Begin(ctx)
+	err := clearIndexes(ctx, b, store, sessionId, true, true)
+	if err != nil {
+		b.Rollback(ctx)
+		return 0, err
+	}
+
+	c := 0
+	for _, typ := range storedb.DataTyps() {
+		if storedb.IsIndexTyp(typ) {
+			continue
+		}
+		k := storedb.EntryKey(sessionId, typ)
+		v, err := store.Read(ctx, k)
+		if err != nil {
+			if visedb.IsNotFound(err) {
+				continue
+			}
+			b.Rollback(ctx)
+			return 0, err
+		}
+		if len(v) == 0 {
+			continue
+		}
+		err = b.Put(ctx, k, []byte{})
+		if err != nil {
+			b.Rollback(ctx)
+			return 0, err
+		}
+		c++
+	}
+	err = b.Commit(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return c, RemoveScheduledSession(ctx, store, sessionId)
+}
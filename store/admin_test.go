@@ -0,0 +1,152 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	visedb "git.defalsify.org/vise.git/db"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountSummary(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+
+	err := WriteEntries(ctx, store, sessionId, map[storedb.DataTyp][]byte{
+		storedb.DATA_PUBLIC_KEY:             []byte("0x5d5c2bd7C61C6F6D1D30E8B6C61E94AC2C4C2D92"),
+		storedb.DATA_FIRST_NAME:             []byte("John"),
+		storedb.DATA_SELECTED_LANGUAGE_CODE: []byte("swa"),
+		storedb.DATA_ACTIVE_SYM:             []byte("SRF"),
+		storedb.DATA_INCORRECT_PIN_ATTEMPTS: []byte("3"),
+	})
+	require.NoError(t, err)
+	_, _, err = LockAccount(ctx, store, sessionId, []time.Duration{time.Hour})
+	require.NoError(t, err)
+	_, err = GrantRole(ctx, store, sessionId, RoleSupport)
+	require.NoError(t, err)
+
+	summary, err := ReadAccountSummary(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, "0x5d5c2bd7C61C6F6D1D30E8B6C61E94AC2C4C2D92", summary.PublicKey)
+	assert.Equal(t, "John", summary.FirstName)
+	assert.Equal(t, "", summary.FamilyName)
+	assert.Equal(t, "swa", summary.Language)
+	assert.Equal(t, "SRF", summary.ActiveSym)
+	assert.Equal(t, uint64(3), summary.IncorrectPinAttempts)
+	assert.Equal(t, uint64(1), summary.PinLockouts)
+	assert.False(t, summary.LockoutExpiry.IsZero())
+	assert.False(t, summary.SelfPinReset)
+	assert.True(t, summary.Activated.IsZero())
+	assert.Equal(t, []string{RoleSupport}, summary.Roles)
+
+	err = UnblockAccount(ctx, store, sessionId)
+	require.NoError(t, err)
+	summary, err = ReadAccountSummary(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), summary.IncorrectPinAttempts)
+	assert.Equal(t, uint64(0), summary.PinLockouts)
+	assert.True(t, summary.LockoutExpiry.IsZero())
+
+	err = ForcePinReset(ctx, store, sessionId)
+	require.NoError(t, err)
+	summary, err = ReadAccountSummary(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.True(t, summary.SelfPinReset)
+
+	err = ClearActiveVoucher(ctx, store, sessionId)
+	require.NoError(t, err)
+	err = ResetLanguage(ctx, store, sessionId)
+	require.NoError(t, err)
+	summary, err = ReadAccountSummary(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, "", summary.ActiveSym)
+	assert.Equal(t, "", summary.Language)
+}
+
+func TestAnonymizeAccount(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+	address := "0x5d5c2bd7C61C6F6D1D30E8B6C61E94AC2C4C2D92"
+
+	err := WriteEntries(ctx, store, sessionId, map[storedb.DataTyp][]byte{
+		storedb.DATA_PUBLIC_KEY:    []byte(address),
+		storedb.DATA_FIRST_NAME:    []byte("John"),
+		storedb.DATA_LOCATION:      []byte("Kilifi"),
+		storedb.DATA_ACCOUNT_ALIAS: []byte("john.sarafu.eth"),
+		storedb.DATA_ACTIVE_SYM:    []byte("SRF"),
+	})
+	require.NoError(t, err)
+	err = WriteAliasIndex(ctx, store, sessionId, "", "john.sarafu.eth", address)
+	require.NoError(t, err)
+
+	err = AnonymizeAccount(ctx, store, sessionId)
+	require.NoError(t, err)
+
+	for _, typ := range []storedb.DataTyp{storedb.DATA_FIRST_NAME, storedb.DATA_LOCATION, storedb.DATA_ACCOUNT_ALIAS} {
+		v, err := store.ReadEntry(ctx, sessionId, typ)
+		require.NoError(t, err)
+		assert.Equal(t, "", string(v))
+	}
+	v, err := store.ReadEntry(ctx, sessionId, storedb.DATA_ACTIVE_SYM)
+	require.NoError(t, err)
+	assert.Equal(t, "SRF", string(v))
+
+	_, _, err = ResolveAlias(ctx, store, "john.sarafu.eth")
+	assert.True(t, visedb.IsNotFound(err))
+}
+
+func TestDeleteAccount(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+	otherId := "+254711223344"
+	address := "0x5d5c2bd7C61C6F6D1D30E8B6C61E94AC2C4C2D92"
+	normalAddress := "5d5c2bd7c61c6f6d1d30e8b6c61e94ac2c4c2d92"
+
+	err := WriteEntries(ctx, store, sessionId, map[storedb.DataTyp][]byte{
+		storedb.DATA_PUBLIC_KEY:    []byte(address),
+		storedb.DATA_FIRST_NAME:    []byte("John"),
+		storedb.DATA_ACCOUNT_ALIAS: []byte("john.sarafu.eth"),
+		storedb.DATA_RECIPIENT:     []byte("0x1234"),
+	})
+	require.NoError(t, err)
+	err = store.WriteIndex(ctx, storedb.DATA_PUBLIC_KEY_REVERSE, normalAddress, []byte(sessionId))
+	require.NoError(t, err)
+	// the alias has since been taken over by another account
+	err = WriteAliasIndex(ctx, store, otherId, "", "john.sarafu.eth", address)
+	require.NoError(t, err)
+	err = AddContact(ctx, store, sessionId, ContactRecord{Name: "Jane", Recipient: otherId})
+	require.NoError(t, err)
+	err = store.WriteEntry(ctx, otherId, storedb.DATA_FIRST_NAME, []byte("Jane"))
+	require.NoError(t, err)
+
+	c, err := DeleteAccount(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, 5, c)
+
+	for _, typ := range []storedb.DataTyp{storedb.DATA_PUBLIC_KEY, storedb.DATA_FIRST_NAME, storedb.DATA_RECIPIENT} {
+		v, err := store.ReadEntry(ctx, sessionId, typ)
+		require.NoError(t, err)
+		assert.Equal(t, "", string(v))
+	}
+	contacts, err := ReadContacts(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(contacts))
+
+	v, err := store.ReadIndex(ctx, storedb.DATA_PUBLIC_KEY_REVERSE, normalAddress)
+	require.NoError(t, err)
+	assert.Equal(t, "", string(v))
+	_, aliasSessionId, err := ResolveAlias(ctx, store, "john.sarafu.eth")
+	require.NoError(t, err)
+	assert.Equal(t, otherId, aliasSessionId)
+
+	v, err = store.ReadEntry(ctx, otherId, storedb.DATA_FIRST_NAME)
+	require.NoError(t, err)
+	assert.Equal(t, "Jane", string(v))
+
+	// nothing is left to clear
+	c, err = DeleteAccount(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, 0, c)
+}
@@ -0,0 +1,77 @@
+package store
+
+import (
+	"context"
+	"strings"
+
+	visedb "git.defalsify.org/vise.git/db"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+// NormalizeAlias returns the form of an alias used as index key.
+func NormalizeAlias(alias string) string {
+	return strings.ToLower(strings.TrimSpace(alias))
+}
+
+// WriteAliasIndex maps the given alias to the session id and address of an account.
+//
+// If oldAlias is set and still maps to the same session, its index entries are
+// cleared, so that the previous alias of the account no longer resolves locally.
+func WriteAliasIndex(ctx context.Context, store DataStore, sessionId string, oldAlias string, alias string, address string) error {
+	alias = NormalizeAlias(alias)
+	oldAlias = NormalizeAlias(oldAlias)
+	if alias == "" {
+		return nil
+	}
+
+	b := store.Begin(ctx)
+	if oldAlias != "" && oldAlias != alias {
+		v, err := store.ReadIndex(ctx, storedb.DATA_ALIAS_REVERSE, oldAlias)
+		if err == nil && string(v) == sessionId {
+			err = b.Put(ctx, storedb.IndexKey(storedb.DATA_ALIAS_REVERSE, oldAlias), []byte{})
+			if err == nil {
+				err = b.Put(ctx, storedb.IndexKey(storedb.DATA_ALIAS_ADDRESS, oldAlias), []byte{})
+			}
+		} else if visedb.IsNotFound(err) {
+			err = nil
+		}
+		if err != nil {
+			b.Rollback(ctx)
+			return err
+		}
+	}
+	err := b.Put(ctx, storedb.IndexKey(storedb.DATA_ALIAS_REVERSE, alias), []byte(sessionId))
+	if err != nil {
+		b.Rollback(ctx)
+		return err
+	}
+	err = b.Put(ctx, storedb.IndexKey(storedb.DATA_ALIAS_ADDRESS, alias), []byte(address))
+	if err != nil {
+		b.Rollback(ctx)
+		return err
+	}
+	return b.Commit(ctx)
+}
+
+// ResolveAlias looks up the address and session id of the account the given
+// alias is assigned to in the local index.
+//
+// A not found error is returned if the alias is not known locally.
+func ResolveAlias(ctx context.Context, store DataStore, alias string) (string, string, error) {
+	alias = NormalizeAlias(alias)
+	address, err := store.ReadIndex(ctx, storedb.DATA_ALIAS_ADDRESS, alias)
+	if err != nil {
+		return "", "", err
+	}
+	if len(address) == 0 {
+		return "", "", visedb.NewErrNotFound([]byte(alias))
+	}
+	sessionId, err := store.ReadIndex(ctx, storedb.DATA_ALIAS_REVERSE, alias)
+	if err != nil {
+		if !visedb.IsNotFound(err) {
+			return "", "", err
+		}
+		sessionId = nil
+	}
+	return string(address), string(sessionId), nil
+}
@@ -0,0 +1,47 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+
+	visedb "git.defalsify.org/vise.git/db"
+)
+
+func TestAliasIndex(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+	address := "0xd4c288865Ce0985a481Eef3be02443dF5E2e4Ea9"
+
+	_, _, err := ResolveAlias(ctx, store, "foo.sarafu.eth")
+	assert.True(t, visedb.IsNotFound(err))
+
+	err = WriteAliasIndex(ctx, store, sessionId, "", "Foo.sarafu.eth", address)
+	require.NoError(t, err)
+
+	a, s, err := ResolveAlias(ctx, store, "foo.sarafu.eth")
+	require.NoError(t, err)
+	assert.Equal(t, address, a)
+	assert.Equal(t, sessionId, s)
+
+	// the previous alias no longer resolves after a change
+	err = WriteAliasIndex(ctx, store, sessionId, "foo.sarafu.eth", "bar.sarafu.eth", address)
+	require.NoError(t, err)
+	_, _, err = ResolveAlias(ctx, store, "foo.sarafu.eth")
+	assert.True(t, visedb.IsNotFound(err))
+	a, s, err = ResolveAlias(ctx, store, "bar.sarafu.eth")
+	require.NoError(t, err)
+	assert.Equal(t, address, a)
+	assert.Equal(t, sessionId, s)
+
+	// an alias taken over by another account is left alone
+	err = WriteAliasIndex(ctx, store, "+254700000000", "", "baz.sarafu.eth", "0x41c188d63Qa")
+	require.NoError(t, err)
+	err = WriteAliasIndex(ctx, store, sessionId, "baz.sarafu.eth", "xyzzy.sarafu.eth", address)
+	require.NoError(t, err)
+	a, s, err = ResolveAlias(ctx, store, "baz.sarafu.eth")
+	require.NoError(t, err)
+	assert.Equal(t, "0x41c188d63Qa", a)
+	assert.Equal(t, "+254700000000", s)
+}
@@ -0,0 +1,137 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+// Outcome of an audited action.
+const (
+	AuditSuccess = "success"
+	AuditFailure = "failure"
+)
+
+// Audited actions.
+const (
+	AuditResetPin         = "reset_pin"
+	AuditGuardianResetPin = "guardian_reset_pin"
+	AuditRoleGrant        = "role_grant"
+	AuditRoleRevoke       = "role_revoke"
+	AuditUnblock          = "unblock"
+	AuditClearVoucher     = "clear_voucher"
+	AuditClearPool        = "clear_pool"
+	AuditResetLanguage    = "reset_language"
+	AuditAnonymize        = "anonymize"
+	AuditDelete           = "delete"
+	AuditExport           = "export"
+	AuditErase            = "erase"
+)
+
+// AuditRecord is a single entry of the audit log of actions changing the security state of other accounts.
+//
+// Actor is the session id of the account that performed the action, or the name of the operator
+// for actions made from the command line.
+type AuditRecord struct {
+	Id      string `json:"id"`
+	Time    int64  `json:"time"`
+	Actor   string `json:"actor"`
+	Target  string `json:"target"`
+	Action  string `json:"action"`
+	Outcome string `json:"outcome"`
+	Detail  string `json:"detail,omitempty"`
+}
+
+// AuditFilter selects entries of the audit log.
+//
+// Empty fields match all entries.
+type AuditFilter struct {
+	Actor  string
+	Target string
+	Since  time.Time
+	Until  time.Time
+}
+
+// Match returns true if the entry is selected by the filter.
+func (f AuditFilter) Match(r AuditRecord) bool {
+	if f.Actor != "" && r.Actor != f.Actor {
+		return false
+	}
+	if f.Target != "" && r.Target != f.Target {
+		return false
+	}
+	if !f.Since.IsZero() && r.Time < f.Since.Unix() {
+		return false
+	}
+	if !f.Until.IsZero() && r.Time >= f.Until.Unix() {
+		return false
+	}
+	return true
+}
+
+func auditKey(id string) storedb.Key {
+	return storedb.IndexKey(storedb.DATA_AUDIT_LOG, id)
+}
+
+// newAuditId returns a new id for an entry of the audit log appended at the given time.
+//
+// Ids sort in the order entries were appended. The random suffix keeps entries appended at the same
+// time, such as by the server and the command line tools, from sharing an id.
+func newAuditId(t time.Time) (string, error) {
+	b := make([]byte, 8)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%020d.%s", t.UnixNano(), hex.EncodeToString(b)), nil
+}
+
+// AppendAudit adds an entry to the end of the audit log.
+//
+// Entries are never changed once written. The id and, if not set, the time of the entry are assigned
+// on append, and the saved record is returned.
+//
+// Each entry is written once under its own id, so that appends need no lock and do not collide across
+// processes sharing the userdata store.
+func AppendAudit(ctx context.Context, store DataStore, record AuditRecord) (AuditRecord, error) {
+	now := time.Now()
+	id, err := newAuditId(now)
+	if err != nil {
+		return record, err
+	}
+	record.Id = id
+	if record.Time == 0 {
+		record.Time = now.Unix()
+	}
+	v, err := json.Marshal(record)
+	if err != nil {
+		return record, err
+	}
+	return record, store.Write(ctx, auditKey(id), v)
+}
+
+// ReadAudit retrieves the entries of the audit log selected by the filter, oldest first.
+func ReadAudit(ctx context.Context, store DataStore, filter AuditFilter) ([]AuditRecord, error) {
+	entries, err := readIndexEntries(ctx, store, storedb.DATA_AUDIT_LOG)
+	if err != nil {
+		return nil, err
+	}
+	r := []AuditRecord{}
+	for _, entry := range entries {
+		var record AuditRecord
+		err = json.Unmarshal(entry.value, &record)
+		if err != nil {
+			return nil, fmt.Errorf("invalid audit entry %s: %v", entry.id, err)
+		}
+		record.Id = entry.id
+		if filter.Match(record) {
+			r = append(r, record)
+		}
+	}
+	return r, nil
+}
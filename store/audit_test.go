@@ -0,0 +1,76 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAudit(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+
+	records, err := ReadAudit(ctx, store, AuditFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(records))
+
+	now := time.Now()
+	entries := []AuditRecord{
+		{Actor: "+254711111111", Target: "+254712345678", Action: AuditResetPin, Outcome: AuditSuccess, Time: now.Add(-48 * time.Hour).Unix()},
+		{Actor: "alice", Target: "+254712345678", Action: AuditRoleGrant, Outcome: AuditSuccess},
+		{Actor: "+254711111111", Target: "+254722222222", Action: AuditResetPin, Outcome: AuditFailure},
+	}
+	var ids []string
+	for _, v := range entries {
+		r, err := AppendAudit(ctx, store, v)
+		require.NoError(t, err)
+		assert.NotEqual(t, "", r.Id)
+		assert.NotEqual(t, int64(0), r.Time)
+		ids = append(ids, r.Id)
+	}
+
+	records, err = ReadAudit(ctx, store, AuditFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 3, len(records))
+	assert.Equal(t, AuditRoleGrant, records[1].Action)
+
+	records, err = ReadAudit(ctx, store, AuditFilter{Actor: "+254711111111"})
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(records))
+	assert.Equal(t, ids[0], records[0].Id)
+	assert.Equal(t, ids[2], records[1].Id)
+
+	records, err = ReadAudit(ctx, store, AuditFilter{Target: "+254712345678", Since: now.Add(-time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, "alice", records[0].Actor)
+
+	records, err = ReadAudit(ctx, store, AuditFilter{Until: now.Add(-time.Hour)})
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(records))
+	assert.Equal(t, ids[0], records[0].Id)
+
+	// entries appended at the same time do not share an id
+	then := time.Now()
+	a, err := newAuditId(then)
+	require.NoError(t, err)
+	b, err := newAuditId(then)
+	require.NoError(t, err)
+	assert.NotEqual(t, a, b)
+	assert.True(t, ids[2] < a)
+
+	// entries appended by other processes are read in append order
+	err = store.Write(ctx, storedb.IndexKey(storedb.DATA_AUDIT_LOG, a), []byte(`{"actor":"bob"}`))
+	require.NoError(t, err)
+	r, err := AppendAudit(ctx, store, AuditRecord{Actor: "carol"})
+	require.NoError(t, err)
+	records, err = ReadAudit(ctx, store, AuditFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, 5, len(records))
+	assert.Equal(t, "bob", records[3].Actor)
+	assert.Equal(t, a, records[3].Id)
+	assert.Equal(t, "carol", records[4].Actor)
+	assert.Equal(t, r.Id, records[4].Id)
+}
@@ -0,0 +1,66 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+// AuthorizationRecord is a menu node authorized by a PIN entry.
+type AuthorizationRecord struct {
+	Node string `json:"node"`
+	Time int64  `json:"time"`
+}
+
+// GrantAuthorization records a PIN entry of the account at the given menu node.
+//
+// Any earlier PIN entry and the actions it authorized are discarded.
+func GrantAuthorization(ctx context.Context, store DataStore, sessionId string, node string) error {
+	records := []AuthorizationRecord{
+		{
+			Node: node,
+			Time: time.Now().Unix(),
+		},
+	}
+	return writeRecordList(ctx, store, sessionId, storedb.DATA_AUTHORIZATION, records)
+}
+
+// RevokeAuthorization discards the most recent PIN entry of the account, so that the next action asks for the PIN.
+func RevokeAuthorization(ctx context.Context, store DataStore, sessionId string) error {
+	return writeRecordList(ctx, store, sessionId, storedb.DATA_AUTHORIZATION, []AuthorizationRecord{})
+}
+
+// UseAuthorization authorizes the given menu node with the most recent PIN entry of the account.
+//
+// The PIN entry is valid for the given lifetime, and for the given number of actions, where 0 means no limit.
+// Repeated use by the same node counts as a single action.
+//
+// Returns false if there is no valid PIN entry, in which case nothing is recorded.
+func UseAuthorization(ctx context.Context, store DataStore, sessionId string, node string, lifetime time.Duration, actions uint) (bool, error) {
+	records, err := readRecordList[AuthorizationRecord](ctx, store, sessionId, storedb.DATA_AUTHORIZATION)
+	if err != nil {
+		return false, err
+	}
+	if len(records) == 0 {
+		return false, nil
+	}
+	if time.Since(time.Unix(records[0].Time, 0)) >= lifetime {
+		return false, nil
+	}
+	if records[len(records)-1].Node == node {
+		return true, nil
+	}
+	if actions > 0 && uint(len(records)-1) >= actions {
+		return false, nil
+	}
+	records = append(records, AuthorizationRecord{
+		Node: node,
+		Time: time.Now().Unix(),
+	})
+	err = writeRecordList(ctx, store, sessionId, storedb.DATA_AUTHORIZATION, records)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUseAuthorization(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+
+	ok, err := UseAuthorization(ctx, store, sessionId, "view_profile", time.Minute, 2)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	err = GrantAuthorization(ctx, store, sessionId, "pin_entry")
+	require.NoError(t, err)
+
+	ok, err = UseAuthorization(ctx, store, sessionId, "view_profile", time.Minute, 2)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// repeated use by the same node is a single action
+	ok, err = UseAuthorization(ctx, store, sessionId, "view_profile", time.Minute, 2)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = UseAuthorization(ctx, store, sessionId, "change_language", time.Minute, 2)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = UseAuthorization(ctx, store, sessionId, "my_account_alias", time.Minute, 2)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// no limit on the number of actions
+	ok, err = UseAuthorization(ctx, store, sessionId, "my_account_alias", time.Minute, 0)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// expired
+	ok, err = UseAuthorization(ctx, store, sessionId, "view_profile", 0, 0)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	err = RevokeAuthorization(ctx, store, sessionId)
+	require.NoError(t, err)
+	ok, err = UseAuthorization(ctx, store, sessionId, "view_profile", time.Minute, 0)
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,132 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	visedb "git.defalsify.org/vise.git/db"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+// batchEntry records the value of an entry before it was changed by a batch.
+type batchEntry struct {
+	key   storedb.Key
+	value []byte
+}
+
+// Batch groups several userdata entry writes so that they are applied together.
+//
+// The writes are made inside a database transaction where the backend supports it
+// (postgres), in which case Commit and Rollback map directly onto the transaction.
+//
+// Backends without transactions (gdbm, fs) are handled best-effort: the previous
+// value of each entry is recorded before it is overwritten, and restored in reverse
+// order on Rollback. Entries that did not exist before the batch are reset to an
+// empty value, as the underlying store has no delete operation.
+type Batch struct {
+	store DataStore
+	undo  []batchEntry
+	tx    bool
+	done  bool
+}
+
+// NewBatch starts a new batch of writes on the given store.
+//
+// If a transaction cannot be started, for example because one is already active
+// on the connection, the batch proceeds with the writes joining the current
+// transaction state and relies on undo for rollback.
+func NewBatch(ctx context.Context, store DataStore) *Batch {
+	b := &Batch{
+		store: store,
+	}
+	err := store.Start(ctx)
+	if err != nil {
+		logg.DebugCtxf(ctx, "batch continues without new transaction", "error", err)
+	} else {
+		b.tx = true
+	}
+	return b
+}
+
+// Put writes an entry as part of the batch.
+func (b *Batch) Put(ctx context.Context, key storedb.Key, value []byte) error {
+	if b.done {
+		return fmt.Errorf("batch already closed")
+	}
+	entry := batchEntry{
+		key: key,
+	}
+	v, err := b.store.Read(ctx, key)
+	if err == nil {
+		entry.value = v
+	} else if !visedb.IsNotFound(err) {
+		return err
+	}
+	err = b.store.Write(ctx, key, value)
+	if err != nil {
+		return err
+	}
+	b.undo = append(b.undo, entry)
+	return nil
+}
+
+// Commit makes all writes of the batch permanent.
+func (b *Batch) Commit(ctx context.Context) error {
+	if b.done {
+		return fmt.Errorf("batch already closed")
+	}
+	b.done = true
+	if !b.tx {
+		return nil
+	}
+	return b.store.Stop(ctx)
+}
+
+// Rollback reverts all writes made in the batch.
+//
+// It returns the first error encountered when restoring previous values.
+func (b *Batch) Rollback(ctx context.Context) error {
+	var rerr error
+	if b.done {
+		return nil
+	}
+	b.done = true
+	for i := len(b.undo) - 1; i >= 0; i-- {
+		entry := b.undo[i]
+		err := b.store.Write(ctx, entry.key, entry.value)
+		if err != nil {
+			logg.ErrorCtxf(ctx, "failed to restore entry on batch rollback", "key", entry.key, "error", err)
+			if rerr == nil {
+				rerr = err
+			}
+		}
+	}
+	if b.tx {
+		b.store.Abort(ctx)
+	}
+	return rerr
+}
+
+// WriteEntries writes several entries for the same session as a single batch.
+//
+// Either all entries are written, or none are.
+func WriteEntries(ctx context.Context, store DataStore, sessionId string, entries map[storedb.DataTyp][]byte) error {
+	keys := make([]storedb.DataTyp, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i] < keys[j]
+	})
+
+	b := store.Begin(ctx)
+	for _, k := range keys {
+		err := b.Put(ctx, storedb.EntryKey(sessionId, k), entries[k])
+		if err != nil {
+			b.Rollback(ctx)
+			return err
+		}
+	}
+	return b.Commit(ctx)
+}
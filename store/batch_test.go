@@ -0,0 +1,120 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+
+	visedb "git.defalsify.org/vise.git/db"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+func TestBatchCommit(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "session123"
+
+	b := store.Begin(ctx)
+	require.NoError(t, b.Put(ctx, storedb.AccountKey(sessionId, storedb.DATA_ACTIVE_SYM), []byte("SRF")))
+	require.NoError(t, b.Put(ctx, storedb.AccountKey(sessionId, storedb.DATA_ACTIVE_ADDRESS), []byte("0xd4c288865Ce")))
+	require.NoError(t, b.Put(ctx, storedb.IndexKey(storedb.DATA_PUBLIC_KEY_REVERSE, "0xd4c288865ce"), []byte(sessionId)))
+	require.NoError(t, b.Commit(ctx))
+
+	v, err := store.ReadEntry(ctx, sessionId, storedb.DATA_ACTIVE_SYM)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("SRF"), v)
+	v, err = store.ReadEntry(ctx, sessionId, storedb.DATA_ACTIVE_ADDRESS)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("0xd4c288865Ce"), v)
+	v, err = store.ReadIndex(ctx, storedb.DATA_PUBLIC_KEY_REVERSE, "0xd4c288865ce")
+	require.NoError(t, err)
+	assert.Equal(t, []byte(sessionId), v)
+
+	assert.Error(t, b.Put(ctx, storedb.AccountKey(sessionId, storedb.DATA_ACTIVE_SYM), []byte("FOO")))
+	assert.Error(t, b.Commit(ctx))
+}
+
+func TestBatchRollback(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "session123"
+
+	err := store.WriteEntry(ctx, sessionId, storedb.DATA_ACTIVE_SYM, []byte("SRF"))
+	require.NoError(t, err)
+
+	b := store.Begin(ctx)
+	require.NoError(t, b.Put(ctx, storedb.AccountKey(sessionId, storedb.DATA_ACTIVE_SYM), []byte("MILO")))
+	require.NoError(t, b.Put(ctx, storedb.AccountKey(sessionId, storedb.DATA_ACTIVE_SYM), []byte("BAR")))
+	require.NoError(t, b.Put(ctx, storedb.AccountKey(sessionId, storedb.DATA_ACTIVE_ADDRESS), []byte("0x41c188d63Qa")))
+	require.NoError(t, b.Rollback(ctx))
+
+	v, err := store.ReadEntry(ctx, sessionId, storedb.DATA_ACTIVE_SYM)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("SRF"), v)
+
+	// entries that did not exist before are either gone or reset to empty,
+	// depending on whether the backend supports transactions.
+	v, err = store.ReadEntry(ctx, sessionId, storedb.DATA_ACTIVE_ADDRESS)
+	if err != nil {
+		assert.True(t, visedb.IsNotFound(err))
+	} else {
+		assert.Equal(t, 0, len(v))
+	}
+}
+
+func TestWriteEntries(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "session123"
+
+	entries := map[storedb.DataTyp][]byte{
+		storedb.DATA_ACTIVE_POOL_NAME:    []byte("Kenya ROLA Pool"),
+		storedb.DATA_ACTIVE_POOL_SYM:     []byte("ROLA"),
+		storedb.DATA_ACTIVE_POOL_ADDRESS: []byte("0x48a953cA5cf5298bc6f6Af3C608351f537AAcb9e"),
+	}
+	err := WriteEntries(ctx, store, sessionId, entries)
+	require.NoError(t, err)
+
+	for k, expected := range entries {
+		v, err := store.ReadEntry(ctx, sessionId, k)
+		require.NoError(t, err)
+		assert.Equal(t, expected, v)
+	}
+}
+
+func TestBatchRollbackReadList(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "session123"
+
+	// legacy format entries of an account not yet migrated to record lists
+	err := store.WriteEntry(ctx, sessionId, storedb.DATA_VOUCHER_SYMBOLS, []byte("1:SRF"))
+	require.NoError(t, err)
+	err = store.WriteEntry(ctx, sessionId, storedb.DATA_VOUCHER_BALANCES, []byte("1:100"))
+	require.NoError(t, err)
+	err = store.WriteEntry(ctx, sessionId, storedb.DATA_VOUCHER_DECIMALS, []byte("1:6"))
+	require.NoError(t, err)
+	err = store.WriteEntry(ctx, sessionId, storedb.DATA_VOUCHER_ADDRESSES, []byte("1:0xd4c288865Ce"))
+	require.NoError(t, err)
+
+	vouchers, err := EncodeList([]VoucherRecord{
+		{Symbol: "MILO", Balance: "200", Decimals: "4", Address: "0x41c188d63Qa"},
+	})
+	require.NoError(t, err)
+	pools, err := EncodeList([]PoolRecord{
+		{Name: "Kenya ROLA Pool", Symbol: "ROLA", Address: "0x48a953cA5cf5298bc6f6Af3C608351f537AAcb9e"},
+	})
+	require.NoError(t, err)
+
+	b := store.Begin(ctx)
+	require.NoError(t, b.Put(ctx, storedb.AccountKey(sessionId, storedb.DATA_VOUCHER_LIST), vouchers))
+	require.NoError(t, b.Put(ctx, storedb.AccountKey(sessionId, storedb.DATA_POOL_LIST), pools))
+	require.NoError(t, b.Rollback(ctx))
+
+	voucherList, err := ReadVoucherList(ctx, store, sessionId, storedb.DATA_VOUCHER_LIST)
+	require.NoError(t, err)
+	assert.Equal(t, []VoucherRecord{
+		{Symbol: "SRF", Balance: "100", Decimals: "6", Address: "0xd4c288865Ce"},
+	}, voucherList)
+
+	poolList, err := ReadPoolList(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(poolList))
+}
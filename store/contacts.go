@@ -0,0 +1,161 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	visedb "git.defalsify.org/vise.git/db"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+const (
+	// maximum number of saved contacts per account.
+	contactsSize = 5
+	// maximum length of the name of a saved contact.
+	ContactNameMax = 12
+	// number of recent recipients remembered per account.
+	recentRecipientsSize = 5
+	// maximum number of recipients offered for selection when sending.
+	sendContactsSize = 5
+)
+
+var (
+	// ErrContactsFull is returned when adding a contact to a full address book.
+	ErrContactsFull = errors.New("address book is full")
+)
+
+// ContactRecord is a single entry in the address book of an account.
+//
+// The recipient is stored as given by the user, and may be a phone number, address or alias.
+type ContactRecord struct {
+	Name      string `json:"name"`
+	Recipient string `json:"recipient"`
+}
+
+func readRecordList[T any](ctx context.Context, store DataStore, sessionId string, key storedb.DataTyp) ([]T, error) {
+	v, err := store.ReadEntry(ctx, sessionId, key)
+	if err != nil {
+		if visedb.IsNotFound(err) {
+			return []T{}, nil
+		}
+		return nil, err
+	}
+	if len(v) == 0 {
+		return []T{}, nil
+	}
+	return DecodeList[T](v)
+}
+
+func writeRecordList[T any](ctx context.Context, store DataStore, sessionId string, key storedb.DataTyp, items []T) error {
+	v, err := EncodeList(items)
+	if err != nil {
+		return err
+	}
+	return store.WriteEntry(ctx, sessionId, key, v)
+}
+
+// ReadContacts retrieves the address book of the account.
+func ReadContacts(ctx context.Context, store DataStore, sessionId string) ([]ContactRecord, error) {
+	return readRecordList[ContactRecord](ctx, store, sessionId, storedb.DATA_CONTACTS)
+}
+
+// AddContact saves a recipient under the given name in the address book of the account.
+//
+// If the recipient is already saved, its name is changed instead.
+func AddContact(ctx context.Context, store DataStore, sessionId string, contact ContactRecord) error {
+	contacts, err := ReadContacts(ctx, store, sessionId)
+	if err != nil {
+		return err
+	}
+	for i, v := range contacts {
+		if strings.EqualFold(v.Recipient, contact.Recipient) {
+			contacts[i].Name = contact.Name
+			return writeRecordList(ctx, store, sessionId, storedb.DATA_CONTACTS, contacts)
+		}
+	}
+	if len(contacts) >= contactsSize {
+		return ErrContactsFull
+	}
+	contacts = append(contacts, contact)
+	return writeRecordList(ctx, store, sessionId, storedb.DATA_CONTACTS, contacts)
+}
+
+// RenameContact changes the name of the contact at the given 0-based index of the address book.
+func RenameContact(ctx context.Context, store DataStore, sessionId string, index int, name string) error {
+	contacts, err := ReadContacts(ctx, store, sessionId)
+	if err != nil {
+		return err
+	}
+	if index < 0 || index >= len(contacts) {
+		return fmt.Errorf("contact not found: index %d out of range", index)
+	}
+	contacts[index].Name = name
+	return writeRecordList(ctx, store, sessionId, storedb.DATA_CONTACTS, contacts)
+}
+
+// DeleteContact removes the contact at the given 0-based index of the address book.
+func DeleteContact(ctx context.Context, store DataStore, sessionId string, index int) error {
+	contacts, err := ReadContacts(ctx, store, sessionId)
+	if err != nil {
+		return err
+	}
+	if index < 0 || index >= len(contacts) {
+		return fmt.Errorf("contact not found: index %d out of range", index)
+	}
+	contacts = append(contacts[:index], contacts[index+1:]...)
+	return writeRecordList(ctx, store, sessionId, storedb.DATA_CONTACTS, contacts)
+}
+
+// ReadRecentRecipients retrieves the recipients of the most recent transfers of the account, newest first.
+func ReadRecentRecipients(ctx context.Context, store DataStore, sessionId string) ([]string, error) {
+	return readRecordList[string](ctx, store, sessionId, storedb.DATA_RECENT_RECIPIENTS)
+}
+
+// AddRecentRecipient records the recipient of a successful transfer as the most recent.
+func AddRecentRecipient(ctx context.Context, store DataStore, sessionId string, recipient string) error {
+	recipients, err := ReadRecentRecipients(ctx, store, sessionId)
+	if err != nil {
+		return err
+	}
+	r := []string{recipient}
+	for _, v := range recipients {
+		if !strings.EqualFold(v, recipient) && len(r) < recentRecipientsSize {
+			r = append(r, v)
+		}
+	}
+	return writeRecordList(ctx, store, sessionId, storedb.DATA_RECENT_RECIPIENTS, r)
+}
+
+// SendContacts returns the saved contacts followed by the recent recipients not among them,
+// as offered for selection when sending.
+//
+// At most sendContactsSize entries are returned. Recent recipients are returned without name.
+func SendContacts(ctx context.Context, store DataStore, sessionId string) ([]ContactRecord, error) {
+	contacts, err := ReadContacts(ctx, store, sessionId)
+	if err != nil {
+		return nil, err
+	}
+	recipients, err := ReadRecentRecipients(ctx, store, sessionId)
+	if err != nil {
+		return nil, err
+	}
+	r := contacts
+	for _, recipient := range recipients {
+		saved := false
+		for _, v := range contacts {
+			if strings.EqualFold(v.Recipient, recipient) {
+				saved = true
+				break
+			}
+		}
+		if !saved {
+			r = append(r, ContactRecord{Recipient: recipient})
+		}
+	}
+	if len(r) > sendContactsSize {
+		r = r[:sendContactsSize]
+	}
+	return r, nil
+}
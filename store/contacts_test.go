@@ -0,0 +1,72 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContacts(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+
+	contacts, err := ReadContacts(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(contacts))
+
+	for i := 0; i < contactsSize; i++ {
+		err = AddContact(ctx, store, sessionId, ContactRecord{Name: fmt.Sprintf("foo%d", i), Recipient: fmt.Sprintf("07%08d", i)})
+		require.NoError(t, err)
+	}
+	err = AddContact(ctx, store, sessionId, ContactRecord{Name: "bar", Recipient: "0799999999"})
+	assert.Equal(t, ErrContactsFull, err)
+
+	// a saved recipient is renamed rather than added
+	err = AddContact(ctx, store, sessionId, ContactRecord{Name: "bar", Recipient: "0700000001"})
+	require.NoError(t, err)
+
+	err = RenameContact(ctx, store, sessionId, 0, "baz")
+	require.NoError(t, err)
+	err = DeleteContact(ctx, store, sessionId, 2)
+	require.NoError(t, err)
+	err = DeleteContact(ctx, store, sessionId, contactsSize)
+	assert.Error(t, err)
+
+	contacts, err = ReadContacts(ctx, store, sessionId)
+	require.NoError(t, err)
+	require.Equal(t, contactsSize-1, len(contacts))
+	assert.Equal(t, ContactRecord{Name: "baz", Recipient: "0700000000"}, contacts[0])
+	assert.Equal(t, ContactRecord{Name: "bar", Recipient: "0700000001"}, contacts[1])
+	assert.Equal(t, ContactRecord{Name: "foo3", Recipient: "0700000003"}, contacts[2])
+}
+
+func TestSendContacts(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+
+	for _, v := range []string{"alice", "bob", "0700000001", "alice", "carol", "dave", "erin"} {
+		err := AddRecentRecipient(ctx, store, sessionId, v)
+		require.NoError(t, err)
+	}
+	recipients, err := ReadRecentRecipients(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"erin", "dave", "carol", "alice", "0700000001"}, recipients)
+
+	err = AddContact(ctx, store, sessionId, ContactRecord{Name: "Jane", Recipient: "0700000001"})
+	require.NoError(t, err)
+	err = AddContact(ctx, store, sessionId, ContactRecord{Name: "Carol", Recipient: "CAROL"})
+	require.NoError(t, err)
+
+	// saved contacts come first, and recent recipients are not repeated
+	contacts, err := SendContacts(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, []ContactRecord{
+		{Name: "Jane", Recipient: "0700000001"},
+		{Name: "Carol", Recipient: "CAROL"},
+		{Recipient: "erin"},
+		{Recipient: "dave"},
+		{Recipient: "alice"},
+	}, contacts)
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"git.grassecon.net/grassrootseconomics/common/hex"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+// CounterpartyLabel returns a readable label for the account with the given address.
+//
+// For a registered account, the label is the profile name or alias of the account followed
+// by its masked phone number, e.g. "Jane M. (0712***678)". Otherwise the shortened address
+// is returned.
+func CounterpartyLabel(ctx context.Context, store DataStore, address string) string {
+	publicKeyNormalized, err := hex.NormalizeHex(address)
+	if err != nil {
+		return ShortenAddress(address)
+	}
+	sessionId, err := store.ReadIndex(ctx, storedb.DATA_PUBLIC_KEY_REVERSE, publicKeyNormalized)
+	if err != nil || len(sessionId) == 0 {
+		return ShortenAddress(address)
+	}
+	phoneNumber := MaskPhoneNumber(string(sessionId))
+
+	name := profileName(ctx, store, string(sessionId))
+	if name == "" {
+		alias, err := store.ReadEntry(ctx, string(sessionId), storedb.DATA_ACCOUNT_ALIAS)
+		if err == nil {
+			name = string(alias)
+		}
+	}
+	if name == "" {
+		return phoneNumber
+	}
+	return name + " (" + phoneNumber + ")"
+}
+
+// profileName returns the first name and family name initial of the account, e.g. "Jane M.".
+func profileName(ctx context.Context, store DataStore, sessionId string) string {
+	firstName, err := store.ReadEntry(ctx, sessionId, storedb.DATA_FIRST_NAME)
+	if err != nil || len(firstName) == 0 {
+		return ""
+	}
+	name := string(firstName)
+	familyName, err := store.ReadEntry(ctx, sessionId, storedb.DATA_FAMILY_NAME)
+	if err == nil && len(familyName) > 0 {
+		initial, _ := utf8.DecodeRune(familyName)
+		name += " " + strings.ToUpper(string(initial)) + "."
+	}
+	return name
+}
+
+// MaskPhoneNumber returns the phone number in local format, with all but the
+// first four and last three digits hidden, e.g. "0712***678".
+func MaskPhoneNumber(phoneNumber string) string {
+	if strings.HasPrefix(phoneNumber, "+254") {
+		phoneNumber = "0" + phoneNumber[4:]
+	}
+	if len(phoneNumber) < 8 {
+		return phoneNumber
+	}
+	return phoneNumber[:4] + "***" + phoneNumber[len(phoneNumber)-3:]
+}
+
+// ShortenAddress returns the address with its middle part elided, e.g. "0x41c1...63Qa".
+func ShortenAddress(address string) string {
+	if len(address) <= 13 {
+		return address
+	}
+	return address[:6] + "..." + address[len(address)-4:]
+}
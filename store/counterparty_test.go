@@ -0,0 +1,42 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+func TestCounterpartyLabel(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+	address := "0xd4c288865Ce0985a481Eef3be02443dF5E2e4Ea9"
+
+	// unknown accounts are shown by their address
+	assert.Equal(t, "0xd4c2...4Ea9", CounterpartyLabel(ctx, store, address))
+	assert.Equal(t, "foo", CounterpartyLabel(ctx, store, "foo"))
+
+	err := store.WriteIndex(ctx, storedb.DATA_PUBLIC_KEY_REVERSE, "d4c288865ce0985a481eef3be02443df5e2e4ea9", []byte(sessionId))
+	require.NoError(t, err)
+	assert.Equal(t, "0712***678", CounterpartyLabel(ctx, store, address))
+
+	err = store.WriteEntry(ctx, sessionId, storedb.DATA_ACCOUNT_ALIAS, []byte("jane.sarafu.eth"))
+	require.NoError(t, err)
+	assert.Equal(t, "jane.sarafu.eth (0712***678)", CounterpartyLabel(ctx, store, address))
+
+	err = store.WriteEntry(ctx, sessionId, storedb.DATA_FIRST_NAME, []byte("Jane"))
+	require.NoError(t, err)
+	assert.Equal(t, "Jane (0712***678)", CounterpartyLabel(ctx, store, address))
+
+	err = store.WriteEntry(ctx, sessionId, storedb.DATA_FAMILY_NAME, []byte("mwangi"))
+	require.NoError(t, err)
+	assert.Equal(t, "Jane M. (0712***678)", CounterpartyLabel(ctx, store, address))
+}
+
+func TestMaskPhoneNumber(t *testing.T) {
+	assert.Equal(t, "0712***678", MaskPhoneNumber("+254712345678"))
+	assert.Equal(t, "0712***678", MaskPhoneNumber("0712345678"))
+	assert.Equal(t, "12345", MaskPhoneNumber("12345"))
+}
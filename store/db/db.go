@@ -9,13 +9,15 @@ import (
 
 // DataType is a subprefix value used in association with vise/db.DATATYPE_USERDATA.
 //
-// All keys are used only within the context of a single account. Unless otherwise specified, the user context is the session id.
+// Keys are scoped as described by Scope. Account and menu keys are used only within the context of
+// a single account, where the user context is the session id. Index keys are global.
 //
 // * The first byte is vise/db.DATATYPE_USERDATA
 // * The last 2 bytes are the DataTyp value, big-endian.
 // * The intermediate bytes are the id of the user context.
 //
-// All values are strings
+// Values are strings, except for lists of records, which are stored as versioned JSON
+// (see store.EncodeList).
 type DataTyp uint16
 
 const (
@@ -101,6 +103,82 @@ const (
 	DATA_RECIPIENT_INPUT
 	// Holds the transaction voucher
 	DATA_TRANSACTION_CUSTOM_VOUCHER
+	// Reverse mapping of a fully qualified account alias to a session id.
+	DATA_ALIAS_REVERSE
+	// Mapping of a fully qualified account alias to the checksum address of the account.
+	DATA_ALIAS_ADDRESS
+	// Random nonce identifying the transaction intent shown in the current preview.
+	DATA_TRANSACTION_NONCE
+	// Versioned record list of recently submitted transaction intents.
+	DATA_INTENT_LIST
+	// Versioned record list of submitted transactions and their confirmation status.
+	DATA_TRANSACTION_LEDGER
+	// Unix timestamp of the activation of the custodial account, as reported by the registration event.
+	DATA_ACCOUNT_ACTIVATED
+	// Filter applied to the transaction statement being browsed.
+	DATA_STATEMENT_FILTER
+	// Page of the transaction statement being browsed.
+	DATA_STATEMENT_PAGE
+	// Versioned record list of the unix timestamps of the statements recently sent by SMS.
+	DATA_STATEMENT_SMS_LOG
+	// Index in the filtered statement of the transaction being viewed.
+	DATA_STATEMENT_SELECTED
+	// Versioned record list of the saved contacts of the account.
+	DATA_CONTACTS
+	// Versioned record list of the recipients of the most recent transfers, newest first.
+	DATA_RECENT_RECIPIENTS
+	// Index of the saved contact being edited.
+	DATA_CONTACT_SELECTED
+	// Versioned record list of the scheduled transfers of the account.
+	DATA_SCHEDULES
+	// Repeat interval of the scheduled transfer being set up.
+	DATA_SCHEDULE_INTERVAL
+	// Index of the scheduled transfer being viewed.
+	DATA_SCHEDULE_SELECTED
+	// Versioned record list of the session ids of all accounts with scheduled transfers.
+	DATA_SCHEDULED_SESSIONS
+	// Versioned record list of the open payment requests received by the account.
+	DATA_PAYMENT_REQUESTS
+	// Versioned record list of the unix timestamps of the payment requests recently sent by the account.
+	DATA_PAYMENT_REQUEST_LOG
+	// Id of the payment request being viewed.
+	DATA_PAYMENT_REQUEST_SELECTED
+	// Versioned record list of the contact groups of the account.
+	DATA_CONTACT_GROUPS
+	// Index of the contact group being edited.
+	DATA_CONTACT_GROUP_SELECTED
+	// Versioned record list of the legs of the multi-recipient transfer being set up.
+	DATA_MULTI_SEND_LEGS
+	// Versioned record list of the most recently submitted multi-recipient transfers and the results of their legs.
+	DATA_MULTI_SENDS
+	// Versioned record list of the voucher amounts spent by the account within the daily spending window.
+	DATA_SPEND_LOG
+	// Symbol of the voucher whose spending limit was reached.
+	DATA_SPEND_LIMIT_SYMBOL
+	// Versioned record list of the most recent PIN entry of the account and the actions it authorized since.
+	DATA_AUTHORIZATION
+	// Number of times the account has been locked out after too many incorrect PIN attempts.
+	DATA_PIN_LOCKOUTS
+	// Unix timestamp at which the current temporary PIN lockout of the account ends.
+	DATA_PIN_LOCKOUT_EXPIRY
+	// Versioned record list of the guardians nominated by the account to approve the recovery of its PIN.
+	DATA_GUARDIANS
+	// Session id of the guardian being added or removed.
+	DATA_GUARDIAN_SELECTED
+	// Versioned record list holding the open PIN recovery request of the account, if any.
+	DATA_RECOVERY
+	// Versioned record list of the open PIN recovery requests received by the account as a guardian.
+	DATA_RECOVERY_REQUESTS
+	// Id of the PIN recovery request being approved.
+	DATA_RECOVERY_SELECTED
+	// Entry of the append-only audit log of admin actions, indexed by the time it was appended and a random suffix.
+	DATA_AUDIT_LOG
+	// Versioned record list of the admin roles granted to the account.
+	DATA_ROLES
+	// Pending request of an account for the export or erasure of its data, indexed by the session id and the kind of request.
+	DATA_PRIVACY_REQUESTS
+	// Token address of the voucher whose spending limit was reached.
+	DATA_SPEND_LIMIT_ADDRESS
 )
 
 const (
@@ -120,6 +198,10 @@ const (
 	DATA_ORDERED_VOUCHER_DECIMALS
 	// List of ordered voucher EVM addresses in the user context.
 	DATA_ORDERED_VOUCHER_ADDRESSES
+	// Versioned record list of vouchers valid in the user context.
+	DATA_VOUCHER_LIST
+	// Versioned record list of ordered vouchers in the user context.
+	DATA_ORDERED_VOUCHER_LIST
 )
 
 const (
@@ -139,10 +221,12 @@ const (
 	DATA_TX_SYMBOLS
 	// List of voucher decimal counts for valid transactions in the user context.
 	DATA_TX_DECIMALS
+	// Versioned record list of valid transactions in the user context.
+	DATA_TX_LIST
 )
 
 const (
-	// Token transfer list
+	// Versioned record list of the local transfer history of the account, newest first.
 	DATA_TRANSACTIONS = 1024 + iota
 )
 
@@ -169,6 +253,12 @@ const (
 	DATA_POOL_TO_DECIMALS
 	// List of swap to EVM addresses for vouchers valid in the pools context.
 	DATA_POOL_TO_ADDRESSES
+	// Versioned record list of pools in the top pools context.
+	DATA_POOL_LIST
+	// Versioned record list of swap from vouchers in the pools context.
+	DATA_POOL_FROM_LIST
+	// Versioned record list of swap to vouchers in the pools context.
+	DATA_POOL_TO_LIST
 )
 
 var (
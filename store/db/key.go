@@ -0,0 +1,182 @@
+package db
+
+import (
+	"sort"
+)
+
+// Scope is the context in which a userdata entry is valid.
+type Scope uint8
+
+const (
+	// Entries belonging to a single account, identified by its session id (the phone number).
+	SCOPE_ACCOUNT Scope = iota
+	// Global lookup indexes, mapping an external identifier (such as an address) to the session id of an account.
+	SCOPE_INDEX
+	// Short-lived entries of an ongoing menu interaction of an account.
+	SCOPE_MENU
+)
+
+const (
+	// First byte of a menu scope key, following the session id.
+	//
+	// Account scope keys start with the high byte of the DataTyp, which never takes this value.
+	KEY_MARKER_MENU = 0xfe
+	// First byte of an index scope key.
+	//
+	// Index keys are stored without a session id.
+	KEY_MARKER_INDEX = 0xff
+)
+
+var (
+	indexTyps = map[DataTyp]bool{
+		DATA_PUBLIC_KEY_REVERSE: true,
+		DATA_ALIAS_REVERSE:      true,
+		DATA_ALIAS_ADDRESS:      true,
+		DATA_SCHEDULED_SESSIONS: true,
+		DATA_AUDIT_LOG:          true,
+		DATA_PRIVACY_REQUESTS:   true,
+	}
+	menuTyps = map[DataTyp]bool{
+		DATA_RECIPIENT:                        true,
+		DATA_AMOUNT:                           true,
+		DATA_TEMPORARY_VALUE:                  true,
+		DATA_SUGGESTED_ALIAS:                  true,
+		DATA_ACTIVE_SWAP_MAX_AMOUNT:           true,
+		DATA_ACTIVE_SWAP_AMOUNT:               true,
+		DATA_SEND_TRANSACTION_TYPE:            true,
+		DATA_RECIPIENT_PHONE_NUMBER:           true,
+		DATA_TRANSACTION_CUSTOM_VOUCHER_STATE: true,
+		DATA_RECIPIENT_INPUT:                  true,
+		DATA_TRANSACTION_CUSTOM_VOUCHER:       true,
+		DATA_TRANSACTION_NONCE:                true,
+		DATA_STATEMENT_FILTER:                 true,
+		DATA_STATEMENT_PAGE:                   true,
+		DATA_STATEMENT_SELECTED:               true,
+		DATA_CONTACT_SELECTED:                 true,
+		DATA_SCHEDULE_INTERVAL:                true,
+		DATA_SCHEDULE_SELECTED:                true,
+		DATA_PAYMENT_REQUEST_SELECTED:         true,
+		DATA_CONTACT_GROUP_SELECTED:           true,
+		DATA_MULTI_SEND_LEGS:                  true,
+		DATA_SPEND_LIMIT_SYMBOL:               true,
+		DATA_SPEND_LIMIT_ADDRESS:              true,
+		DATA_AUTHORIZATION:                    true,
+		DATA_GUARDIAN_SELECTED:                true,
+		DATA_RECOVERY_SELECTED:                true,
+	}
+)
+
+// Key identifies a single entry in the userdata store.
+//
+// Account and menu scope keys are resolved within the session of the account,
+// while index scope keys are global.
+type Key struct {
+	Scope Scope
+	Typ   DataTyp
+	// Session id of the account for account and menu scope, the indexed value for index scope.
+	Id string
+}
+
+// AccountKey returns the key of an entry belonging to the account of the given session.
+func AccountKey(sessionId string, typ DataTyp) Key {
+	return Key{
+		Scope: SCOPE_ACCOUNT,
+		Typ:   typ,
+		Id:    sessionId,
+	}
+}
+
+// MenuKey returns the key of a short-lived menu entry of the account of the given session.
+func MenuKey(sessionId string, typ DataTyp) Key {
+	return Key{
+		Scope: SCOPE_MENU,
+		Typ:   typ,
+		Id:    sessionId,
+	}
+}
+
+// IndexKey returns the key of the global index entry of the given type for the given value.
+func IndexKey(typ DataTyp, value string) Key {
+	return Key{
+		Scope: SCOPE_INDEX,
+		Typ:   typ,
+		Id:    value,
+	}
+}
+
+// EntryKey returns the key of an entry of the account of the given session, in the scope of the entry type.
+func EntryKey(sessionId string, typ DataTyp) Key {
+	if menuTyps[typ] {
+		return MenuKey(sessionId, typ)
+	}
+	return AccountKey(sessionId, typ)
+}
+
+// IsIndexTyp returns true if entries of the given type are global index entries.
+func IsIndexTyp(typ DataTyp) bool {
+	return indexTyps[typ]
+}
+
+// IsMenuTyp returns true if entries of the given type are short-lived menu entries.
+func IsMenuTyp(typ DataTyp) bool {
+	return menuTyps[typ]
+}
+
+// DataTyps returns all entry types, in ascending order.
+//
+// The ranges must be extended when types are added.
+func DataTyps() []DataTyp {
+	ranges := [][2]DataTyp{
+		{DATA_TRACKING_ID, DATA_SPEND_LIMIT_ADDRESS},
+		{DATA_VOUCHER_SYMBOLS, DATA_ORDERED_VOUCHER_LIST},
+		{DATA_TX_SENDERS, DATA_TX_LIST},
+		{DATA_TRANSACTIONS, DATA_TRANSACTIONS},
+		{DATA_POOL_NAMES, DATA_POOL_TO_LIST},
+	}
+	var r []DataTyp
+	for _, v := range ranges {
+		for typ := v[0]; typ <= v[1]; typ++ {
+			r = append(r, typ)
+		}
+	}
+	return r
+}
+
+// MenuTyps returns all entry types stored in menu scope.
+func MenuTyps() []DataTyp {
+	var r []DataTyp
+	for k := range menuTyps {
+		r = append(r, k)
+	}
+	sort.Slice(r, func(i, j int) bool {
+		return r[i] < r[j]
+	})
+	return r
+}
+
+// Session returns the session id to set on the store when resolving the key.
+func (k Key) Session() string {
+	if k.Scope == SCOPE_INDEX {
+		return ""
+	}
+	return k.Id
+}
+
+// Bytes returns the lookup key of the entry within its session.
+func (k Key) Bytes() []byte {
+	switch k.Scope {
+	case SCOPE_MENU:
+		return append([]byte{KEY_MARKER_MENU}, ToBytes(k.Typ)...)
+	case SCOPE_INDEX:
+		return append([]byte{KEY_MARKER_INDEX}, PackKey(k.Typ, []byte(k.Id))...)
+	}
+	return ToBytes(k.Typ)
+}
+
+// Legacy returns the key under which the entry was stored before scopes were introduced.
+//
+// Index entries used the indexed value in place of the session id, and menu entries
+// were stored as account entries.
+func (k Key) Legacy() Key {
+	return AccountKey(k.Id, k.Typ)
+}
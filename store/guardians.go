@@ -0,0 +1,240 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"slices"
+	"time"
+
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+const (
+	// maximum number of guardians per account.
+	GuardiansSize = 3
+	// time after which an unfinished recovery request expires.
+	RecoveryExpiry = 24 * time.Hour
+)
+
+var (
+	// ErrGuardiansFull is returned when adding a guardian to an account that has the maximum number.
+	ErrGuardiansFull = errors.New("too many guardians")
+	// ErrGuardianExists is returned when adding a guardian the account has already nominated.
+	ErrGuardianExists = errors.New("guardian already nominated")
+	// ErrGuardianNotFound is returned when removing an account that is not a guardian of the account.
+	ErrGuardianNotFound = errors.New("guardian not found")
+	// ErrRecoveryNotFound is returned when no open recovery request with the given id exists.
+	ErrRecoveryNotFound = errors.New("recovery request not found")
+)
+
+// GuardianRecord is an account nominated to approve the recovery of the PIN of another account.
+type GuardianRecord struct {
+	SessionId string `json:"session_id"`
+	Added     int64  `json:"added"`
+}
+
+// RecoveryRecord is a request of a blocked account for its guardians to approve the recovery of its PIN.
+//
+// Approvals holds the session ids of the guardians that have approved so far.
+type RecoveryRecord struct {
+	Id        string   `json:"id"`
+	Requester string   `json:"requester"`
+	Created   int64    `json:"created"`
+	Expires   int64    `json:"expires"`
+	Approvals []string `json:"approvals"`
+}
+
+// Expired returns true if the recovery request is no longer open at the given time.
+func (r RecoveryRecord) Expired(now time.Time) bool {
+	return r.Expires <= now.Unix()
+}
+
+// ReadGuardians retrieves the guardians nominated by the account, in the order they were added.
+func ReadGuardians(ctx context.Context, store DataStore, sessionId string) ([]GuardianRecord, error) {
+	return readRecordList[GuardianRecord](ctx, store, sessionId, storedb.DATA_GUARDIANS)
+}
+
+// IsGuardian returns true if the account with the given guardian session id is a guardian of the account.
+func IsGuardian(ctx context.Context, store DataStore, sessionId string, guardian string) (bool, error) {
+	guardians, err := ReadGuardians(ctx, store, sessionId)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range guardians {
+		if v.SessionId == guardian {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// AddGuardian nominates the account with the given guardian session id as a guardian of the account.
+func AddGuardian(ctx context.Context, store DataStore, sessionId string, guardian string) error {
+	return updateRecordList(ctx, store, sessionId, storedb.DATA_GUARDIANS, func(guardians []GuardianRecord) ([]GuardianRecord, error) {
+		for _, v := range guardians {
+			if v.SessionId == guardian {
+				return nil, ErrGuardianExists
+			}
+		}
+		if len(guardians) >= GuardiansSize {
+			return nil, ErrGuardiansFull
+		}
+		return append(guardians, GuardianRecord{
+			SessionId: guardian,
+			Added:     time.Now().Unix(),
+		}), nil
+	})
+}
+
+// RemoveGuardian removes the account with the given guardian session id from the guardians of the account.
+func RemoveGuardian(ctx context.Context, store DataStore, sessionId string, guardian string) error {
+	return updateRecordList(ctx, store, sessionId, storedb.DATA_GUARDIANS, func(guardians []GuardianRecord) ([]GuardianRecord, error) {
+		r := []GuardianRecord{}
+		for _, v := range guardians {
+			if v.SessionId != guardian {
+				r = append(r, v)
+			}
+		}
+		if len(r) == len(guardians) {
+			return nil, ErrGuardianNotFound
+		}
+		return r, nil
+	})
+}
+
+// ReadRecovery retrieves the open recovery request of the account.
+//
+// Returns false if the account has no open recovery request.
+func ReadRecovery(ctx context.Context, store DataStore, sessionId string) (RecoveryRecord, bool, error) {
+	records, err := readRecordList[RecoveryRecord](ctx, store, sessionId, storedb.DATA_RECOVERY)
+	if err != nil {
+		return RecoveryRecord{}, false, err
+	}
+	if len(records) == 0 || records[0].Expired(time.Now()) {
+		return RecoveryRecord{}, false, nil
+	}
+	return records[0], true, nil
+}
+
+// StartRecovery opens a new recovery request of the account, replacing any earlier one.
+//
+// The request is assigned a random id and expires after RecoveryExpiry. The saved record is returned.
+func StartRecovery(ctx context.Context, store DataStore, sessionId string) (RecoveryRecord, error) {
+	b := make([]byte, 8)
+	_, err := rand.Read(b)
+	if err != nil {
+		return RecoveryRecord{}, err
+	}
+	now := time.Now()
+	record := RecoveryRecord{
+		Id:        hex.EncodeToString(b),
+		Requester: sessionId,
+		Created:   now.Unix(),
+		Expires:   now.Add(RecoveryExpiry).Unix(),
+		Approvals: []string{},
+	}
+	unlock := lockKey(storedb.EntryKey(sessionId, storedb.DATA_RECOVERY))
+	defer unlock()
+	return record, writeRecordList(ctx, store, sessionId, storedb.DATA_RECOVERY, []RecoveryRecord{record})
+}
+
+// ApproveRecovery records the approval of the open recovery request with the given id by a guardian of the account.
+//
+// Repeated approvals by the same guardian count once. Once the given number of guardians have approved, the
+// request is closed and true is returned. Only the approval that completes the request returns true, also
+// when guardians approve concurrently. The updated record is returned.
+func ApproveRecovery(ctx context.Context, store DataStore, sessionId string, id string, guardian string, threshold int) (RecoveryRecord, bool, error) {
+	var record RecoveryRecord
+	var done bool
+	err := updateRecordList(ctx, store, sessionId, storedb.DATA_RECOVERY, func(records []RecoveryRecord) ([]RecoveryRecord, error) {
+		if len(records) == 0 || records[0].Expired(time.Now()) || records[0].Id != id {
+			return nil, ErrRecoveryNotFound
+		}
+		record = records[0]
+		if slices.Contains(record.Approvals, guardian) {
+			return nil, errUnchanged
+		}
+		record.Approvals = append(record.Approvals, guardian)
+		if len(record.Approvals) >= threshold {
+			done = true
+			return []RecoveryRecord{}, nil
+		}
+		return []RecoveryRecord{record}, nil
+	})
+	if err != nil {
+		return RecoveryRecord{}, false, err
+	}
+	return record, done, nil
+}
+
+// EndRecovery closes the open recovery request of the account.
+func EndRecovery(ctx context.Context, store DataStore, sessionId string) error {
+	unlock := lockKey(storedb.EntryKey(sessionId, storedb.DATA_RECOVERY))
+	defer unlock()
+	return writeRecordList(ctx, store, sessionId, storedb.DATA_RECOVERY, []RecoveryRecord{})
+}
+
+// ReadRecoveryRequests retrieves the open recovery requests received by the account as a guardian, oldest first.
+//
+// Expired requests are left out.
+func ReadRecoveryRequests(ctx context.Context, store DataStore, sessionId string) ([]RecoveryRecord, error) {
+	requests, err := readRecordList[RecoveryRecord](ctx, store, sessionId, storedb.DATA_RECOVERY_REQUESTS)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	r := []RecoveryRecord{}
+	for _, v := range requests {
+		if !v.Expired(now) {
+			r = append(r, v)
+		}
+	}
+	return r, nil
+}
+
+// AddRecoveryRequest saves a recovery request received by the account as a guardian.
+//
+// Any earlier request of the same requester is replaced.
+func AddRecoveryRequest(ctx context.Context, store DataStore, sessionId string, request RecoveryRecord) error {
+	now := time.Now()
+	return updateRecordList(ctx, store, sessionId, storedb.DATA_RECOVERY_REQUESTS, func(requests []RecoveryRecord) ([]RecoveryRecord, error) {
+		r := []RecoveryRecord{}
+		for _, v := range requests {
+			if v.Requester != request.Requester && !v.Expired(now) {
+				r = append(r, v)
+			}
+		}
+		return append(r, request), nil
+	})
+}
+
+// GetRecoveryRequest retrieves the open recovery request with the given id received by the account as a guardian.
+func GetRecoveryRequest(ctx context.Context, store DataStore, sessionId string, id string) (RecoveryRecord, error) {
+	requests, err := ReadRecoveryRequests(ctx, store, sessionId)
+	if err != nil {
+		return RecoveryRecord{}, err
+	}
+	for _, v := range requests {
+		if v.Id == id {
+			return v, nil
+		}
+	}
+	return RecoveryRecord{}, ErrRecoveryNotFound
+}
+
+// RemoveRecoveryRequest removes the recovery request with the given id received by the account as a guardian,
+// after it was approved or the recovery finished.
+func RemoveRecoveryRequest(ctx context.Context, store DataStore, sessionId string, id string) error {
+	now := time.Now()
+	return updateRecordList(ctx, store, sessionId, storedb.DATA_RECOVERY_REQUESTS, func(requests []RecoveryRecord) ([]RecoveryRecord, error) {
+		r := []RecoveryRecord{}
+		for _, v := range requests {
+			if v.Id != id && !v.Expired(now) {
+				r = append(r, v)
+			}
+		}
+		return r, nil
+	})
+}
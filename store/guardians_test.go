@@ -0,0 +1,130 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuardians(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+
+	for _, v := range []string{"+254711111111", "+254722222222", "+254733333333"} {
+		err := AddGuardian(ctx, store, sessionId, v)
+		require.NoError(t, err)
+	}
+	err := AddGuardian(ctx, store, sessionId, "+254722222222")
+	assert.Equal(t, ErrGuardianExists, err)
+	err = AddGuardian(ctx, store, sessionId, "+254744444444")
+	assert.Equal(t, ErrGuardiansFull, err)
+
+	ok, err := IsGuardian(ctx, store, sessionId, "+254722222222")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	err = RemoveGuardian(ctx, store, sessionId, "+254722222222")
+	require.NoError(t, err)
+	err = RemoveGuardian(ctx, store, sessionId, "+254722222222")
+	assert.Equal(t, ErrGuardianNotFound, err)
+	guardians, err := ReadGuardians(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(guardians))
+	assert.Equal(t, "+254711111111", guardians[0].SessionId)
+	assert.Equal(t, "+254733333333", guardians[1].SessionId)
+	ok, err = IsGuardian(ctx, store, sessionId, "+254722222222")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestRecovery(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+
+	_, ok, err := ReadRecovery(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	recovery, err := StartRecovery(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, sessionId, recovery.Requester)
+	assert.NotEqual(t, "", recovery.Id)
+
+	_, _, err = ApproveRecovery(ctx, store, sessionId, "foo", "+254711111111", 2)
+	assert.Equal(t, ErrRecoveryNotFound, err)
+	recovery, done, err := ApproveRecovery(ctx, store, sessionId, recovery.Id, "+254711111111", 2)
+	require.NoError(t, err)
+	assert.False(t, done)
+	// approvals by the same guardian count once
+	recovery, done, err = ApproveRecovery(ctx, store, sessionId, recovery.Id, "+254711111111", 2)
+	require.NoError(t, err)
+	assert.False(t, done)
+	assert.Equal(t, []string{"+254711111111"}, recovery.Approvals)
+
+	r, ok, err := ReadRecovery(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, recovery, r)
+
+	// the approval reaching the threshold closes the request
+	recovery, done, err = ApproveRecovery(ctx, store, sessionId, recovery.Id, "+254722222222", 2)
+	require.NoError(t, err)
+	assert.True(t, done)
+	assert.Equal(t, []string{"+254711111111", "+254722222222"}, recovery.Approvals)
+	_, ok, err = ReadRecovery(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	_, _, err = ApproveRecovery(ctx, store, sessionId, recovery.Id, "+254733333333", 2)
+	assert.Equal(t, ErrRecoveryNotFound, err)
+
+	recovery, err = StartRecovery(ctx, store, sessionId)
+	require.NoError(t, err)
+	err = EndRecovery(ctx, store, sessionId)
+	require.NoError(t, err)
+	_, ok, err = ReadRecovery(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	_, _, err = ApproveRecovery(ctx, store, sessionId, recovery.Id, "+254722222222", 2)
+	assert.Equal(t, ErrRecoveryNotFound, err)
+
+	now := time.Now()
+	assert.False(t, recovery.Expired(now))
+	assert.True(t, recovery.Expired(now.Add(RecoveryExpiry+time.Second)))
+}
+
+func TestRecoveryRequests(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254711111111"
+
+	expires := time.Now().Add(RecoveryExpiry).Unix()
+	err := AddRecoveryRequest(ctx, store, sessionId, RecoveryRecord{Id: "a", Requester: "+254712345678", Expires: expires})
+	require.NoError(t, err)
+	err = AddRecoveryRequest(ctx, store, sessionId, RecoveryRecord{Id: "b", Requester: "+254722222222", Expires: expires})
+	require.NoError(t, err)
+	// a new request of the same requester replaces the earlier one
+	err = AddRecoveryRequest(ctx, store, sessionId, RecoveryRecord{Id: "c", Requester: "+254712345678", Expires: expires})
+	require.NoError(t, err)
+	err = AddRecoveryRequest(ctx, store, sessionId, RecoveryRecord{Id: "d", Requester: "+254733333333", Expires: time.Now().Unix() - 1})
+	require.NoError(t, err)
+
+	requests, err := ReadRecoveryRequests(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(requests))
+	assert.Equal(t, "b", requests[0].Id)
+	assert.Equal(t, "c", requests[1].Id)
+
+	r, err := GetRecoveryRequest(ctx, store, sessionId, "c")
+	require.NoError(t, err)
+	assert.Equal(t, "+254712345678", r.Requester)
+	_, err = GetRecoveryRequest(ctx, store, sessionId, "a")
+	assert.Equal(t, ErrRecoveryNotFound, err)
+
+	err = RemoveRecoveryRequest(ctx, store, sessionId, "b")
+	require.NoError(t, err)
+	requests, err = ReadRecoveryRequests(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(requests))
+	assert.Equal(t, "c", requests[0].Id)
+}
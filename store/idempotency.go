@@ -0,0 +1,186 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	visedb "git.defalsify.org/vise.git/db"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+const (
+	// number of submitted intents remembered per account.
+	intentHistorySize = 10
+)
+
+// IntentRecord is a single entry in the stored list of submitted transaction intents.
+//
+// A record with an empty tracking id is an intent whose submission has not yet completed.
+type IntentRecord struct {
+	Key        string `json:"key"`
+	TrackingId string `json:"tracking_id"`
+	// Tracking ids of the completed steps of an intent submitted in several steps.
+	Steps   []string `json:"steps,omitempty"`
+	Created int64    `json:"created"`
+}
+
+// Pending returns true if the submission of the intent has not yet completed.
+func (r IntentRecord) Pending() bool {
+	return r.TrackingId == ""
+}
+
+// NewIntentNonce creates a new random nonce for the transaction about to be previewed.
+//
+// It must be called each time a transaction preview is shown, so that a new
+// transaction with the same parameters is not mistaken for a resubmission.
+func NewIntentNonce(ctx context.Context, store DataStore, sessionId string) (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	nonce := hex.EncodeToString(b)
+	err = store.WriteEntry(ctx, sessionId, storedb.DATA_TRANSACTION_NONCE, []byte(nonce))
+	if err != nil {
+		return "", err
+	}
+	return nonce, nil
+}
+
+// IntentKey returns the idempotency key of a transaction of the given kind and parameters,
+// bound to the nonce of the current preview.
+//
+// An empty key is returned if no preview nonce exists.
+func IntentKey(ctx context.Context, store DataStore, sessionId string, kind string, params ...string) (string, error) {
+	nonce, err := store.ReadEntry(ctx, sessionId, storedb.DATA_TRANSACTION_NONCE)
+	if err != nil {
+		if visedb.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if len(nonce) == 0 {
+		return "", nil
+	}
+	h := sha256.New()
+	h.Write([]byte(kind))
+	h.Write([]byte{0})
+	h.Write(nonce)
+	for _, v := range params {
+		h.Write([]byte{0})
+		h.Write([]byte(strings.TrimSpace(v)))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ReadIntentList retrieves the recently submitted transaction intents of the account.
+func ReadIntentList(ctx context.Context, store DataStore, sessionId string) ([]IntentRecord, error) {
+	return readRecordList[IntentRecord](ctx, store, sessionId, storedb.DATA_INTENT_LIST)
+}
+
+// updateIntentList changes the intents of the account while holding the lock of the list,
+// keeping only the most recent intentHistorySize intents.
+func updateIntentList(ctx context.Context, store DataStore, sessionId string, fn func([]IntentRecord) ([]IntentRecord, error)) error {
+	return updateRecordList(ctx, store, sessionId, storedb.DATA_INTENT_LIST, func(intents []IntentRecord) ([]IntentRecord, error) {
+		intents, err := fn(intents)
+		if err != nil {
+			return nil, err
+		}
+		if len(intents) > intentHistorySize {
+			intents = intents[len(intents)-intentHistorySize:]
+		}
+		return intents, nil
+	})
+}
+
+// BeginIntent registers the submission of the intent with the given key.
+//
+// If the intent was already submitted, the earlier record is returned and
+// nothing is changed. Otherwise a pending record is stored and nil is returned.
+//
+// The check and the registration are made while holding the lock of the intent list,
+// so that of concurrent submissions of the same intent only one can proceed.
+func BeginIntent(ctx context.Context, store DataStore, sessionId string, key string) (*IntentRecord, error) {
+	var prev *IntentRecord
+	err := updateIntentList(ctx, store, sessionId, func(intents []IntentRecord) ([]IntentRecord, error) {
+		for _, v := range intents {
+			if v.Key == key {
+				prev = &v
+				return nil, errUnchanged
+			}
+		}
+		return append(intents, IntentRecord{
+			Key:     key,
+			Created: time.Now().Unix(),
+		}), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return prev, nil
+}
+
+// StepIntent records the tracking id of a completed step of an intent submitted in several steps,
+// such as a swap followed by a transfer of the swapped tokens.
+//
+// An intent with completed steps is never aborted, as part of it has been submitted.
+func StepIntent(ctx context.Context, store DataStore, sessionId string, key string, trackingId string) error {
+	return updateIntentList(ctx, store, sessionId, func(intents []IntentRecord) ([]IntentRecord, error) {
+		for i, v := range intents {
+			if v.Key == key {
+				intents[i].Steps = append(intents[i].Steps, trackingId)
+				return intents, nil
+			}
+		}
+		return append(intents, IntentRecord{
+			Key:     key,
+			Steps:   []string{trackingId},
+			Created: time.Now().Unix(),
+		}), nil
+	})
+}
+
+// CompleteIntent records the tracking id of a successfully submitted intent.
+func CompleteIntent(ctx context.Context, store DataStore, sessionId string, key string, trackingId string) error {
+	return updateIntentList(ctx, store, sessionId, func(intents []IntentRecord) ([]IntentRecord, error) {
+		for i, v := range intents {
+			if v.Key == key {
+				intents[i].TrackingId = trackingId
+				return intents, nil
+			}
+		}
+		return append(intents, IntentRecord{
+			Key:        key,
+			TrackingId: trackingId,
+			Created:    time.Now().Unix(),
+		}), nil
+	})
+}
+
+// AbortIntent removes the record of an intent whose submission failed, so that it may be submitted again.
+//
+// Returns false if the intent was kept because some of its steps were completed.
+func AbortIntent(ctx context.Context, store DataStore, sessionId string, key string) (bool, error) {
+	aborted := true
+	err := updateIntentList(ctx, store, sessionId, func(intents []IntentRecord) ([]IntentRecord, error) {
+		for i, v := range intents {
+			if v.Key != key {
+				continue
+			}
+			if len(v.Steps) > 0 {
+				aborted = false
+				return nil, errUnchanged
+			}
+			return append(intents[:i], intents[i+1:]...), nil
+		}
+		return nil, errUnchanged
+	})
+	if err != nil {
+		return false, err
+	}
+	return aborted, nil
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntentKey(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+
+	k, err := IntentKey(ctx, store, sessionId, "transfer", "1", "0xabc")
+	require.NoError(t, err)
+	assert.Equal(t, "", k)
+
+	_, err = NewIntentNonce(ctx, store, sessionId)
+	require.NoError(t, err)
+	k, err = IntentKey(ctx, store, sessionId, "transfer", "1", "0xabc")
+	require.NoError(t, err)
+	assert.NotEqual(t, "", k)
+	kk, err := IntentKey(ctx, store, sessionId, "transfer", "1", "0xabc")
+	require.NoError(t, err)
+	assert.Equal(t, k, kk)
+	kk, err = IntentKey(ctx, store, sessionId, "transfer", "2", "0xabc")
+	require.NoError(t, err)
+	assert.NotEqual(t, k, kk)
+	kk, err = IntentKey(ctx, store, sessionId, "swap", "1", "0xabc")
+	require.NoError(t, err)
+	assert.NotEqual(t, k, kk)
+
+	// a new preview gives a new key for the same parameters
+	_, err = NewIntentNonce(ctx, store, sessionId)
+	require.NoError(t, err)
+	kk, err = IntentKey(ctx, store, sessionId, "transfer", "1", "0xabc")
+	require.NoError(t, err)
+	assert.NotEqual(t, k, kk)
+}
+
+func TestIntentLifecycle(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+
+	r, err := BeginIntent(ctx, store, sessionId, "foo")
+	require.NoError(t, err)
+	assert.Zero(t, r)
+
+	r, err = BeginIntent(ctx, store, sessionId, "foo")
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.True(t, r.Pending())
+
+	err = CompleteIntent(ctx, store, sessionId, "foo", "deadbeef")
+	require.NoError(t, err)
+	r, err = BeginIntent(ctx, store, sessionId, "foo")
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.False(t, r.Pending())
+	assert.Equal(t, "deadbeef", r.TrackingId)
+
+	_, err = BeginIntent(ctx, store, sessionId, "bar")
+	require.NoError(t, err)
+	ok, err := AbortIntent(ctx, store, sessionId, "bar")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	r, err = BeginIntent(ctx, store, sessionId, "bar")
+	require.NoError(t, err)
+	assert.Zero(t, r)
+}
+
+func TestIntentStep(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+
+	_, err := BeginIntent(ctx, store, sessionId, "foo")
+	require.NoError(t, err)
+	err = StepIntent(ctx, store, sessionId, "foo", "swap1")
+	require.NoError(t, err)
+
+	// an intent with completed steps is kept
+	ok, err := AbortIntent(ctx, store, sessionId, "foo")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	r, err := BeginIntent(ctx, store, sessionId, "foo")
+	require.NoError(t, err)
+	require.NotNil(t, r)
+	assert.True(t, r.Pending())
+	assert.Equal(t, []string{"swap1"}, r.Steps)
+}
+
+func TestIntentListCap(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+
+	for i := 0; i < intentHistorySize+5; i++ {
+		_, err := BeginIntent(ctx, store, sessionId, fmt.Sprintf("key%d", i))
+		require.NoError(t, err)
+	}
+	intents, err := ReadIntentList(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, intentHistorySize, len(intents))
+	assert.Equal(t, "key5", intents[0].Key)
+}
@@ -0,0 +1,55 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"sort"
+
+	visedb "git.defalsify.org/vise.git/db"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+// indexEntry is an entry of a global index, with the value it is indexed by.
+type indexEntry struct {
+	id    string
+	value []byte
+}
+
+// readIndexEntries returns all entries of the global index of the given type, in ascending order of the
+// values they are indexed by.
+//
+// Entries that are written once under a unique key, rather than read, changed and written back, need no
+// lock, and can thus be added by several processes sharing the userdata store. Empty entries, as left by a
+// rolled back batch or a removal, are skipped.
+func readIndexEntries(ctx context.Context, store DataStore, typ storedb.DataTyp) ([]indexEntry, error) {
+	prefix := storedb.IndexKey(typ, "").Bytes()
+	store.SetPrefix(visedb.DATATYPE_USERDATA)
+	store.SetSession("")
+	d, err := store.Dump(ctx, prefix)
+	if err != nil {
+		if visedb.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	// index keys are stored without a session id
+	var r []indexEntry
+	for {
+		k, v := d.Next(ctx)
+		if k == nil {
+			break
+		}
+		if len(k) <= len(prefix) || !bytes.HasPrefix(k, prefix) || len(v) == 0 {
+			continue
+		}
+		r = append(r, indexEntry{
+			id:    string(k[len(prefix):]),
+			value: v,
+		})
+	}
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].id < r[j].id
+	})
+	return r, nil
+}
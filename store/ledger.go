@@ -0,0 +1,154 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+const (
+	// number of submitted transactions kept in the ledger of each account.
+	ledgerSize = 20
+	// time after which a pending transaction that has not been confirmed is considered expired.
+	LedgerExpiry = 24 * time.Hour
+)
+
+// Types of submitted transactions.
+const (
+	LedgerTransfer = "transfer"
+	LedgerSwap     = "swap"
+	LedgerMpesa    = "mpesa"
+	LedgerPayDebt  = "paydebt"
+	LedgerDeposit  = "deposit"
+)
+
+// Status of submitted transactions.
+const (
+	LedgerPending   = "pending"
+	LedgerConfirmed = "confirmed"
+	// the submission was rejected.
+	LedgerFailed = "failed"
+	// no confirmation was seen within LedgerExpiry.
+	LedgerExpired = "expired"
+)
+
+// LedgerEntry is a single transaction submitted by the account.
+//
+// From, To, TokenAddress and Value describe the token transfer expected to
+// confirm the transaction. An empty field matches any value.
+type LedgerEntry struct {
+	TrackingId   string `json:"tracking_id"`
+	Type         string `json:"type"`
+	Amount       string `json:"amount"`
+	Symbol       string `json:"symbol"`
+	ToAmount     string `json:"to_amount,omitempty"`
+	ToSymbol     string `json:"to_symbol,omitempty"`
+	Counterparty string `json:"counterparty"`
+	From         string `json:"from"`
+	To           string `json:"to"`
+	TokenAddress string `json:"token_address"`
+	Value        string `json:"value"`
+	Submitted    int64  `json:"submitted"`
+	Status       string `json:"status"`
+	TxHash       string `json:"tx_hash,omitempty"`
+}
+
+// Pending returns true if no transfer confirming the transaction has been seen.
+func (e LedgerEntry) Pending() bool {
+	return e.Status == LedgerPending
+}
+
+func (e LedgerEntry) matches(from string, to string, tokenAddress string, value string) bool {
+	return matchField(e.From, from) && matchField(e.To, to) && matchField(e.TokenAddress, tokenAddress) && matchField(e.Value, value)
+}
+
+func matchField(want string, have string) bool {
+	return want == "" || strings.EqualFold(want, have)
+}
+
+// ReadLedger retrieves the submitted transactions of the account, oldest first.
+func ReadLedger(ctx context.Context, store DataStore, sessionId string) ([]LedgerEntry, error) {
+	return readRecordList[LedgerEntry](ctx, store, sessionId, storedb.DATA_TRANSACTION_LEDGER)
+}
+
+// updateLedger changes the ledger of the account while holding the lock of the ledger,
+// keeping only the most recent ledgerSize entries.
+func updateLedger(ctx context.Context, store DataStore, sessionId string, fn func([]LedgerEntry) ([]LedgerEntry, error)) error {
+	return updateRecordList(ctx, store, sessionId, storedb.DATA_TRANSACTION_LEDGER, func(entries []LedgerEntry) ([]LedgerEntry, error) {
+		entries, err := fn(entries)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) > ledgerSize {
+			entries = entries[len(entries)-ledgerSize:]
+		}
+		return entries, nil
+	})
+}
+
+// AddLedgerEntry records a submitted transaction.
+//
+// The entry is recorded as pending unless its status is set, such as for a rejected submission.
+// The oldest entries are dropped when the ledger is full.
+func AddLedgerEntry(ctx context.Context, store DataStore, sessionId string, entry LedgerEntry) error {
+	if entry.Submitted == 0 {
+		entry.Submitted = time.Now().Unix()
+	}
+	if entry.Status == "" {
+		entry.Status = LedgerPending
+	}
+	return updateLedger(ctx, store, sessionId, func(entries []LedgerEntry) ([]LedgerEntry, error) {
+		return append(entries, entry), nil
+	})
+}
+
+// ConfirmLedgerEntry marks the oldest pending transaction matching the given token transfer as confirmed.
+//
+// Transactions that have expired are confirmed as well, should the confirmation arrive late.
+//
+// Returns true if a matching transaction was found.
+func ConfirmLedgerEntry(ctx context.Context, store DataStore, sessionId string, from string, to string, tokenAddress string, value string, txHash string) (bool, error) {
+	var ok bool
+	err := updateLedger(ctx, store, sessionId, func(entries []LedgerEntry) ([]LedgerEntry, error) {
+		for i, v := range entries {
+			if (!v.Pending() && v.Status != LedgerExpired) || !v.matches(from, to, tokenAddress, value) {
+				continue
+			}
+			entries[i].Status = LedgerConfirmed
+			entries[i].TxHash = txHash
+			ok = true
+			return entries, nil
+		}
+		return nil, errUnchanged
+	})
+	if err != nil {
+		return false, err
+	}
+	return ok, nil
+}
+
+// ExpireLedgerEntries marks the pending transactions submitted more than LedgerExpiry before the given time as expired.
+//
+// It returns the number of transactions expired.
+func ExpireLedgerEntries(ctx context.Context, store DataStore, sessionId string, now time.Time) (int, error) {
+	var c int
+	since := now.Add(-LedgerExpiry).Unix()
+	err := updateLedger(ctx, store, sessionId, func(entries []LedgerEntry) ([]LedgerEntry, error) {
+		for i, v := range entries {
+			if v.Pending() && v.Submitted < since {
+				entries[i].Status = LedgerExpired
+				c++
+			}
+		}
+		if c == 0 {
+			return nil, errUnchanged
+		}
+		return entries, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return c, nil
+}
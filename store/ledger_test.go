@@ -0,0 +1,146 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLedger(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+	account := "0xd4c288865Ce0985a481Eef3be02443dF5E2e4Ea9"
+	recipient := "0x41c188d63Qa"
+	token := "0x1324262343rfdGW23"
+
+	entries, err := ReadLedger(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(entries))
+
+	err = AddLedgerEntry(ctx, store, sessionId, LedgerEntry{
+		TrackingId:   "foo",
+		Type:         LedgerTransfer,
+		Amount:       "1.00",
+		Symbol:       "SRF",
+		Counterparty: "0711223344",
+		From:         account,
+		To:           recipient,
+		TokenAddress: token,
+		Value:        "1000000",
+	})
+	require.NoError(t, err)
+	err = AddLedgerEntry(ctx, store, sessionId, LedgerEntry{
+		TrackingId: "bar",
+		Type:       LedgerMpesa,
+		Amount:     "100",
+		Symbol:     "Ksh",
+		To:         account,
+	})
+	require.NoError(t, err)
+
+	entries, err = ReadLedger(ctx, store, sessionId)
+	require.NoError(t, err)
+	require.Equal(t, 2, len(entries))
+	assert.True(t, entries[0].Pending())
+	assert.NotEqual(t, int64(0), entries[0].Submitted)
+
+	// a transfer with a different value does not confirm the submission
+	ok, err := ConfirmLedgerEntry(ctx, store, sessionId, account, recipient, token, "2000000", "0xabc")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// addresses are compared regardless of case
+	ok, err = ConfirmLedgerEntry(ctx, store, sessionId, account, "0x41C188D63QA", token, "1000000", "0xdef")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// empty fields match any transfer
+	ok, err = ConfirmLedgerEntry(ctx, store, sessionId, "", account, token, "42", "0x123")
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	entries, err = ReadLedger(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, LedgerConfirmed, entries[0].Status)
+	assert.Equal(t, "0xdef", entries[0].TxHash)
+	assert.Equal(t, LedgerConfirmed, entries[1].Status)
+	assert.Equal(t, "0x123", entries[1].TxHash)
+
+	// confirmed entries are not confirmed again
+	ok, err = ConfirmLedgerEntry(ctx, store, sessionId, account, recipient, token, "1000000", "0x456")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestLedgerCap(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+
+	for i := 0; i < ledgerSize+3; i++ {
+		err := AddLedgerEntry(ctx, store, sessionId, LedgerEntry{
+			TrackingId: fmt.Sprintf("id%d", i),
+			Type:       LedgerTransfer,
+		})
+		require.NoError(t, err)
+	}
+	entries, err := ReadLedger(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, ledgerSize, len(entries))
+	assert.Equal(t, "id3", entries[0].TrackingId)
+}
+
+func TestLedgerFailedExpired(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+	now := time.Now()
+
+	err := AddLedgerEntry(ctx, store, sessionId, LedgerEntry{
+		Type:   LedgerTransfer,
+		Value:  "1000000",
+		Status: LedgerFailed,
+	})
+	require.NoError(t, err)
+	err = AddLedgerEntry(ctx, store, sessionId, LedgerEntry{
+		TrackingId: "old",
+		Type:       LedgerTransfer,
+		Value:      "2000000",
+		Submitted:  now.Add(-LedgerExpiry - time.Minute).Unix(),
+	})
+	require.NoError(t, err)
+	err = AddLedgerEntry(ctx, store, sessionId, LedgerEntry{
+		TrackingId: "new",
+		Type:       LedgerTransfer,
+		Value:      "3000000",
+		Submitted:  now.Unix(),
+	})
+	require.NoError(t, err)
+
+	c, err := ExpireLedgerEntries(ctx, store, sessionId, now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, c)
+	c, err = ExpireLedgerEntries(ctx, store, sessionId, now)
+	require.NoError(t, err)
+	assert.Equal(t, 0, c)
+
+	entries, err := ReadLedger(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, LedgerFailed, entries[0].Status)
+	assert.Equal(t, LedgerExpired, entries[1].Status)
+	assert.Equal(t, LedgerPending, entries[2].Status)
+
+	// failed submissions are never confirmed
+	ok, err := ConfirmLedgerEntry(ctx, store, sessionId, "", "", "", "1000000", "0xabc")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// a late confirmation still confirms an expired submission
+	ok, err = ConfirmLedgerEntry(ctx, store, sessionId, "", "", "", "2000000", "0xdef")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	entries, err = ReadLedger(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, LedgerConfirmed, entries[1].Status)
+}
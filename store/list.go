@@ -0,0 +1,89 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+const (
+	// ListVersion is the schema version of record lists written to the userdata store.
+	ListVersion = 1
+)
+
+// VoucherRecord is a single voucher entry in a stored voucher list.
+type VoucherRecord struct {
+	Symbol   string `json:"symbol"`
+	Balance  string `json:"balance"`
+	Decimals string `json:"decimals"`
+	Address  string `json:"address"`
+}
+
+// PoolRecord is a single pool entry in a stored pool list.
+type PoolRecord struct {
+	Name    string `json:"name"`
+	Symbol  string `json:"symbol"`
+	Address string `json:"address"`
+}
+
+// recordList is the versioned envelope in which record lists are stored.
+type recordList[T any] struct {
+	Version int `json:"v"`
+	Items   []T `json:"items"`
+}
+
+// EncodeList serializes a record list with the current schema version.
+func EncodeList[T any](items []T) ([]byte, error) {
+	if items == nil {
+		items = []T{}
+	}
+	return json.Marshal(recordList[T]{
+		Version: ListVersion,
+		Items:   items,
+	})
+}
+
+// DecodeList deserializes a record list written by EncodeList.
+//
+// An error is returned if the list was written with an unknown schema version.
+func DecodeList[T any](data []byte) ([]T, error) {
+	var l recordList[T]
+	err := json.Unmarshal(data, &l)
+	if err != nil {
+		return nil, fmt.Errorf("invalid record list: %v", err)
+	}
+	if l.Version != ListVersion {
+		return nil, fmt.Errorf("unsupported record list version: %d", l.Version)
+	}
+	return l.Items, nil
+}
+
+// splitLegacyList splits a legacy newline separated list.
+//
+// If indexed is set, the "<index>:" prefix of each item is removed.
+func splitLegacyList(data string, indexed bool) []string {
+	var r []string
+	if data == "" {
+		return r
+	}
+	for _, v := range strings.Split(data, "\n") {
+		if indexed {
+			parts := strings.SplitN(v, ":", 2)
+			if len(parts) == 2 {
+				v = parts[1]
+			} else {
+				v = ""
+			}
+		}
+		r = append(r, strings.TrimSpace(v))
+	}
+	return r
+}
+
+// legacyItem safely returns the item at the given index of a legacy list.
+func legacyItem(items []string, i int) string {
+	if i < len(items) {
+		return items[i]
+	}
+	return ""
+}
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+// keyLock is the lock of a single userdata entry, with the number of its holders and waiters.
+type keyLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+var (
+	locksMu sync.Mutex
+	locks   = make(map[storedb.Key]*keyLock)
+
+	// errUnchanged is returned by an update function to leave the entry unchanged without error.
+	errUnchanged = errors.New("entry unchanged")
+)
+
+// lockKey acquires the lock of the userdata entry with the given key, and returns the function releasing it.
+//
+// The userdata store has no compare-and-swap operation. Entries that are read, changed and
+// written back, and that may be changed concurrently by another session or worker, must be
+// updated while holding their lock. The lock is held in process memory, and thus only serializes
+// updates made by the same process.
+func lockKey(key storedb.Key) func() {
+	locksMu.Lock()
+	l, ok := locks[key]
+	if !ok {
+		l = &keyLock{}
+		locks[key] = l
+	}
+	l.refs++
+	locksMu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		locksMu.Lock()
+		l.refs--
+		if l.refs == 0 {
+			delete(locks, key)
+		}
+		locksMu.Unlock()
+	}
+}
+
+// updateRecordList replaces the record list of the account under the given key with the list
+// returned by fn for its current items, while holding the lock of the entry.
+//
+// If fn returns an error, the list is left unchanged and the error is returned, unless it is errUnchanged.
+func updateRecordList[T any](ctx context.Context, store DataStore, sessionId string, typ storedb.DataTyp, fn func([]T) ([]T, error)) error {
+	unlock := lockKey(storedb.EntryKey(sessionId, typ))
+	defer unlock()
+	items, err := readRecordList[T](ctx, store, sessionId, typ)
+	if err != nil {
+		return err
+	}
+	items, err = fn(items)
+	if err == errUnchanged {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return writeRecordList(ctx, store, sessionId, typ, items)
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	visedb "git.defalsify.org/vise.git/db"
+	"git.grassecon.net/grassrootseconomics/common/pin"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+func readUint(ctx context.Context, store DataStore, sessionId string, key storedb.DataTyp) (uint64, error) {
+	v, err := store.ReadEntry(ctx, sessionId, key)
+	if err != nil {
+		if visedb.IsNotFound(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	if len(v) == 0 {
+		return 0, nil
+	}
+	return strconv.ParseUint(string(v), 10, 64)
+}
+
+// LockAccount records a lockout of the account after too many incorrect PIN attempts.
+//
+// Each lockout lasts for the next of the given durations, and its end is returned. Once all of them
+// have been used the lockout is permanent, in which case false is returned.
+func LockAccount(ctx context.Context, store DataStore, sessionId string, durations []time.Duration) (time.Time, bool, error) {
+	count, err := readUint(ctx, store, sessionId, storedb.DATA_PIN_LOCKOUTS)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	err = store.WriteEntry(ctx, sessionId, storedb.DATA_PIN_LOCKOUTS, []byte(strconv.FormatUint(count+1, 10)))
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if count >= uint64(len(durations)) {
+		err = store.WriteEntry(ctx, sessionId, storedb.DATA_PIN_LOCKOUT_EXPIRY, []byte(""))
+		return time.Time{}, false, err
+	}
+	expiry := time.Now().Add(durations[count])
+	err = store.WriteEntry(ctx, sessionId, storedb.DATA_PIN_LOCKOUT_EXPIRY, []byte(strconv.FormatInt(expiry.Unix(), 10)))
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return expiry, true, nil
+}
+
+// LockoutExpiry returns the time the current temporary lockout of the account ends.
+//
+// Returns false if the account is not temporarily locked out. It may still be blocked permanently.
+func LockoutExpiry(ctx context.Context, store DataStore, sessionId string) (time.Time, bool, error) {
+	v, err := readUint(ctx, store, sessionId, storedb.DATA_PIN_LOCKOUT_EXPIRY)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	if v == 0 {
+		return time.Time{}, false, nil
+	}
+	return time.Unix(int64(v), 0), true, nil
+}
+
+// LiftLockout ends the current temporary lockout of the account, and allows new PIN attempts.
+//
+// Earlier lockouts are kept, so that the next lockout lasts longer.
+func LiftLockout(ctx context.Context, store DataStore, sessionId string) error {
+	err := store.WriteEntry(ctx, sessionId, storedb.DATA_PIN_LOCKOUT_EXPIRY, []byte(""))
+	if err != nil {
+		return err
+	}
+	return store.WriteEntry(ctx, sessionId, storedb.DATA_INCORRECT_PIN_ATTEMPTS, []byte("0"))
+}
+
+// ClearLockouts forgets all lockouts of the account, such as after a correct PIN entry or a PIN reset.
+func ClearLockouts(ctx context.Context, store DataStore, sessionId string) error {
+	err := store.WriteEntry(ctx, sessionId, storedb.DATA_PIN_LOCKOUT_EXPIRY, []byte(""))
+	if err != nil {
+		return err
+	}
+	return store.WriteEntry(ctx, sessionId, storedb.DATA_PIN_LOCKOUTS, []byte("0"))
+}
+
+// AccountBlocked returns true if the account cannot authorize transactions at the given time, because
+// a PIN reset is pending, or it is locked out after too many incorrect PIN attempts.
+//
+// An expired temporary lockout does not block the account, as it is lifted when the account next dials in.
+func AccountBlocked(ctx context.Context, store DataStore, sessionId string, now time.Time) (bool, error) {
+	v, err := store.ReadEntry(ctx, sessionId, storedb.DATA_SELF_PIN_RESET)
+	if err != nil && !visedb.IsNotFound(err) {
+		return false, err
+	}
+	if string(v) == "1" {
+		return true, nil
+	}
+	expiry, locked, err := LockoutExpiry(ctx, store, sessionId)
+	if err != nil {
+		return false, err
+	}
+	if locked {
+		return now.Before(expiry), nil
+	}
+	attempts, err := readUint(ctx, store, sessionId, storedb.DATA_INCORRECT_PIN_ATTEMPTS)
+	if err != nil {
+		return false, err
+	}
+	return attempts >= uint64(pin.AllowedPINAttempts), nil
+}
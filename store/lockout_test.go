@@ -0,0 +1,59 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockAccount(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+	durations := []time.Duration{15 * time.Minute, time.Hour}
+
+	_, ok, err := LockoutExpiry(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	expiry, ok, err := LockAccount(ctx, store, sessionId, durations)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, time.Until(expiry) > 14*time.Minute && time.Until(expiry) <= 15*time.Minute)
+	v, ok, err := LockoutExpiry(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, expiry.Unix(), v.Unix())
+
+	err = LiftLockout(ctx, store, sessionId)
+	require.NoError(t, err)
+	_, ok, err = LockoutExpiry(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	attempts, err := store.ReadEntry(ctx, sessionId, storedb.DATA_INCORRECT_PIN_ATTEMPTS)
+	require.NoError(t, err)
+	assert.Equal(t, "0", string(attempts))
+
+	// each lockout lasts longer than the previous one
+	expiry, ok, err = LockAccount(ctx, store, sessionId, durations)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, time.Until(expiry) > 59*time.Minute)
+
+	// and the account is blocked once all have been used
+	_, ok, err = LockAccount(ctx, store, sessionId, durations)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	_, ok, err = LockoutExpiry(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	err = ClearLockouts(ctx, store, sessionId)
+	require.NoError(t, err)
+	expiry, ok, err = LockAccount(ctx, store, sessionId, durations)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, time.Until(expiry) <= 15*time.Minute)
+}
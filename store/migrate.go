@@ -0,0 +1,126 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"sort"
+
+	visedb "git.defalsify.org/vise.git/db"
+	"git.grassecon.net/grassrootseconomics/common/hex"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+// MigrateKeys moves the entries of the account of the given session from the
+// legacy key layout to the scoped key layout.
+//
+// The reverse lookup of the account address is moved to the global index, and
+// short-lived menu entries are moved to menu scope. The alias of the account is
+// added to the alias index if not already there. Entries that already exist
+// in the new layout are not overwritten, and legacy entries are left in place,
+// so the migration can safely be run more than once.
+//
+// It returns the number of entries migrated.
+func MigrateKeys(ctx context.Context, store DataStore, sessionId string) (int, error) {
+	var keys []storedb.Key
+
+	publicKey, err := store.ReadEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY)
+	if err == nil {
+		address, err := hex.NormalizeHex(string(publicKey))
+		if err != nil {
+			return 0, err
+		}
+		keys = append(keys, storedb.IndexKey(storedb.DATA_PUBLIC_KEY_REVERSE, address))
+	} else if !visedb.IsNotFound(err) {
+		return 0, err
+	}
+	for _, typ := range storedb.MenuTyps() {
+		keys = append(keys, storedb.MenuKey(sessionId, typ))
+	}
+
+	c := 0
+	b := store.Begin(ctx)
+	for _, k := range keys {
+		_, err := store.Read(ctx, k)
+		if err == nil {
+			continue
+		} else if !visedb.IsNotFound(err) {
+			b.Rollback(ctx)
+			return 0, err
+		}
+		v, err := store.Read(ctx, k.Legacy())
+		if err != nil {
+			if visedb.IsNotFound(err) {
+				continue
+			}
+			b.Rollback(ctx)
+			return 0, err
+		}
+		err = b.Put(ctx, k, v)
+		if err != nil {
+			b.Rollback(ctx)
+			return 0, err
+		}
+		logg.DebugCtxf(ctx, "migrated entry", "session", sessionId, "key", k)
+		c++
+	}
+
+	alias, err := store.ReadEntry(ctx, sessionId, storedb.DATA_ACCOUNT_ALIAS)
+	if err == nil && len(alias) > 0 && len(publicKey) > 0 {
+		k := storedb.IndexKey(storedb.DATA_ALIAS_REVERSE, NormalizeAlias(string(alias)))
+		_, err = store.Read(ctx, k)
+		if visedb.IsNotFound(err) {
+			err = b.Put(ctx, k, []byte(sessionId))
+			if err == nil {
+				k = storedb.IndexKey(storedb.DATA_ALIAS_ADDRESS, NormalizeAlias(string(alias)))
+				err = b.Put(ctx, k, publicKey)
+			}
+			if err == nil {
+				logg.DebugCtxf(ctx, "indexed alias", "session", sessionId, "alias", string(alias))
+				c += 2
+			}
+		}
+	}
+	if err != nil && !visedb.IsNotFound(err) {
+		b.Rollback(ctx)
+		return 0, err
+	}
+
+	err = b.Commit(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return c, nil
+}
+
+// ListSessions returns the session ids of all accounts in the userdata store, in ascending order.
+//
+// An account is recognized by the entry holding its address, which is stored in account
+// scope in both the legacy and the scoped key layout. Accounts whose address has been
+// cleared are skipped.
+func ListSessions(ctx context.Context, store DataStore) ([]string, error) {
+	store.SetPrefix(visedb.DATATYPE_USERDATA)
+	store.SetSession("")
+	d, err := store.Dump(ctx, []byte{})
+	if err != nil {
+		return nil, err
+	}
+
+	// keys are the session id followed by the entry key
+	suffix := storedb.ToBytes(storedb.DATA_PUBLIC_KEY)
+	var r []string
+	for {
+		k, v := d.Next(ctx)
+		if k == nil {
+			break
+		}
+		if len(k) <= len(suffix) || k[0] == storedb.KEY_MARKER_INDEX || !bytes.HasSuffix(k, suffix) {
+			continue
+		}
+		if len(v) == 0 {
+			continue
+		}
+		r = append(r, string(k[:len(k)-len(suffix)]))
+	}
+	sort.Strings(r)
+	return r, nil
+}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+
+	visedb "git.defalsify.org/vise.git/db"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+func TestMigrateKeys(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+	publicKey := "0xd4c288865Ce0985a481Eef3be02443dF5E2e4Ea9"
+	address := "d4c288865ce0985a481eef3be02443df5e2e4ea9"
+
+	// legacy layout
+	err := store.WriteEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY, []byte(publicKey))
+	require.NoError(t, err)
+	err = store.Write(ctx, storedb.AccountKey(address, storedb.DATA_PUBLIC_KEY_REVERSE), []byte(sessionId))
+	require.NoError(t, err)
+	err = store.Write(ctx, storedb.AccountKey(sessionId, storedb.DATA_AMOUNT), []byte("42"))
+	require.NoError(t, err)
+
+	_, err = store.Read(ctx, storedb.IndexKey(storedb.DATA_PUBLIC_KEY_REVERSE, address))
+	assert.True(t, visedb.IsNotFound(err))
+	_, err = store.Read(ctx, storedb.MenuKey(sessionId, storedb.DATA_AMOUNT))
+	assert.True(t, visedb.IsNotFound(err))
+
+	// legacy entries are read through until migrated
+	v, err := store.ReadIndex(ctx, storedb.DATA_PUBLIC_KEY_REVERSE, address)
+	require.NoError(t, err)
+	assert.Equal(t, []byte(sessionId), v)
+	v, err = store.ReadEntry(ctx, sessionId, storedb.DATA_AMOUNT)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("42"), v)
+
+	c, err := MigrateKeys(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, 2, c)
+
+	v, err = store.ReadIndex(ctx, storedb.DATA_PUBLIC_KEY_REVERSE, address)
+	require.NoError(t, err)
+	assert.Equal(t, []byte(sessionId), v)
+	v, err = store.ReadEntry(ctx, sessionId, storedb.DATA_AMOUNT)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("42"), v)
+
+	// newer values are kept when migrating again
+	err = store.WriteEntry(ctx, sessionId, storedb.DATA_AMOUNT, []byte("13"))
+	require.NoError(t, err)
+	c, err = MigrateKeys(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, 0, c)
+	v, err = store.ReadEntry(ctx, sessionId, storedb.DATA_AMOUNT)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("13"), v)
+}
+
+func TestMigrateKeysAlias(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+	publicKey := "0xd4c288865Ce0985a481Eef3be02443dF5E2e4Ea9"
+
+	err := store.WriteEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY, []byte(publicKey))
+	require.NoError(t, err)
+	err = store.WriteEntry(ctx, sessionId, storedb.DATA_ACCOUNT_ALIAS, []byte("foo.sarafu.eth"))
+	require.NoError(t, err)
+
+	c, err := MigrateKeys(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, 2, c)
+
+	a, s, err := ResolveAlias(ctx, store, "foo.sarafu.eth")
+	require.NoError(t, err)
+	assert.Equal(t, publicKey, a)
+	assert.Equal(t, sessionId, s)
+
+	c, err = MigrateKeys(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, 0, c)
+}
+
+func TestListSessions(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	publicKey := "0xd4c288865Ce0985a481Eef3be02443dF5E2e4Ea9"
+
+	for _, sessionId := range []string{"+254712345678", "+254711223344"} {
+		err := store.WriteEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY, []byte(publicKey))
+		require.NoError(t, err)
+		err = store.WriteEntry(ctx, sessionId, storedb.DATA_AMOUNT, []byte("42"))
+		require.NoError(t, err)
+	}
+	// a deleted account
+	err := store.WriteEntry(ctx, "+254700000000", storedb.DATA_PUBLIC_KEY, []byte(""))
+	require.NoError(t, err)
+	// an account that has not completed registration
+	err = store.WriteEntry(ctx, "+254799999999", storedb.DATA_FIRST_NAME, []byte("John"))
+	require.NoError(t, err)
+	err = store.WriteIndex(ctx, storedb.DATA_PUBLIC_KEY_REVERSE, "d4c288865ce0985a481eef3be02443df5e2e4ea9", []byte("+254712345678"))
+	require.NoError(t, err)
+
+	sessionIds, err := ListSessions(ctx, store)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"+254711223344", "+254712345678"}, sessionIds)
+}
@@ -0,0 +1,178 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+const (
+	// maximum number of recipients of a multi-recipient transfer, and of members of a contact group.
+	MultiSendSize = 5
+	// maximum number of contact groups per account.
+	contactGroupsSize = 5
+	// number of multi-recipient transfers remembered per account.
+	multiSendsSize = 5
+)
+
+var (
+	// ErrContactGroupsFull is returned when adding a contact group to an account that has the maximum number.
+	ErrContactGroupsFull = errors.New("too many contact groups")
+)
+
+// ContactGroupRecord is a named group of recipients that can be paid together.
+//
+// The recipients are stored as given by the user, and may be phone numbers, addresses or aliases.
+type ContactGroupRecord struct {
+	Name       string   `json:"name"`
+	Recipients []string `json:"recipients"`
+}
+
+// MultiSendLeg is a single transfer of a multi-recipient transfer.
+//
+// RecipientInput is the recipient as given by the user, and Recipient the resolved
+// address. Once the transfer is submitted, either TrackingId or Error is set.
+type MultiSendLeg struct {
+	RecipientInput string `json:"recipient_input"`
+	Recipient      string `json:"recipient"`
+	TrackingId     string `json:"tracking_id,omitempty"`
+	Error          string `json:"error,omitempty"`
+}
+
+// MultiSendRecord is a submitted multi-recipient transfer of the same amount to each of its legs.
+type MultiSendRecord struct {
+	Amount       string         `json:"amount"`
+	Symbol       string         `json:"symbol"`
+	TokenAddress string         `json:"token_address"`
+	Submitted    int64          `json:"submitted"`
+	Legs         []MultiSendLeg `json:"legs"`
+}
+
+// Succeeded returns the number of legs that were submitted successfully.
+func (b MultiSendRecord) Succeeded() int {
+	var c int
+	for _, v := range b.Legs {
+		if v.Error == "" {
+			c++
+		}
+	}
+	return c
+}
+
+// ParseMultiSendRecipients splits comma-separated recipient input into its recipients.
+//
+// Empty entries and repeated recipients are left out.
+func ParseMultiSendRecipients(input string) []string {
+	var r []string
+	for _, v := range strings.Split(input, ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		seen := false
+		for _, w := range r {
+			if strings.EqualFold(v, w) {
+				seen = true
+				break
+			}
+		}
+		if !seen {
+			r = append(r, v)
+		}
+	}
+	return r
+}
+
+// ReadContactGroups retrieves the contact groups of the account.
+func ReadContactGroups(ctx context.Context, store DataStore, sessionId string) ([]ContactGroupRecord, error) {
+	return readRecordList[ContactGroupRecord](ctx, store, sessionId, storedb.DATA_CONTACT_GROUPS)
+}
+
+// AddContactGroup saves a contact group of the account.
+//
+// If a group with the same name exists, its recipients are replaced instead.
+func AddContactGroup(ctx context.Context, store DataStore, sessionId string, group ContactGroupRecord) error {
+	if len(group.Recipients) == 0 || len(group.Recipients) > MultiSendSize {
+		return fmt.Errorf("invalid number of group members: %d", len(group.Recipients))
+	}
+	groups, err := ReadContactGroups(ctx, store, sessionId)
+	if err != nil {
+		return err
+	}
+	for i, v := range groups {
+		if strings.EqualFold(v.Name, group.Name) {
+			groups[i].Recipients = group.Recipients
+			return writeRecordList(ctx, store, sessionId, storedb.DATA_CONTACT_GROUPS, groups)
+		}
+	}
+	if len(groups) >= contactGroupsSize {
+		return ErrContactGroupsFull
+	}
+	groups = append(groups, group)
+	return writeRecordList(ctx, store, sessionId, storedb.DATA_CONTACT_GROUPS, groups)
+}
+
+// DeleteContactGroup removes the contact group at the given 0-based index.
+func DeleteContactGroup(ctx context.Context, store DataStore, sessionId string, index int) error {
+	groups, err := ReadContactGroups(ctx, store, sessionId)
+	if err != nil {
+		return err
+	}
+	if index < 0 || index >= len(groups) {
+		return fmt.Errorf("contact group not found: index %d out of range", index)
+	}
+	groups = append(groups[:index], groups[index+1:]...)
+	return writeRecordList(ctx, store, sessionId, storedb.DATA_CONTACT_GROUPS, groups)
+}
+
+// FindContactGroup returns the contact group with the given name, ignoring case and spaces.
+func FindContactGroup(ctx context.Context, store DataStore, sessionId string, name string) (ContactGroupRecord, bool, error) {
+	groups, err := ReadContactGroups(ctx, store, sessionId)
+	if err != nil {
+		return ContactGroupRecord{}, false, err
+	}
+	name = strings.ReplaceAll(name, " ", "")
+	for _, v := range groups {
+		if strings.EqualFold(strings.ReplaceAll(v.Name, " ", ""), name) {
+			return v, true, nil
+		}
+	}
+	return ContactGroupRecord{}, false, nil
+}
+
+// ReadMultiSendLegs retrieves the legs of the multi-recipient transfer being set up.
+func ReadMultiSendLegs(ctx context.Context, store DataStore, sessionId string) ([]MultiSendLeg, error) {
+	return readRecordList[MultiSendLeg](ctx, store, sessionId, storedb.DATA_MULTI_SEND_LEGS)
+}
+
+// WriteMultiSendLegs saves the legs of the multi-recipient transfer being set up.
+func WriteMultiSendLegs(ctx context.Context, store DataStore, sessionId string, legs []MultiSendLeg) error {
+	return writeRecordList(ctx, store, sessionId, storedb.DATA_MULTI_SEND_LEGS, legs)
+}
+
+// ReadMultiSends retrieves the most recently submitted multi-recipient transfers of the account, newest first.
+func ReadMultiSends(ctx context.Context, store DataStore, sessionId string) ([]MultiSendRecord, error) {
+	return readRecordList[MultiSendRecord](ctx, store, sessionId, storedb.DATA_MULTI_SENDS)
+}
+
+// AddMultiSend records a submitted multi-recipient transfer with the results of its legs.
+//
+// Only the multiSendsSize most recent multi-recipient transfers are kept.
+func AddMultiSend(ctx context.Context, store DataStore, sessionId string, send MultiSendRecord) error {
+	sends, err := ReadMultiSends(ctx, store, sessionId)
+	if err != nil {
+		return err
+	}
+	if send.Submitted == 0 {
+		send.Submitted = time.Now().Unix()
+	}
+	sends = append([]MultiSendRecord{send}, sends...)
+	if len(sends) > multiSendsSize {
+		sends = sends[:multiSendsSize]
+	}
+	return writeRecordList(ctx, store, sessionId, storedb.DATA_MULTI_SENDS, sends)
+}
@@ -0,0 +1,78 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMultiSendRecipients(t *testing.T) {
+	assert.Equal(t, []string{"0711223344", "0722334455", "alice"}, ParseMultiSendRecipients("0711223344, 0722334455,,alice,0711223344"))
+	assert.Equal(t, []string{"0711223344"}, ParseMultiSendRecipients("0711223344"))
+	assert.Equal(t, 0, len(ParseMultiSendRecipients(" , ")))
+}
+
+func TestContactGroups(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+
+	err := AddContactGroup(ctx, store, sessionId, ContactGroupRecord{Name: "Chama"})
+	assert.Error(t, err)
+
+	err = AddContactGroup(ctx, store, sessionId, ContactGroupRecord{Name: "Chama", Recipients: []string{"0711223344", "0722334455"}})
+	require.NoError(t, err)
+
+	// a group with the same name is replaced
+	err = AddContactGroup(ctx, store, sessionId, ContactGroupRecord{Name: "chama", Recipients: []string{"0711223344", "0733445566"}})
+	require.NoError(t, err)
+	groups, err := ReadContactGroups(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(groups))
+	assert.Equal(t, []string{"0711223344", "0733445566"}, groups[0].Recipients)
+
+	g, ok, err := FindContactGroup(ctx, store, sessionId, "CHAMA")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "Chama", g.Name)
+	_, ok, err = FindContactGroup(ctx, store, sessionId, "family")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	for i := 1; i < contactGroupsSize; i++ {
+		err = AddContactGroup(ctx, store, sessionId, ContactGroupRecord{Name: string(rune('a' + i)), Recipients: []string{"0711223344"}})
+		require.NoError(t, err)
+	}
+	err = AddContactGroup(ctx, store, sessionId, ContactGroupRecord{Name: "family", Recipients: []string{"0711223344"}})
+	assert.Equal(t, ErrContactGroupsFull, err)
+
+	err = DeleteContactGroup(ctx, store, sessionId, 0)
+	require.NoError(t, err)
+	_, ok, err = FindContactGroup(ctx, store, sessionId, "chama")
+	require.NoError(t, err)
+	assert.False(t, ok)
+	err = DeleteContactGroup(ctx, store, sessionId, contactGroupsSize)
+	assert.Error(t, err)
+}
+
+func TestAddMultiSend(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+
+	for i := 0; i <= multiSendsSize; i++ {
+		err := AddMultiSend(ctx, store, sessionId, MultiSendRecord{
+			Amount: string(rune('0' + i)),
+			Legs: []MultiSendLeg{
+				{Recipient: "0x1111", TrackingId: "1"},
+				{Recipient: "0x2222", Error: "failed"},
+			},
+		})
+		require.NoError(t, err)
+	}
+	sends, err := ReadMultiSends(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, multiSendsSize, len(sends))
+	assert.Equal(t, string(rune('0'+multiSendsSize)), sends[0].Amount)
+	assert.Equal(t, 1, sends[0].Succeeded())
+	assert.True(t, sends[0].Submitted > 0)
+}
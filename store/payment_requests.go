@@ -0,0 +1,154 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+const (
+	// maximum number of open payment requests an account can receive.
+	paymentRequestsSize = 5
+	// maximum number of open payment requests an account can receive from the same account.
+	paymentRequestsPerRequester = 2
+	// maximum number of payment requests an account can send within PaymentRequestWindow.
+	PaymentRequestLimit = 5
+	// window over which PaymentRequestLimit applies.
+	PaymentRequestWindow = 24 * time.Hour
+	// time after which an unanswered payment request expires.
+	PaymentRequestExpiry = 72 * time.Hour
+)
+
+var (
+	// ErrPaymentRequestsFull is returned when the recipient of a payment request has too many open requests.
+	ErrPaymentRequestsFull = errors.New("too many open payment requests")
+	// ErrPaymentRequestNotFound is returned when no open payment request with the given id exists.
+	ErrPaymentRequestNotFound = errors.New("payment request not found")
+)
+
+// PaymentRequestRecord is a request for payment received by an account.
+//
+// Requester is the session id of the account asking for payment, and Recipient
+// its public key, to which the payment is made. Amount is in the voucher with
+// the given symbol and token address.
+type PaymentRequestRecord struct {
+	Id           string `json:"id"`
+	Requester    string `json:"requester"`
+	Recipient    string `json:"recipient"`
+	Amount       string `json:"amount"`
+	Symbol       string `json:"symbol"`
+	TokenAddress string `json:"token_address"`
+	Created      int64  `json:"created"`
+	Expires      int64  `json:"expires"`
+}
+
+// Expired returns true if the payment request is no longer open at the given time.
+func (r PaymentRequestRecord) Expired(now time.Time) bool {
+	return r.Expires <= now.Unix()
+}
+
+// ReadPaymentRequests retrieves the open payment requests received by the account, oldest first.
+//
+// Expired requests are left out.
+func ReadPaymentRequests(ctx context.Context, store DataStore, sessionId string) ([]PaymentRequestRecord, error) {
+	requests, err := readRecordList[PaymentRequestRecord](ctx, store, sessionId, storedb.DATA_PAYMENT_REQUESTS)
+	if err != nil {
+		return nil, err
+	}
+	return openPaymentRequests(requests, time.Now()), nil
+}
+
+func openPaymentRequests(requests []PaymentRequestRecord, now time.Time) []PaymentRequestRecord {
+	r := []PaymentRequestRecord{}
+	for _, v := range requests {
+		if !v.Expired(now) {
+			r = append(r, v)
+		}
+	}
+	return r
+}
+
+// AddPaymentRequest saves a payment request received by the account of the given session.
+//
+// The request is assigned a random id and expires after PaymentRequestExpiry. If the account
+// already has paymentRequestsPerRequester open requests from the same requester, the oldest of
+// them is replaced. The saved record is returned.
+func AddPaymentRequest(ctx context.Context, store DataStore, sessionId string, request PaymentRequestRecord) (PaymentRequestRecord, error) {
+	b := make([]byte, 8)
+	_, err := rand.Read(b)
+	if err != nil {
+		return request, err
+	}
+	now := time.Now()
+	request.Id = hex.EncodeToString(b)
+	request.Created = now.Unix()
+	request.Expires = now.Add(PaymentRequestExpiry).Unix()
+
+	err = updateRecordList(ctx, store, sessionId, storedb.DATA_PAYMENT_REQUESTS, func(requests []PaymentRequestRecord) ([]PaymentRequestRecord, error) {
+		requests = openPaymentRequests(requests, now)
+		oldest := -1
+		var c int
+		for i, v := range requests {
+			if v.Requester != request.Requester {
+				continue
+			}
+			if oldest < 0 {
+				oldest = i
+			}
+			c++
+		}
+		if c >= paymentRequestsPerRequester {
+			requests = append(requests[:oldest], requests[oldest+1:]...)
+		} else if len(requests) >= paymentRequestsSize {
+			return nil, ErrPaymentRequestsFull
+		}
+		return append(requests, request), nil
+	})
+	return request, err
+}
+
+// GetPaymentRequest retrieves the open payment request with the given id.
+func GetPaymentRequest(ctx context.Context, store DataStore, sessionId string, id string) (PaymentRequestRecord, error) {
+	requests, err := ReadPaymentRequests(ctx, store, sessionId)
+	if err != nil {
+		return PaymentRequestRecord{}, err
+	}
+	for _, v := range requests {
+		if v.Id == id {
+			return v, nil
+		}
+	}
+	return PaymentRequestRecord{}, ErrPaymentRequestNotFound
+}
+
+// RemovePaymentRequest closes the open payment request with the given id, after it was paid or declined.
+//
+// The closed request is returned.
+func RemovePaymentRequest(ctx context.Context, store DataStore, sessionId string, id string) (PaymentRequestRecord, error) {
+	var r PaymentRequestRecord
+	err := updateRecordList(ctx, store, sessionId, storedb.DATA_PAYMENT_REQUESTS, func(requests []PaymentRequestRecord) ([]PaymentRequestRecord, error) {
+		requests = openPaymentRequests(requests, time.Now())
+		for i, v := range requests {
+			if v.Id == id {
+				r = v
+				return append(requests[:i], requests[i+1:]...), nil
+			}
+		}
+		return nil, ErrPaymentRequestNotFound
+	})
+	return r, err
+}
+
+// AllowPaymentRequest sends a payment request of the account with fn, unless it has already
+// sent PaymentRequestLimit requests within PaymentRequestWindow.
+//
+// The request only counts towards the limit if fn succeeds.
+//
+// Returns false if the limit was reached, in which case fn is not called.
+func AllowPaymentRequest(ctx context.Context, store DataStore, sessionId string, fn func() error) (bool, error) {
+	return AllowEvent(ctx, store, sessionId, storedb.DATA_PAYMENT_REQUEST_LOG, PaymentRequestLimit, PaymentRequestWindow, fn)
+}
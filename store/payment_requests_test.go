@@ -0,0 +1,100 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+func TestPaymentRequests(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+
+	// expired requests are not open
+	err := writeRecordList(ctx, store, sessionId, storedb.DATA_PAYMENT_REQUESTS, []PaymentRequestRecord{
+		{Id: "old", Requester: "+254700000001", Amount: "5", Expires: time.Now().Add(-time.Minute).Unix()},
+	})
+	require.NoError(t, err)
+	requests, err := ReadPaymentRequests(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(requests))
+
+	var ids []string
+	for i := 0; i < paymentRequestsSize; i++ {
+		requester := fmt.Sprintf("+25470000000%d", i)
+		r, err := AddPaymentRequest(ctx, store, sessionId, PaymentRequestRecord{Requester: requester, Amount: "10", Symbol: "SRF"})
+		require.NoError(t, err)
+		assert.True(t, r.Expires > time.Now().Unix())
+		ids = append(ids, r.Id)
+	}
+	_, err = AddPaymentRequest(ctx, store, sessionId, PaymentRequestRecord{Requester: "+254700000009", Amount: "10", Symbol: "SRF"})
+	assert.Equal(t, ErrPaymentRequestsFull, err)
+
+	r, err := GetPaymentRequest(ctx, store, sessionId, ids[1])
+	require.NoError(t, err)
+	assert.Equal(t, "10", r.Amount)
+
+	_, err = RemovePaymentRequest(ctx, store, sessionId, ids[1])
+	require.NoError(t, err)
+	_, err = GetPaymentRequest(ctx, store, sessionId, ids[1])
+	assert.Equal(t, ErrPaymentRequestNotFound, err)
+	requests, err = ReadPaymentRequests(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, paymentRequestsSize-1, len(requests))
+}
+
+func TestPaymentRequestsPerRequester(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+	requester := "+254700000001"
+
+	var ids []string
+	for i := 0; i < paymentRequestsPerRequester+1; i++ {
+		r, err := AddPaymentRequest(ctx, store, sessionId, PaymentRequestRecord{Requester: requester, Amount: "10", Symbol: "SRF"})
+		require.NoError(t, err)
+		ids = append(ids, r.Id)
+	}
+
+	// the oldest request from the same requester is replaced
+	requests, err := ReadPaymentRequests(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, paymentRequestsPerRequester, len(requests))
+	_, err = GetPaymentRequest(ctx, store, sessionId, ids[0])
+	assert.Equal(t, ErrPaymentRequestNotFound, err)
+	_, err = GetPaymentRequest(ctx, store, sessionId, ids[len(ids)-1])
+	require.NoError(t, err)
+}
+
+func TestAllowPaymentRequest(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+
+	// failed requests do not count towards the limit
+	ok, err := AllowPaymentRequest(ctx, store, sessionId, func() error {
+		return ErrPaymentRequestsFull
+	})
+	assert.Equal(t, ErrPaymentRequestsFull, err)
+	assert.True(t, ok)
+
+	var c int
+	for i := 0; i < PaymentRequestLimit; i++ {
+		ok, err := AllowPaymentRequest(ctx, store, sessionId, func() error {
+			c++
+			return nil
+		})
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+	ok, err = AllowPaymentRequest(ctx, store, sessionId, func() error {
+		c++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, PaymentRequestLimit, c)
+}
@@ -3,93 +3,121 @@ package store
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 
+	"git.defalsify.org/vise.git/db"
 	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
 	dataserviceapi "github.com/grassrootseconomics/ussd-data-service/pkg/api"
 )
 
-// PoolsMetadata helps organize data fields
-type PoolsMetadata struct {
-	PoolNames             string
-	PoolSymbols           string
-	PoolContractAdrresses string
-}
-
-// ProcessPools converts pools into formatted strings
-func ProcessPools(pools []dataserviceapi.PoolDetails) PoolsMetadata {
-	var data PoolsMetadata
-	var poolNames, poolSymbols, poolContractAdrresses []string
+// ProcessPools converts pools into pool records
+func ProcessPools(pools []dataserviceapi.PoolDetails) []PoolRecord {
+	records := make([]PoolRecord, 0, len(pools))
 
-	for i, p := range pools {
-		poolNames = append(poolNames, fmt.Sprintf("%d:%s", i+1, p.PoolName))
-		poolSymbols = append(poolSymbols, fmt.Sprintf("%d:%s", i+1, p.PoolSymbol))
-		poolContractAdrresses = append(poolContractAdrresses, fmt.Sprintf("%d:%s", i+1, p.PoolContractAdrress))
+	for _, p := range pools {
+		records = append(records, PoolRecord{
+			Name:    p.PoolName,
+			Symbol:  p.PoolSymbol,
+			Address: p.PoolContractAdrress,
+		})
 	}
 
-	data.PoolNames = strings.Join(poolNames, "\n")
-	data.PoolSymbols = strings.Join(poolSymbols, "\n")
-	data.PoolContractAdrresses = strings.Join(poolContractAdrresses, "\n")
+	return records
+}
 
-	return data
+// WritePoolList stores the pool records as a single entry under DATA_POOL_LIST.
+func WritePoolList(ctx context.Context, store DataStore, sessionId string, pools []PoolRecord) error {
+	v, err := EncodeList(pools)
+	if err != nil {
+		return err
+	}
+	return store.WriteEntry(ctx, sessionId, storedb.DATA_POOL_LIST, v)
 }
 
-// GetPoolData retrieves and matches pool data
-// if no match is found, it fetches the API with the symbol
-func GetPoolData(ctx context.Context, store DataStore, sessionId string, input string) (*dataserviceapi.PoolDetails, error) {
+// ReadPoolList retrieves the pool records stored under DATA_POOL_LIST.
+//
+// If the list has not been written yet, the records are read from the legacy
+// format entries instead.
+//
+// An empty entry, as left by a rolled back batch or a deleted account, counts as
+// not written. Legacy entries missing in that case are read as empty lists.
+func ReadPoolList(ctx context.Context, store DataStore, sessionId string) ([]PoolRecord, error) {
+	v, err := store.ReadEntry(ctx, sessionId, storedb.DATA_POOL_LIST)
+	if err == nil && len(v) > 0 {
+		return DecodeList[PoolRecord](v)
+	}
+	if err != nil && !db.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get data key %x: %v", storedb.DATA_POOL_LIST, err)
+	}
+	cleared := err == nil
+
 	keys := []storedb.DataTyp{
 		storedb.DATA_POOL_NAMES,
 		storedb.DATA_POOL_SYMBOLS,
 		storedb.DATA_POOL_ADDRESSES,
 	}
-	data := make(map[storedb.DataTyp]string)
+	data := make(map[storedb.DataTyp][]string)
 
 	for _, key := range keys {
 		value, err := store.ReadEntry(ctx, sessionId, key)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get data key %x: %v", key, err)
+			if !cleared || !db.IsNotFound(err) {
+				return nil, fmt.Errorf("failed to get data key %x: %v", key, err)
+			}
 		}
-		data[key] = string(value)
+		data[key] = splitLegacyList(string(value), true)
 	}
 
-	name, symbol, address := MatchPool(input,
-		data[storedb.DATA_POOL_NAMES],
-		data[storedb.DATA_POOL_SYMBOLS],
-		data[storedb.DATA_POOL_ADDRESSES],
-	)
+	symbols := data[storedb.DATA_POOL_SYMBOLS]
+	pools := make([]PoolRecord, 0, len(symbols))
+	for i, sym := range symbols {
+		pools = append(pools, PoolRecord{
+			Name:    legacyItem(data[storedb.DATA_POOL_NAMES], i),
+			Symbol:  sym,
+			Address: legacyItem(data[storedb.DATA_POOL_ADDRESSES], i),
+		})
+	}
+	return pools, nil
+}
 
-	if symbol == "" {
+// GetPoolData retrieves and matches pool data
+// if no match is found, it fetches the API with the symbol
+func GetPoolData(ctx context.Context, store DataStore, sessionId string, input string) (*dataserviceapi.PoolDetails, error) {
+	pools, err := ReadPoolList(ctx, store, sessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, ok := MatchPool(input, pools)
+	if !ok {
 		return nil, nil
 	}
 
 	return &dataserviceapi.PoolDetails{
-		PoolName:            string(name),
-		PoolSymbol:          string(symbol),
-		PoolContractAdrress: string(address),
+		PoolName:            pool.Name,
+		PoolSymbol:          pool.Symbol,
+		PoolContractAdrress: pool.Address,
 	}, nil
 }
 
-// MatchPool finds the matching pool name, symbol and pool contract address based on the input.
-func MatchPool(input, names, symbols, addresses string) (name, symbol, address string) {
-	nameList := strings.Split(names, "\n")
-	symList := strings.Split(symbols, "\n")
-	addrList := strings.Split(addresses, "\n")
-
-	for i, sym := range symList {
-		parts := strings.SplitN(sym, ":", 2)
-
-		if input == parts[0] || strings.EqualFold(input, parts[1]) {
-			symbol = parts[1]
-			if i < len(nameList) {
-				name = strings.SplitN(nameList[i], ":", 2)[1]
-			}
-			if i < len(addrList) {
-				address = strings.SplitN(addrList[i], ":", 2)[1]
-			}
-			break
+// MatchPool finds the pool matching the input, either by its 1-based list index or by its symbol.
+func MatchPool(input string, pools []PoolRecord) (PoolRecord, bool) {
+	for i, p := range pools {
+		if input == strconv.Itoa(i+1) || strings.EqualFold(input, p.Symbol) {
+			return p, true
 		}
 	}
-	return
+	return PoolRecord{}, false
+}
+
+// FormatPoolSymbols returns the pool symbols as a newline separated "<index>:<symbol>" list.
+func FormatPoolSymbols(pools []PoolRecord) string {
+	var symbols []string
+	for i, p := range pools {
+		symbols = append(symbols, fmt.Sprintf("%d:%s", i+1, p.Symbol))
+	}
+	return strings.Join(symbols, "\n")
 }
 
 // StoreTemporaryPool saves pool metadata as temporary entries in the DataStore.
@@ -126,17 +154,11 @@ func UpdatePoolData(ctx context.Context, store DataStore, sessionId string, data
 	logg.InfoCtxf(ctx, "UpdatePoolData", "data", data)
 	// Active pool data entry
 	activeEntries := map[storedb.DataTyp][]byte{
-		storedb.DATA_ACTIVE_POOL_NAME:     []byte(data.PoolName),
+		storedb.DATA_ACTIVE_POOL_NAME:    []byte(data.PoolName),
 		storedb.DATA_ACTIVE_POOL_SYM:     []byte(data.PoolSymbol),
 		storedb.DATA_ACTIVE_POOL_ADDRESS: []byte(data.PoolContractAdrress),
 	}
 
 	// Write active data
-	for key, value := range activeEntries {
-		if err := store.WriteEntry(ctx, sessionId, key, value); err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
\ No newline at end of file
+	return WriteEntries(ctx, store, sessionId, activeEntries)
+}
@@ -0,0 +1,247 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	visedb "git.defalsify.org/vise.git/db"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+// Kinds of requests of accounts for their data.
+const (
+	// a copy of the data held about the account.
+	PrivacyExport = "export"
+	// the erasure of the personal data of the account.
+	PrivacyErasure = "erasure"
+)
+
+var (
+	// ErrPrivacyRequestExists is returned when a request of the same kind is already pending for the account.
+	ErrPrivacyRequestExists = errors.New("privacy request already pending")
+)
+
+// PrivacyRequestRecord is a pending request of an account for the export or erasure of its data.
+type PrivacyRequestRecord struct {
+	SessionId string `json:"session_id"`
+	Kind      string `json:"kind"`
+	Time      int64  `json:"time"`
+}
+
+// ExportEntry is a single stored entry in a data export.
+//
+// Id is the indexed value for index entries, and empty otherwise.
+type ExportEntry struct {
+	Typ   storedb.DataTyp `json:"typ"`
+	Id    string          `json:"id,omitempty"`
+	Value string          `json:"value"`
+}
+
+// AccountExport is all data held about an account.
+type AccountExport struct {
+	SessionId string `json:"session_id"`
+	Time      int64  `json:"time"`
+	// Entries of the account, including those of its ongoing menu interaction.
+	Entries []ExportEntry `json:"entries"`
+	// Global index entries that map to the account.
+	Indexes []ExportEntry `json:"indexes"`
+	// Entries of the account in the log database.
+	Log []ExportEntry `json:"log"`
+	// Admin actions on the account.
+	Audit []AuditRecord `json:"audit"`
+}
+
+// privacyRequestKey returns the key of the pending request of the given kind of the account.
+//
+// Each request is stored under its own key, so that requests added by the server and removed by the
+// command line tools do not overwrite each other.
+func privacyRequestKey(sessionId string, kind string) storedb.Key {
+	return storedb.IndexKey(storedb.DATA_PRIVACY_REQUESTS, sessionId+":"+kind)
+}
+
+// ReadPrivacyRequests retrieves the pending requests of all accounts, oldest first.
+func ReadPrivacyRequests(ctx context.Context, store DataStore) ([]PrivacyRequestRecord, error) {
+	entries, err := readIndexEntries(ctx, store, storedb.DATA_PRIVACY_REQUESTS)
+	if err != nil {
+		return nil, err
+	}
+	r := []PrivacyRequestRecord{}
+	for _, entry := range entries {
+		var request PrivacyRequestRecord
+		err = json.Unmarshal(entry.value, &request)
+		if err != nil {
+			return nil, fmt.Errorf("invalid privacy request %s: %v", entry.id, err)
+		}
+		r = append(r, request)
+	}
+	sort.SliceStable(r, func(i, j int) bool {
+		return r[i].Time < r[j].Time
+	})
+	return r, nil
+}
+
+// hasPrivacyRequest returns true if a request of the given kind of the account is pending.
+func hasPrivacyRequest(ctx context.Context, store DataStore, sessionId string, kind string) (bool, error) {
+	v, err := store.Read(ctx, privacyRequestKey(sessionId, kind))
+	if err != nil {
+		if visedb.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return len(v) > 0, nil
+}
+
+// AddPrivacyRequest records a request of the account for the export or erasure of its data,
+// to be processed by support staff.
+func AddPrivacyRequest(ctx context.Context, store DataStore, sessionId string, kind string) error {
+	k := privacyRequestKey(sessionId, kind)
+	unlock := lockKey(k)
+	defer unlock()
+	ok, err := hasPrivacyRequest(ctx, store, sessionId, kind)
+	if err != nil {
+		return err
+	}
+	if ok {
+		return ErrPrivacyRequestExists
+	}
+	v, err := json.Marshal(PrivacyRequestRecord{
+		SessionId: sessionId,
+		Kind:      kind,
+		Time:      time.Now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+	return store.Write(ctx, k, v)
+}
+
+// RemovePrivacyRequest removes the pending request of the given kind of the account once it has been processed.
+//
+// Returns false if no such request was pending.
+func RemovePrivacyRequest(ctx context.Context, store DataStore, sessionId string, kind string) (bool, error) {
+	k := privacyRequestKey(sessionId, kind)
+	unlock := lockKey(k)
+	defer unlock()
+	ok, err := hasPrivacyRequest(ctx, store, sessionId, kind)
+	if err != nil || !ok {
+		return false, err
+	}
+	// the store has no delete operation, and empty entries are not pending
+	return true, store.Write(ctx, k, []byte{})
+}
+
+// ExportAccount collects all data held about the account.
+//
+// The log database is optional. If nil, the export has no log entries.
+func ExportAccount(ctx context.Context, store DataStore, logDb *LogDb, sessionId string) (AccountExport, error) {
+	export := AccountExport{
+		SessionId: sessionId,
+		Time:      time.Now().Unix(),
+		Entries:   []ExportEntry{},
+		Indexes:   []ExportEntry{},
+		Log:       []ExportEntry{},
+	}
+
+	for _, typ := range storedb.DataTyps() {
+		if storedb.IsIndexTyp(typ) {
+			continue
+		}
+		v, err := store.ReadEntry(ctx, sessionId, typ)
+		if err != nil {
+			if visedb.IsNotFound(err) {
+				continue
+			}
+			return export, err
+		}
+		if len(v) == 0 {
+			continue
+		}
+		export.Entries = append(export.Entries, ExportEntry{Typ: typ, Value: string(v)})
+	}
+
+	keys, err := ownIndexes(ctx, store, sessionId, true, true)
+	if err != nil {
+		return export, err
+	}
+	for _, k := range keys {
+		v, err := store.Read(ctx, k)
+		if err != nil {
+			if visedb.IsNotFound(err) {
+				continue
+			}
+			return export, err
+		}
+		export.Indexes = append(export.Indexes, ExportEntry{Typ: k.Typ, Id: k.Id, Value: string(v)})
+	}
+
+	if logDb != nil {
+		for _, typ := range storedb.DataTyps() {
+			v, err := logDb.ReadLogEntry(ctx, sessionId, typ)
+			if err != nil {
+				if visedb.IsNotFound(err) {
+					continue
+				}
+				return export, err
+			}
+			if len(v) == 0 {
+				continue
+			}
+			export.Log = append(export.Log, ExportEntry{Typ: typ, Value: string(v)})
+		}
+	}
+
+	export.Audit, err = ReadAudit(ctx, store, AuditFilter{Target: sessionId})
+	if err != nil {
+		return export, err
+	}
+	return export, nil
+}
+
+// EraseAccount erases the personal data of the account.
+//
+// The profile, the alias and the temporary menu value of the account are cleared, and the index
+// entries mapping its address and alias to it are removed. In the log database, the profile and the
+// guardian and recovery entries naming other accounts are cleared as well. The address of the account
+// and its transaction records are kept for the integrity of the ledger.
+//
+// The log database is optional. If nil, only the userdata store is erased.
+func EraseAccount(ctx context.Context, store DataStore, logDb *LogDb, sessionId string) error {
+	err := clearProfile(ctx, store, sessionId, true)
+	if err != nil {
+		return err
+	}
+	err = store.WriteEntry(ctx, sessionId, storedb.DATA_TEMPORARY_VALUE, []byte(""))
+	if err != nil {
+		return err
+	}
+
+	if logDb == nil {
+		return nil
+	}
+	typs := append(profileTyps,
+		storedb.DATA_PUBLIC_KEY_REVERSE,
+		storedb.DATA_TEMPORARY_VALUE,
+		storedb.DATA_GUARDIANS,
+		storedb.DATA_RECOVERY,
+		storedb.DATA_RECOVERY_REQUESTS,
+	)
+	for _, typ := range typs {
+		_, err := logDb.ReadLogEntry(ctx, sessionId, typ)
+		if err != nil {
+			if visedb.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		err = logDb.WriteLogEntry(ctx, sessionId, typ, []byte(""))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"testing"
+
+	visedb "git.defalsify.org/vise.git/db"
+	memdb "git.defalsify.org/vise.git/db/mem"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrivacyRequests(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+
+	err := AddPrivacyRequest(ctx, store, sessionId, PrivacyExport)
+	require.NoError(t, err)
+	err = AddPrivacyRequest(ctx, store, sessionId, PrivacyExport)
+	assert.Equal(t, ErrPrivacyRequestExists, err)
+	err = AddPrivacyRequest(ctx, store, sessionId, PrivacyErasure)
+	require.NoError(t, err)
+
+	// requests made within the same second are in no particular order
+	requests, err := ReadPrivacyRequests(ctx, store)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(requests))
+	assert.Equal(t, sessionId, requests[0].SessionId)
+	assert.NotEqual(t, requests[0].Kind, requests[1].Kind)
+
+	// each request is stored on its own, so that a request written meanwhile by another process is kept
+	err = store.Write(ctx, privacyRequestKey("+254722222222", PrivacyExport), []byte(`{"session_id":"+254722222222","kind":"export","time":1}`))
+	require.NoError(t, err)
+
+	ok, err := RemovePrivacyRequest(ctx, store, sessionId, PrivacyExport)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = RemovePrivacyRequest(ctx, store, sessionId, PrivacyExport)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	requests, err = ReadPrivacyRequests(ctx, store)
+	require.NoError(t, err)
+	assert.Equal(t, 2, len(requests))
+	assert.Equal(t, "+254722222222", requests[0].SessionId)
+	assert.Equal(t, PrivacyErasure, requests[1].Kind)
+}
+
+func TestExportEraseAccount(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+	address := "0x5d5c2bd7C61C6F6D1D30E8B6C61E94AC2C4C2D92"
+	normalAddress := "5d5c2bd7c61c6f6d1d30e8b6c61e94ac2c4c2d92"
+
+	db := memdb.NewMemDb()
+	err := db.Connect(ctx, "")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		db.Close(ctx)
+	})
+	logDb := &LogDb{Db: db}
+
+	err = WriteEntries(ctx, store, sessionId, map[storedb.DataTyp][]byte{
+		storedb.DATA_PUBLIC_KEY:         []byte(address),
+		storedb.DATA_FIRST_NAME:         []byte("John"),
+		storedb.DATA_YOB:                []byte("1990"),
+		storedb.DATA_TEMPORARY_VALUE:    []byte("Jane"),
+		storedb.DATA_ACCOUNT_ALIAS:      []byte("john.sarafu.eth"),
+		storedb.DATA_TRANSACTION_LEDGER: []byte(`{"v":1,"items":[]}`),
+	})
+	require.NoError(t, err)
+	err = store.WriteIndex(ctx, storedb.DATA_PUBLIC_KEY_REVERSE, normalAddress, []byte(sessionId))
+	require.NoError(t, err)
+	err = WriteAliasIndex(ctx, store, sessionId, "", "john.sarafu.eth", address)
+	require.NoError(t, err)
+	err = logDb.WriteLogEntry(ctx, sessionId, storedb.DATA_FIRST_NAME, []byte("John"))
+	require.NoError(t, err)
+	err = logDb.WriteLogEntry(ctx, sessionId, storedb.DATA_PUBLIC_KEY_REVERSE, []byte(sessionId))
+	require.NoError(t, err)
+	err = logDb.WriteLogEntry(ctx, sessionId, storedb.DATA_GUARDIANS, []byte("add +254711111111"))
+	require.NoError(t, err)
+	_, err = AppendAudit(ctx, store, AuditRecord{Actor: "admin", Target: sessionId, Action: AuditResetPin, Outcome: AuditSuccess})
+	require.NoError(t, err)
+
+	export, err := ExportAccount(ctx, store, logDb, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, sessionId, export.SessionId)
+	assert.Equal(t, []ExportEntry{
+		{Typ: storedb.DATA_PUBLIC_KEY, Value: address},
+		{Typ: storedb.DATA_FIRST_NAME, Value: "John"},
+		{Typ: storedb.DATA_YOB, Value: "1990"},
+		{Typ: storedb.DATA_TEMPORARY_VALUE, Value: "Jane"},
+		{Typ: storedb.DATA_ACCOUNT_ALIAS, Value: "john.sarafu.eth"},
+		{Typ: storedb.DATA_TRANSACTION_LEDGER, Value: `{"v":1,"items":[]}`},
+	}, export.Entries)
+	assert.Equal(t, []ExportEntry{
+		{Typ: storedb.DATA_PUBLIC_KEY_REVERSE, Id: normalAddress, Value: sessionId},
+		{Typ: storedb.DATA_ALIAS_REVERSE, Id: "john.sarafu.eth", Value: sessionId},
+		{Typ: storedb.DATA_ALIAS_ADDRESS, Id: "john.sarafu.eth", Value: address},
+	}, export.Indexes)
+	assert.Equal(t, []ExportEntry{
+		{Typ: storedb.DATA_FIRST_NAME, Value: "John"},
+		{Typ: storedb.DATA_PUBLIC_KEY_REVERSE, Value: sessionId},
+		{Typ: storedb.DATA_GUARDIANS, Value: "add +254711111111"},
+	}, export.Log)
+	assert.Equal(t, 1, len(export.Audit))
+
+	err = EraseAccount(ctx, store, logDb, sessionId)
+	require.NoError(t, err)
+
+	export, err = ExportAccount(ctx, store, logDb, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, []ExportEntry{
+		{Typ: storedb.DATA_PUBLIC_KEY, Value: address},
+		{Typ: storedb.DATA_TRANSACTION_LEDGER, Value: `{"v":1,"items":[]}`},
+	}, export.Entries)
+	assert.Equal(t, []ExportEntry{}, export.Indexes)
+	assert.Equal(t, []ExportEntry{}, export.Log)
+
+	v, err := store.ReadIndex(ctx, storedb.DATA_PUBLIC_KEY_REVERSE, normalAddress)
+	require.NoError(t, err)
+	assert.Equal(t, "", string(v))
+	_, _, err = ResolveAlias(ctx, store, "john.sarafu.eth")
+	assert.True(t, visedb.IsNotFound(err))
+}
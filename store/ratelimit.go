@@ -0,0 +1,66 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	visedb "git.defalsify.org/vise.git/db"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+// AllowEvent carries out an event of the account and records it in the timestamp list stored under
+// the given key, unless limit events were already recorded within the preceding window.
+//
+// The event is carried out by fn, while holding the lock of the list, and is only recorded if fn
+// succeeds. If fn is nil, the event is only recorded.
+//
+// Returns false if the limit was reached, in which case fn is not called. If fn fails, nothing is
+// recorded and its error is returned.
+func AllowEvent(ctx context.Context, store DataStore, sessionId string, key storedb.DataTyp, limit int, window time.Duration, fn func() error) (bool, error) {
+	unlock := lockKey(storedb.EntryKey(sessionId, key))
+	defer unlock()
+
+	var events []int64
+	v, err := store.ReadEntry(ctx, sessionId, key)
+	if err != nil {
+		if !visedb.IsNotFound(err) {
+			return false, err
+		}
+	} else if len(v) > 0 {
+		events, err = DecodeList[int64](v)
+		if err != nil {
+			logg.WarnCtxf(ctx, "discarding unreadable event list", "key", key, "error", err)
+			events = nil
+		}
+	}
+
+	now := time.Now()
+	since := now.Add(-window).Unix()
+	var recent []int64
+	for _, t := range events {
+		if t > since {
+			recent = append(recent, t)
+		}
+	}
+	if len(recent) >= limit {
+		return false, nil
+	}
+
+	if fn != nil {
+		err = fn()
+		if err != nil {
+			return true, err
+		}
+	}
+
+	recent = append(recent, now.Unix())
+	v, err = EncodeList(recent)
+	if err != nil {
+		return false, err
+	}
+	err = store.WriteEntry(ctx, sessionId, key, v)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,54 @@
+package store
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+func TestAllowEvent(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+
+	for i := 0; i < 2; i++ {
+		ok, err := AllowEvent(ctx, store, sessionId, storedb.DATA_STATEMENT_SMS_LOG, 2, time.Hour, nil)
+		require.NoError(t, err)
+		assert.True(t, ok)
+	}
+	ok, err := AllowEvent(ctx, store, sessionId, storedb.DATA_STATEMENT_SMS_LOG, 2, time.Hour, nil)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// events outside of the window do not count
+	old := time.Now().Add(-2 * time.Hour).Unix()
+	v, err := EncodeList([]int64{old, old})
+	require.NoError(t, err)
+	err = store.WriteEntry(ctx, sessionId, storedb.DATA_STATEMENT_SMS_LOG, v)
+	require.NoError(t, err)
+	ok, err = AllowEvent(ctx, store, sessionId, storedb.DATA_STATEMENT_SMS_LOG, 2, time.Hour, nil)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	v, err = store.ReadEntry(ctx, sessionId, storedb.DATA_STATEMENT_SMS_LOG)
+	require.NoError(t, err)
+	events, err := DecodeList[int64](v)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(events))
+
+	// failed events are not recorded
+	ok, err = AllowEvent(ctx, store, sessionId, storedb.DATA_STATEMENT_SMS_LOG, 2, time.Hour, func() error {
+		return fmt.Errorf("send failed")
+	})
+	assert.Error(t, err)
+	assert.True(t, ok)
+	v, err = store.ReadEntry(ctx, sessionId, storedb.DATA_STATEMENT_SMS_LOG)
+	require.NoError(t, err)
+	events, err = DecodeList[int64](v)
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(events))
+}
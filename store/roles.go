@@ -0,0 +1,100 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+// Roles granting access to admin actions.
+const (
+	// may reset the PIN of other accounts.
+	RolePinReset = "pin_reset"
+	// may view the PIN status of other accounts.
+	RoleSupport = "support"
+	// may manage pools.
+	RolePoolManager = "pool_manager"
+)
+
+var (
+	// ErrInvalidRole is returned when granting or revoking a role that does not exist.
+	ErrInvalidRole = errors.New("invalid role")
+)
+
+// Roles returns all roles that can be granted.
+func Roles() []string {
+	return []string{RolePinReset, RoleSupport, RolePoolManager}
+}
+
+// ValidRole returns true if the role is one of the roles that can be granted.
+func ValidRole(role string) bool {
+	for _, v := range Roles() {
+		if v == role {
+			return true
+		}
+	}
+	return false
+}
+
+// ReadRoles retrieves the roles granted to the account.
+func ReadRoles(ctx context.Context, store DataStore, sessionId string) ([]string, error) {
+	return readRecordList[string](ctx, store, sessionId, storedb.DATA_ROLES)
+}
+
+// HasRole returns true if the role has been granted to the account.
+func HasRole(ctx context.Context, store DataStore, sessionId string, role string) (bool, error) {
+	roles, err := ReadRoles(ctx, store, sessionId)
+	if err != nil {
+		return false, err
+	}
+	for _, v := range roles {
+		if v == role {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GrantRole grants the role to the account.
+//
+// Returns false if the account already has the role.
+func GrantRole(ctx context.Context, store DataStore, sessionId string, role string) (bool, error) {
+	if !ValidRole(role) {
+		return false, ErrInvalidRole
+	}
+	var changed bool
+	err := updateRecordList(ctx, store, sessionId, storedb.DATA_ROLES, func(roles []string) ([]string, error) {
+		if slices.Contains(roles, role) {
+			return nil, errUnchanged
+		}
+		changed = true
+		return append(roles, role), nil
+	})
+	return changed, err
+}
+
+// RevokeRole revokes the role from the account.
+//
+// Returns false if the account did not have the role.
+func RevokeRole(ctx context.Context, store DataStore, sessionId string, role string) (bool, error) {
+	if !ValidRole(role) {
+		return false, ErrInvalidRole
+	}
+	var changed bool
+	err := updateRecordList(ctx, store, sessionId, storedb.DATA_ROLES, func(roles []string) ([]string, error) {
+		r := []string{}
+		for _, v := range roles {
+			if v != role {
+				r = append(r, v)
+			}
+		}
+		if len(r) == len(roles) {
+			return nil, errUnchanged
+		}
+		changed = true
+		return r, nil
+	})
+	return changed, err
+}
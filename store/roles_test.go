@@ -0,0 +1,48 @@
+package store
+
+import (
+	"testing"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoles(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+
+	ok, err := HasRole(ctx, store, sessionId, RolePinReset)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = GrantRole(ctx, store, sessionId, "root")
+	assert.Equal(t, ErrInvalidRole, err)
+
+	ok, err = GrantRole(ctx, store, sessionId, RolePinReset)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = GrantRole(ctx, store, sessionId, RolePinReset)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = GrantRole(ctx, store, sessionId, RoleSupport)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	roles, err := ReadRoles(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, []string{RolePinReset, RoleSupport}, roles)
+
+	ok, err = RevokeRole(ctx, store, sessionId, RolePinReset)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = RevokeRole(ctx, store, sessionId, RolePinReset)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	ok, err = HasRole(ctx, store, sessionId, RolePinReset)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = HasRole(ctx, store, sessionId, RoleSupport)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
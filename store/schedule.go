@@ -0,0 +1,315 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	visedb "git.defalsify.org/vise.git/db"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+const (
+	// maximum number of scheduled transfers per account.
+	schedulesSize = 5
+	// number of consecutive failed runs after which a scheduled transfer is suspended.
+	ScheduleMaxFailures = 3
+	// delay before a failed scheduled transfer is tried again.
+	scheduleRetryDelay = time.Hour
+)
+
+// Repeat intervals of scheduled transfers.
+const (
+	ScheduleDaily   = "daily"
+	ScheduleWeekly  = "weekly"
+	ScheduleMonthly = "monthly"
+)
+
+// Outcome of the last run of a scheduled transfer.
+const (
+	ScheduleSubmitted = "submitted"
+	ScheduleFailed    = "failed"
+)
+
+var (
+	// ErrSchedulesFull is returned when adding a scheduled transfer to an account that has the maximum number.
+	ErrSchedulesFull = errors.New("too many scheduled transfers")
+	// ErrScheduleNotFound is returned when no scheduled transfer with the given id exists.
+	ErrScheduleNotFound = errors.New("scheduled transfer not found")
+)
+
+// ScheduleRecord is a recurring token transfer set up by the account.
+//
+// Recipient, TokenAddress and Value are the parameters of the token transfer, with
+// Value in the smallest unit of the token. RecipientInput, Amount and Symbol are
+// kept as shown to the user.
+//
+// Scheduled is the next regular run. Next is earlier than Scheduled while a failed run
+// is waiting to be tried again.
+type ScheduleRecord struct {
+	Id             string `json:"id"`
+	RecipientInput string `json:"recipient_input"`
+	Recipient      string `json:"recipient"`
+	Amount         string `json:"amount"`
+	Symbol         string `json:"symbol"`
+	TokenAddress   string `json:"token_address"`
+	Value          string `json:"value"`
+	Interval       string `json:"interval"`
+	Next           int64  `json:"next"`
+	Scheduled      int64  `json:"scheduled,omitempty"`
+	Active         bool   `json:"active"`
+	LastRun        int64  `json:"last_run,omitempty"`
+	LastStatus     string `json:"last_status,omitempty"`
+	LastTrackingId string `json:"last_tracking_id,omitempty"`
+	Failures       int    `json:"failures,omitempty"`
+}
+
+// Due returns true if the scheduled transfer is active and its next run is not after the given time.
+func (s ScheduleRecord) Due(now time.Time) bool {
+	return s.Active && s.Next <= now.Unix()
+}
+
+// ValidScheduleInterval returns true if the interval is one of the supported repeat intervals.
+func ValidScheduleInterval(interval string) bool {
+	switch interval {
+	case ScheduleDaily, ScheduleWeekly, ScheduleMonthly:
+		return true
+	}
+	return false
+}
+
+// NextScheduleRun returns the time one repeat interval after the given time.
+func NextScheduleRun(interval string, t time.Time) time.Time {
+	switch interval {
+	case ScheduleDaily:
+		return t.AddDate(0, 0, 1)
+	case ScheduleMonthly:
+		return t.AddDate(0, 1, 0)
+	}
+	return t.AddDate(0, 0, 7)
+}
+
+// ReadSchedules retrieves the scheduled transfers of the account.
+func ReadSchedules(ctx context.Context, store DataStore, sessionId string) ([]ScheduleRecord, error) {
+	return readRecordList[ScheduleRecord](ctx, store, sessionId, storedb.DATA_SCHEDULES)
+}
+
+// AddSchedule saves a new scheduled transfer for the account, and registers the
+// account for the scheduler.
+//
+// A random id is assigned to the scheduled transfer, which is returned.
+func AddSchedule(ctx context.Context, store DataStore, sessionId string, schedule ScheduleRecord) (string, error) {
+	if !ValidScheduleInterval(schedule.Interval) {
+		return "", fmt.Errorf("invalid schedule interval: %s", schedule.Interval)
+	}
+	b := make([]byte, 8)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	schedule.Id = hex.EncodeToString(b)
+	schedule.Active = true
+	schedule.Scheduled = schedule.Next
+
+	err = updateRecordList(ctx, store, sessionId, storedb.DATA_SCHEDULES, func(schedules []ScheduleRecord) ([]ScheduleRecord, error) {
+		if len(schedules) >= schedulesSize {
+			return nil, ErrSchedulesFull
+		}
+		return append(schedules, schedule), nil
+	})
+	if err != nil {
+		return "", err
+	}
+	err = addScheduledSession(ctx, store, sessionId)
+	if err != nil {
+		return "", err
+	}
+	return schedule.Id, nil
+}
+
+// CancelSchedule removes the scheduled transfer with the given id from the account.
+func CancelSchedule(ctx context.Context, store DataStore, sessionId string, id string) error {
+	return updateRecordList(ctx, store, sessionId, storedb.DATA_SCHEDULES, func(schedules []ScheduleRecord) ([]ScheduleRecord, error) {
+		for i, v := range schedules {
+			if v.Id == id {
+				return append(schedules[:i], schedules[i+1:]...), nil
+			}
+		}
+		return nil, ErrScheduleNotFound
+	})
+}
+
+// ClaimScheduleRun claims the due run of the scheduled transfer with the given id, whose
+// next run was read as next, by moving its next run to the first regular run after the
+// given time. Any runs missed while the scheduler was not running are skipped.
+//
+// It must be called before the transfer is submitted, so that a transfer is never
+// submitted twice for the same run should the outcome fail to be recorded.
+//
+// Returns false if the run was not claimed, because the scheduled transfer was suspended
+// or its next run was changed since it was read, such as by another run of the scheduler.
+// The claim is only atomic for callers in the same process, so that the scheduler must run
+// inside the single server process serving the userdata store.
+func ClaimScheduleRun(ctx context.Context, store DataStore, sessionId string, id string, next int64, now time.Time) (ScheduleRecord, bool, error) {
+	var claimed bool
+	r, err := updateSchedule(ctx, store, sessionId, id, func(v *ScheduleRecord) bool {
+		if !v.Active || v.Next != next {
+			return false
+		}
+		// a retry of a failed run does not move the regular runs
+		t := time.Unix(v.Next, 0)
+		if v.Scheduled > v.Next {
+			t = time.Unix(v.Scheduled, 0)
+		}
+		for !t.After(now) {
+			t = NextScheduleRun(v.Interval, t)
+		}
+		v.Next = t.Unix()
+		v.Scheduled = v.Next
+		claimed = true
+		return true
+	})
+	return r, claimed, err
+}
+
+// RecordScheduleRun records the outcome of a run of the scheduled transfer with the given id.
+//
+// A failed run is tried again after scheduleRetryDelay, or at the next regular run if that
+// is sooner, until ScheduleMaxFailures consecutive runs have failed, after which the
+// scheduled transfer is suspended.
+//
+// The updated record is returned.
+func RecordScheduleRun(ctx context.Context, store DataStore, sessionId string, id string, now time.Time, trackingId string, runErr error) (ScheduleRecord, error) {
+	return updateSchedule(ctx, store, sessionId, id, func(v *ScheduleRecord) bool {
+		v.LastRun = now.Unix()
+		if runErr == nil {
+			v.LastStatus = ScheduleSubmitted
+			v.LastTrackingId = trackingId
+			v.Failures = 0
+			return true
+		}
+		v.LastStatus = ScheduleFailed
+		v.Failures++
+		if v.Failures >= ScheduleMaxFailures {
+			v.Active = false
+			return true
+		}
+		v.Next = now.Add(scheduleRetryDelay).Unix()
+		if v.Scheduled > 0 && v.Scheduled < v.Next {
+			v.Next = v.Scheduled
+		}
+		return true
+	})
+}
+
+// updateSchedule applies update to the scheduled transfer with the given id while holding the lock of
+// the scheduled transfers of the account. The record is left unchanged if update returns false.
+//
+// The resulting record is returned.
+func updateSchedule(ctx context.Context, store DataStore, sessionId string, id string, update func(*ScheduleRecord) bool) (ScheduleRecord, error) {
+	var r ScheduleRecord
+	err := updateRecordList(ctx, store, sessionId, storedb.DATA_SCHEDULES, func(schedules []ScheduleRecord) ([]ScheduleRecord, error) {
+		for i := range schedules {
+			if schedules[i].Id != id {
+				continue
+			}
+			changed := update(&schedules[i])
+			r = schedules[i]
+			if !changed {
+				return nil, errUnchanged
+			}
+			return schedules, nil
+		}
+		return nil, ErrScheduleNotFound
+	})
+	return r, err
+}
+
+// DueSchedules returns the scheduled transfers of the account that are due at the given time.
+func DueSchedules(ctx context.Context, store DataStore, sessionId string, now time.Time) ([]ScheduleRecord, error) {
+	schedules, err := ReadSchedules(ctx, store, sessionId)
+	if err != nil {
+		return nil, err
+	}
+	var r []ScheduleRecord
+	for _, v := range schedules {
+		if v.Due(now) {
+			r = append(r, v)
+		}
+	}
+	return r, nil
+}
+
+// ReadScheduledSessions retrieves the session ids of the accounts that have set up scheduled transfers.
+//
+// Accounts stay registered after their scheduled transfers are cancelled, until
+// removed with RemoveScheduledSession.
+//
+// The registrations are only serialized for callers in the same process.
+func ReadScheduledSessions(ctx context.Context, store DataStore) ([]string, error) {
+	v, err := store.Read(ctx, storedb.IndexKey(storedb.DATA_SCHEDULED_SESSIONS, ""))
+	if err != nil {
+		if visedb.IsNotFound(err) {
+			return []string{}, nil
+		}
+		return nil, err
+	}
+	if len(v) == 0 {
+		return []string{}, nil
+	}
+	return DecodeList[string](v)
+}
+
+func writeScheduledSessions(ctx context.Context, store DataStore, sessionIds []string) error {
+	v, err := EncodeList(sessionIds)
+	if err != nil {
+		return err
+	}
+	return store.Write(ctx, storedb.IndexKey(storedb.DATA_SCHEDULED_SESSIONS, ""), v)
+}
+
+func addScheduledSession(ctx context.Context, store DataStore, sessionId string) error {
+	unlock := lockKey(storedb.IndexKey(storedb.DATA_SCHEDULED_SESSIONS, ""))
+	defer unlock()
+	sessionIds, err := ReadScheduledSessions(ctx, store)
+	if err != nil {
+		return err
+	}
+	for _, v := range sessionIds {
+		if v == sessionId {
+			return nil
+		}
+	}
+	return writeScheduledSessions(ctx, store, append(sessionIds, sessionId))
+}
+
+// RemoveScheduledSession unregisters the account if it no longer has any scheduled transfers.
+func RemoveScheduledSession(ctx context.Context, store DataStore, sessionId string) error {
+	unlock := lockKey(storedb.IndexKey(storedb.DATA_SCHEDULED_SESSIONS, ""))
+	defer unlock()
+	// a scheduled transfer may have been added since the caller last read them
+	schedules, err := ReadSchedules(ctx, store, sessionId)
+	if err != nil {
+		return err
+	}
+	if len(schedules) > 0 {
+		return nil
+	}
+	sessionIds, err := ReadScheduledSessions(ctx, store)
+	if err != nil {
+		return err
+	}
+	var r []string
+	for _, v := range sessionIds {
+		if v != sessionId {
+			r = append(r, v)
+		}
+	}
+	if len(r) == len(sessionIds) {
+		return nil
+	}
+	return writeScheduledSessions(ctx, store, r)
+}
@@ -0,0 +1,127 @@
+package store
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedules(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+	start := time.Date(2024, 10, 3, 7, 0, 0, 0, time.UTC)
+
+	schedule := ScheduleRecord{
+		RecipientInput: "0700000001",
+		Recipient:      "0x41c188d63Qa",
+		Amount:         "10",
+		Symbol:         "SRF",
+		TokenAddress:   "0xd4c288865Ce",
+		Value:          "10000000",
+		Interval:       ScheduleWeekly,
+		Next:           start.Unix(),
+	}
+	_, err := AddSchedule(ctx, store, sessionId, ScheduleRecord{Interval: "hourly"})
+	assert.Error(t, err)
+	id, err := AddSchedule(ctx, store, sessionId, schedule)
+	require.NoError(t, err)
+
+	sessionIds, err := ReadScheduledSessions(ctx, store)
+	require.NoError(t, err)
+	assert.Equal(t, []string{sessionId}, sessionIds)
+
+	due, err := DueSchedules(ctx, store, sessionId, start.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(due))
+	due, err = DueSchedules(ctx, store, sessionId, start)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(due))
+	assert.Equal(t, id, due[0].Id)
+
+	// runs missed by more than one interval are skipped
+	now := start.AddDate(0, 0, 8)
+	r, ok, err := ClaimScheduleRun(ctx, store, sessionId, id, start.Unix(), now)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, start.AddDate(0, 0, 14).Unix(), r.Next)
+
+	// a run is only claimed once
+	_, ok, err = ClaimScheduleRun(ctx, store, sessionId, id, start.Unix(), now)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	r, err = RecordScheduleRun(ctx, store, sessionId, id, now, "1234567890", nil)
+	require.NoError(t, err)
+	assert.Equal(t, ScheduleSubmitted, r.LastStatus)
+	assert.Equal(t, "1234567890", r.LastTrackingId)
+
+	// failed runs are retried
+	runErr := errors.New("connection reset")
+	for i := 1; i < ScheduleMaxFailures; i++ {
+		r, err = RecordScheduleRun(ctx, store, sessionId, id, now, "", runErr)
+		require.NoError(t, err)
+		assert.True(t, r.Active)
+		assert.Equal(t, now.Add(scheduleRetryDelay).Unix(), r.Next)
+	}
+
+	// a retry does not move the regular runs
+	now = now.Add(scheduleRetryDelay)
+	r, ok, err = ClaimScheduleRun(ctx, store, sessionId, id, now.Unix(), now)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, start.AddDate(0, 0, 14).Unix(), r.Next)
+	r, err = RecordScheduleRun(ctx, store, sessionId, id, now, "1234567891", nil)
+	require.NoError(t, err)
+	assert.Equal(t, 0, r.Failures)
+
+	// a failure close to the next regular run is retried at that run
+	now = start.AddDate(0, 0, 14).Add(-time.Minute)
+	r, err = RecordScheduleRun(ctx, store, sessionId, id, now, "", runErr)
+	require.NoError(t, err)
+	assert.True(t, r.Active)
+	assert.Equal(t, start.AddDate(0, 0, 14).Unix(), r.Next)
+
+	// the schedule is suspended after too many failed runs
+	for i := 1; i < ScheduleMaxFailures; i++ {
+		r, err = RecordScheduleRun(ctx, store, sessionId, id, now, "", runErr)
+		require.NoError(t, err)
+	}
+	assert.False(t, r.Active)
+	assert.Equal(t, ScheduleFailed, r.LastStatus)
+	due, err = DueSchedules(ctx, store, sessionId, now.AddDate(1, 0, 0))
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(due))
+
+	// accounts with scheduled transfers stay registered
+	err = RemoveScheduledSession(ctx, store, sessionId)
+	require.NoError(t, err)
+	sessionIds, err = ReadScheduledSessions(ctx, store)
+	require.NoError(t, err)
+	assert.Equal(t, []string{sessionId}, sessionIds)
+
+	err = CancelSchedule(ctx, store, sessionId, id)
+	require.NoError(t, err)
+	err = CancelSchedule(ctx, store, sessionId, id)
+	assert.Equal(t, ErrScheduleNotFound, err)
+
+	err = RemoveScheduledSession(ctx, store, sessionId)
+	require.NoError(t, err)
+	sessionIds, err = ReadScheduledSessions(ctx, store)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(sessionIds))
+}
+
+func TestSchedulesFull(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+
+	for i := 0; i < schedulesSize; i++ {
+		_, err := AddSchedule(ctx, store, sessionId, ScheduleRecord{Interval: ScheduleDaily})
+		require.NoError(t, err)
+	}
+	_, err := AddSchedule(ctx, store, sessionId, ScheduleRecord{Interval: ScheduleDaily})
+	assert.Equal(t, ErrSchedulesFull, err)
+}
@@ -0,0 +1,162 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"git.grassecon.net/grassrootseconomics/sarafu-api/remote/http"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/services/resilient"
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+const (
+	// window over which the daily spending limit applies.
+	SpendWindow = 24 * time.Hour
+	// margin allowed when comparing amounts against spending limits, to absorb float rounding.
+	spendTolerance = 1e-9
+)
+
+// SpendRecord is an amount of a voucher spent by the account.
+//
+// Symbol is kept as shown to the user. Amounts are attributed to vouchers by TokenAddress.
+type SpendRecord struct {
+	TokenAddress string  `json:"token_address"`
+	Symbol       string  `json:"symbol"`
+	Amount       float64 `json:"amount"`
+	Time         int64   `json:"time"`
+}
+
+// SpendLimits are the spending limits of an account for a single voucher, in voucher units.
+//
+// A limit of 0 means no limit.
+type SpendLimits struct {
+	Transaction float64
+	Daily       float64
+}
+
+// Limited returns true if any limit applies.
+func (l SpendLimits) Limited() bool {
+	return l.Transaction > 0 || l.Daily > 0
+}
+
+// Remaining returns the amount that can still be spent within SpendWindow, given the amount already spent.
+//
+// Returns false if there is no daily limit.
+func (l SpendLimits) Remaining(spent float64) (float64, bool) {
+	if l.Daily <= 0 {
+		return 0, false
+	}
+	if spent >= l.Daily {
+		return 0, true
+	}
+	return l.Daily - spent, true
+}
+
+// Allowance returns the largest amount that can be spent in a single transaction, given the amount
+// already spent within SpendWindow.
+//
+// Returns false if no limit applies.
+func (l SpendLimits) Allowance(spent float64) (float64, bool) {
+	if !l.Limited() {
+		return 0, false
+	}
+	allowance, ok := l.Remaining(spent)
+	if l.Transaction > 0 && (!ok || l.Transaction < allowance) {
+		allowance = l.Transaction
+	}
+	return allowance, true
+}
+
+// Allows returns true if a transaction of the given amount is within the limits, given the amount
+// already spent within SpendWindow.
+//
+// The amount is checked against the transaction limit, and the total against the amount that can
+// still be spent. They differ only when the same amount is sent to several recipients.
+func (l SpendLimits) Allows(spent float64, amount float64, total float64) bool {
+	if l.Transaction > 0 && amount > l.Transaction+spendTolerance {
+		return false
+	}
+	remaining, ok := l.Remaining(spent)
+	return !ok || total <= remaining+spendTolerance
+}
+
+// ReadSpend retrieves the amounts spent by the account within SpendWindow, oldest first.
+func ReadSpend(ctx context.Context, store DataStore, sessionId string) ([]SpendRecord, error) {
+	records, err := readRecordList[SpendRecord](ctx, store, sessionId, storedb.DATA_SPEND_LOG)
+	if err != nil {
+		return nil, err
+	}
+	return recentSpend(records, time.Now()), nil
+}
+
+func recentSpend(records []SpendRecord, now time.Time) []SpendRecord {
+	since := now.Add(-SpendWindow).Unix()
+	r := []SpendRecord{}
+	for _, v := range records {
+		if v.Time > since {
+			r = append(r, v)
+		}
+	}
+	return r
+}
+
+// DailySpend returns the amount of the voucher with the given token address spent by the account within SpendWindow.
+func DailySpend(ctx context.Context, store DataStore, sessionId string, tokenAddress string) (float64, error) {
+	records, err := ReadSpend(ctx, store, sessionId)
+	if err != nil {
+		return 0, err
+	}
+	return spentOf(records, tokenAddress), nil
+}
+
+func spentOf(records []SpendRecord, tokenAddress string) float64 {
+	var spent float64
+	for _, v := range records {
+		if strings.EqualFold(v.TokenAddress, tokenAddress) {
+			spent += v.Amount
+		}
+	}
+	return spent
+}
+
+// SpendWithin carries out a transaction of the account with fn, and records the amount of the voucher with
+// the given token address spent, unless the amount is over the given limits.
+//
+// The limits are checked and fn is called while holding the lock of the amounts spent by the account, so
+// that concurrent transactions cannot together exceed the limits.
+//
+// Returns false if the amount is over the limits, in which case fn is not called. If fn fails, its error is
+// returned. The amount is then only left unrecorded if the error shows that the transaction was rejected, as
+// after any other failure it may still have been carried out.
+func SpendWithin(ctx context.Context, store DataStore, sessionId string, record SpendRecord, limits SpendLimits, fn func() error) (bool, error) {
+	unlock := lockKey(storedb.EntryKey(sessionId, storedb.DATA_SPEND_LOG))
+	defer unlock()
+
+	records, err := ReadSpend(ctx, store, sessionId)
+	if err != nil {
+		return false, err
+	}
+	if !limits.Allows(spentOf(records, record.TokenAddress), record.Amount, record.Amount) {
+		return false, nil
+	}
+	fnErr := fn()
+	if fnErr != nil && rejected(fnErr) {
+		return true, fnErr
+	}
+
+	record.Time = time.Now().Unix()
+	err = writeRecordList(ctx, store, sessionId, storedb.DATA_SPEND_LOG, append(records, record))
+	if err != nil {
+		// the transaction may have been carried out, so failure to record it is only logged
+		logg.ErrorCtxf(ctx, "failed to record spent amount", "tokenAddress", record.TokenAddress, "amount", record.Amount, "error", err)
+	}
+	return true, fnErr
+}
+
+// rejected returns true if the error of a transaction shows that it was not carried out.
+func rejected(err error) bool {
+	var apiErr *http.APIError
+	return errors.As(err, &apiErr) || errors.Is(err, resilient.ErrServiceDegraded)
+}
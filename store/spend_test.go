@@ -0,0 +1,134 @@
+package store
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+
+	"git.grassecon.net/grassrootseconomics/sarafu-api/remote/http"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/services/resilient"
+)
+
+func TestSpendLimits(t *testing.T) {
+	limits := SpendLimits{}
+	assert.False(t, limits.Limited())
+	_, ok := limits.Allowance(10)
+	assert.False(t, ok)
+
+	limits = SpendLimits{Transaction: 50, Daily: 200}
+	assert.True(t, limits.Limited())
+	remaining, ok := limits.Remaining(180)
+	assert.True(t, ok)
+	assert.Equal(t, 20.0, remaining)
+	remaining, ok = limits.Remaining(250)
+	assert.True(t, ok)
+	assert.Equal(t, 0.0, remaining)
+
+	allowance, ok := limits.Allowance(100)
+	assert.True(t, ok)
+	assert.Equal(t, 50.0, allowance)
+	allowance, ok = limits.Allowance(180)
+	assert.True(t, ok)
+	assert.Equal(t, 20.0, allowance)
+
+	limits = SpendLimits{Transaction: 50}
+	_, ok = limits.Remaining(0)
+	assert.False(t, ok)
+	allowance, ok = limits.Allowance(1000)
+	assert.True(t, ok)
+	assert.Equal(t, 50.0, allowance)
+
+	limits = SpendLimits{Transaction: 50, Daily: 200}
+	assert.True(t, limits.Allows(150, 50, 50))
+	assert.False(t, limits.Allows(0, 60, 60))
+	assert.False(t, limits.Allows(100, 40, 120))
+}
+
+func TestSpendWithin(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+	srf := "0xd4c288865Ce"
+	cusd := "0x765DE816845"
+	limits := SpendLimits{Transaction: 10, Daily: 15}
+
+	spent, err := DailySpend(ctx, store, sessionId, srf)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, spent)
+
+	var c int
+	fn := func() error {
+		c++
+		return nil
+	}
+	ok, err := SpendWithin(ctx, store, sessionId, SpendRecord{TokenAddress: srf, Symbol: "SRF", Amount: 10}, limits, fn)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = SpendWithin(ctx, store, sessionId, SpendRecord{TokenAddress: srf, Symbol: "SRF", Amount: 2.5}, limits, fn)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	ok, err = SpendWithin(ctx, store, sessionId, SpendRecord{TokenAddress: cusd, Symbol: "cUSD", Amount: 1}, limits, fn)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// amounts over the limits are not spent
+	ok, err = SpendWithin(ctx, store, sessionId, SpendRecord{TokenAddress: srf, Symbol: "SRF", Amount: 3}, limits, fn)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = SpendWithin(ctx, store, sessionId, SpendRecord{TokenAddress: cusd, Symbol: "cUSD", Amount: 11}, limits, fn)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 3, c)
+
+	// rejected transactions are not recorded
+	_, err = SpendWithin(ctx, store, sessionId, SpendRecord{TokenAddress: cusd, Symbol: "cUSD", Amount: 1}, limits, func() error {
+		return &http.APIError{Code: "E01"}
+	})
+	assert.Error(t, err)
+	_, err = SpendWithin(ctx, store, sessionId, SpendRecord{TokenAddress: cusd, Symbol: "cUSD", Amount: 1}, limits, func() error {
+		return resilient.ErrServiceDegraded
+	})
+	assert.Error(t, err)
+
+	// transactions that failed otherwise may have been carried out, and are recorded
+	ok, err = SpendWithin(ctx, store, sessionId, SpendRecord{TokenAddress: cusd, Symbol: "cUSD", Amount: 1}, limits, func() error {
+		return errors.New("connection reset by peer")
+	})
+	assert.Error(t, err)
+	assert.True(t, ok)
+
+	// vouchers are told apart by token address, whatever the case
+	spent, err = DailySpend(ctx, store, sessionId, strings.ToLower(srf))
+	require.NoError(t, err)
+	assert.Equal(t, 12.5, spent)
+	spent, err = DailySpend(ctx, store, sessionId, cusd)
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, spent)
+
+	// only amounts spent within the window count
+	now := time.Now()
+	records := []SpendRecord{
+		{TokenAddress: srf, Symbol: "SRF", Amount: 5, Time: now.Add(-SpendWindow - time.Minute).Unix()},
+		{TokenAddress: srf, Symbol: "SRF", Amount: 3, Time: now.Add(-time.Hour).Unix()},
+	}
+	assert.Equal(t, []SpendRecord{records[1]}, recentSpend(records, now))
+}
+
+func TestAccountActivated(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+
+	_, ok, err := AccountActivated(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = MarkAccountActive(ctx, store, sessionId)
+	require.NoError(t, err)
+	activated, ok, err := AccountActivated(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, time.Since(activated) < time.Minute)
+}
@@ -98,76 +98,12 @@ func ReadSwapPreviewData(ctx context.Context, store DataStore, sessionId string)
 
 // GetSwapFromVoucherData retrieves and matches swap from voucher data
 func GetSwapFromVoucherData(ctx context.Context, store DataStore, sessionId string, input string) (*dataserviceapi.TokenHoldings, error) {
-	keys := []storedb.DataTyp{
-		storedb.DATA_POOL_FROM_SYMBOLS,
-		storedb.DATA_POOL_FROM_BALANCES,
-		storedb.DATA_POOL_FROM_DECIMALS,
-		storedb.DATA_POOL_FROM_ADDRESSES,
-	}
-	data := make(map[storedb.DataTyp]string)
-
-	for _, key := range keys {
-		value, err := store.ReadEntry(ctx, sessionId, key)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get data key %x: %v", key, err)
-		}
-		data[key] = string(value)
-	}
-
-	symbol, balance, decimal, address := MatchVoucher(input,
-		data[storedb.DATA_POOL_FROM_SYMBOLS],
-		data[storedb.DATA_POOL_FROM_BALANCES],
-		data[storedb.DATA_POOL_FROM_DECIMALS],
-		data[storedb.DATA_POOL_FROM_ADDRESSES],
-	)
-
-	if symbol == "" {
-		return nil, nil
-	}
-
-	return &dataserviceapi.TokenHoldings{
-		TokenSymbol:   string(symbol),
-		Balance:       string(balance),
-		TokenDecimals: string(decimal),
-		TokenAddress:  string(address),
-	}, nil
+	return getMatchingVoucher(ctx, store, sessionId, storedb.DATA_POOL_FROM_LIST, input)
 }
 
 // GetSwapToVoucherData retrieves and matches token data
 func GetSwapToVoucherData(ctx context.Context, store DataStore, sessionId string, input string) (*dataserviceapi.TokenHoldings, error) {
-	keys := []storedb.DataTyp{
-		storedb.DATA_POOL_TO_SYMBOLS,
-		storedb.DATA_POOL_TO_BALANCES,
-		storedb.DATA_POOL_TO_DECIMALS,
-		storedb.DATA_POOL_TO_ADDRESSES,
-	}
-	data := make(map[storedb.DataTyp]string)
-
-	for _, key := range keys {
-		value, err := store.ReadEntry(ctx, sessionId, key)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get data key %x: %v", key, err)
-		}
-		data[key] = string(value)
-	}
-
-	symbol, balance, decimal, address := MatchVoucher(input,
-		data[storedb.DATA_POOL_TO_SYMBOLS],
-		data[storedb.DATA_POOL_TO_BALANCES],
-		data[storedb.DATA_POOL_TO_DECIMALS],
-		data[storedb.DATA_POOL_TO_ADDRESSES],
-	)
-
-	if symbol == "" {
-		return nil, nil
-	}
-
-	return &dataserviceapi.TokenHoldings{
-		TokenSymbol:   string(symbol),
-		Balance:       string(balance),
-		TokenDecimals: string(decimal),
-		TokenAddress:  string(address),
-	}, nil
+	return getMatchingVoucher(ctx, store, sessionId, storedb.DATA_POOL_TO_LIST, input)
 }
 
 // UpdateSwapToVoucherData updates the active swap to voucher data in the DataStore.
@@ -181,13 +117,7 @@ func UpdateSwapToVoucherData(ctx context.Context, store DataStore, sessionId str
 	}
 
 	// Write active data
-	for key, value := range activeEntries {
-		if err := store.WriteEntry(ctx, sessionId, key, value); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return WriteEntries(ctx, store, sessionId, activeEntries)
 }
 
 // UpdateSwapFromVoucherData updates the active swap from voucher data in the DataStore.
@@ -202,13 +132,7 @@ func UpdateSwapFromVoucherData(ctx context.Context, store DataStore, sessionId s
 	}
 
 	// Write active data
-	for key, value := range activeEntries {
-		if err := store.WriteEntry(ctx, sessionId, key, value); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return WriteEntries(ctx, store, sessionId, activeEntries)
 }
 
 // ReadSwapFromVoucher retrieves the voucher being swapped into the pool (swap from)
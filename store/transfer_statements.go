@@ -6,122 +6,215 @@ import (
 	"strings"
 	"time"
 
+	visedb "git.defalsify.org/vise.git/db"
 	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
 	dataserviceapi "github.com/grassrootseconomics/ussd-data-service/pkg/api"
 )
 
-// TransferMetadata helps organize data fields
-type TransferMetadata struct {
-	Senders        string
-	Recipients     string
-	TransferValues string
-	Addresses      string
-	TxHashes       string
-	Dates          string
-	Symbols        string
-	Decimals       string
+const (
+	// number of transfers kept in the local transfer history of each account.
+	//
+	// The data service only provides the most recent transfers of an account, so the history
+	// is not complete: it holds the transfers seen from events since it was first backfilled,
+	// up to this number.
+	transferHistorySize = 100
+	// MintSender is the sender of minted tokens, as in the token transfer log of the mint.
+	MintSender = "0x0000000000000000000000000000000000000000"
+)
+
+// TransferRecord is a single transfer entry in a stored transaction list.
+type TransferRecord struct {
+	Sender          string    `json:"sender"`
+	Recipient       string    `json:"recipient"`
+	Value           string    `json:"value"`
+	ContractAddress string    `json:"contract_address"`
+	TxHash          string    `json:"tx_hash"`
+	Date            time.Time `json:"date"`
+	Symbol          string    `json:"symbol"`
+	Decimals        string    `json:"decimals"`
 }
 
-// ProcessTransfers converts transfers into formatted strings
-func ProcessTransfers(transfers []dataserviceapi.Last10TxResponse) TransferMetadata {
-	var data TransferMetadata
-	var senders, recipients, transferValues, addresses, txHashes, dates, symbols, decimals []string
+// ProcessTransfers converts transfers into transfer records
+func ProcessTransfers(transfers []dataserviceapi.Last10TxResponse) []TransferRecord {
+	records := make([]TransferRecord, 0, len(transfers))
 
 	for _, t := range transfers {
-		senders = append(senders, t.Sender)
-		recipients = append(recipients, t.Recipient)
-
-		// Scale down the amount
-		scaledBalance := ScaleDownBalance(t.TransferValue, t.TokenDecimals)
-		transferValues = append(transferValues, scaledBalance)
-
-		addresses = append(addresses, t.ContractAddress)
-		txHashes = append(txHashes, t.TxHash)
-		dates = append(dates, fmt.Sprintf("%s", t.DateBlock))
-		symbols = append(symbols, t.TokenSymbol)
-		decimals = append(decimals, t.TokenDecimals)
-	}
-
-	data.Senders = strings.Join(senders, "\n")
-	data.Recipients = strings.Join(recipients, "\n")
-	data.TransferValues = strings.Join(transferValues, "\n")
-	data.Addresses = strings.Join(addresses, "\n")
-	data.TxHashes = strings.Join(txHashes, "\n")
-	data.Dates = strings.Join(dates, "\n")
-	data.Symbols = strings.Join(symbols, "\n")
-	data.Decimals = strings.Join(decimals, "\n")
-
-	return data
+		records = append(records, TransferRecord{
+			Sender:    t.Sender,
+			Recipient: t.Recipient,
+			// Scale down the amount
+			Value:           ScaleDownBalance(t.TransferValue, t.TokenDecimals),
+			ContractAddress: t.ContractAddress,
+			TxHash:          t.TxHash,
+			Date:            t.DateBlock,
+			Symbol:          t.TokenSymbol,
+			Decimals:        t.TokenDecimals,
+		})
+	}
+
+	return records
 }
 
-// GetTransferData retrieves and matches transfer data
-// returns a formatted string of the full transaction/statement
-func GetTransferData(ctx context.Context, db storedb.PrefixDb, publicKey string, index int) (string, error) {
-	keys := []storedb.DataTyp{
-		storedb.DATA_TX_SENDERS,
-		storedb.DATA_TX_RECIPIENTS,
-		storedb.DATA_TX_VALUES,
-		storedb.DATA_TX_ADDRESSES,
-		storedb.DATA_TX_HASHES,
-		storedb.DATA_TX_DATES,
-		storedb.DATA_TX_SYMBOLS,
-	}
-	data := make(map[storedb.DataTyp]string)
-
-	for _, key := range keys {
-		value, err := db.Get(ctx, storedb.ToBytes(key))
-		if err != nil {
-			return "", fmt.Errorf("failed to get %s: %v", storedb.ToBytes(key), err)
+// Filters of the transaction statement.
+const (
+	StatementFilterAll      = "all"
+	StatementFilterSent     = "sent"
+	StatementFilterReceived = "received"
+	StatementFilterVoucher  = "voucher"
+)
+
+// FilterTransfers returns the transfers of the account with the given public key matching the filter.
+//
+// For StatementFilterVoucher, only transfers of the voucher with the given address are returned.
+// Any other filter returns all transfers.
+func FilterTransfers(transfers []TransferRecord, filter string, publicKey string, voucherAddress string) []TransferRecord {
+	var match func(TransferRecord) bool
+	switch filter {
+	case StatementFilterSent:
+		match = func(t TransferRecord) bool {
+			return strings.EqualFold(t.Sender, publicKey)
+		}
+	case StatementFilterReceived:
+		match = func(t TransferRecord) bool {
+			return !strings.EqualFold(t.Sender, publicKey)
+		}
+	case StatementFilterVoucher:
+		match = func(t TransferRecord) bool {
+			return strings.EqualFold(t.ContractAddress, voucherAddress)
+		}
+	default:
+		return transfers
+	}
+	r := []TransferRecord{}
+	for _, t := range transfers {
+		if match(t) {
+			r = append(r, t)
 		}
-		data[key] = string(value)
 	}
+	return r
+}
 
-	// Split the data
-	senders := strings.Split(string(data[storedb.DATA_TX_SENDERS]), "\n")
-	recipients := strings.Split(string(data[storedb.DATA_TX_RECIPIENTS]), "\n")
-	values := strings.Split(string(data[storedb.DATA_TX_VALUES]), "\n")
-	addresses := strings.Split(string(data[storedb.DATA_TX_ADDRESSES]), "\n")
-	hashes := strings.Split(string(data[storedb.DATA_TX_HASHES]), "\n")
-	dates := strings.Split(string(data[storedb.DATA_TX_DATES]), "\n")
-	syms := strings.Split(string(data[storedb.DATA_TX_SYMBOLS]), "\n")
+// TransferSummary returns the short statement of the transfer, naming the counterparty by the given label.
+func TransferSummary(t TransferRecord, publicKey string, label string) string {
+	transactionType := "Received"
+	party := "From"
+	if strings.EqualFold(t.Sender, publicKey) {
+		transactionType = "Sent"
+		party = "To"
+	}
+	return fmt.Sprintf("%s %s %s\n%s: %s\nDate: %s", transactionType, t.Value, t.Symbol, party, label, formatDate(t.Date))
+}
 
+// TransferDetail returns the formatted statement of the transfer at the given 1-based index.
+func TransferDetail(transfers []TransferRecord, publicKey string, index int) (string, error) {
 	// Check if index is within range
-	if index < 1 || index > len(senders) {
+	if index < 1 || index > len(transfers) {
 		return "", fmt.Errorf("transaction not found: index %d out of range", index)
 	}
 
 	// Adjust for 0-based indexing
-	i := index - 1
+	t := transfers[index-1]
 	transactionType := "Received"
-	party := fmt.Sprintf("From: %s", strings.TrimSpace(senders[i]))
-	if strings.TrimSpace(senders[i]) == publicKey {
+	party := fmt.Sprintf("From: %s", t.Sender)
+	if strings.EqualFold(t.Sender, publicKey) {
 		transactionType = "Sent"
-		party = fmt.Sprintf("To: %s", strings.TrimSpace(recipients[i]))
+		party = fmt.Sprintf("To: %s", t.Recipient)
 	}
 
-	formattedDate := formatDate(strings.TrimSpace(dates[i]))
-
 	// Build the full transaction detail
 	detail := fmt.Sprintf(
 		"%s %s %s\n%s\nContract address: %s\nTxhash: %s\nDate: %s",
 		transactionType,
-		strings.TrimSpace(values[i]),
-		strings.TrimSpace(syms[i]),
+		t.Value,
+		t.Symbol,
 		party,
-		strings.TrimSpace(addresses[i]),
-		strings.TrimSpace(hashes[i]),
-		formattedDate,
+		t.ContractAddress,
+		t.TxHash,
+		formatDate(t.Date),
 	)
 
 	return detail, nil
 }
 
-// Helper function to format date in desired output
-func formatDate(dateStr string) string {
-	parsedDate, err := time.Parse("2006-01-02 15:04:05 -0700 MST", dateStr)
+// ReadTransferHistory retrieves the local transfer history of the account, newest first.
+//
+// History written in the format preceding record lists is disregarded, and
+// reported as empty so that it is backfilled anew.
+func ReadTransferHistory(ctx context.Context, store DataStore, sessionId string) ([]TransferRecord, error) {
+	v, err := store.ReadEntry(ctx, sessionId, storedb.DATA_TRANSACTIONS)
+	if err != nil {
+		if visedb.IsNotFound(err) {
+			return []TransferRecord{}, nil
+		}
+		return nil, err
+	}
+	if len(v) == 0 {
+		return []TransferRecord{}, nil
+	}
+	transfers, err := DecodeList[TransferRecord](v)
 	if err != nil {
-		fmt.Println("Error parsing date:", err)
+		logg.WarnCtxf(ctx, "disregarding transfer history in unknown format", "session", sessionId, "error", err)
+		return []TransferRecord{}, nil
+	}
+	return transfers, nil
+}
+
+// identifies a transfer; a single transaction may contain several transfers.
+func transferKey(t TransferRecord) string {
+	return strings.ToLower(strings.Join([]string{t.TxHash, t.ContractAddress, t.Sender, t.Recipient}, "\x00"))
+}
+
+// AddTransfers adds transfers to the local transfer history of the account.
+//
+// The transfers, newest first, are placed before those already in the history,
+// skipping any already present. The oldest transfers are dropped once the history
+// exceeds its maximum size.
+//
+// Returns the number of transfers added.
+func AddTransfers(ctx context.Context, store DataStore, sessionId string, transfers []TransferRecord) (int, error) {
+	history, err := ReadTransferHistory(ctx, store, sessionId)
+	if err != nil {
+		return 0, err
+	}
+	seen := make(map[string]bool)
+	for _, t := range history {
+		if t.TxHash != "" {
+			seen[transferKey(t)] = true
+		}
+	}
+	var added []TransferRecord
+	for _, t := range transfers {
+		if t.TxHash != "" {
+			k := transferKey(t)
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+		}
+		added = append(added, t)
+	}
+	if len(added) == 0 {
+		return 0, nil
+	}
+	history = append(added, history...)
+	if len(history) > transferHistorySize {
+		history = history[:transferHistorySize]
+	}
+	v, err := EncodeList(history)
+	if err != nil {
+		return 0, err
+	}
+	err = store.WriteEntry(ctx, sessionId, storedb.DATA_TRANSACTIONS, v)
+	if err != nil {
+		return 0, err
+	}
+	return len(added), nil
+}
+
+// Helper function to format date in desired output
+func formatDate(date time.Time) string {
+	if date.IsZero() {
 		return ""
 	}
-	return parsedDate.Format("2006-01-02 03:04:05 PM")
+	return date.Format("2006-01-02 03:04:05 PM")
 }
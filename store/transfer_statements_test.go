@@ -0,0 +1,106 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/assert/v2"
+	"github.com/stretchr/testify/require"
+
+	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
+)
+
+func TestTransferHistory(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+	account := "0xd4c288865Ce0985a481Eef3be02443dF5E2e4Ea9"
+	token := "0x1324262343rfdGW23"
+	date := time.Date(2024, 10, 3, 7, 23, 12, 0, time.UTC)
+
+	// history in the earlier string format is disregarded
+	err := store.WriteEntry(ctx, sessionId, storedb.DATA_TRANSACTIONS, []byte("0 {foo bar}"))
+	require.NoError(t, err)
+	history, err := ReadTransferHistory(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, 0, len(history))
+
+	n, err := AddTransfers(ctx, store, sessionId, []TransferRecord{
+		{Sender: account, Recipient: "0x41c188d63Qa", Value: "1", ContractAddress: token, TxHash: "0xaaa", Date: date, Symbol: "SRF"},
+		{Sender: "0x41c188d63Qa", Recipient: account, Value: "2", ContractAddress: token, TxHash: "0xbbb", Date: date, Symbol: "SRF"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	// a repeated transfer is skipped, regardless of case
+	n, err = AddTransfers(ctx, store, sessionId, []TransferRecord{
+		{Sender: account, Recipient: "0x41c188d63Qa", Value: "1", ContractAddress: token, TxHash: "0xAAA", Date: date, Symbol: "SRF"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// another transfer of the same transaction is kept
+	n, err = AddTransfers(ctx, store, sessionId, []TransferRecord{
+		{Sender: "0x41c188d63Qa", Recipient: account, Value: "3", ContractAddress: "0xfff", TxHash: "0xaaa", Date: date.Add(time.Minute), Symbol: "USD"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	history, err = ReadTransferHistory(ctx, store, sessionId)
+	require.NoError(t, err)
+	require.Equal(t, 3, len(history))
+	assert.Equal(t, "3", history[0].Value)
+	assert.Equal(t, "1", history[1].Value)
+	assert.Equal(t, "2", history[2].Value)
+
+	s, err := TransferDetail(history, account, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "Sent 1 SRF\nTo: 0x41c188d63Qa\nContract address: 0x1324262343rfdGW23\nTxhash: 0xaaa\nDate: 2024-10-03 07:23:12 AM", s)
+}
+
+func TestTransferHistoryCap(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "+254712345678"
+
+	for i := 0; i < transferHistorySize+5; i++ {
+		_, err := AddTransfers(ctx, store, sessionId, []TransferRecord{
+			{TxHash: fmt.Sprintf("0x%d", i), Value: fmt.Sprintf("%d", i)},
+		})
+		require.NoError(t, err)
+	}
+	history, err := ReadTransferHistory(ctx, store, sessionId)
+	require.NoError(t, err)
+	assert.Equal(t, transferHistorySize, len(history))
+	assert.Equal(t, fmt.Sprintf("%d", transferHistorySize+4), history[0].Value)
+	assert.Equal(t, "5", history[len(history)-1].Value)
+}
+
+func TestFilterTransfers(t *testing.T) {
+	account := "0xd4c288865Ce0985a481Eef3be02443dF5E2e4Ea9"
+	transfers := []TransferRecord{
+		{Sender: account, Recipient: "0x41c188d63Qa", Value: "1", ContractAddress: "0xaaa"},
+		{Sender: "0x41c188d63Qa", Recipient: account, Value: "2", ContractAddress: "0xbbb"},
+		{Sender: strings.ToLower(account), Recipient: "0x41c188d63Qa", Value: "3", ContractAddress: "0xBBB"},
+	}
+
+	assert.Equal(t, 3, len(FilterTransfers(transfers, StatementFilterAll, account, "")))
+	assert.Equal(t, 3, len(FilterTransfers(transfers, "foo", account, "")))
+
+	r := FilterTransfers(transfers, StatementFilterSent, account, "")
+	require.Equal(t, 2, len(r))
+	assert.Equal(t, "1", r[0].Value)
+	assert.Equal(t, "3", r[1].Value)
+
+	r = FilterTransfers(transfers, StatementFilterReceived, account, "")
+	require.Equal(t, 1, len(r))
+	assert.Equal(t, "2", r[0].Value)
+
+	r = FilterTransfers(transfers, StatementFilterVoucher, account, "0xbbb")
+	require.Equal(t, 2, len(r))
+	assert.Equal(t, "2", r[0].Value)
+	assert.Equal(t, "3", r[1].Value)
+
+	r = FilterTransfers(transfers, StatementFilterVoucher, account, "")
+	assert.Equal(t, 0, len(r))
+}
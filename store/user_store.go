@@ -13,29 +13,83 @@ import (
 // TODO: Rename interface, "datastore" is redundant naming and too general
 type DataStore interface {
 	visedb.Db
+	Read(ctx context.Context, key db.Key) ([]byte, error)
+	Write(ctx context.Context, key db.Key, value []byte) error
 	ReadEntry(ctx context.Context, sessionId string, typ db.DataTyp) ([]byte, error)
 	WriteEntry(ctx context.Context, sessionId string, typ db.DataTyp, value []byte) error
+	ReadIndex(ctx context.Context, typ db.DataTyp, value string) ([]byte, error)
+	WriteIndex(ctx context.Context, typ db.DataTyp, value string, sessionId []byte) error
+	Begin(ctx context.Context) *Batch
 }
 
 type UserDataStore struct {
 	visedb.Db
 }
 
-// ReadEntry retrieves an entry to the userdata store.
-func (store *UserDataStore) ReadEntry(ctx context.Context, sessionId string, typ db.DataTyp) ([]byte, error) {
+// Read retrieves the entry of the given key from the userdata store.
+func (store *UserDataStore) Read(ctx context.Context, key db.Key) ([]byte, error) {
 	store.SetPrefix(visedb.DATATYPE_USERDATA)
-	store.SetSession(sessionId)
-	k := storedb.ToBytes(typ)
-	return store.Get(ctx, k)
+	store.SetSession(key.Session())
+	return store.Get(ctx, key.Bytes())
 }
 
-// WriteEntry adds an entry to the userdata store.
-// BUG: this uses sessionId twice
-func (store *UserDataStore) WriteEntry(ctx context.Context, sessionId string, typ db.DataTyp, value []byte) error {
+// Write adds the entry of the given key to the userdata store.
+func (store *UserDataStore) Write(ctx context.Context, key db.Key, value []byte) error {
 	store.SetPrefix(visedb.DATATYPE_USERDATA)
-	store.SetSession(sessionId)
-	k := storedb.ToBytes(typ)
-	return store.Put(ctx, k, value)
+	store.SetSession(key.Session())
+	return store.Put(ctx, key.Bytes(), value)
+}
+
+// ReadEntry retrieves an entry of the account of the given session from the userdata store.
+//
+// Menu entries not yet migrated are read from their legacy key.
+func (store *UserDataStore) ReadEntry(ctx context.Context, sessionId string, typ db.DataTyp) ([]byte, error) {
+	return store.readLegacy(ctx, storedb.EntryKey(sessionId, typ))
+}
+
+// WriteEntry adds an entry of the account of the given session to the userdata store.
+func (store *UserDataStore) WriteEntry(ctx context.Context, sessionId string, typ db.DataTyp, value []byte) error {
+	return store.Write(ctx, storedb.EntryKey(sessionId, typ), value)
+}
+
+// ReadIndex retrieves the session id indexed by the given value.
+//
+// Index entries not yet migrated are read from their legacy key.
+func (store *UserDataStore) ReadIndex(ctx context.Context, typ db.DataTyp, value string) ([]byte, error) {
+	return store.readLegacy(ctx, storedb.IndexKey(typ, value))
+}
+
+// readLegacy retrieves the entry of the given key, falling back to the legacy
+// key of index and menu entries if it is not found.
+//
+// This keeps a store written before scoped keys were introduced readable until
+// the key migration has been run. Writes always go to the scoped key, which then
+// takes precedence.
+func (store *UserDataStore) readLegacy(ctx context.Context, key db.Key) ([]byte, error) {
+	v, err := store.Read(ctx, key)
+	if err == nil || key.Scope == db.SCOPE_ACCOUNT || !visedb.IsNotFound(err) {
+		return v, err
+	}
+	v, lerr := store.Read(ctx, key.Legacy())
+	if lerr != nil {
+		if visedb.IsNotFound(lerr) {
+			return nil, err
+		}
+		return nil, lerr
+	}
+	return v, nil
+}
+
+// WriteIndex adds an index entry from the given value to a session id.
+func (store *UserDataStore) WriteIndex(ctx context.Context, typ db.DataTyp, value string, sessionId []byte) error {
+	return store.Write(ctx, storedb.IndexKey(typ, value), sessionId)
+}
+
+// Begin starts a batch of writes to the userdata store.
+//
+// The batch must be completed with either Commit or Rollback.
+func (store *UserDataStore) Begin(ctx context.Context) *Batch {
+	return NewBatch(ctx, store)
 }
 
 func StoreToPrefixDb(userStore *UserDataStore, pfx []byte) storedb.PrefixDb {
@@ -65,11 +119,7 @@ func IdentityFromAddress(ctx context.Context, userStore *UserDataStore, address
 
 // load matching session from address from db store.
 func getSessionIdByAddress(ctx context.Context, userStore *UserDataStore, address string) (string, error) {
-	// TODO: replace with userdatastore when double sessionid issue fixed
-	//r, err := store.ReadEntry(ctx, address, common.DATA_PUBLIC_KEY_REVERSE)
-	userStore.Db.SetPrefix(visedb.DATATYPE_USERDATA)
-	userStore.Db.SetSession(address)
-	r, err := userStore.Db.Get(ctx, storedb.PackKey(storedb.DATA_PUBLIC_KEY_REVERSE, []byte{}))
+	r, err := userStore.ReadIndex(ctx, storedb.DATA_PUBLIC_KEY_REVERSE, address)
 	if err != nil {
 		return "", err
 	}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"math/big"
+	"strconv"
 	"strings"
 
+	"git.defalsify.org/vise.git/db"
 	"git.defalsify.org/vise.git/logging"
 	storedb "git.grassecon.net/grassrootseconomics/sarafu-vise/store/db"
 	dataserviceapi "github.com/grassrootseconomics/ussd-data-service/pkg/api"
@@ -20,14 +22,6 @@ var symbolReplacements = map[string]string{
 	"USD₮": "USDT",
 }
 
-// VoucherMetadata helps organize data fields
-type VoucherMetadata struct {
-	Symbols   string
-	Balances  string
-	Decimals  string
-	Addresses string
-}
-
 // sanitizeSymbol replaces known invalid token symbols with normalized ones
 func sanitizeSymbol(symbol string) string {
 	if replacement, ok := symbolReplacements[symbol]; ok {
@@ -36,31 +30,124 @@ func sanitizeSymbol(symbol string) string {
 	return symbol
 }
 
-// ProcessVouchers converts holdings into formatted strings
-func ProcessVouchers(holdings []dataserviceapi.TokenHoldings) VoucherMetadata {
-	var data VoucherMetadata
-	var symbols, balances, decimals, addresses []string
+// legacyVoucherKeys maps voucher list keys to the keys of the
+// symbol, balance, decimals and address lists of the legacy format.
+var legacyVoucherKeys = map[storedb.DataTyp][4]storedb.DataTyp{
+	storedb.DATA_VOUCHER_LIST: {
+		storedb.DATA_VOUCHER_SYMBOLS,
+		storedb.DATA_VOUCHER_BALANCES,
+		storedb.DATA_VOUCHER_DECIMALS,
+		storedb.DATA_VOUCHER_ADDRESSES,
+	},
+	storedb.DATA_ORDERED_VOUCHER_LIST: {
+		storedb.DATA_ORDERED_VOUCHER_SYMBOLS,
+		storedb.DATA_ORDERED_VOUCHER_BALANCES,
+		storedb.DATA_ORDERED_VOUCHER_DECIMALS,
+		storedb.DATA_ORDERED_VOUCHER_ADDRESSES,
+	},
+	storedb.DATA_POOL_FROM_LIST: {
+		storedb.DATA_POOL_FROM_SYMBOLS,
+		storedb.DATA_POOL_FROM_BALANCES,
+		storedb.DATA_POOL_FROM_DECIMALS,
+		storedb.DATA_POOL_FROM_ADDRESSES,
+	},
+	storedb.DATA_POOL_TO_LIST: {
+		storedb.DATA_POOL_TO_SYMBOLS,
+		storedb.DATA_POOL_TO_BALANCES,
+		storedb.DATA_POOL_TO_DECIMALS,
+		storedb.DATA_POOL_TO_ADDRESSES,
+	},
+}
+
+// ProcessVouchers converts holdings into voucher records
+func ProcessVouchers(holdings []dataserviceapi.TokenHoldings) []VoucherRecord {
+	vouchers := make([]VoucherRecord, 0, len(holdings))
+
+	for _, h := range holdings {
+		vouchers = append(vouchers, VoucherRecord{
+			// normalize token symbol before use
+			Symbol: sanitizeSymbol(h.TokenSymbol),
+			// Scale down the balance
+			Balance:  ScaleDownBalance(h.Balance, h.TokenDecimals),
+			Decimals: h.TokenDecimals,
+			Address:  h.TokenAddress,
+		})
+	}
 
-	for i, h := range holdings {
-		// normalize token symbol before use
-		cleanSymbol := sanitizeSymbol(h.TokenSymbol)
+	return vouchers
+}
 
-		symbols = append(symbols, fmt.Sprintf("%d:%s", i+1, cleanSymbol))
+// ToTokenHoldings converts the voucher record to the api token holdings representation.
+func (v VoucherRecord) ToTokenHoldings() *dataserviceapi.TokenHoldings {
+	return &dataserviceapi.TokenHoldings{
+		TokenSymbol:   v.Symbol,
+		Balance:       v.Balance,
+		TokenDecimals: v.Decimals,
+		TokenAddress:  v.Address,
+	}
+}
 
-		// Scale down the balance
-		scaledBalance := ScaleDownBalance(h.Balance, h.TokenDecimals)
+// WriteVoucherList stores the voucher records as a single entry under the given list key.
+func WriteVoucherList(ctx context.Context, store DataStore, sessionId string, typ storedb.DataTyp, vouchers []VoucherRecord) error {
+	v, err := EncodeList(vouchers)
+	if err != nil {
+		return err
+	}
+	return store.WriteEntry(ctx, sessionId, typ, v)
+}
 
-		balances = append(balances, fmt.Sprintf("%d:%s", i+1, scaledBalance))
-		decimals = append(decimals, fmt.Sprintf("%d:%s", i+1, h.TokenDecimals))
-		addresses = append(addresses, fmt.Sprintf("%d:%s", i+1, h.TokenAddress))
+// ReadVoucherList retrieves the voucher records stored under the given list key.
+//
+// If the list has not been written yet, the records are read from the legacy
+// format entries instead.
+//
+// An empty entry, as left by a rolled back batch or a deleted account, counts as
+// not written. Legacy entries missing in that case are read as empty lists.
+func ReadVoucherList(ctx context.Context, store DataStore, sessionId string, typ storedb.DataTyp) ([]VoucherRecord, error) {
+	v, err := store.ReadEntry(ctx, sessionId, typ)
+	if err == nil && len(v) > 0 {
+		return DecodeList[VoucherRecord](v)
+	}
+	if err != nil && !db.IsNotFound(err) {
+		return nil, fmt.Errorf("failed to get data key %x: %v", typ, err)
+	}
+	cleared := err == nil
+	keys, ok := legacyVoucherKeys[typ]
+	if !ok {
+		if cleared {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get data key %x: %v", typ, err)
 	}
+	return readLegacyVoucherList(ctx, store, sessionId, keys, cleared)
+}
+
+// readLegacyVoucherList reads voucher records from the legacy parallel "<index>:<value>" lists.
+//
+// If cleared is set, missing lists are read as empty.
+func readLegacyVoucherList(ctx context.Context, store DataStore, sessionId string, keys [4]storedb.DataTyp, cleared bool) ([]VoucherRecord, error) {
+	var data [4][]string
 
-	data.Symbols = strings.Join(symbols, "\n")
-	data.Balances = strings.Join(balances, "\n")
-	data.Decimals = strings.Join(decimals, "\n")
-	data.Addresses = strings.Join(addresses, "\n")
+	for i, key := range keys {
+		value, err := store.ReadEntry(ctx, sessionId, key)
+		if err != nil {
+			if !cleared || !db.IsNotFound(err) {
+				return nil, fmt.Errorf("failed to get data key %x: %v", key, err)
+			}
+		}
+		data[i] = splitLegacyList(string(value), true)
+	}
 
-	return data
+	vouchers := make([]VoucherRecord, 0, len(data[0]))
+	for i, sym := range data[0] {
+		vouchers = append(vouchers, VoucherRecord{
+			Symbol:   sym,
+			Balance:  legacyItem(data[1], i),
+			Decimals: legacyItem(data[2], i),
+			Address:  legacyItem(data[3], i),
+		})
+	}
+	return vouchers, nil
 }
 
 func ScaleDownBalance(balance, decimals string) string {
@@ -85,108 +172,40 @@ func ScaleDownBalance(balance, decimals string) string {
 
 // GetVoucherData retrieves and matches voucher data
 func GetVoucherData(ctx context.Context, store DataStore, sessionId string, input string) (*dataserviceapi.TokenHoldings, error) {
-	keys := []storedb.DataTyp{
-		storedb.DATA_VOUCHER_SYMBOLS,
-		storedb.DATA_VOUCHER_BALANCES,
-		storedb.DATA_VOUCHER_DECIMALS,
-		storedb.DATA_VOUCHER_ADDRESSES,
-	}
-	data := make(map[storedb.DataTyp]string)
-
-	for _, key := range keys {
-		value, err := store.ReadEntry(ctx, sessionId, key)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get data key %x: %v", key, err)
-		}
-		data[key] = string(value)
-	}
-
-	symbol, balance, decimal, address := MatchVoucher(input,
-		data[storedb.DATA_VOUCHER_SYMBOLS],
-		data[storedb.DATA_VOUCHER_BALANCES],
-		data[storedb.DATA_VOUCHER_DECIMALS],
-		data[storedb.DATA_VOUCHER_ADDRESSES],
-	)
-
-	if symbol == "" {
-		return nil, nil
-	}
-
-	return &dataserviceapi.TokenHoldings{
-		TokenSymbol:   string(symbol),
-		Balance:       string(balance),
-		TokenDecimals: string(decimal),
-		TokenAddress:  string(address),
-	}, nil
+	return getMatchingVoucher(ctx, store, sessionId, storedb.DATA_VOUCHER_LIST, input)
 }
 
 // GetOrderedVoucherData retrieves and matches ordered voucher data
 func GetOrderedVoucherData(ctx context.Context, store DataStore, sessionId string, input string) (*dataserviceapi.TokenHoldings, error) {
-	keys := []storedb.DataTyp{
-		storedb.DATA_ORDERED_VOUCHER_SYMBOLS,
-		storedb.DATA_ORDERED_VOUCHER_BALANCES,
-		storedb.DATA_ORDERED_VOUCHER_DECIMALS,
-		storedb.DATA_ORDERED_VOUCHER_ADDRESSES,
-	}
-	data := make(map[storedb.DataTyp]string)
+	return getMatchingVoucher(ctx, store, sessionId, storedb.DATA_ORDERED_VOUCHER_LIST, input)
+}
 
-	for _, key := range keys {
-		value, err := store.ReadEntry(ctx, sessionId, key)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get data key %x: %v", key, err)
-		}
-		data[key] = string(value)
+// getMatchingVoucher retrieves the voucher list stored under the given key and matches it against the input.
+//
+// Returns nil if no voucher matches.
+func getMatchingVoucher(ctx context.Context, store DataStore, sessionId string, typ storedb.DataTyp, input string) (*dataserviceapi.TokenHoldings, error) {
+	vouchers, err := ReadVoucherList(ctx, store, sessionId, typ)
+	if err != nil {
+		return nil, err
 	}
 
-	symbol, balance, decimal, address := MatchVoucher(input,
-		data[storedb.DATA_ORDERED_VOUCHER_SYMBOLS],
-		data[storedb.DATA_ORDERED_VOUCHER_BALANCES],
-		data[storedb.DATA_ORDERED_VOUCHER_DECIMALS],
-		data[storedb.DATA_ORDERED_VOUCHER_ADDRESSES],
-	)
-
-	if symbol == "" {
+	voucher, ok := MatchVoucher(input, vouchers)
+	if !ok {
 		return nil, nil
 	}
 
-	return &dataserviceapi.TokenHoldings{
-		TokenSymbol:   string(symbol),
-		Balance:       string(balance),
-		TokenDecimals: string(decimal),
-		TokenAddress:  string(address),
-	}, nil
+	return voucher.ToTokenHoldings(), nil
 }
 
-// MatchVoucher finds the matching voucher symbol, balance, decimals and contract address based on the input.
-func MatchVoucher(input, symbols, balances, decimals, addresses string) (symbol, balance, decimal, address string) {
-	// case for invalid input with no current symbols
-	if symbols == "" {
-		return
-	}
-	symList := strings.Split(symbols, "\n")
-	balList := strings.Split(balances, "\n")
-	decList := strings.Split(decimals, "\n")
-	addrList := strings.Split(addresses, "\n")
-
-	logg.Tracef("found", "symlist", symList, "syms", symbols, "input", input)
-	for i, sym := range symList {
-		parts := strings.SplitN(sym, ":", 2)
-
-		if input == parts[0] || strings.EqualFold(input, parts[1]) {
-			symbol = parts[1]
-			if i < len(balList) {
-				balance = strings.SplitN(balList[i], ":", 2)[1]
-			}
-			if i < len(decList) {
-				decimal = strings.SplitN(decList[i], ":", 2)[1]
-			}
-			if i < len(addrList) {
-				address = strings.SplitN(addrList[i], ":", 2)[1]
-			}
-			break
+// MatchVoucher finds the voucher matching the input, either by its 1-based list index or by its symbol.
+func MatchVoucher(input string, vouchers []VoucherRecord) (VoucherRecord, bool) {
+	logg.Tracef("found", "vouchers", vouchers, "input", input)
+	for i, v := range vouchers {
+		if input == strconv.Itoa(i+1) || strings.EqualFold(input, v.Symbol) {
+			return v, true
 		}
 	}
-	return
+	return VoucherRecord{}, false
 }
 
 // StoreTransactionVoucher saves voucher metadata in the DataStore.
@@ -231,42 +250,33 @@ func UpdateVoucherData(ctx context.Context, store DataStore, sessionId string, d
 	}
 
 	// Write active data
-	for key, value := range activeEntries {
-		if err := store.WriteEntry(ctx, sessionId, key, value); err != nil {
-			return err
-		}
-	}
-
-	return nil
+	return WriteEntries(ctx, store, sessionId, activeEntries)
 }
 
 // FormatVoucherList combines the voucher symbols with their balances (SRF 0.11)
-func FormatVoucherList(ctx context.Context, symbolsData, balancesData string) []string {
-	symbols := strings.Split(symbolsData, "\n")
-	balances := strings.Split(balancesData, "\n")
-
+func FormatVoucherList(ctx context.Context, vouchers []VoucherRecord) []string {
 	var combined []string
-	for i := 0; i < len(symbols) && i < len(balances); i++ {
-		symbolParts := strings.SplitN(symbols[i], ":", 2)
-		balanceParts := strings.SplitN(balances[i], ":", 2)
-
-		if len(symbolParts) == 2 && len(balanceParts) == 2 {
-			index := strings.TrimSpace(symbolParts[0])
-			symbol := strings.TrimSpace(symbolParts[1])
-			rawBalance := strings.TrimSpace(balanceParts[1])
-
-			formattedBalance, err := TruncateDecimalString(rawBalance, 2)
-			if err != nil {
-				logg.ErrorCtxf(ctx, "failed to format balance", "balance", rawBalance, "error", err)
-				formattedBalance = rawBalance
-			}
-
-			combined = append(combined, fmt.Sprintf("%s: %s %s", index, symbol, formattedBalance))
+	for i, v := range vouchers {
+		formattedBalance, err := TruncateDecimalString(v.Balance, 2)
+		if err != nil {
+			logg.ErrorCtxf(ctx, "failed to format balance", "balance", v.Balance, "error", err)
+			formattedBalance = v.Balance
 		}
+
+		combined = append(combined, fmt.Sprintf("%d: %s %s", i+1, v.Symbol, formattedBalance))
 	}
 	return combined
 }
 
+// FormatVoucherSymbols returns the voucher symbols as a newline separated "<index>:<symbol>" list.
+func FormatVoucherSymbols(vouchers []VoucherRecord) string {
+	var symbols []string
+	for i, v := range vouchers {
+		symbols = append(symbols, fmt.Sprintf("%d:%s", i+1, v.Symbol))
+	}
+	return strings.Join(symbols, "\n")
+}
+
 // AddDecimalStrings adds two decimal numbers represented as strings
 // and returns the result as a string without losing precision.
 func AddDecimalStrings(a, b string) string {
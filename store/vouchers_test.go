@@ -33,28 +33,32 @@ func InitializeTestDb(t *testing.T) (context.Context, *UserDataStore) {
 }
 
 func TestMatchVoucher(t *testing.T) {
-	symbols := "1:SRF\n2:MILO"
-	balances := "1:100\n2:200"
-	decimals := "1:6\n2:4"
-	addresses := "1:0xd4c288865Ce\n2:0x41c188d63Qa"
+	vouchers := []VoucherRecord{
+		{Symbol: "SRF", Balance: "100", Decimals: "6", Address: "0xd4c288865Ce"},
+		{Symbol: "MILO", Balance: "200", Decimals: "4", Address: "0x41c188d63Qa"},
+	}
 
-	// Test for valid voucher
-	symbol, balance, decimal, address := MatchVoucher("2", symbols, balances, decimals, addresses)
+	// Test for valid voucher by index
+	voucher, ok := MatchVoucher("2", vouchers)
 
 	// Assertions for valid voucher
-	assert.Equal(t, "MILO", symbol)
-	assert.Equal(t, "200", balance)
-	assert.Equal(t, "4", decimal)
-	assert.Equal(t, "0x41c188d63Qa", address)
+	assert.True(t, ok)
+	assert.Equal(t, "MILO", voucher.Symbol)
+	assert.Equal(t, "200", voucher.Balance)
+	assert.Equal(t, "4", voucher.Decimals)
+	assert.Equal(t, "0x41c188d63Qa", voucher.Address)
+
+	// Test for valid voucher by symbol
+	voucher, ok = MatchVoucher("srf", vouchers)
+	assert.True(t, ok)
+	assert.Equal(t, "SRF", voucher.Symbol)
 
 	// Test for non-existent voucher
-	symbol, balance, decimal, address = MatchVoucher("3", symbols, balances, decimals, addresses)
+	voucher, ok = MatchVoucher("3", vouchers)
 
 	// Assertions for non-match
-	assert.Equal(t, "", symbol)
-	assert.Equal(t, "", balance)
-	assert.Equal(t, "", decimal)
-	assert.Equal(t, "", address)
+	assert.False(t, ok)
+	assert.Equal(t, VoucherRecord{}, voucher)
 }
 
 func TestProcessVouchers(t *testing.T) {
@@ -64,11 +68,10 @@ func TestProcessVouchers(t *testing.T) {
 		{TokenAddress: "0x41c143d63Qa", TokenSymbol: "USD₮", TokenDecimals: "6", Balance: "300000000"},
 	}
 
-	expectedResult := VoucherMetadata{
-		Symbols:   "1:SRF\n2:MILO\n3:USDT",
-		Balances:  "1:100\n2:20000\n3:300",
-		Decimals:  "1:6\n2:4\n3:6",
-		Addresses: "1:0xd4c288865Ce\n2:0x41c188d63Qa\n3:0x41c143d63Qa",
+	expectedResult := []VoucherRecord{
+		{Symbol: "SRF", Balance: "100", Decimals: "6", Address: "0xd4c288865Ce"},
+		{Symbol: "MILO", Balance: "20000", Decimals: "4", Address: "0x41c188d63Qa"},
+		{Symbol: "USDT", Balance: "300", Decimals: "6", Address: "0x41c143d63Qa"},
 	}
 
 	result := ProcessVouchers(holdings)
@@ -76,6 +79,36 @@ func TestProcessVouchers(t *testing.T) {
 	assert.Equal(t, expectedResult, result)
 }
 
+func TestVoucherList(t *testing.T) {
+	ctx, store := InitializeTestDb(t)
+	sessionId := "session123"
+
+	vouchers := []VoucherRecord{
+		{Symbol: "SRF:X", Balance: "100", Decimals: "6", Address: "0xd4c288865Ce"},
+		{Symbol: "MILO", Balance: "200", Decimals: "4", Address: "0x41c188d63Qa"},
+	}
+
+	err := WriteVoucherList(ctx, store, sessionId, storedb.DATA_VOUCHER_LIST, vouchers)
+	require.NoError(t, err)
+
+	result, err := ReadVoucherList(ctx, store, sessionId, storedb.DATA_VOUCHER_LIST)
+	require.NoError(t, err)
+	assert.Equal(t, vouchers, result)
+
+	// the list takes precedence over the legacy format entries
+	err = store.WriteEntry(ctx, sessionId, storedb.DATA_VOUCHER_SYMBOLS, []byte("1:FOO"))
+	require.NoError(t, err)
+
+	voucher, err := GetVoucherData(ctx, store, sessionId, "SRF:X")
+	require.NoError(t, err)
+	assert.Equal(t, "0xd4c288865Ce", voucher.TokenAddress)
+}
+
+func TestDecodeListUnknownVersion(t *testing.T) {
+	_, err := DecodeList[VoucherRecord]([]byte(`{"v":42,"items":[]}`))
+	assert.Error(t, err)
+}
+
 func TestGetVoucherData(t *testing.T) {
 	ctx, store := InitializeTestDb(t)
 	sessionId := "session123"
@@ -18,6 +18,7 @@ import (
 	"git.grassecon.net/grassrootseconomics/sarafu-api/testutil/testservice"
 	"git.grassecon.net/grassrootseconomics/sarafu-vise/config"
 	"git.grassecon.net/grassrootseconomics/sarafu-vise/handlers"
+	"git.grassecon.net/grassrootseconomics/sarafu-vise/store"
 	"git.grassecon.net/grassrootseconomics/sarafu-vise/testutil/testtag"
 	"git.grassecon.net/grassrootseconomics/visedriver/storage"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -113,6 +114,8 @@ func TestEngine(sessionId string) (engine.Engine, func(), chan bool, *persist.Pe
 		os.Exit(1)
 	}
 
+	userdataDb = userDataStore
+
 	logdb, err := menuStorageService.GetLogDb(ctx, userDataStore, "test-db-logs", "user-data")
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "get log db error: %v\n", err)
@@ -175,3 +178,21 @@ func TestEngine(sessionId string) (engine.Engine, func(), chan bool, *persist.Pe
 	}
 	return en, cleanFn, eventChannel, pe, parser
 }
+
+// ResetAuthorization discards the PIN entries of the session in the userdata store of the last test engine,
+// so that the next menu node asking for the PIN prompts for it regardless of the authorization policy.
+func ResetAuthorization(ctx context.Context, sessionId string) error {
+	return store.RevokeAuthorization(ctx, &store.UserDataStore{Db: userdataDb}, sessionId)
+}
+
+// SetRole grants or revokes the role of the session in the userdata store of the last test engine.
+func SetRole(ctx context.Context, sessionId string, role string, grant bool) error {
+	userStore := &store.UserDataStore{Db: userdataDb}
+	var err error
+	if grant {
+		_, err = store.GrantRole(ctx, userStore, sessionId, role)
+	} else {
+		_, err = store.RevokeRole(ctx, userStore, sessionId, role)
+	}
+	return err
+}
@@ -16,6 +16,8 @@ var (
 	logg       = logging.NewVanilla().WithDomain("sarafu-vise.testutil").WithContextKey("SessionId")
 	conns      storage.Conns
 	resourceDb db.Db
+	// userdata store of the last test engine.
+	userdataDb db.Db
 	baseDir    = testdataloader.GetBasePath()
 	scriptDir  = path.Join(baseDir, "services", "registration")
 	override   = config.NewOverride()